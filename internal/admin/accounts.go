@@ -0,0 +1,163 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/auth"
+	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/models"
+)
+
+// ErrBadCredentials covers an unknown account, a wrong password, a disabled
+// account and an invalid token alike, so callers can't tell which
+var ErrBadCredentials = errors.New("invalid admin credentials")
+
+// AccountManager handles named admin accounts and their sessions
+type AccountManager struct {
+	db     database.AdminStore
+	tokens *auth.TokenSigner
+}
+
+// NewAccountManager creates a new account manager
+func NewAccountManager(db database.AdminStore, tokens *auth.TokenSigner) *AccountManager {
+	return &AccountManager{db: db, tokens: tokens}
+}
+
+// Bootstrap makes sure username exists as a superuser with password
+// It lets ADMIN_USERNAME/ADMIN_PASSWORD create the first account; if the
+// account exists with a different password, the configured one wins.
+func (am *AccountManager) Bootstrap(ctx context.Context, username, password string) error {
+	account, err := am.db.GetAdminAccount(ctx, username)
+	if err != nil {
+		return fmt.Errorf("failed to look up %s: %w", username, err)
+	}
+
+	if account == nil {
+		if _, err := am.CreateAccount(ctx, username, password, models.AdminRoleSuperuser); err != nil {
+			return err
+		}
+		slog.Info("Created bootstrap admin account", "username", username)
+		return nil
+	}
+
+	if auth.CheckPassword(account.PasswordHash, password) {
+		return nil
+	}
+	if err := am.SetPassword(ctx, account, password); err != nil {
+		return err
+	}
+	slog.Warn("Bootstrap admin password changed to match ADMIN_PASSWORD", "username", username)
+	return nil
+}
+
+// CreateAccount creates an active admin account
+func (am *AccountManager) CreateAccount(ctx context.Context, username, password string, role models.AdminRole) (*models.AdminAccount, error) {
+	if username == "" {
+		return nil, fmt.Errorf("username is required")
+	}
+	if !role.Valid() {
+		return nil, fmt.Errorf("unknown role %q", role)
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	account := &models.AdminAccount{
+		Username:          username,
+		PasswordHash:      hash,
+		Role:              role,
+		IsActive:          true,
+		PasswordChangedAt: now,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	if err := am.db.CreateAdminAccount(ctx, account); err != nil {
+		return nil, fmt.Errorf("failed to create admin account: %w", err)
+	}
+	return account, nil
+}
+
+// GetAccount returns the named account, or nil
+func (am *AccountManager) GetAccount(ctx context.Context, username string) (*models.AdminAccount, error) {
+	return am.db.GetAdminAccount(ctx, username)
+}
+
+// ListAccounts returns every admin account
+func (am *AccountManager) ListAccounts(ctx context.Context) ([]*models.AdminAccount, error) {
+	return am.db.ListAdminAccounts(ctx)
+}
+
+// UpdateAccount saves a changed role or active flag
+func (am *AccountManager) UpdateAccount(ctx context.Context, account *models.AdminAccount) error {
+	if !account.Role.Valid() {
+		return fmt.Errorf("unknown role %q", account.Role)
+	}
+	account.UpdatedAt = time.Now()
+	return am.db.UpdateAdminAccount(ctx, account)
+}
+
+// SetPassword changes an account's password, ending its existing sessions
+func (am *AccountManager) SetPassword(ctx context.Context, account *models.AdminAccount, password string) error {
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	account.PasswordHash = hash
+	account.PasswordChangedAt = now
+	account.UpdatedAt = now
+	return am.db.UpdateAdminAccount(ctx, account)
+}
+
+// Authenticate checks a username and password
+func (am *AccountManager) Authenticate(ctx context.Context, username, password string) (*models.AdminAccount, error) {
+	account, err := am.db.GetAdminAccount(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		auth.WastePasswordCheck(password)
+		return nil, ErrBadCredentials
+	}
+	if !auth.CheckPassword(account.PasswordHash, password) || !account.IsActive {
+		return nil, ErrBadCredentials
+	}
+	return account, nil
+}
+
+// Login authenticates and issues a session token
+func (am *AccountManager) Login(ctx context.Context, username, password string) (*models.AdminAccount, string, time.Time, error) {
+	account, err := am.Authenticate(ctx, username, password)
+	if err != nil {
+		return nil, "", time.Time{}, err
+	}
+	token, expiresAt, err := am.tokens.Issue(account.Username, time.Now())
+	if err != nil {
+		return nil, "", time.Time{}, fmt.Errorf("failed to issue token: %w", err)
+	}
+	return account, token, expiresAt, nil
+}
+
+// VerifyToken returns the account a session token belongs to
+// The account is read on every call, so disabling it or changing its role or
+// password takes effect immediately.
+func (am *AccountManager) VerifyToken(ctx context.Context, token string) (*models.AdminAccount, error) {
+	username, issuedAt, err := am.tokens.Verify(token, time.Now())
+	if err != nil {
+		return nil, ErrBadCredentials
+	}
+	account, err := am.db.GetAdminAccount(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil || !account.IsActive || issuedAt.Before(account.PasswordChangedAt.Truncate(time.Millisecond)) {
+		return nil, ErrBadCredentials
+	}
+	return account, nil
+}
@@ -0,0 +1,143 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/leader"
+	"github.com/akua/bsv-broadcaster/internal/logging"
+	"github.com/akua/bsv-broadcaster/internal/models"
+)
+
+// AnchorAction is the audit action of an on-chain anchor of the chain head
+const AnchorAction = "audit.anchor"
+
+// anchorPrefix starts every anchor's OP_RETURN payload
+const anchorPrefix = "bsv-broadcaster/audit-anchor"
+
+// PublishFunc queues data for broadcast in an OP_RETURN, returning the
+// request UUID and txid
+type PublishFunc func(ctx context.Context, data []byte) (string, string, error)
+
+// AnchorPayload is the OP_RETURN data anchoring the entry at seq with hash:
+// "bsv-broadcaster/audit-anchor <seq> <hash>"
+func AnchorPayload(seq int64, hash string) []byte {
+	return []byte(fmt.Sprintf("%s %d %s", anchorPrefix, seq, hash))
+}
+
+// ParseAnchorPayload reads the seq and hash back out of an AnchorPayload
+func ParseAnchorPayload(data []byte) (int64, string, error) {
+	fields := strings.Fields(string(data))
+	if len(fields) != 3 || fields[0] != anchorPrefix {
+		return 0, "", fmt.Errorf("not an audit anchor")
+	}
+	seq, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid anchor seq: %w", err)
+	}
+	return seq, fields[2], nil
+}
+
+// Anchorer periodically publishes the audit chain's head hash through the
+// train, so the log can later be shown not to have been rewritten
+// Only the elected leader anchors, and only when the chain has grown since
+// the last anchor. Each anchor is itself recorded in the chain.
+type Anchorer struct {
+	audit   *AuditLog
+	publish PublishFunc
+	elector *leader.Elector
+	every   time.Duration
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewAnchorer creates an anchorer publishing every interval
+func NewAnchorer(audit *AuditLog, publish PublishFunc, elector *leader.Elector, every time.Duration) *Anchorer {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Anchorer{
+		audit:   audit,
+		publish: publish,
+		elector: elector,
+		every:   every,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Start begins anchoring in the background
+func (an *Anchorer) Start() {
+	an.wg.Add(1)
+	go an.run()
+	slog.Info("Audit anchorer started", "every", an.every)
+}
+
+// Stop stops anchoring
+func (an *Anchorer) Stop() {
+	an.cancel()
+	an.wg.Wait()
+	slog.Info("Audit anchorer stopped")
+}
+
+// run is the anchoring loop
+func (an *Anchorer) run() {
+	defer an.wg.Done()
+
+	ticker := time.NewTicker(an.every)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !an.elector.IsLeader() {
+				continue
+			}
+			if err := an.Anchor(an.ctx); err != nil {
+				slog.Error("Audit anchoring failed", logging.Err(err))
+			}
+		case <-an.ctx.Done():
+			return
+		}
+	}
+}
+
+// Anchor publishes the current head, unless it is already an anchor
+func (an *Anchorer) Anchor(ctx context.Context) error {
+	head, err := an.audit.Head(ctx)
+	if err != nil {
+		return err
+	}
+	if head == nil || head.Action == AnchorAction {
+		return nil
+	}
+
+	requestUUID, txid, err := an.publish(ctx, AnchorPayload(head.Seq, head.Hash))
+	if err != nil {
+		return fmt.Errorf("failed to publish anchor for seq %d: %w", head.Seq, err)
+	}
+
+	err = an.audit.Record(ctx, &models.AuditEntry{
+		Actor:  "system",
+		Action: AnchorAction,
+		Target: strconv.FormatInt(head.Seq, 10),
+		Details: map[string]string{
+			"anchored_seq":  strconv.FormatInt(head.Seq, 10),
+			"anchored_hash": head.Hash,
+			"txid":          txid,
+			"request_uuid":  requestUUID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "Anchored audit log", "seq", head.Seq, "hash", head.Hash,
+		logging.KeyTxID, txid, logging.KeyRequestUUID, requestUUID)
+	return nil
+}
@@ -0,0 +1,243 @@
+package admin
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/models"
+)
+
+// GenesisHash is the PrevHash of the first entry in the chain
+const GenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// maxAppendAttempts bounds retries when replicas race for the next Seq
+const maxAppendAttempts = 10
+
+// verifyPage is how many entries Verify reads per store round trip
+const verifyPage = 500
+
+// AuditLog records admin actions and security events in the store's
+// append-only, hash-chained audit log
+type AuditLog struct {
+	db database.AdminStore
+	mu sync.Mutex // Serialises this replica's appends; other replicas are caught by ErrAuditSeqTaken
+}
+
+// NewAuditLog creates a new audit log
+func NewAuditLog(db database.AdminStore) *AuditLog {
+	return &AuditLog{db: db}
+}
+
+// Record appends an entry at the head of the chain
+func (a *AuditLog) Record(ctx context.Context, entry *models.AuditEntry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Stores keep milliseconds; hash the time that will be read back
+	entry.At = time.Now().Truncate(time.Millisecond)
+
+	for attempt := 1; ; attempt++ {
+		head, err := a.db.LastAuditEntry(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read audit chain head: %w", err)
+		}
+
+		entry.Seq, entry.PrevHash = 1, GenesisHash
+		if head != nil {
+			entry.Seq, entry.PrevHash = head.Seq+1, head.Hash
+		}
+		entry.Hash = EntryHash(entry)
+
+		err = a.db.AppendAuditEntry(ctx, entry)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, database.ErrAuditSeqTaken) || attempt == maxAppendAttempts {
+			return fmt.Errorf("failed to record audit entry: %w", err)
+		}
+	}
+}
+
+// List returns the newest entries, optionally for one actor
+func (a *AuditLog) List(ctx context.Context, actor string, limit int) ([]models.AuditEntry, error) {
+	return a.db.ListAuditEntries(ctx, actor, limit)
+}
+
+// Head returns the newest chained entry, or nil if the chain is empty
+func (a *AuditLog) Head(ctx context.Context) (*models.AuditEntry, error) {
+	return a.db.LastAuditEntry(ctx)
+}
+
+// EntryHash is the hex SHA-256 of an entry's PrevHash and contents
+// Contents are hashed as JSON with a fixed field order (map keys sorted), and
+// an empty Details hashes the same as none.
+func EntryHash(entry *models.AuditEntry) string {
+	details := entry.Details
+	if len(details) == 0 {
+		details = nil
+	}
+
+	content, _ := json.Marshal(struct {
+		Seq      int64             `json:"seq"`
+		PrevHash string            `json:"prev_hash"`
+		At       int64             `json:"at"`
+		Actor    string            `json:"actor"`
+		Role     models.AdminRole  `json:"role"`
+		Action   string            `json:"action"`
+		Target   string            `json:"target"`
+		Status   int               `json:"status"`
+		RemoteIP string            `json:"remote_ip"`
+		Details  map[string]string `json:"details"`
+	}{
+		Seq:      entry.Seq,
+		PrevHash: entry.PrevHash,
+		At:       entry.At.UnixMilli(),
+		Actor:    entry.Actor,
+		Role:     entry.Role,
+		Action:   entry.Action,
+		Target:   entry.Target,
+		Status:   entry.Status,
+		RemoteIP: entry.RemoteIP,
+		Details:  details,
+	})
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditVerification is the outcome of walking the audit chain
+type AuditVerification struct {
+	Valid    bool          `json:"valid"`
+	Entries  int64         `json:"entries"`            // Chained entries checked
+	HeadSeq  int64         `json:"headSeq"`            // Last entry that checked out
+	HeadHash string        `json:"headHash"`           // Its hash
+	BrokenAt int64         `json:"brokenAt,omitempty"` // First Seq that doesn't check out, or an anchored one that changed
+	Problem  string        `json:"problem,omitempty"`
+	Anchors  []AuditAnchor `json:"anchors"`
+}
+
+// AuditAnchor is a head hash published on-chain, as recorded in the log
+type AuditAnchor struct {
+	Seq          int64  `json:"seq"`          // The anchor's own entry
+	AnchoredSeq  int64  `json:"anchoredSeq"`  // The head it published
+	AnchoredHash string `json:"anchoredHash"` // That head's hash, as published
+	TxID         string `json:"txid"`
+	Matches      bool   `json:"matches"` // AnchoredHash is still the chain's hash at AnchoredSeq
+}
+
+// Verify walks the chain from the first entry, recomputing every hash
+// It stops at the first entry that doesn't check out: a missing Seq, a
+// PrevHash that isn't the previous entry's hash, or contents that don't hash
+// to Hash. Anchors recorded in the chain are checked against it too.
+//
+// A chain that verifies was not edited in place, but whoever can write the
+// store could rebuild it end to end; only anchors published on-chain, or
+// head hashes kept elsewhere, rule that out. See ExpectHash.
+func (a *AuditLog) Verify(ctx context.Context) (*AuditVerification, error) {
+	result := &AuditVerification{Valid: true, HeadHash: GenesisHash, Anchors: []AuditAnchor{}}
+
+	for {
+		entries, err := a.db.ListAuditChain(ctx, result.HeadSeq, verifyPage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit chain: %w", err)
+		}
+
+		for i := range entries {
+			entry := &entries[i]
+			if problem := checkLink(entry, result.HeadSeq, result.HeadHash); problem != "" {
+				result.Valid = false
+				result.BrokenAt = result.HeadSeq + 1
+				result.Problem = problem
+				return result, nil
+			}
+
+			result.Entries++
+			result.HeadSeq, result.HeadHash = entry.Seq, entry.Hash
+
+			if entry.Action == AnchorAction {
+				result.Anchors = append(result.Anchors, anchorFromEntry(entry))
+			}
+		}
+
+		if len(entries) < verifyPage {
+			break
+		}
+	}
+
+	for i := range result.Anchors {
+		anchor := &result.Anchors[i]
+		hash, err := a.hashAt(ctx, anchor.AnchoredSeq)
+		if err != nil {
+			return nil, err
+		}
+		anchor.Matches = hash != "" && hash == anchor.AnchoredHash
+		if !anchor.Matches && result.Valid {
+			result.Valid = false
+			result.BrokenAt = anchor.AnchoredSeq
+			result.Problem = fmt.Sprintf("anchor at seq %d published a hash for seq %d that no longer matches", anchor.Seq, anchor.AnchoredSeq)
+		}
+	}
+
+	return result, nil
+}
+
+// ExpectHash checks that the chain entry at seq has hash, e.g. one read from
+// an anchor transaction on-chain
+func (a *AuditLog) ExpectHash(ctx context.Context, seq int64, hash string) error {
+	got, err := a.hashAt(ctx, seq)
+	if err != nil {
+		return err
+	}
+	if got == "" {
+		return fmt.Errorf("no audit entry at seq %d", seq)
+	}
+	if got != strings.ToLower(hash) {
+		return fmt.Errorf("audit entry %d hashes to %s, expected %s", seq, got, hash)
+	}
+	return nil
+}
+
+// hashAt returns the stored hash of the entry at seq, or "" if there is none
+func (a *AuditLog) hashAt(ctx context.Context, seq int64) (string, error) {
+	entries, err := a.db.ListAuditChain(ctx, seq-1, 1)
+	if err != nil {
+		return "", fmt.Errorf("failed to read audit entry %d: %w", seq, err)
+	}
+	if len(entries) == 0 || entries[0].Seq != seq {
+		return "", nil
+	}
+	return entries[0].Hash, nil
+}
+
+// checkLink reports what is wrong with entry as the successor of prevSeq
+func checkLink(entry *models.AuditEntry, prevSeq int64, prevHash string) string {
+	switch {
+	case entry.Seq != prevSeq+1:
+		return fmt.Sprintf("entry %d is missing (next is %d)", prevSeq+1, entry.Seq)
+	case entry.PrevHash != prevHash:
+		return fmt.Sprintf("entry %d does not link to entry %d", entry.Seq, prevSeq)
+	case EntryHash(entry) != entry.Hash:
+		return fmt.Sprintf("entry %d was modified after it was written", entry.Seq)
+	}
+	return ""
+}
+
+// anchorFromEntry reads an anchor's details
+func anchorFromEntry(entry *models.AuditEntry) AuditAnchor {
+	anchoredSeq, _ := strconv.ParseInt(entry.Details["anchored_seq"], 10, 64)
+	return AuditAnchor{
+		Seq:          entry.Seq,
+		AnchoredSeq:  anchoredSeq,
+		AnchoredHash: entry.Details["anchored_hash"],
+		TxID:         entry.Details["txid"],
+	}
+}
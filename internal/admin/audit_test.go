@@ -0,0 +1,236 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/models"
+)
+
+// editableAuditStore keeps the audit chain in a slice that tests can edit
+// behind the AuditLog's back, the way someone with write access to the
+// store could
+type editableAuditStore struct {
+	database.AdminStore
+	entries []models.AuditEntry
+}
+
+func (s *editableAuditStore) AppendAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	s.entries = append(s.entries, *entry)
+	return nil
+}
+
+func (s *editableAuditStore) LastAuditEntry(ctx context.Context) (*models.AuditEntry, error) {
+	if len(s.entries) == 0 {
+		return nil, nil
+	}
+	last := s.entries[len(s.entries)-1]
+	return &last, nil
+}
+
+func (s *editableAuditStore) ListAuditChain(ctx context.Context, afterSeq int64, limit int) ([]models.AuditEntry, error) {
+	var entries []models.AuditEntry
+	for _, entry := range s.entries {
+		if entry.Seq > afterSeq && len(entries) < limit {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// entry returns the stored entry at seq for editing
+func (s *editableAuditStore) entry(t *testing.T, seq int64) *models.AuditEntry {
+	t.Helper()
+
+	for i := range s.entries {
+		if s.entries[i].Seq == seq {
+			return &s.entries[i]
+		}
+	}
+	t.Fatalf("no audit entry at seq %d", seq)
+	return nil
+}
+
+// newAuditChain records five admin actions, anchors the head (seq 6
+// anchoring seq 5) and records one more action (seq 7)
+func newAuditChain(t *testing.T) (*AuditLog, *editableAuditStore) {
+	t.Helper()
+	ctx := context.Background()
+
+	store := &editableAuditStore{AdminStore: database.NewMemoryStore()}
+	audit := NewAuditLog(store)
+	record := func(i int) {
+		err := audit.Record(ctx, &models.AuditEntry{
+			Actor:    "alice",
+			Role:     models.AdminRoleOperator,
+			Action:   "POST /admin/clients/register",
+			Target:   fmt.Sprintf("client-%d", i),
+			Status:   200,
+			RemoteIP: "10.0.0.1",
+			Details:  map[string]string{"name": fmt.Sprintf("client %d", i)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 1; i <= 5; i++ {
+		record(i)
+	}
+
+	publish := func(ctx context.Context, data []byte) (string, string, error) {
+		return "anchor-request", strings.Repeat("ab", 32), nil
+	}
+	if err := NewAnchorer(audit, publish, nil, time.Hour).Anchor(ctx); err != nil {
+		t.Fatal(err)
+	}
+	record(7)
+	return audit, store
+}
+
+func TestAuditChainVerifies(t *testing.T) {
+	audit, store := newAuditChain(t)
+
+	result, err := audit.Verify(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Valid || result.Entries != 7 || result.HeadSeq != 7 || result.HeadHash != store.entry(t, 7).Hash {
+		t.Fatalf("verification = %+v, want all 7 entries valid", result)
+	}
+	if len(result.Anchors) != 1 || !result.Anchors[0].Matches || result.Anchors[0].AnchoredSeq != 5 {
+		t.Fatalf("anchors = %+v, want one matching anchor of seq 5", result.Anchors)
+	}
+
+	if err := audit.ExpectHash(context.Background(), 5, store.entry(t, 5).Hash); err != nil {
+		t.Fatalf("expect the stored hash: %v", err)
+	}
+	if err := audit.ExpectHash(context.Background(), 5, store.entry(t, 4).Hash); err == nil {
+		t.Fatal("expect another entry's hash succeeded")
+	}
+}
+
+func TestAuditVerifyFindsTampering(t *testing.T) {
+	tests := []struct {
+		name         string
+		tamper       func(t *testing.T, store *editableAuditStore)
+		wantBrokenAt int64
+		wantProblem  string
+	}{
+		{
+			name: "edited contents",
+			tamper: func(t *testing.T, store *editableAuditStore) {
+				store.entry(t, 3).Target = "client-99"
+			},
+			wantBrokenAt: 3,
+			wantProblem:  "modified",
+		},
+		{
+			name: "edited contents with the entry rehashed",
+			tamper: func(t *testing.T, store *editableAuditStore) {
+				entry := store.entry(t, 3)
+				entry.Status = 403
+				entry.Hash = EntryHash(entry)
+			},
+			wantBrokenAt: 4,
+			wantProblem:  "does not link",
+		},
+		{
+			name: "deleted entry",
+			tamper: func(t *testing.T, store *editableAuditStore) {
+				store.entries = append(store.entries[:2], store.entries[3:]...)
+			},
+			wantBrokenAt: 3,
+			wantProblem:  "missing",
+		},
+		{
+			name: "edited anchor",
+			tamper: func(t *testing.T, store *editableAuditStore) {
+				store.entry(t, 6).Details["anchored_hash"] = strings.Repeat("00", 32)
+			},
+			wantBrokenAt: 6,
+			wantProblem:  "modified",
+		},
+		{
+			// Links hold, but the hash published on-chain for seq 5 doesn't
+			name: "chain rebuilt under an anchor",
+			tamper: func(t *testing.T, store *editableAuditStore) {
+				prevHash := store.entry(t, 2).Hash
+				for seq := int64(3); seq <= 7; seq++ {
+					entry := store.entry(t, seq)
+					if seq == 3 {
+						entry.Target = "client-99"
+					}
+					entry.PrevHash = prevHash
+					entry.Hash = EntryHash(entry)
+					prevHash = entry.Hash
+				}
+			},
+			wantBrokenAt: 5,
+			wantProblem:  "anchor at seq 6",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit, store := newAuditChain(t)
+			tt.tamper(t, store)
+
+			result, err := audit.Verify(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Valid {
+				t.Fatalf("verification = %+v, want invalid", result)
+			}
+			if result.BrokenAt != tt.wantBrokenAt || !strings.Contains(result.Problem, tt.wantProblem) {
+				t.Fatalf("broken at %d (%s), want %d (%s)", result.BrokenAt, result.Problem, tt.wantBrokenAt, tt.wantProblem)
+			}
+		})
+	}
+}
+
+func TestEntryHashKnownAnswer(t *testing.T) {
+	// sha256 of {"seq":1,"prev_hash":"00..00","at":1700000000000,"actor":"alice",
+	// "role":"operator","action":"POST /admin/clients/register","target":"client-1",
+	// "status":200,"remote_ip":"10.0.0.1","details":{"name":"acme","tier":"pilot"}}
+	first := &models.AuditEntry{
+		Seq:      1,
+		PrevHash: GenesisHash,
+		At:       time.UnixMilli(1_700_000_000_000),
+		Actor:    "alice",
+		Role:     models.AdminRoleOperator,
+		Action:   "POST /admin/clients/register",
+		Target:   "client-1",
+		Status:   200,
+		RemoteIP: "10.0.0.1",
+		Details:  map[string]string{"tier": "pilot", "name": "acme"},
+	}
+	const firstHash = "3e950933b2903c881c17a74c3946083686e10e8f96993249610c89170c3e57a9"
+	if got := EntryHash(first); got != firstHash {
+		t.Fatalf("EntryHash = %s, want %s", got, firstHash)
+	}
+
+	// Empty details hash as null, the same as none
+	second := &models.AuditEntry{
+		Seq:      2,
+		PrevHash: firstHash,
+		At:       time.UnixMilli(1_700_000_001_000),
+		Actor:    "system",
+		Action:   AnchorAction,
+		Target:   strconv.Itoa(1),
+		Details:  map[string]string{},
+	}
+	const secondHash = "72c905c4a740f7f9c796a90e6fab9a986361d3e1a31f55461aea72efcbc3530b"
+	if got := EntryHash(second); got != secondHash {
+		t.Fatalf("EntryHash with empty details = %s, want %s", got, secondHash)
+	}
+	second.Details = nil
+	if got := EntryHash(second); got != secondHash {
+		t.Fatalf("EntryHash with no details = %s, want %s", got, secondHash)
+	}
+}
@@ -13,16 +13,30 @@ import (
 
 // ClientManager handles client registration and management
 type ClientManager struct {
-	db *database.Database
+	db       database.ClientStore
+	counters database.RateCounterStore // Per-second and per-minute rate limits
 }
 
 // NewClientManager creates a new client manager
-func NewClientManager(db *database.Database) *ClientManager {
-	return &ClientManager{db: db}
+func NewClientManager(db database.ClientStore, counters database.RateCounterStore) *ClientManager {
+	return &ClientManager{db: db, counters: counters}
 }
 
 // RegisterClient creates a new API client with authentication credentials
 func (cm *ClientManager) RegisterClient(ctx context.Context, name, publicKey, siteOrigin string, maxDailyTx int) (string, *models.Client, error) {
+	// Keys are stored uncompressed, and belong to one client, so mutual
+	// authentication can find the client by identity key
+	if publicKey != "" {
+		normalized, err := auth.NormalizePublicKey(publicKey)
+		if err != nil {
+			return "", nil, err
+		}
+		if _, err := cm.db.GetClientByPublicKey(ctx, normalized); err == nil {
+			return "", nil, fmt.Errorf("public key is already registered to another client")
+		}
+		publicKey = normalized
+	}
+
 	// Generate API key
 	rawKey, hashedKey, err := auth.GenerateAPIKey()
 	if err != nil {
@@ -65,13 +79,6 @@ func (cm *ClientManager) GetClientByID(ctx context.Context, clientID primitive.O
 	return cm.db.GetClientByID(ctx, clientID)
 }
 
-// IncrementClientTxCount increments the transaction count for a client
-// Resets the counter if it's a new day
-func (cm *ClientManager) IncrementClientTxCount(ctx context.Context, clientID primitive.ObjectID) error {
-	today := time.Now().Format("2006-01-02")
-	return cm.db.IncrementClientTxCount(ctx, clientID, today)
-}
-
 // DeactivateClient disables a client's API access
 func (cm *ClientManager) DeactivateClient(ctx context.Context, clientID primitive.ObjectID) error {
 	return cm.db.UpdateClientStatus(ctx, clientID, false)
@@ -0,0 +1,153 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Rate limit windows
+const (
+	WindowSecond = "second"
+	WindowMinute = "minute"
+	WindowDay    = "day"
+)
+
+// Quota is a request's charge against its client's rate limits, describing
+// the window closest to running out (or the one that ran out)
+type Quota struct {
+	Window    string    // WindowSecond, WindowMinute or WindowDay
+	Limit     int       // Requests the window allows
+	Remaining int       // Requests left in the window
+	Reset     time.Time // When the window starts over
+
+	cm       *ClientManager
+	clientID primitive.ObjectID
+	counters []string // Rate counter keys charged
+	day      string   // Day the daily quota was charged, if it was
+}
+
+// ReserveQuota charges a request against the client's per-second and
+// per-minute limits and its daily quota, all counted atomically in the store
+// so concurrent requests and replicas can't overshoot them
+// When a limit is used up it returns database.ErrQuotaExceeded, with the
+// Quota naming that window and nothing charged.
+func (cm *ClientManager) ReserveQuota(ctx context.Context, client *models.Client, now time.Time) (*Quota, error) {
+	q := &Quota{cm: cm, clientID: client.ID, Remaining: math.MaxInt}
+
+	windows := []struct {
+		name   string
+		limit  int
+		length time.Duration
+	}{
+		{WindowSecond, client.MaxPerSecond, time.Second},
+		{WindowMinute, client.MaxPerMinute, time.Minute},
+	}
+	for _, w := range windows {
+		if w.limit <= 0 {
+			continue
+		}
+		key, allowed, count, reset, err := cm.reserveWindow(ctx, client.ID.Hex(), w.name, w.limit, w.length, now)
+		if err != nil {
+			return q.refuse(ctx, w.name, w.limit, reset, err)
+		}
+		q.counters = append(q.counters, key)
+		q.observe(w.name, w.limit, allowed-count, reset)
+	}
+
+	// Days run midnight to midnight UTC
+	today := now.UTC().Format("2006-01-02")
+	y, m, d := now.UTC().Date()
+	midnight := time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+	count, err := cm.db.ReserveClientTx(ctx, client.ID, today, client.MaxDailyTx)
+	if err != nil {
+		return q.refuse(ctx, WindowDay, client.MaxDailyTx, midnight, err)
+	}
+	q.day = today
+	q.observe(WindowDay, client.MaxDailyTx, client.MaxDailyTx-count, midnight)
+
+	return q, nil
+}
+
+// reserveWindow counts a request in a sliding window: the current fixed
+// window's count plus the previous one's, weighted by how much of it still
+// overlaps the last length of time
+func (cm *ClientManager) reserveWindow(ctx context.Context, clientID, name string, limit int, length time.Duration, now time.Time) (key string, allowed, count int, reset time.Time, err error) {
+	start := now.Truncate(length)
+	reset = start.Add(length)
+
+	previous, err := cm.counters.GetRateCounter(ctx, rateCounterKey(clientID, name, start.Add(-length)))
+	if err != nil {
+		return "", 0, 0, reset, err
+	}
+	overlap := 1 - float64(now.Sub(start))/float64(length)
+	allowed = limit - int(math.Ceil(float64(previous)*overlap))
+	if allowed <= 0 {
+		return "", 0, 0, reset, database.ErrQuotaExceeded
+	}
+
+	// Kept through the next window, which weighs it
+	key = rateCounterKey(clientID, name, start)
+	count, err = cm.counters.IncrementRateCounter(ctx, key, allowed, start.Add(2*length))
+	if err != nil {
+		return "", 0, 0, reset, err
+	}
+	return key, allowed, count, reset, nil
+}
+
+// rateCounterKey names a client's counter for the window starting at start
+func rateCounterKey(clientID, window string, start time.Time) string {
+	return fmt.Sprintf("%s|%s|%d", clientID, window, start.Unix())
+}
+
+// observe reports the window if it has the fewest requests left so far
+func (q *Quota) observe(window string, limit, remaining int, reset time.Time) {
+	if remaining < 0 {
+		remaining = 0
+	}
+	if remaining < q.Remaining {
+		q.Window, q.Limit, q.Remaining, q.Reset = window, limit, remaining, reset
+	}
+}
+
+// refuse gives back what was charged before window failed, and reports it
+func (q *Quota) refuse(ctx context.Context, window string, limit int, reset time.Time, err error) (*Quota, error) {
+	if refundErr := q.Refund(ctx); refundErr != nil {
+		err = errors.Join(err, refundErr)
+	}
+	q.Window, q.Limit, q.Remaining, q.Reset = window, limit, 0, reset
+	return q, err
+}
+
+// Refund gives back a request's charge, for a request that failed before its
+// transaction was queued for broadcast or that ARC never took; refunding
+// twice is harmless
+func (q *Quota) Refund(ctx context.Context) error {
+	if len(q.counters) == 0 && q.day == "" {
+		return nil
+	}
+
+	var errs []error
+	for _, key := range q.counters {
+		if err := q.cm.counters.DecrementRateCounter(ctx, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if q.day != "" {
+		if err := q.cm.db.RefundClientTx(ctx, q.clientID, q.day); err != nil {
+			errs = append(errs, fmt.Errorf("failed to refund daily quota: %w", err))
+		}
+	}
+	q.counters, q.day = nil, ""
+
+	if q.Remaining < q.Limit {
+		q.Remaining++
+	}
+	return errors.Join(errs...)
+}
@@ -3,28 +3,32 @@ package admin
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/akua/bsv-broadcaster/internal/arc"
 	"github.com/akua/bsv-broadcaster/internal/bsv"
 	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/logging"
+	"github.com/akua/bsv-broadcaster/internal/metrics"
 	"github.com/akua/bsv-broadcaster/internal/models"
 	"github.com/bsv-blockchain/go-sdk/transaction"
-	"github.com/bsv-blockchain/go-sdk/transaction/template/p2pkh"
 )
 
 // Sweeper handles UTXO consolidation operations
 type Sweeper struct {
-	db            *database.Database
+	db            database.Store
+	fundingKey    *bsv.KeyPair
 	publishingKey *bsv.KeyPair
 	arcClient     *arc.Client
 	feeRate       float64
 }
 
 // NewSweeper creates a new UTXO sweeper
-func NewSweeper(db *database.Database, publishingKey *bsv.KeyPair, arcClient *arc.Client, feeRate float64) *Sweeper {
+// Inputs are signed with whichever key (and HD child) controls each UTXO
+func NewSweeper(db database.Store, fundingKey, publishingKey *bsv.KeyPair, arcClient *arc.Client, feeRate float64) *Sweeper {
 	return &Sweeper{
 		db:            db,
+		fundingKey:    fundingKey,
 		publishingKey: publishingKey,
 		arcClient:     arcClient,
 		feeRate:       feeRate,
@@ -43,20 +47,18 @@ func (s *Sweeper) SweepUTXOs(ctx context.Context, destAddress string, maxInputs
 		return "", 0, fmt.Errorf("no available UTXOs found")
 	}
 
-	log.Printf("🧹 Sweeping %d UTXOs to %s", len(utxos), destAddress)
+	slog.InfoContext(ctx, "Sweeping UTXOs", "count", len(utxos), "destination", destAddress)
 
 	// 2. Build consolidation transaction
 	tx := transaction.NewTransaction()
 	var totalInputSats uint64
 
-	// Get unlocker
-	unlocker, err := p2pkh.Unlock(s.publishingKey.PrivateKey, nil)
-	if err != nil {
-		return "", 0, fmt.Errorf("failed to create unlocker: %w", err)
-	}
-
-	// Add all inputs
+	// Add all inputs, each signed by the child key that controls it
 	for _, utxo := range utxos {
+		unlocker, err := bsv.ResolveUnlocker(utxo, s.publishingKey, s.fundingKey)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to create unlocker: %w", err)
+		}
 		if err := tx.AddInputFrom(utxo.TxID, utxo.Vout, utxo.ScriptPubKey, utxo.Satoshis, unlocker); err != nil {
 			return "", 0, fmt.Errorf("failed to add input: %w", err)
 		}
@@ -91,8 +93,7 @@ func (s *Sweeper) SweepUTXOs(ctx context.Context, destAddress string, maxInputs
 	txHex := tx.String()
 	txid := tx.TxID().String()
 
-	log.Printf("Sweep TX: %s", txid)
-	log.Printf("Size: %d bytes, Fee: %d sats, Output: %d sats", len(txHex)/2, fee, outputAmount)
+	slog.InfoContext(ctx, "Built sweep transaction", logging.KeyTxID, txid, "bytes", len(txHex)/2, "fee", fee, "output", outputAmount)
 
 	// 6. Broadcast transaction
 	response, err := s.arcClient.BroadcastSingle(ctx, txHex)
@@ -100,16 +101,30 @@ func (s *Sweeper) SweepUTXOs(ctx context.Context, destAddress string, maxInputs
 		return "", 0, fmt.Errorf("failed to broadcast: %w", err)
 	}
 
-	log.Printf("Broadcast status: %s", response.TxStatus)
+	slog.InfoContext(ctx, "Sweep broadcast", logging.KeyTxID, txid, "arc_status", response.TxStatus)
+
+	// A rejected sweep spent nothing - leave the inputs available
+	outcome := response.Outcome()
+	if !outcome.HoldsInputs() {
+		return "", 0, arc.CheckAccepted(response)
+	}
+
+	metrics.Sweeps.Inc()
+	metrics.SweptSatoshis.Add(float64(outputAmount))
 
 	// 7. Mark all input UTXOs as spent in database
+	// ARC holds the sweep from here on, even if it isn't accepted yet
 	for _, utxo := range utxos {
 		outpoint := fmt.Sprintf("%s:%d", utxo.TxID, utxo.Vout)
 		if err := s.db.MarkUTXOSpent(ctx, outpoint, txid); err != nil {
-			log.Printf("Warning: failed to mark UTXO %s as spent: %v", outpoint, err)
+			slog.WarnContext(ctx, "Failed to mark swept UTXO spent", logging.KeyUTXO, outpoint, logging.Err(err))
 		}
 	}
 
+	if !outcome.Succeeded() {
+		return txid, 0, arc.CheckAccepted(response)
+	}
+
 	return txid, outputAmount, nil
 }
 
@@ -0,0 +1,181 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/arc"
+	"github.com/akua/bsv-broadcaster/internal/arc/arctest"
+	"github.com/akua/bsv-broadcaster/internal/bsv"
+	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/models"
+	"github.com/bsv-blockchain/go-sdk/script"
+	"github.com/bsv-blockchain/go-sdk/transaction"
+	"github.com/bsv-blockchain/go-sdk/transaction/template/p2pkh"
+)
+
+const testChangeUTXOs = 4
+
+type sweeperHarness struct {
+	*Sweeper
+	db    *database.MemoryStore
+	arc   *arctest.Server
+	utxos []*models.UTXO
+}
+
+// newSweeperHarness seeds change UTXOs paying the funding key and points a
+// sweeper at a fake ARC
+func newSweeperHarness(t *testing.T) *sweeperHarness {
+	t.Helper()
+
+	db := database.NewMemoryStore()
+	db.SetLockOwner(database.NewLockOwner(time.Minute))
+	funding, err := bsv.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishing, err := bsv.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := script.NewAddressFromString(funding.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock, err := p2pkh.Lock(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var utxos []*models.UTXO
+	for i := 1; i <= testChangeUTXOs; i++ {
+		txid := fmt.Sprintf("%064x", i)
+		utxo := &models.UTXO{
+			Outpoint:     txid + ":0",
+			TxID:         txid,
+			Satoshis:     1000,
+			ScriptPubKey: lock.String(),
+			Status:       models.UTXOStatusAvailable,
+			Type:         models.UTXOTypeChange,
+		}
+		if err := db.InsertUTXO(context.Background(), utxo); err != nil {
+			t.Fatal(err)
+		}
+		utxos = append(utxos, utxo)
+	}
+
+	fake := arctest.NewServer()
+	t.Cleanup(fake.Close)
+
+	sweeper := NewSweeper(db, funding, publishing, fake.Client(), 0.5)
+	return &sweeperHarness{Sweeper: sweeper, db: db, arc: fake, utxos: utxos}
+}
+
+// sweep consolidates every change UTXO back to the funding address
+func (h *sweeperHarness) sweep() (string, uint64, error) {
+	return h.ConsolidateDust(context.Background(), h.fundingKey.Address, testChangeUTXOs)
+}
+
+func (h *sweeperHarness) count(t *testing.T, status models.UTXOStatus) int {
+	t.Helper()
+
+	utxos, err := h.db.FindUTXOsByType(context.Background(), models.UTXOTypeChange, status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return len(utxos)
+}
+
+func TestSweepMarksInputsSpent(t *testing.T) {
+	h := newSweeperHarness(t)
+	h.arc.SetLatency(5 * time.Millisecond)
+
+	txid, swept, err := h.sweep()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if swept == 0 || swept >= testChangeUTXOs*1000 {
+		t.Fatalf("swept %d sats, want the inputs less a fee", swept)
+	}
+	if got := h.count(t, models.UTXOStatusSpent); got != testChangeUTXOs {
+		t.Fatalf("%d inputs spent, want %d", got, testChangeUTXOs)
+	}
+
+	if mined := h.arc.Mine(txid); mined != 1 {
+		t.Fatalf("mined %d, want the sweep", mined)
+	}
+	if resp, ok := h.arc.Status(txid); !ok || resp.TxStatus != arc.TxStatusMined {
+		t.Fatalf("sweep status = %+v, want MINED", resp)
+	}
+}
+
+func TestSweepLeavesInputsAvailableWhenARCRefuses(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(fake *arctest.Server)
+	}{
+		{"rejected", func(fake *arctest.Server) { fake.SetDefaultStatus(arc.TxStatusRejected) }},
+		{"server error", func(fake *arctest.Server) { fake.FailNext(500, 1) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newSweeperHarness(t)
+			tt.setup(h.arc)
+
+			if _, _, err := h.sweep(); err == nil {
+				t.Fatal("sweep succeeded, want the ARC failure")
+			}
+			if got := h.count(t, models.UTXOStatusAvailable); got != testChangeUTXOs {
+				t.Fatalf("%d inputs available, want all %d", got, testChangeUTXOs)
+			}
+		})
+	}
+}
+
+func TestSweepLosingADoubleSpendStillSpendsInputs(t *testing.T) {
+	h := newSweeperHarness(t)
+
+	// Something else already spent one of the inputs
+	competing := h.spendElsewhere(t, h.utxos[0])
+
+	txid, _, err := h.sweep()
+	if err == nil || !strings.Contains(err.Error(), competing) {
+		t.Fatalf("sweep error = %v, want a double spend against %s", err, competing)
+	}
+	if txid == "" {
+		t.Fatal("no txid returned for the contested sweep")
+	}
+
+	// ARC holds the sweep, so none of its inputs can be handed out again
+	if got := h.count(t, models.UTXOStatusSpent); got != testChangeUTXOs {
+		t.Fatalf("%d inputs spent, want %d", got, testChangeUTXOs)
+	}
+}
+
+// spendElsewhere broadcasts a transaction spending utxo outside the sweeper
+func (h *sweeperHarness) spendElsewhere(t *testing.T, utxo *models.UTXO) string {
+	t.Helper()
+
+	unlocker, err := bsv.ResolveUnlocker(utxo, h.fundingKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := transaction.NewTransaction()
+	if err := tx.AddInputFrom(utxo.TxID, utxo.Vout, utxo.ScriptPubKey, utxo.Satoshis, unlocker); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.PayToAddress(h.publishingKey.Address, utxo.Satoshis-200); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Sign(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.arc.Client().BroadcastSingle(context.Background(), tx.String()); err != nil {
+		t.Fatal(err)
+	}
+	return tx.TxID().String()
+}
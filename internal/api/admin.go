@@ -1,22 +1,86 @@
 package api
 
 import (
-	"log"
+	"log/slog"
+	"strconv"
+	"strings"
 
 	"github.com/akua/bsv-broadcaster/internal/admin"
+	"github.com/akua/bsv-broadcaster/internal/logging"
+	"github.com/akua/bsv-broadcaster/internal/metrics"
 	"github.com/akua/bsv-broadcaster/internal/models"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // RegisterAdminRoutes sets up all admin endpoints
-func (s *Server) RegisterAdminRoutes(clientMgr *admin.ClientManager, sweeper *admin.Sweeper, adminPassword string) {
-	adminAuth := AdminAuthMiddleware(adminPassword)
+// Every admin is authenticated and every change is audited; reads are open
+// to all roles, changes need the role named on the route. legacyUser is the
+// account the X-Admin-Password header logs in as.
+func (s *Server) RegisterAdminRoutes(clientMgr *admin.ClientManager, sweeper *admin.Sweeper, accounts *admin.AccountManager, legacyUser string) {
+	adminAuth := AdminAuthMiddleware(accounts, legacyUser)
+	auditLog := AuditMiddleware(s.audit)
+	operator := RequireRole(models.AdminRoleOperator)
+	treasury := RequireRole(models.AdminRoleTreasury)
+	superuser := RequireRole(models.AdminRoleSuperuser)
+
+	s.app.Post("/admin/login", s.handleAdminLogin(accounts))
+
+	// Prometheus scrape target; any admin account may read it, since the
+	// publish counters are labelled with client IDs
+	s.app.Get("/metrics", adminAuth, adaptor.HTTPHandler(metrics.Handler()))
+
+	// Account management endpoints
+	s.registerAccountRoutes(s.app.Group("/admin/accounts", adminAuth, auditLog), accounts, superuser)
+
+	s.app.Get("/admin/me", adminAuth, func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"account": adminFromContext(c),
+		})
+	})
+
+	s.app.Get("/admin/audit", adminAuth, func(c *fiber.Ctx) error {
+		limit := c.QueryInt("limit", 100)
+		if limit <= 0 || limit > 1000 {
+			limit = 100
+		}
+
+		entries, err := s.audit.List(c.Context(), c.Query("actor"), limit)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"entries": entries,
+			"count":   len(entries),
+		})
+	})
+
+	s.app.Get("/admin/audit/verify", adminAuth, func(c *fiber.Ctx) error {
+		result, err := s.audit.Verify(c.Context())
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		if !result.Valid {
+			slog.ErrorContext(c.UserContext(), "Audit chain verification failed", "broken_at", result.BrokenAt, "problem", result.Problem)
+		}
+		return c.JSON(result)
+	})
+
+	// UTXO splitting spends funding UTXOs
+	s.app.Post("/admin/split", adminAuth, auditLog, treasury, s.handleSplit)
+	s.app.Post("/admin/split-phase2", adminAuth, auditLog, treasury, s.handleSplitPhase2)
 
 	// Client management endpoints
-	clients := s.app.Group("/admin/clients", adminAuth)
+	clients := s.app.Group("/admin/clients", adminAuth, auditLog)
 
-	clients.Post("/register", func(c *fiber.Ctx) error {
+	clients.Post("/register", operator, func(c *fiber.Ctx) error {
 		var req struct {
 			Name       string   `json:"name"`
 			PublicKey  string   `json:"public_key"` // NOW OPTIONAL for pilot tier
@@ -63,6 +127,9 @@ func (s *Server) RegisterAdminRoutes(clientMgr *admin.ClientManager, sweeper *ad
 			req.MaxDailyTx = 1000
 		}
 
+		auditDetail(c, "name", req.Name)
+		auditDetail(c, "tier", req.Tier)
+
 		// Register client with legacy method
 		rawKey, client, err := clientMgr.RegisterClient(
 			c.Context(),
@@ -103,9 +170,11 @@ func (s *Server) RegisterAdminRoutes(clientMgr *admin.ClientManager, sweeper *ad
 			gracePeriodHours,
 		)
 		if err != nil {
-			log.Printf("⚠️ Failed to apply tier settings: %v", err)
+			slog.WarnContext(c.UserContext(), "Failed to apply tier settings", "client", client.Name, logging.Err(err))
 		}
 
+		auditDetail(c, "client_id", client.ID.Hex())
+
 		return c.JSON(fiber.Map{
 			"success": true,
 			"message": "Client registered successfully. Save the API key - it will only be shown once!",
@@ -134,7 +203,7 @@ func (s *Server) RegisterAdminRoutes(clientMgr *admin.ClientManager, sweeper *ad
 		})
 	})
 
-	clients.Post("/:id/activate", func(c *fiber.Ctx) error {
+	clients.Post("/:id/activate", operator, func(c *fiber.Ctx) error {
 		id := c.Params("id")
 		objID, err := primitive.ObjectIDFromHex(id)
 		if err != nil {
@@ -155,7 +224,7 @@ func (s *Server) RegisterAdminRoutes(clientMgr *admin.ClientManager, sweeper *ad
 		})
 	})
 
-	clients.Post("/:id/deactivate", func(c *fiber.Ctx) error {
+	clients.Post("/:id/deactivate", operator, func(c *fiber.Ctx) error {
 		id := c.Params("id")
 		objID, err := primitive.ObjectIDFromHex(id)
 		if err != nil {
@@ -177,7 +246,7 @@ func (s *Server) RegisterAdminRoutes(clientMgr *admin.ClientManager, sweeper *ad
 	})
 
 	// PHASE 5: Runtime Security Management
-	clients.Patch("/:id/security", func(c *fiber.Ctx) error {
+	clients.Patch("/:id/security", operator, func(c *fiber.Ctx) error {
 		id := c.Params("id")
 		objID, err := primitive.ObjectIDFromHex(id)
 		if err != nil {
@@ -249,7 +318,11 @@ func (s *Server) RegisterAdminRoutes(clientMgr *admin.ClientManager, sweeper *ad
 			})
 		}
 
-		log.Printf("✅ Updated security for client %s: tier=%s, require_signature=%v", currentClient.Name, tier, requireSignature)
+		slog.InfoContext(c.UserContext(), "Updated client security", "client", currentClient.Name, "tier", tier, "require_signature", requireSignature)
+		auditDetail(c, "tier", tier)
+		auditDetail(c, "require_signature", strconv.FormatBool(requireSignature))
+		auditDetail(c, "allowed_ips", strings.Join(allowedIPs, ","))
+		auditDetail(c, "grace_period_hours", strconv.Itoa(gracePeriodHours))
 
 		return c.JSON(fiber.Map{
 			"success":   true,
@@ -264,10 +337,161 @@ func (s *Server) RegisterAdminRoutes(clientMgr *admin.ClientManager, sweeper *ad
 		})
 	})
 
+	clients.Patch("/:id/limits", operator, func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid client ID",
+			})
+		}
+
+		var req struct {
+			MaxDailyTx   *int `json:"max_daily_tx"`
+			MaxInFlight  *int `json:"max_in_flight"`  // 0 resets to the train default
+			MaxPerSecond *int `json:"max_per_second"` // 0 turns the limit off
+			MaxPerMinute *int `json:"max_per_minute"` // 0 turns the limit off
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		currentClient, err := clientMgr.GetClientByID(c.Context(), objID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Client not found",
+			})
+		}
+
+		maxDailyTx := currentClient.MaxDailyTx
+		if req.MaxDailyTx != nil {
+			maxDailyTx = *req.MaxDailyTx
+		}
+
+		maxInFlight := currentClient.MaxInFlight
+		if req.MaxInFlight != nil {
+			maxInFlight = *req.MaxInFlight
+		}
+
+		maxPerSecond := currentClient.MaxPerSecond
+		if req.MaxPerSecond != nil {
+			maxPerSecond = *req.MaxPerSecond
+		}
+
+		maxPerMinute := currentClient.MaxPerMinute
+		if req.MaxPerMinute != nil {
+			maxPerMinute = *req.MaxPerMinute
+		}
+
+		if maxDailyTx < 0 || maxInFlight < 0 || maxPerSecond < 0 || maxPerMinute < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "limits must not be negative",
+			})
+		}
+
+		if err := s.db.UpdateClientLimits(c.Context(), objID, maxDailyTx, maxInFlight, maxPerSecond, maxPerMinute); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		slog.InfoContext(c.UserContext(), "Updated client limits", "client", currentClient.Name, "max_daily_tx", maxDailyTx, "max_in_flight", maxInFlight,
+			"max_per_second", maxPerSecond, "max_per_minute", maxPerMinute)
+		auditDetail(c, "max_daily_tx", strconv.Itoa(maxDailyTx))
+		auditDetail(c, "max_in_flight", strconv.Itoa(maxInFlight))
+		auditDetail(c, "max_per_second", strconv.Itoa(maxPerSecond))
+		auditDetail(c, "max_per_minute", strconv.Itoa(maxPerMinute))
+
+		return c.JSON(fiber.Map{
+			"success":   true,
+			"client_id": objID.Hex(),
+			"limits": fiber.Map{
+				"max_daily_tx":   maxDailyTx,
+				"max_in_flight":  maxInFlight,
+				"max_per_second": maxPerSecond,
+				"max_per_minute": maxPerMinute,
+			},
+		})
+	})
+
+	clients.Patch("/:id/settings", operator, func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid client ID",
+			})
+		}
+
+		var req struct {
+			AutoRepublish *bool `json:"auto_republish"` // Reissue double-spent payloads on a fresh UTXO
+		}
+
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		currentClient, err := clientMgr.GetClientByID(c.Context(), objID)
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Client not found",
+			})
+		}
+
+		autoRepublish := currentClient.AutoRepublish
+		if req.AutoRepublish != nil {
+			autoRepublish = *req.AutoRepublish
+		}
+
+		if err := s.db.UpdateClientAutoRepublish(c.Context(), objID, autoRepublish); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		slog.InfoContext(c.UserContext(), "Updated client settings", "client", currentClient.Name, "auto_republish", autoRepublish)
+		auditDetail(c, "auto_republish", strconv.FormatBool(autoRepublish))
+
+		return c.JSON(fiber.Map{
+			"success":   true,
+			"client_id": objID.Hex(),
+			"settings": fiber.Map{
+				"auto_republish": autoRepublish,
+			},
+		})
+	})
+
+	// Incident endpoints
+	incidents := s.app.Group("/admin/incidents", adminAuth, auditLog)
+
+	incidents.Get("/double-spends", func(c *fiber.Ctx) error {
+		limit := c.QueryInt("limit", 100)
+		if limit <= 0 || limit > 1000 {
+			limit = 100
+		}
+
+		list, err := s.db.ListDoubleSpendIncidents(c.Context(), c.Query("client_id"), limit)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"incidents": list,
+			"count":     len(list),
+		})
+	})
+
 	// Maintenance endpoints
-	maintenance := s.app.Group("/admin/maintenance", adminAuth)
+	maintenance := s.app.Group("/admin/maintenance", adminAuth, auditLog)
 
-	maintenance.Post("/sweep", func(c *fiber.Ctx) error {
+	maintenance.Post("/sweep", treasury, func(c *fiber.Ctx) error {
 		var req struct {
 			DestAddress string `json:"dest_address"`
 			MaxInputs   int    `json:"max_inputs"`
@@ -294,8 +518,15 @@ func (s *Server) RegisterAdminRoutes(clientMgr *admin.ClientManager, sweeper *ad
 		if req.UTXOType == "funding" {
 			utxoType = models.UTXOTypeFunding
 		}
+		auditDetail(c, "dest_address", req.DestAddress)
+		auditDetail(c, "utxo_type", string(utxoType))
+		auditDetail(c, "max_inputs", strconv.Itoa(req.MaxInputs))
 
 		txID, amount, err := sweeper.SweepUTXOs(c.Context(), req.DestAddress, req.MaxInputs, utxoType)
+		if txID != "" {
+			auditDetail(c, "txid", txID)
+			auditDetail(c, "amount", strconv.FormatUint(amount, 10))
+		}
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": err.Error(),
@@ -310,7 +541,7 @@ func (s *Server) RegisterAdminRoutes(clientMgr *admin.ClientManager, sweeper *ad
 		})
 	})
 
-	maintenance.Post("/consolidate-dust", func(c *fiber.Ctx) error {
+	maintenance.Post("/consolidate-dust", treasury, func(c *fiber.Ctx) error {
 		var req struct {
 			FundingAddress string `json:"funding_address"`
 			MaxInputs      int    `json:"max_inputs"`
@@ -332,7 +563,14 @@ func (s *Server) RegisterAdminRoutes(clientMgr *admin.ClientManager, sweeper *ad
 			req.MaxInputs = 100
 		}
 
+		auditDetail(c, "funding_address", req.FundingAddress)
+		auditDetail(c, "max_inputs", strconv.Itoa(req.MaxInputs))
+
 		txID, amount, err := sweeper.ConsolidateDust(c.Context(), req.FundingAddress, req.MaxInputs)
+		if txID != "" {
+			auditDetail(c, "txid", txID)
+			auditDetail(c, "amount", strconv.FormatUint(amount, 10))
+		}
 		if err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": err.Error(),
@@ -373,9 +611,9 @@ func (s *Server) RegisterAdminRoutes(clientMgr *admin.ClientManager, sweeper *ad
 	})
 
 	// Emergency endpoints
-	emergency := s.app.Group("/admin/emergency", adminAuth)
+	emergency := s.app.Group("/admin/emergency", adminAuth, auditLog)
 
-	emergency.Post("/stop-train", func(c *fiber.Ctx) error {
+	emergency.Post("/stop-train", operator, func(c *fiber.Ctx) error {
 		s.train.Stop()
 		return c.JSON(fiber.Map{
 			"success": true,
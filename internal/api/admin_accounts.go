@@ -0,0 +1,223 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/admin"
+	"github.com/akua/bsv-broadcaster/internal/auth"
+	"github.com/akua/bsv-broadcaster/internal/logging"
+	"github.com/akua/bsv-broadcaster/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// handleAdminLogin exchanges a username and password for a session token
+// Logins aren't behind AuditMiddleware, so both outcomes are recorded here.
+func (s *Server) handleAdminLogin(accounts *admin.AccountManager) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var req struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+		}
+		if err := c.BodyParser(&req); err != nil || req.Username == "" || req.Password == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "username and password are required",
+			})
+		}
+
+		account, token, expiresAt, err := accounts.Login(c.UserContext(), req.Username, req.Password)
+
+		entry := &models.AuditEntry{
+			Action:   c.Method() + " " + c.Route().Path,
+			Actor:    req.Username,
+			Status:   fiber.StatusOK,
+			RemoteIP: c.IP(),
+		}
+		switch {
+		case errors.Is(err, admin.ErrBadCredentials):
+			entry.Status = fiber.StatusUnauthorized
+		case err != nil:
+			entry.Status = fiber.StatusInternalServerError
+		default:
+			entry.Role = account.Role
+		}
+		if auditErr := s.audit.Record(c.UserContext(), entry); auditErr != nil {
+			slog.ErrorContext(c.UserContext(), "Failed to write audit entry", "action", entry.Action, logging.Err(auditErr))
+		}
+
+		if err != nil {
+			if errors.Is(err, admin.ErrBadCredentials) {
+				slog.WarnContext(c.UserContext(), "Admin login failed", "username", req.Username, "remote_ip", c.IP())
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": "Invalid username or password",
+				})
+			}
+			slog.ErrorContext(c.UserContext(), "Admin login failed", "username", req.Username, logging.Err(err))
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to log in",
+			})
+		}
+
+		slog.InfoContext(c.UserContext(), "Admin logged in", "username", account.Username, "role", account.Role)
+
+		return c.JSON(fiber.Map{
+			"success":    true,
+			"token":      token,
+			"expires_at": expiresAt.UTC().Format(time.RFC3339),
+			"username":   account.Username,
+			"role":       account.Role,
+		})
+	}
+}
+
+// registerAccountRoutes sets up admin account management, for superusers only
+func (s *Server) registerAccountRoutes(group fiber.Router, accounts *admin.AccountManager, superuser fiber.Handler) {
+	group.Get("", superuser, func(c *fiber.Ctx) error {
+		list, err := accounts.ListAccounts(c.UserContext())
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		return c.JSON(fiber.Map{
+			"accounts": list,
+			"count":    len(list),
+		})
+	})
+
+	group.Post("", superuser, func(c *fiber.Ctx) error {
+		var req struct {
+			Username string           `json:"username"`
+			Password string           `json:"password"`
+			Role     models.AdminRole `json:"role"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		auditDetail(c, "username", req.Username)
+		auditDetail(c, "role", string(req.Role))
+
+		if req.Username == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "username is required",
+			})
+		}
+		if !req.Role.Valid() {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "role must be 'viewer', 'operator', 'treasury', or 'admin'",
+			})
+		}
+		if len(req.Password) < auth.MinPasswordLength {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "password must be at least " + strconv.Itoa(auth.MinPasswordLength) + " characters",
+			})
+		}
+
+		existing, err := accounts.GetAccount(c.UserContext(), req.Username)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if existing != nil {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "account already exists",
+			})
+		}
+
+		account, err := accounts.CreateAccount(c.UserContext(), req.Username, req.Password, req.Role)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		slog.InfoContext(c.UserContext(), "Created admin account", "username", account.Username, "role", account.Role)
+
+		return c.JSON(fiber.Map{
+			"success": true,
+			"account": account,
+		})
+	})
+
+	group.Patch("/:username", superuser, func(c *fiber.Ctx) error {
+		var req struct {
+			Role     *models.AdminRole `json:"role"`
+			IsActive *bool             `json:"is_active"`
+			Password *string           `json:"password"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+
+		account, err := accounts.GetAccount(c.UserContext(), c.Params("username"))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if account == nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "account not found",
+			})
+		}
+
+		// An admin can't lock themselves out
+		self := adminFromContext(c).Username == account.Username
+		if self && ((req.IsActive != nil && !*req.IsActive) || (req.Role != nil && *req.Role != models.AdminRoleSuperuser)) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "you cannot deactivate or demote your own account",
+			})
+		}
+
+		if req.Password != nil && len(*req.Password) < auth.MinPasswordLength {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "password must be at least " + strconv.Itoa(auth.MinPasswordLength) + " characters",
+			})
+		}
+		if req.Role != nil {
+			if !req.Role.Valid() {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "role must be 'viewer', 'operator', 'treasury', or 'admin'",
+				})
+			}
+			account.Role = *req.Role
+			auditDetail(c, "role", string(account.Role))
+		}
+		if req.IsActive != nil {
+			account.IsActive = *req.IsActive
+			auditDetail(c, "is_active", strconv.FormatBool(account.IsActive))
+		}
+
+		if err := accounts.UpdateAccount(c.UserContext(), account); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+
+		// Never put the password itself in the audit log
+		if req.Password != nil {
+			if err := accounts.SetPassword(c.UserContext(), account, *req.Password); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+			auditDetail(c, "password_changed", "true")
+		}
+
+		slog.InfoContext(c.UserContext(), "Updated admin account", "username", account.Username, "role", account.Role, "active", account.IsActive)
+
+		return c.JSON(fiber.Map{
+			"success": true,
+			"account": account,
+		})
+	})
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/akua/bsv-broadcaster/internal/admin"
+	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/models"
+)
+
+// adminRequest builds an admin request authenticated with Basic auth
+func adminRequest(method, path, username, password string, body any) *http.Request {
+	var reader *bytes.Reader
+	if body != nil {
+		data, _ := json.Marshal(body)
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(username, password)
+	return req
+}
+
+// createAccount adds an admin account with role
+func createAccount(t *testing.T, accounts *admin.AccountManager, username, password string, role models.AdminRole) *models.AdminAccount {
+	t.Helper()
+
+	account, err := accounts.CreateAccount(context.Background(), username, password, role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return account
+}
+
+func TestAdminRoutesEnforceRoles(t *testing.T) {
+	ts := newTestServer(t, 0)
+	accounts := ts.withAdminRoutes(t, "viewer", "viewer-password")
+	createAccount(t, accounts, "operator", "operator-password", models.AdminRoleOperator)
+	createAccount(t, accounts, "root", "root-password", models.AdminRoleSuperuser)
+
+	newAccount := map[string]string{"username": "intruder", "password": "intruder-password", "role": "admin"}
+	tests := []struct {
+		name     string
+		req      *http.Request
+		want     int
+		mutating bool
+	}{
+		{"viewer can't stop the train", adminRequest(http.MethodPost, "/admin/emergency/stop-train", "viewer", "viewer-password", nil), http.StatusForbidden, true},
+		{"viewer reads the emergency status", adminRequest(http.MethodGet, "/admin/emergency/status", "viewer", "viewer-password", nil), http.StatusOK, false},
+		{"operator can't list accounts", adminRequest(http.MethodGet, "/admin/accounts", "operator", "operator-password", nil), http.StatusForbidden, false},
+		{"operator can't create accounts", adminRequest(http.MethodPost, "/admin/accounts", "operator", "operator-password", newAccount), http.StatusForbidden, true},
+		{"superuser lists accounts", adminRequest(http.MethodGet, "/admin/accounts", "root", "root-password", nil), http.StatusOK, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := ts.do(t, tt.req)
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.want, body)
+			}
+		})
+	}
+
+	if account, err := accounts.GetAccount(context.Background(), "intruder"); err != nil || account != nil {
+		t.Fatalf("refused account creation left %+v (%v)", account, err)
+	}
+	if !ts.train.IsRunning() {
+		t.Fatal("refused stop-train stopped the train")
+	}
+
+	// Refused changes are audited too: an intent, then the 403
+	entries, err := ts.audit.List(context.Background(), "operator", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("%d audit entries for the operator, want the intent and the refusal", len(entries))
+	}
+	result, intent := entries[0], entries[1]
+	if intent.Details["phase"] != "intent" || intent.Action != "POST /admin/accounts" {
+		t.Fatalf("intent entry = %+v, want POST /admin/accounts", intent)
+	}
+	if result.Status != http.StatusForbidden || result.Details["intent_seq"] != strconv.FormatInt(intent.Seq, 10) {
+		t.Fatalf("result entry = %+v, want a 403 pointing at intent %d", result, intent.Seq)
+	}
+}
+
+func TestAdminTokensEndWithPasswordChange(t *testing.T) {
+	ts := newTestServer(t, 0)
+	accounts := ts.withAdminRoutes(t, "viewer", "viewer-password")
+	createAccount(t, accounts, "root", "root-password", models.AdminRoleSuperuser)
+
+	login := httptest.NewRequest(http.MethodPost, "/admin/login", bytes.NewReader([]byte(`{"username":"viewer","password":"viewer-password"}`)))
+	login.Header.Set("Content-Type", "application/json")
+	resp, body := ts.do(t, login)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("login status = %d, want %d: %s", resp.StatusCode, http.StatusOK, body)
+	}
+	var session struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &session); err != nil || session.Token == "" {
+		t.Fatalf("login response = %s (%v), want a token", body, err)
+	}
+	me := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/admin/me", nil)
+		req.Header.Set("Authorization", "Bearer "+session.Token)
+		resp, _ := ts.do(t, req)
+		return resp.StatusCode
+	}
+	if got := me(); got != http.StatusOK {
+		t.Fatalf("token status = %d, want %d", got, http.StatusOK)
+	}
+
+	// Changed straight away, within the second the token was issued
+	change := adminRequest(http.MethodPatch, "/admin/accounts/viewer", "root", "root-password", map[string]string{"password": "new-viewer-password"})
+	if resp, body := ts.do(t, change); resp.StatusCode != http.StatusOK {
+		t.Fatalf("password change status = %d, want %d: %s", resp.StatusCode, http.StatusOK, body)
+	}
+
+	if got := me(); got != http.StatusUnauthorized {
+		t.Fatalf("token after password change status = %d, want %d", got, http.StatusUnauthorized)
+	}
+	if resp, _ := ts.do(t, adminRequest(http.MethodGet, "/admin/me", "viewer", "viewer-password", nil)); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("old password status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+	if resp, _ := ts.do(t, adminRequest(http.MethodGet, "/admin/me", "viewer", "new-viewer-password", nil)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("new password status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+// brokenAuditStore refuses every audit write
+type brokenAuditStore struct {
+	database.AdminStore
+}
+
+func (brokenAuditStore) AppendAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	return errors.New("audit log unavailable")
+}
+
+func TestAuditMiddlewareFailsClosed(t *testing.T) {
+	ts := newTestServer(t, 0)
+	ts.audit = admin.NewAuditLog(brokenAuditStore{ts.db})
+	accounts := ts.withAdminRoutes(t, "viewer", "viewer-password")
+	createAccount(t, accounts, "root", "root-password", models.AdminRoleSuperuser)
+
+	newAccount := map[string]string{"username": "unaudited", "password": "unaudited-password", "role": "viewer"}
+	resp, body := ts.do(t, adminRequest(http.MethodPost, "/admin/accounts", "root", "root-password", newAccount))
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("unauditable change status = %d, want %d: %s", resp.StatusCode, http.StatusInternalServerError, body)
+	}
+	if account, err := accounts.GetAccount(context.Background(), "unaudited"); err != nil || account != nil {
+		t.Fatalf("unaudited change went through: %+v (%v)", account, err)
+	}
+
+	// Reads aren't audited, so they still work
+	if resp, body := ts.do(t, adminRequest(http.MethodGet, "/admin/accounts", "root", "root-password", nil)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("read status = %d, want %d: %s", resp.StatusCode, http.StatusOK, body)
+	}
+}
@@ -3,10 +3,15 @@ package api
 import (
 	"crypto/sha256"
 	"encoding/hex"
-	"log"
+	"errors"
+	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/akua/bsv-broadcaster/internal/auth"
+	"github.com/akua/bsv-broadcaster/internal/logging"
+	"github.com/akua/bsv-broadcaster/internal/metrics"
+	"github.com/akua/bsv-broadcaster/internal/models"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -45,23 +50,38 @@ func (s *Server) HandleRegisterPublicKey(c *fiber.Ctx) error {
 		})
 	}
 
-	// Validate public key format (should be 65 bytes hex = 130 chars)
-	if len(req.PublicKey) != 130 {
+	// Keys are stored uncompressed, so mutual authentication can find the
+	// client by its (compressed) identity key
+	publicKey, err := auth.NormalizePublicKey(req.PublicKey)
+	if err != nil {
 		return c.Status(400).JSON(fiber.Map{
-			"error": "invalid public key format (expected 130 hex characters)",
+			"error": "invalid public key format (expected 66 or 130 hex characters)",
+		})
+	}
+	if s.publicKeyTaken(c, publicKey, apiKeyHash) {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "public key is already registered to another client",
 		})
 	}
 
 	// Attempt to bind the public key to this API key
-	err := s.db.BindPublicKeyToClient(c.Context(), apiKeyHash, req.PublicKey)
+	err = s.db.BindPublicKeyToClient(c.Context(), apiKeyHash, publicKey)
 	if err != nil {
-		log.Printf("❌ Public key registration failed: %v", err)
+		slog.WarnContext(c.UserContext(), "Public key registration failed", logging.Err(err))
 		return c.Status(401).JSON(fiber.Map{
 			"error": "unauthorized or public key already registered",
 		})
 	}
 
-	log.Printf("✅ Public key registered for client with API key hash: %s", apiKeyHash[:8])
+	slog.InfoContext(c.UserContext(), "Public key registered", "api_key_hash_prefix", apiKeyHash[:8])
+
+	clientID := "api-key:" + apiKeyHash[:8]
+	if client, err := s.db.GetClientByAPIKey(c.Context(), apiKeyHash); err == nil {
+		clientID = client.ID.Hex()
+	}
+	s.auditClientEvent(c, clientID, fiber.StatusOK, map[string]string{
+		"public_key": publicKey,
+	})
 
 	return c.JSON(fiber.Map{
 		"success": true,
@@ -90,11 +110,8 @@ func (s *Server) HandleRotatePublicKey(c *fiber.Ctx) error {
 	}
 
 	// Verify the request is signed with the CURRENT public key
-	signature := c.Get("X-Signature")
-	timestamp := c.Get("X-Timestamp")
-	nonce := c.Get("X-Nonce")
-
-	if signature == "" || timestamp == "" || nonce == "" {
+	signed := signedRequest(c)
+	if signed.Signature == "" || signed.Timestamp == "" || signed.Nonce == "" {
 		return c.Status(401).JSON(fiber.Map{
 			"error": "signature headers required for key rotation (X-Signature, X-Timestamp, X-Nonce)",
 		})
@@ -115,26 +132,41 @@ func (s *Server) HandleRotatePublicKey(c *fiber.Ctx) error {
 	}
 
 	// Validate new public key format
-	if len(req.NewPublicKey) != 130 {
+	newPublicKey, err := auth.NormalizePublicKey(req.NewPublicKey)
+	if err != nil {
 		return c.Status(400).JSON(fiber.Map{
-			"error": "invalid public key format (expected 130 hex characters)",
+			"error": "invalid public key format (expected 66 or 130 hex characters)",
+		})
+	}
+	if s.publicKeyTaken(c, newPublicKey, apiKeyHash) {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "public key is already registered to another client",
 		})
 	}
 
-	// Verify signature with CURRENT public key
-	payload := timestamp + nonce + req.NewPublicKey
-	valid, err := auth.VerifySignature(client.PublicKey, payload, signature)
-	if err != nil || !valid {
-		log.Printf("❌ Key rotation failed - invalid signature from client: %s", client.Name)
+	// Verify signature with CURRENT public key; a captured rotation can't be
+	// replayed either, e.g. to undo a later one
+	signed.LegacyData = req.NewPublicKey
+	if _, err := s.signatures.Verify(c.UserContext(), client.ID.Hex(), signed, []string{client.PublicKey}, time.Now()); err != nil {
+		if !errors.Is(err, auth.ErrInvalidSignature) {
+			return refuseSignedRequest(c, client, err)
+		}
+		slog.WarnContext(c.UserContext(), "Key rotation failed: invalid signature", "client", client.Name)
+		metrics.SignatureRejections.WithLabelValues(client.ID.Hex(), metrics.RejectInvalidSignature).Inc()
+		s.auditClientEvent(c, client.ID.Hex(), fiber.StatusUnauthorized, map[string]string{
+			"new_public_key": req.NewPublicKey,
+			"reason":         "invalid signature",
+		})
 		return c.Status(401).JSON(fiber.Map{
 			"error": "invalid signature - must be signed with current public key",
 		})
 	}
+	metrics.SignedRequests.WithLabelValues(strconv.Itoa(signed.Version)).Inc()
 
 	// Perform key rotation
-	err = s.db.RotateClientPublicKey(c.Context(), apiKeyHash, req.NewPublicKey)
+	err = s.db.RotateClientPublicKey(c.Context(), apiKeyHash, newPublicKey)
 	if err != nil {
-		log.Printf("❌ Key rotation failed: %v", err)
+		slog.ErrorContext(c.UserContext(), "Key rotation failed", logging.Err(err))
 		return c.Status(500).JSON(fiber.Map{
 			"error": "failed to rotate public key",
 		})
@@ -145,7 +177,12 @@ func (s *Server) HandleRotatePublicKey(c *fiber.Ctx) error {
 		gracePeriod = 24 // Default
 	}
 
-	log.Printf("🔄 Public key rotated for client: %s (grace period: %dh)", client.Name, gracePeriod)
+	slog.InfoContext(c.UserContext(), "Public key rotated", "client", client.Name, "grace_period_hours", gracePeriod)
+	s.auditClientEvent(c, client.ID.Hex(), fiber.StatusOK, map[string]string{
+		"old_public_key":     client.PublicKey,
+		"new_public_key":     newPublicKey,
+		"grace_period_hours": strconv.Itoa(gracePeriod),
+	})
 
 	return c.JSON(fiber.Map{
 		"success":             true,
@@ -155,6 +192,29 @@ func (s *Server) HandleRotatePublicKey(c *fiber.Ctx) error {
 	})
 }
 
+// auditClientEvent records a client's change to its own credentials in the
+// audit log
+func (s *Server) auditClientEvent(c *fiber.Ctx, clientID string, status int, details map[string]string) {
+	entry := &models.AuditEntry{
+		Actor:    "client:" + clientID,
+		Action:   c.Method() + " " + c.Route().Path,
+		Target:   clientID,
+		Status:   status,
+		RemoteIP: c.IP(),
+		Details:  details,
+	}
+	if err := s.audit.Record(c.UserContext(), entry); err != nil {
+		slog.ErrorContext(c.UserContext(), "Failed to write audit entry", "action", entry.Action, logging.Err(err))
+	}
+}
+
+// publicKeyTaken reports whether a client other than the one with apiKeyHash
+// holds publicKey; identity keys must map to a single client
+func (s *Server) publicKeyTaken(c *fiber.Ctx, publicKey, apiKeyHash string) bool {
+	holder, err := s.db.GetClientByPublicKey(c.Context(), publicKey)
+	return err == nil && holder.APIKeyHash != apiKeyHash
+}
+
 // HandleKeyStatus returns the current key status for a client
 func (s *Server) HandleKeyStatus(c *fiber.Ctx) error {
 	apiKey := c.Get("X-API-Key")
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/admin"
+	"github.com/akua/bsv-broadcaster/internal/auth"
+	"github.com/akua/bsv-broadcaster/internal/metrics"
+	"github.com/akua/bsv-broadcaster/internal/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// withAdminRoutes registers the admin routes with one viewer account and
+// returns the account manager so tests can add more
+func (ts *testServer) withAdminRoutes(t *testing.T, username, password string) *admin.AccountManager {
+	t.Helper()
+
+	accounts := admin.NewAccountManager(ts.db, auth.NewTokenSigner([]byte("test-secret"), time.Hour))
+	if _, err := accounts.CreateAccount(context.Background(), username, password, models.AdminRoleViewer); err != nil {
+		t.Fatal(err)
+	}
+	ts.RegisterAdminRoutes(ts.clients, nil, accounts, username)
+	return accounts
+}
+
+func scrapeRequest() *http.Request {
+	return httptest.NewRequest(http.MethodGet, "/metrics", nil)
+}
+
+func TestMetricsRequireAdmin(t *testing.T) {
+	ts := newTestServer(t, 0)
+	ts.withAdminRoutes(t, "prometheus", "scrape-password")
+
+	resp, _ := ts.do(t, scrapeRequest())
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("anonymous scrape status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	req := scrapeRequest()
+	req.SetBasicAuth("prometheus", "wrong")
+	if resp, _ := ts.do(t, req); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("bad password scrape status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	req = scrapeRequest()
+	req.SetBasicAuth("prometheus", "scrape-password")
+	resp, body := ts.do(t, req)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("viewer scrape status = %d, want %d: %s", resp.StatusCode, http.StatusOK, body)
+	}
+	if !strings.Contains(string(body), "go_goroutines") {
+		t.Fatalf("scrape body is not Prometheus metrics: %.200s", body)
+	}
+}
+
+func TestPublishMetricsCarryClientAndTier(t *testing.T) {
+	// No UTXOs, so the publish ends in the handler as no_utxo
+	ts := newTestServer(t, 0)
+	ts.withAdminRoutes(t, "prometheus", "scrape-password")
+	apiKey, client := ts.registerClient(t, "", 10)
+
+	req := publishRequest(testData)
+	req.Header.Set("X-API-Key", apiKey)
+	if resp, body := ts.do(t, req); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("publish status = %d, want %d: %s", resp.StatusCode, http.StatusServiceUnavailable, body)
+	}
+
+	counter := metrics.Publishes.WithLabelValues(client.ID.Hex(), "pilot", metrics.OutcomeNoUTXO)
+	if got := testutil.ToFloat64(counter); got != 1 {
+		t.Fatalf("publishes for client %s on pilot = %v, want 1", client.ID.Hex(), got)
+	}
+
+	scrape := scrapeRequest()
+	scrape.SetBasicAuth("prometheus", "scrape-password")
+	_, body := ts.do(t, scrape)
+	want := fmt.Sprintf(`broadcaster_publishes_total{client=%q,outcome=%q,tier=%q} 1`, client.ID.Hex(), metrics.OutcomeNoUTXO, "pilot")
+	if !strings.Contains(string(body), want) {
+		t.Fatalf("scrape is missing %s", want)
+	}
+}
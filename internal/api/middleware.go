@@ -1,21 +1,79 @@
 package api
 
 import (
+	"encoding/base64"
 	"encoding/hex"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/akua/bsv-broadcaster/internal/admin"
 	"github.com/akua/bsv-broadcaster/internal/auth"
 	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/logging"
+	"github.com/akua/bsv-broadcaster/internal/metrics"
 	"github.com/akua/bsv-broadcaster/internal/models"
+	"github.com/akua/bsv-broadcaster/internal/tracing"
+	"github.com/bsv-blockchain/go-sdk/auth/brc104"
 	"github.com/gofiber/fiber/v2"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// TracingMiddleware starts a server span for each request, continuing the
+// caller's trace if it sent a traceparent header
+// Handlers reach the span through c.UserContext().
+func TracingMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		carrier := propagation.HeaderCarrier{}
+		for key, values := range c.GetReqHeaders() {
+			carrier[key] = values
+		}
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), carrier)
+
+		ctx, span := tracing.Tracer().Start(ctx, c.Method()+" "+c.Path(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", c.Method()),
+				attribute.String("url.path", c.Path()),
+			),
+		)
+		defer span.End()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		// Name by route template so /status/:uuid spans group together
+		span.SetName(c.Method() + " " + c.Route().Path)
+		status := c.Response().StatusCode()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if err != nil {
+			tracing.RecordError(span, err)
+		} else if status >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", status))
+		}
+		return err
+	}
+}
+
 // AuthMiddleware validates API key and adaptively enforces ECDSA signature based on client tier
-func AuthMiddleware(db *database.Database, clientMgr *admin.ClientManager) fiber.Handler {
+// Signed requests must be fresh and carry an unused nonce (see auth.RequestVerifier).
+// Requests carrying BRC-104 x-bsv-auth-* headers are checked against their
+// mutual authentication session instead, when mutual is set.
+func AuthMiddleware(db database.Store, clientMgr *admin.ClientManager, signatures *auth.RequestVerifier, mutual *auth.MutualAuth) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		// A BRC-103 session stands in for the API key
+		if mutual != nil && c.Get(brc104.HeaderIdentityKey) != "" {
+			return mutualAuthenticate(c, clientMgr, mutual)
+		}
+
 		// Extract API key
 		apiKey := c.Get("X-API-Key")
 		if apiKey == "" {
@@ -42,7 +100,7 @@ func AuthMiddleware(db *database.Database, clientMgr *admin.ClientManager) fiber
 		// ADAPTIVE TIER LOGIC
 		// Pilot Tier: API Key only (optional IP whitelist)
 		if !client.RequireSignature {
-			log.Printf("[PILOT] Legacy request from: %s (tier: %s)", client.Name, client.Tier)
+			slog.DebugContext(c.UserContext(), "Pilot tier request without signature", "client", client.Name, "tier", client.Tier)
 
 			// Optional IP whitelist for pilot tier
 			if len(client.AllowedIPs) > 0 && !isIPWhitelisted(c.IP(), client.AllowedIPs) {
@@ -52,91 +110,166 @@ func AuthMiddleware(db *database.Database, clientMgr *admin.ClientManager) fiber
 				})
 			}
 
-			// Check rate limit
-			if client.TxCount >= client.MaxDailyTx {
-				return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
-					"error": "Daily transaction limit exceeded",
-				})
-			}
-
-			// Increment transaction count
-			if err := clientMgr.IncrementClientTxCount(c.Context(), client.ID); err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error": "Failed to update transaction count",
-				})
-			}
-
-			// Store client in context and proceed
-			c.Locals("client", client)
-			return c.Next()
+			return admitClient(c, clientMgr, client)
 		}
 
 		// Secure/Government Tier: Enforce ECDSA Signature
-		signature := c.Get("X-Signature")
-		timestamp := c.Get("X-Timestamp")
-		nonce := c.Get("X-Nonce")
-
-		if signature == "" || timestamp == "" || nonce == "" {
+		req := signedRequest(c)
+		if req.Signature == "" || req.Timestamp == "" || req.Nonce == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "ECDSA signature headers (X-Signature, X-Timestamp, X-Nonce) are required for this tier",
 				"tier":  client.Tier,
 			})
 		}
 
-		// Parse request body
-		var payload struct {
-			Data string `json:"data"`
-		}
-		if err := c.BodyParser(&payload); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid request body",
-			})
+		if req.Version == auth.SignatureV1 {
+			// Legacy signatures cover only the data field
+			var payload struct {
+				Data string `json:"data"`
+			}
+			if err := c.BodyParser(&payload); err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Invalid request body",
+				})
+			}
+			req.LegacyData = payload.Data
 		}
 
 		// Verify signature with grace period support
-		isValid, err := verifyAdaptiveSignature(client, payload.Data, signature, timestamp, nonce)
-		if !isValid || err != nil {
-			log.Printf("❌ Signature verification failed for client: %s (tier: %s)", client.Name, client.Tier)
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid cryptographic signature",
-			})
+		signedBy, err := signatures.Verify(c.UserContext(), client.ID.Hex(), req, signingKeys(client), time.Now())
+		if err != nil {
+			return refuseSignedRequest(c, client, err)
 		}
-
-		// Check rate limit
-		if client.TxCount >= client.MaxDailyTx {
-			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
-				"error": "Daily transaction limit exceeded",
-			})
+		if signedBy != client.PublicKey {
+			slog.InfoContext(c.UserContext(), "Signature verified with old public key during rotation grace period", "client", client.Name)
 		}
+		metrics.SignedRequests.WithLabelValues(strconv.Itoa(req.Version)).Inc()
 
-		// Increment transaction count
-		if err := clientMgr.IncrementClientTxCount(c.Context(), client.ID); err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to update transaction count",
-			})
+		return admitClient(c, clientMgr, client)
+	}
+}
+
+// admitClient charges an authenticated request against its client's rate
+// limits, reporting what is left in X-RateLimit-* headers, then stores the
+// client for downstream handlers and runs them
+func admitClient(c *fiber.Ctx, clientMgr *admin.ClientManager, client *models.Client) error {
+	quota, err := clientMgr.ReserveQuota(c.UserContext(), client, time.Now())
+	if quota != nil {
+		setRateLimitHeaders(c, quota)
+	}
+	if errors.Is(err, database.ErrQuotaExceeded) {
+		metrics.RateLimited.WithLabelValues(client.ID.Hex(), quota.Window).Inc()
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(time.Until(quota.Reset).Seconds()))))
+		message := "Daily transaction limit exceeded"
+		if quota.Window != admin.WindowDay {
+			message = "Too many transactions per " + quota.Window + ", slow down"
 		}
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": message,
+		})
+	}
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Failed to update transaction count", "client", client.Name, logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update transaction count",
+		})
+	}
 
-		// Store client in context for downstream handlers
-		c.Locals("client", client)
+	c.Locals("quota", quota)
+	setClient(c, client)
+	return c.Next()
+}
 
-		return c.Next()
+// setRateLimitHeaders reports the rate limit window closest to running out
+func setRateLimitHeaders(c *fiber.Ctx, quota *admin.Quota) {
+	c.Set("X-RateLimit-Limit", strconv.Itoa(quota.Limit))
+	c.Set("X-RateLimit-Remaining", strconv.Itoa(quota.Remaining))
+	c.Set("X-RateLimit-Reset", strconv.FormatInt(quota.Reset.Unix(), 10))
+	c.Set("X-RateLimit-Window", quota.Window)
+}
+
+// refundQuota gives back the quota AuthMiddleware charged a request that
+// failed before its transaction was queued for broadcast
+func refundQuota(c *fiber.Ctx) {
+	quota, _ := c.Locals("quota").(*admin.Quota)
+	if quota == nil {
+		return
+	}
+	if err := quota.Refund(c.UserContext()); err != nil {
+		slog.ErrorContext(c.UserContext(), "Failed to refund quota", logging.Err(err))
+		return
 	}
+	setRateLimitHeaders(c, quota)
 }
 
-// verifyAdaptiveSignature verifies ECDSA signature with grace period support for key rotation
-func verifyAdaptiveSignature(client *models.Client, data, signature, timestamp, nonce string) (bool, error) {
-	// Construct signature payload: timestamp + nonce + data
-	payload := timestamp + nonce + data
+// signedRequest reads the parts of a request a client signature covers
+func signedRequest(c *fiber.Ctx) *auth.SignedRequest {
+	return &auth.SignedRequest{
+		Version:     auth.ParseSignatureVersion(c.Get("X-Signature-Version")),
+		Signature:   c.Get("X-Signature"),
+		Timestamp:   c.Get("X-Timestamp"),
+		Nonce:       c.Get("X-Nonce"),
+		Method:      c.Method(),
+		Path:        c.Path(),
+		RawQuery:    string(c.Request().URI().QueryString()),
+		ContentType: c.Get(fiber.HeaderContentType),
+		APIKey:      c.Get("X-API-Key"),
+		Body:        c.Body(),
+	}
+}
 
-	// Try current public key first
-	if client.PublicKey != "" {
-		valid, err := auth.VerifySignature(client.PublicKey, payload, signature)
-		if err == nil && valid {
-			return true, nil
-		}
+// refuseSignedRequest answers a signed client request that failed
+// auth.RequestVerifier, counting why
+func refuseSignedRequest(c *fiber.Ctx, client *models.Client, err error) error {
+	switch {
+	case errors.Is(err, auth.ErrUnsupportedSignatureVersion):
+		return rejectSignedRequest(c, client, metrics.RejectUnsupportedVersion, fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, auth.ErrStaleTimestamp):
+		return rejectSignedRequest(c, client, metrics.RejectStaleTimestamp, fiber.StatusUnauthorized,
+			"X-Timestamp is too old or too far in the future - check your clock")
+	case errors.Is(err, auth.ErrInvalidNonce):
+		return rejectSignedRequest(c, client, metrics.RejectInvalidNonce, fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, auth.ErrInvalidSignature):
+		return rejectSignedRequest(c, client, metrics.RejectInvalidSignature, fiber.StatusUnauthorized,
+			"Invalid cryptographic signature")
+	case errors.Is(err, auth.ErrNonceReused):
+		return rejectSignedRequest(c, client, metrics.RejectReplayedNonce, fiber.StatusUnauthorized,
+			"X-Nonce has already been used - replayed request refused")
 	}
 
-	// If current key fails, check old key if within grace period
+	slog.ErrorContext(c.UserContext(), "Failed to verify signed request", logging.Err(err))
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "Failed to check request nonce",
+	})
+}
+
+// rejectSignedRequest refuses a signed client request, counting why
+func rejectSignedRequest(c *fiber.Ctx, client *models.Client, reason string, status int, message string) error {
+	metrics.SignatureRejections.WithLabelValues(client.ID.Hex(), reason).Inc()
+	slog.WarnContext(c.UserContext(), "Signed request refused", "client", client.Name, "tier", client.Tier, "reason", reason)
+	return c.Status(status).JSON(fiber.Map{
+		"error": message,
+	})
+}
+
+// setClient stores the authenticated client for downstream handlers and tags
+// the request's log records with its ID
+func setClient(c *fiber.Ctx, client *models.Client) {
+	c.Locals("client", client)
+	c.SetUserContext(logging.With(c.UserContext(), slog.String(logging.KeyClientID, client.ID.Hex())))
+}
+
+// clientFromContext returns the client stored by AuthMiddleware, if any
+func clientFromContext(c *fiber.Ctx) *models.Client {
+	client, _ := c.Locals("client").(*models.Client)
+	return client
+}
+
+// signingKeys returns the keys a client's requests may be signed with: its
+// current key, and its old one during a rotation's grace period
+func signingKeys(client *models.Client) []string {
+	keys := []string{client.PublicKey}
+
 	if client.OldPublicKey != "" && client.KeyRotatedAt != nil {
 		gracePeriod := client.GracePeriodHours
 		if gracePeriod == 0 {
@@ -145,16 +278,12 @@ func verifyAdaptiveSignature(client *models.Client, data, signature, timestamp,
 
 		expiresAt := client.KeyRotatedAt.Add(time.Duration(gracePeriod) * time.Hour)
 		if time.Now().Before(expiresAt) {
-			log.Printf("🔄 Trying old public key (grace period active until %s)", expiresAt.Format(time.RFC3339))
-			valid, err := auth.VerifySignature(client.OldPublicKey, payload, signature)
-			if err == nil && valid {
-				log.Printf("✅ Signature verified with old public key during grace period")
-				return true, nil
-			}
+			slog.Debug("Accepting old public key during rotation grace period", "client", client.Name, "grace_until", expiresAt)
+			keys = append(keys, client.OldPublicKey)
 		}
 	}
 
-	return false, nil
+	return keys
 }
 
 // isIPWhitelisted checks if the client IP is in the allowed list
@@ -173,19 +302,169 @@ func isIPWhitelisted(clientIP string, allowedIPs []string) bool {
 	return false
 }
 
-// AdminAuthMiddleware validates admin password
-func AdminAuthMiddleware(adminPassword string) fiber.Handler {
+// AdminAuthMiddleware authenticates a named admin account from
+//
+//	Authorization: Bearer <token>   session token from POST /admin/login
+//	Authorization: Basic <user:pw>  for scripts
+//	X-Admin-Password                legacy header, checked as legacyUser
+//	                                (or X-Admin-User when sent)
+func AdminAuthMiddleware(accounts *admin.AccountManager, legacyUser string) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		password := c.Get("X-Admin-Password")
-		if password == "" || password != adminPassword {
+		var account *models.AdminAccount
+		var err error
+
+		authorization := c.Get(fiber.HeaderAuthorization)
+		switch {
+		case strings.HasPrefix(authorization, "Bearer "):
+			account, err = accounts.VerifyToken(c.UserContext(), strings.TrimPrefix(authorization, "Bearer "))
+		case strings.HasPrefix(authorization, "Basic "):
+			username, password, ok := parseBasicAuth(strings.TrimPrefix(authorization, "Basic "))
+			if !ok {
+				err = admin.ErrBadCredentials
+				break
+			}
+			account, err = accounts.Authenticate(c.UserContext(), username, password)
+		case c.Get("X-Admin-Password") != "":
+			username := c.Get("X-Admin-User", legacyUser)
+			account, err = accounts.Authenticate(c.UserContext(), username, c.Get("X-Admin-Password"))
+		default:
+			err = admin.ErrBadCredentials
+		}
+
+		if err != nil {
+			if !errors.Is(err, admin.ErrBadCredentials) {
+				slog.ErrorContext(c.UserContext(), "Admin authentication failed", logging.Err(err))
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to check admin credentials",
+				})
+			}
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid or missing admin password",
+				"error": "Invalid or missing admin credentials",
 			})
 		}
+
+		c.Locals("admin", account)
+		c.SetUserContext(logging.With(c.UserContext(), slog.String("admin", account.Username)))
 		return c.Next()
 	}
 }
 
+// parseBasicAuth decodes the credentials of a Basic Authorization header
+func parseBasicAuth(encoded string) (string, string, bool) {
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(decoded), ":")
+}
+
+// adminFromContext returns the account stored by AdminAuthMiddleware, if any
+func adminFromContext(c *fiber.Ctx) *models.AdminAccount {
+	account, _ := c.Locals("admin").(*models.AdminAccount)
+	return account
+}
+
+// RequireRole lets through admins with one of roles; superusers always pass
+// It must run after AdminAuthMiddleware
+func RequireRole(roles ...models.AdminRole) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		account := adminFromContext(c)
+		if account == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid or missing admin credentials",
+			})
+		}
+		if account.Role == models.AdminRoleSuperuser {
+			return c.Next()
+		}
+		for _, role := range roles {
+			if account.Role == role {
+				return c.Next()
+			}
+		}
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Your role does not allow this action",
+			"role":  account.Role,
+		})
+	}
+}
+
+// AuditMiddleware records every admin request that changes something - all
+// but GET, HEAD and OPTIONS - in the audit log, including ones RequireRole
+// refused
+// It fails closed: an intent entry is written before the handler runs and
+// the request is refused if that fails, so no action goes unrecorded. The
+// outcome is recorded once the request has been answered. It must run after
+// AdminAuthMiddleware and before RequireRole. Handlers add details with
+// auditDetail.
+func AuditMiddleware(audit *admin.AuditLog) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+
+		intent := auditEntry(c)
+		intent.Details = map[string]string{"phase": "intent"}
+		if auditErr := audit.Record(c.UserContext(), intent); auditErr != nil {
+			metrics.AuditWriteFailures.WithLabelValues("intent").Inc()
+			slog.ErrorContext(c.UserContext(), "Failed to write audit entry, refusing admin action", "action", intent.Action, logging.Err(auditErr))
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to write audit log",
+			})
+		}
+
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			status = fiberErr.Code
+		} else if err != nil {
+			status = fiber.StatusInternalServerError
+		}
+
+		entry := auditEntry(c)
+		entry.Status = status
+		if details, ok := c.Locals("audit_details").(map[string]string); ok {
+			entry.Details = details
+		} else {
+			entry.Details = map[string]string{}
+		}
+		entry.Details["intent_seq"] = strconv.FormatInt(intent.Seq, 10)
+		if auditErr := audit.Record(c.UserContext(), entry); auditErr != nil {
+			// Too late to refuse; the intent entry shows the action was tried
+			metrics.AuditWriteFailures.WithLabelValues("result").Inc()
+			slog.ErrorContext(c.UserContext(), "Failed to write audit entry", "action", entry.Action, "intent_seq", intent.Seq, logging.Err(auditErr))
+		}
+		return err
+	}
+}
+
+// auditEntry describes the admin request being handled
+func auditEntry(c *fiber.Ctx) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		Action:   c.Method() + " " + c.Route().Path,
+		Target:   c.Params("id", c.Params("username")),
+		RemoteIP: c.IP(),
+	}
+	if account := adminFromContext(c); account != nil {
+		entry.Actor = account.Username
+		entry.Role = account.Role
+	}
+	return entry
+}
+
+// auditDetail adds a detail to the request's audit entry
+func auditDetail(c *fiber.Ctx, key, value string) {
+	details, ok := c.Locals("audit_details").(map[string]string)
+	if !ok {
+		details = map[string]string{}
+		c.Locals("audit_details", details)
+	}
+	details[key] = value
+}
+
 // DecodeHexData extracts the data field from requests and decodes it
 func DecodeHexData(c *fiber.Ctx) ([]byte, error) {
 	var payload struct {
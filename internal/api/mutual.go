@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/admin"
+	"github.com/akua/bsv-broadcaster/internal/auth"
+	"github.com/akua/bsv-broadcaster/internal/logging"
+	"github.com/akua/bsv-broadcaster/internal/metrics"
+	"github.com/akua/bsv-broadcaster/internal/models"
+	sdkauth "github.com/bsv-blockchain/go-sdk/auth"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HandleMutualAuth answers BRC-103 handshake messages, opening mutual
+// authentication sessions for registered clients (see auth.MutualAuth)
+func (s *Server) HandleMutualAuth(c *fiber.Ctx) error {
+	var msg sdkauth.AuthMessage
+	if err := json.Unmarshal(c.Body(), &msg); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid auth message",
+		})
+	}
+
+	reply, session, err := s.mutual.HandleMessage(c.UserContext(), &msg, time.Now())
+	if err != nil {
+		clientID := ""
+		if session != nil {
+			clientID = session.ClientID
+			s.auditClientEvent(c, clientID, mutualAuthStatus(err), map[string]string{
+				"message_type": string(msg.MessageType),
+				"reason":       err.Error(),
+			})
+		}
+		return refuseMutualAuth(c, clientID, err)
+	}
+
+	if msg.MessageType == sdkauth.MessageTypeInitialRequest {
+		metrics.MutualAuthSessions.Inc()
+	}
+	s.auditClientEvent(c, session.ClientID, fiber.StatusOK, map[string]string{
+		"message_type": string(msg.MessageType),
+		"identity_key": session.IdentityKey,
+	})
+	return c.JSON(reply)
+}
+
+// mutualAuthenticate serves a request signed under a BRC-103 session in
+// place of AuthMiddleware's API key checks; once the request checks out,
+// every response is signed back, errors included, as clients can't read an
+// unsigned one
+func mutualAuthenticate(c *fiber.Ctx, clientMgr *admin.ClientManager, mutual *auth.MutualAuth) error {
+	r, err := adaptor.ConvertRequest(c, false)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request",
+		})
+	}
+
+	verified, err := mutual.VerifyRequest(c.UserContext(), r)
+	if err != nil {
+		return refuseMutualAuth(c, "", err)
+	}
+	metrics.SignedRequests.WithLabelValues(metrics.VersionMutualAuth).Inc()
+
+	if err := serveMutualRequest(c, clientMgr, verified.Session); err != nil {
+		// Have the error written now so the response can be signed
+		if err := c.App().ErrorHandler(c, err); err != nil {
+			return err
+		}
+	}
+
+	header := http.Header{}
+	c.Response().Header.VisitAll(func(key, value []byte) {
+		header.Add(string(key), string(value))
+	})
+	signed, err := mutual.SignResponse(c.UserContext(), verified, c.Response().StatusCode(), header, c.Response().Body())
+	if err != nil {
+		slog.ErrorContext(c.UserContext(), "Failed to sign mutual auth response", logging.Err(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to sign response",
+		})
+	}
+	for key, values := range signed {
+		c.Set(key, values[0])
+	}
+	return nil
+}
+
+// serveMutualRequest applies AuthMiddleware's client checks to a session's
+// client and runs the rest of the chain
+func serveMutualRequest(c *fiber.Ctx, clientMgr *admin.ClientManager, session *models.AuthSession) error {
+	clientID, err := primitive.ObjectIDFromHex(session.ClientID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid mutual auth session",
+		})
+	}
+	client, err := clientMgr.GetClientByID(c.Context(), clientID)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Client not found",
+		})
+	}
+
+	// A key rotation ends the sessions of the old key
+	identityKey, _ := auth.NormalizePublicKey(session.IdentityKey)
+	currentKey, _ := auth.NormalizePublicKey(client.PublicKey)
+	if identityKey == "" || identityKey != currentKey {
+		return rejectMutualAuth(c, client.ID.Hex(), metrics.RejectNoSession, fiber.StatusUnauthorized,
+			"Identity key is no longer the client's public key - authenticate again with the current key")
+	}
+
+	if !client.IsActive {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Client account is disabled",
+		})
+	}
+
+	return admitClient(c, clientMgr, client)
+}
+
+// refuseMutualAuth answers a mutual authentication message or request that
+// failed auth.MutualAuth, counting why; clientID is empty when the client
+// isn't known yet
+func refuseMutualAuth(c *fiber.Ctx, clientID string, err error) error {
+	status := mutualAuthStatus(err)
+	switch {
+	case errors.Is(err, auth.ErrInvalidAuthMessage):
+		return rejectMutualAuth(c, clientID, metrics.RejectInvalidAuthMessage, status, err.Error())
+	case errors.Is(err, auth.ErrInvalidNonce):
+		return rejectMutualAuth(c, clientID, metrics.RejectInvalidNonce, status, "x-bsv-auth-nonce "+err.Error())
+	case errors.Is(err, auth.ErrUnknownIdentity):
+		return rejectMutualAuth(c, clientID, metrics.RejectUnknownIdentity, status,
+			"Identity key is not a registered client public key")
+	case errors.Is(err, auth.ErrClientDisabled):
+		return rejectMutualAuth(c, clientID, metrics.RejectClientDisabled, status, "Client account is disabled")
+	case errors.Is(err, auth.ErrAuthSessionNotFound):
+		return rejectMutualAuth(c, clientID, metrics.RejectNoSession, status,
+			"Mutual authentication session not found or expired - repeat the handshake")
+	case errors.Is(err, auth.ErrCertificatesRequired):
+		return rejectMutualAuth(c, clientID, metrics.RejectCertificates, status, err.Error())
+	case errors.Is(err, auth.ErrInvalidSignature):
+		return rejectMutualAuth(c, clientID, metrics.RejectInvalidSignature, status, "Invalid cryptographic signature")
+	case errors.Is(err, auth.ErrNonceReused):
+		return rejectMutualAuth(c, clientID, metrics.RejectReplayedNonce, status,
+			"x-bsv-auth-nonce has already been used - replayed request refused")
+	}
+
+	slog.ErrorContext(c.UserContext(), "Failed to check mutual authentication", logging.Err(err))
+	return c.Status(status).JSON(fiber.Map{
+		"error": "Failed to check mutual authentication",
+	})
+}
+
+// mutualAuthStatus is the HTTP status refuseMutualAuth answers err with
+func mutualAuthStatus(err error) int {
+	switch {
+	case errors.Is(err, auth.ErrInvalidAuthMessage), errors.Is(err, auth.ErrInvalidNonce):
+		return fiber.StatusBadRequest
+	case errors.Is(err, auth.ErrClientDisabled):
+		return fiber.StatusForbidden
+	case errors.Is(err, auth.ErrUnknownIdentity), errors.Is(err, auth.ErrAuthSessionNotFound),
+		errors.Is(err, auth.ErrCertificatesRequired), errors.Is(err, auth.ErrInvalidSignature),
+		errors.Is(err, auth.ErrNonceReused):
+		return fiber.StatusUnauthorized
+	}
+	return fiber.StatusInternalServerError
+}
+
+// rejectMutualAuth refuses a mutual authentication message or request,
+// counting why
+func rejectMutualAuth(c *fiber.Ctx, clientID, reason string, status int, message string) error {
+	metrics.SignatureRejections.WithLabelValues(clientID, reason).Inc()
+	slog.WarnContext(c.UserContext(), "Mutual authentication refused", "client_id", clientID, "reason", reason)
+	return c.Status(status).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	clients "github.com/bsv-blockchain/go-sdk/auth/clients/authhttp"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+	"github.com/bsv-blockchain/go-sdk/wallet"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+)
+
+// mutualClient is the go-sdk's BRC-103 client acting as a registered
+// client's identity key, talking to ts over real HTTP
+func (ts *testServer) mutualClient(t *testing.T) (*clients.AuthFetch, string) {
+	t.Helper()
+
+	identity, err := ec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	identityWallet, err := wallet.NewCompletedProtoWallet(identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.registerClient(t, hex.EncodeToString(identity.PubKey().Uncompressed()), 10)
+
+	srv := httptest.NewServer(adaptor.FiberApp(ts.app))
+	t.Cleanup(srv.Close)
+	return clients.New(identityWallet, clients.WithoutLogging()), srv.URL
+}
+
+func TestMutualAuthPublishRoundTrip(t *testing.T) {
+	ts := newTestServer(t, 1)
+	client, baseURL := ts.mutualClient(t)
+
+	// The first fetch runs the handshake against /.well-known/auth, then
+	// sends the signed publish; Fetch fails unless our response signature
+	// checks out
+	body, _ := json.Marshal(PublishRequest{Data: testData})
+	resp, err := client.Fetch(t.Context(), baseURL+"/publish", &clients.SimplifiedFetchRequestOptions{
+		Method:  http.MethodPost,
+		Headers: map[string]string{"content-type": "application/json"},
+		Body:    body,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("publish status = %d, want %d: %s", resp.StatusCode, http.StatusAccepted, respBody)
+	}
+	if got := resp.Header.Get("x-bsv-auth-identity-key"); got != ts.mutual.IdentityKey() {
+		t.Fatalf("response signed by %q, want the server identity %s", got, ts.mutual.IdentityKey())
+	}
+
+	var published PublishResponse
+	if err := json.Unmarshal(respBody, &published); err != nil || published.UUID == "" {
+		t.Fatalf("publish response = %s (%v), want a request UUID", respBody, err)
+	}
+	if _, err := ts.db.GetRequestByUUID(t.Context(), published.UUID); err != nil {
+		t.Fatalf("published request %s not stored: %v", published.UUID, err)
+	}
+
+	// Refusals are signed too, so the client can read them: the pool is empty
+	resp, err = client.Fetch(t.Context(), baseURL+"/publish", &clients.SimplifiedFetchRequestOptions{
+		Method:  http.MethodPost,
+		Headers: map[string]string{"content-type": "application/json"},
+		Body:    body,
+	})
+	if err != nil {
+		t.Fatalf("signed refusal: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("empty pool status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+}
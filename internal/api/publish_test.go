@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestPublishRequiresAPIKey(t *testing.T) {
+	ts := newTestServer(t, 1)
+
+	resp, _ := ts.do(t, publishRequest(testData))
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestPublishConcurrentRequestsStayWithinDailyQuota(t *testing.T) {
+	const maxDailyTx, requests = 10, 40
+	ts := newTestServer(t, requests)
+	apiKey, client := ts.registerClient(t, "", maxDailyTx)
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		statuses = make(map[int]int)
+		errs     []error
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := publishRequest(testData)
+			req.Header.Set("X-API-Key", apiKey)
+			resp, err := ts.app.Test(req, -1)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			resp.Body.Close()
+			statuses[resp.StatusCode]++
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		t.Fatal(errs[0])
+	}
+	if statuses[http.StatusAccepted] != maxDailyTx || statuses[http.StatusTooManyRequests] != requests-maxDailyTx {
+		t.Fatalf("statuses = %v, want %d accepted and %d refused", statuses, maxDailyTx, requests-maxDailyTx)
+	}
+	if got := ts.dailyCount(t, client); got != maxDailyTx {
+		t.Fatalf("daily count = %d, want %d", got, maxDailyTx)
+	}
+}
+
+func TestPublishRefundsQuotaOnFailureBeforeBroadcast(t *testing.T) {
+	ts := newTestServer(t, 1)
+	apiKey, client := ts.registerClient(t, "", 1)
+
+	publish := func(data string) *http.Response {
+		req := publishRequest(data)
+		req.Header.Set("X-API-Key", apiKey)
+		resp, _ := ts.do(t, req)
+		return resp
+	}
+
+	// Refused after authentication: bad input, then an empty pool once the
+	// only UTXO is spent
+	if resp := publish("not hex"); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("bad data status = %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+	if got := ts.dailyCount(t, client); got != 0 {
+		t.Fatalf("daily count after bad request = %d, want 0", got)
+	}
+
+	resp := publish(testData)
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("publish status = %d, want %d", resp.StatusCode, http.StatusAccepted)
+	}
+	if got := resp.Header.Get("X-RateLimit-Remaining"); got != "0" {
+		t.Fatalf("X-RateLimit-Remaining = %q, want 0", got)
+	}
+
+	// The quota is used up, so this one is refused before the pool is tried
+	if resp := publish(testData); resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("over quota status = %d, want %d", resp.StatusCode, http.StatusTooManyRequests)
+	}
+
+	if err := ts.db.UpdateClientLimits(t.Context(), client.ID, 2, 1000, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if resp := publish(testData); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("empty pool status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if got := ts.dailyCount(t, client); got != 1 {
+		t.Fatalf("daily count after empty pool = %d, want 1", got)
+	}
+}
+
+func TestPublishRefundsQuotaWhenARCNeverTakesTheTransaction(t *testing.T) {
+	ts := newTestServer(t, 2)
+	apiKey, client := ts.registerClient(t, "", 10)
+
+	publish := func() (*http.Response, []byte) {
+		body, _ := json.Marshal(PublishRequest{Data: testData})
+		req := httptest.NewRequest(http.MethodPost, "/publish?wait=true", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", apiKey)
+		return ts.do(t, req)
+	}
+
+	// ARC refuses the whole batch, so the transaction never reached it
+	ts.arc.FailNext(http.StatusBadRequest, 1)
+	if resp, body := publish(); resp.StatusCode == http.StatusCreated || resp.StatusCode == http.StatusAccepted {
+		t.Fatalf("refused broadcast status = %d, want an error: %s", resp.StatusCode, body)
+	}
+	if got := ts.dailyCount(t, client); got != 0 {
+		t.Fatalf("daily count after ARC refused the batch = %d, want 0", got)
+	}
+
+	// One ARC takes stays charged
+	if resp, body := publish(); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("broadcast status = %d, want %d: %s", resp.StatusCode, http.StatusCreated, body)
+	}
+	if got := ts.dailyCount(t, client); got != 1 {
+		t.Fatalf("daily count after a broadcast = %d, want 1", got)
+	}
+}
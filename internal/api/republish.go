@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/akua/bsv-broadcaster/internal/logging"
+	"github.com/akua/bsv-broadcaster/internal/models"
+	"github.com/akua/bsv-broadcaster/internal/train"
+	"github.com/bsv-blockchain/go-sdk/transaction"
+	"github.com/google/uuid"
+)
+
+// republish reissues the data output of a double-spent publish on a fresh
+// publishing UTXO and queues it as a new request for the same client
+func (s *Server) republish(ctx context.Context, work train.TxWork) (string, error) {
+	lost, err := transaction.NewTransactionFromHex(work.RawTxHex)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse original transaction: %w", err)
+	}
+
+	var dataOutput *transaction.TransactionOutput
+	for _, out := range lost.Outputs {
+		if out.LockingScript != nil && out.LockingScript.IsData() {
+			dataOutput = out
+			break
+		}
+	}
+	if dataOutput == nil {
+		return "", fmt.Errorf("original transaction has no data output")
+	}
+
+	utxo, err := s.reservations.FindAndLockUTXO(ctx, models.UTXOTypePublishing)
+	if err != nil {
+		return "", fmt.Errorf("no publishing UTXOs available: %w", err)
+	}
+
+	rawHex, txid, err := s.buildPublishTx(utxo, dataOutput.LockingScript)
+	if err != nil {
+		s.db.UnlockUTXO(ctx, utxo.Outpoint)
+		return "", fmt.Errorf("failed to create transaction: %w", err)
+	}
+
+	requestUUID := uuid.New().String()
+	req := &models.BroadcastRequest{
+		UUID:     requestUUID,
+		RawTxHex: rawHex,
+		UTXOUsed: utxo.Outpoint,
+		Status:   models.RequestStatusPending,
+		Priority: string(work.Priority),
+		ClientID: work.ClientID,
+	}
+	if err := s.db.InsertBroadcastRequest(ctx, req); err != nil {
+		s.db.UnlockUTXO(ctx, utxo.Outpoint)
+		return "", fmt.Errorf("failed to save request: %w", err)
+	}
+
+	// A replacement that loses again is reported, not republished forever
+	replacement := train.TxWork{
+		UUID:        requestUUID,
+		RawTxHex:    rawHex,
+		UTXOUsed:    utxo.Outpoint,
+		TxID:        txid,
+		Priority:    work.Priority,
+		ClientID:    work.ClientID,
+		Tier:        work.Tier,
+		MaxInFlight: work.MaxInFlight,
+		SpanContext: work.SpanContext, // Stays in the original publish's trace
+	}
+	if err := s.train.Enqueue(replacement); err != nil {
+		s.db.UnlockUTXO(ctx, utxo.Outpoint)
+		s.db.UpdateRequestStatus(ctx, requestUUID, models.RequestStatusFailed, "", "", err.Error())
+		return "", fmt.Errorf("failed to enqueue: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Republishing double-spent payload", logging.KeyRequestUUID, work.UUID,
+		"new_request_uuid", requestUUID, logging.KeyUTXO, utxo.Outpoint)
+	return requestUUID, nil
+}
@@ -3,50 +3,71 @@ package api
 import (
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/akua/bsv-broadcaster/internal/admin"
 	"github.com/akua/bsv-broadcaster/internal/arc"
+	"github.com/akua/bsv-broadcaster/internal/auth"
 	"github.com/akua/bsv-broadcaster/internal/bsv"
 	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/leader"
+	"github.com/akua/bsv-broadcaster/internal/logging"
+	"github.com/akua/bsv-broadcaster/internal/metrics"
 	"github.com/akua/bsv-broadcaster/internal/models"
+	"github.com/akua/bsv-broadcaster/internal/tracing"
 	"github.com/akua/bsv-broadcaster/internal/train"
 	"github.com/bsv-blockchain/go-sdk/script"
 	"github.com/bsv-blockchain/go-sdk/transaction"
-	"github.com/bsv-blockchain/go-sdk/transaction/template/p2pkh"
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Server handles HTTP API requests
 type Server struct {
-	db            *database.Database
+	db            database.Store
+	reservations  *database.ReservationCache // Publishing UTXOs locked in bulk
 	train         *train.Train
 	publishingKey *bsv.KeyPair
 	splitter      *bsv.Splitter
 	arcClient     *arc.Client
+	audit         *admin.AuditLog
+	clients       *admin.ClientManager  // Authenticates /publish and charges client quotas
+	signatures    *auth.RequestVerifier // Checks signed client requests
+	mutual        *auth.MutualAuth      // BRC-103 mutual authentication sessions
 	app           *fiber.App
 }
 
 // NewServer creates a new API server
-func NewServer(db *database.Database, trainWorker *train.Train, publishingKey *bsv.KeyPair, splitter *bsv.Splitter, arcClient *arc.Client) *Server {
+func NewServer(db database.Store, reservations *database.ReservationCache, trainWorker *train.Train, publishingKey *bsv.KeyPair, splitter *bsv.Splitter, arcClient *arc.Client, audit *admin.AuditLog, clients *admin.ClientManager, signatures *auth.RequestVerifier, mutual *auth.MutualAuth) *Server {
 	app := fiber.New(fiber.Config{
 		AppName:               "BSV AKUA Broadcaster",
 		DisableStartupMessage: true,
 	})
+	app.Use(TracingMiddleware())
 
 	s := &Server{
 		db:            db,
+		reservations:  reservations,
 		train:         trainWorker,
 		publishingKey: publishingKey,
 		splitter:      splitter,
 		arcClient:     arcClient,
+		audit:         audit,
+		clients:       clients,
+		signatures:    signatures,
+		mutual:        mutual,
 		app:           app,
 	}
 
+	trainWorker.SetRepublisher(s.republish)
+
 	s.setupRoutes()
 	return s
 }
@@ -57,7 +78,7 @@ func (s *Server) setupRoutes() {
 	s.app.Get("/health", s.handleHealth)
 
 	// Main endpoints
-	s.app.Post("/publish", s.handlePublish)
+	s.app.Post("/publish", AuthMiddleware(s.db, s.clients, s.signatures, s.mutual), s.handlePublish)
 	s.app.Get("/status/:uuid", s.handleStatus)
 
 	// Self-service auth endpoints
@@ -65,10 +86,12 @@ func (s *Server) setupRoutes() {
 	s.app.Post("/auth/rotate-public-key", s.HandleRotatePublicKey)
 	s.app.Get("/auth/key-status", s.HandleKeyStatus)
 
-	// Admin endpoints (should be protected in production)
+	// BRC-103 mutual authentication handshake (BRC-104 transport)
+	s.app.Post("/.well-known/auth", s.HandleMutualAuth)
+
+	// Pool statistics; the admin endpoints that change anything are in
+	// RegisterAdminRoutes
 	s.app.Get("/admin/stats", s.handleStats)
-	s.app.Post("/admin/split", s.handleSplit)
-	s.app.Post("/admin/split-phase2", s.handleSplitPhase2)
 }
 
 // PublishRequest represents a request to publish an OP_RETURN transaction
@@ -87,12 +110,14 @@ type PublishResponse struct {
 func (s *Server) handlePublish(c *fiber.Ctx) error {
 	var req PublishRequest
 	if err := c.BodyParser(&req); err != nil {
+		refundQuota(c)
 		return c.Status(400).JSON(fiber.Map{
 			"error": "invalid request body",
 		})
 	}
 
 	if req.Data == "" {
+		refundQuota(c)
 		return c.Status(400).JSON(fiber.Map{
 			"error": "data field is required",
 		})
@@ -101,24 +126,36 @@ func (s *Server) handlePublish(c *fiber.Ctx) error {
 	// Validate hex
 	dataBytes, err := hex.DecodeString(req.Data)
 	if err != nil {
+		refundQuota(c)
 		return c.Status(400).JSON(fiber.Map{
 			"error": "data must be valid hex",
 		})
 	}
 
+	submittedAt := time.Now()
+	client := clientFromContext(c)
+	var clientID, tier string
+	if client != nil {
+		clientID, tier = client.ID.Hex(), client.Tier
+	}
+
 	// Get an available publishing UTXO
-	utxo, err := s.db.FindAndLockUTXO(c.Context(), models.UTXOTypePublishing)
+	utxo, err := s.reservations.FindAndLockUTXO(c.Context(), models.UTXOTypePublishing)
 	if err != nil {
-		log.Printf("❌ No publishing UTXOs available: %v", err)
+		slog.ErrorContext(c.UserContext(), "No publishing UTXOs available", logging.Err(err))
+		metrics.ObservePublish(clientID, tier, metrics.OutcomeNoUTXO, submittedAt)
+		refundQuota(c)
 		return c.Status(503).JSON(fiber.Map{
 			"error": "no publishing UTXOs available, try again later",
 		})
 	}
 
 	// Create the OP_RETURN transaction
-	rawHex, err := s.createOPReturnTx(utxo, dataBytes)
+	rawHex, txid, err := s.createOPReturnTx(utxo, dataBytes)
 	if err != nil {
 		s.db.UnlockUTXO(c.Context(), utxo.Outpoint) // Release UTXO
+		metrics.ObservePublish(clientID, tier, metrics.OutcomeError, submittedAt)
+		refundQuota(c)
 		return c.Status(500).JSON(fiber.Map{
 			"error": fmt.Sprintf("failed to create transaction: %v", err),
 		})
@@ -126,6 +163,18 @@ func (s *Server) handlePublish(c *fiber.Ctx) error {
 
 	// Generate UUID for tracking
 	requestUUID := uuid.New().String()
+	ctx := logging.With(c.UserContext(), slog.String(logging.KeyRequestUUID, requestUUID))
+
+	span := trace.SpanFromContext(c.UserContext())
+	span.SetAttributes(
+		tracing.AttrRequestUUID.String(requestUUID),
+		tracing.AttrClientID.String(clientID),
+		tracing.AttrClientTier.String(tier),
+		tracing.AttrTxID.String(txid),
+		tracing.AttrUTXO.String(utxo.Outpoint),
+	)
+
+	priority := requestPriority(c)
 
 	// Check if client wants synchronous wait
 	waitForResult := c.Query("wait") == "true"
@@ -133,7 +182,7 @@ func (s *Server) handlePublish(c *fiber.Ctx) error {
 
 	// If queue is already full (>1000), fall back to async even if wait=true
 	if waitForResult && queueSize >= 1000 {
-		log.Printf("⚠️  Queue is full (%d), falling back to async mode", queueSize)
+		slog.WarnContext(ctx, "Queue is full, falling back to async mode", "queue_size", queueSize)
 		waitForResult = false
 	}
 
@@ -143,6 +192,8 @@ func (s *Server) handlePublish(c *fiber.Ctx) error {
 		RawTxHex: rawHex,
 		UTXOUsed: utxo.Outpoint,
 		Status:   models.RequestStatusPending,
+		Priority: string(priority),
+		ClientID: clientID,
 	}
 
 	// Create response channel if synchronous mode
@@ -152,6 +203,8 @@ func (s *Server) handlePublish(c *fiber.Ctx) error {
 
 	if err := s.db.InsertBroadcastRequest(c.Context(), broadcastReq); err != nil {
 		s.db.UnlockUTXO(c.Context(), utxo.Outpoint)
+		metrics.ObservePublish(clientID, tier, metrics.OutcomeError, submittedAt)
+		refundQuota(c)
 		return c.Status(500).JSON(fiber.Map{
 			"error": "failed to save request",
 		})
@@ -162,10 +215,34 @@ func (s *Server) handlePublish(c *fiber.Ctx) error {
 		UUID:         requestUUID,
 		RawTxHex:     rawHex,
 		UTXOUsed:     utxo.Outpoint,
+		TxID:         txid,
+		Priority:     priority,
+		ClientID:     clientID,
+		Tier:         tier,
+		SubmittedAt:  submittedAt,
+		SpanContext:  span.SpanContext(),
 		ResponseChan: broadcastReq.ResponseChan,
 	}
+	if client != nil {
+		work.MaxInFlight = client.MaxInFlight
+		work.AutoRepublish = client.AutoRepublish
+	}
+	if quota, _ := c.Locals("quota").(*admin.Quota); quota != nil {
+		work.Refund = quota.Refund
+	}
 
 	if err := s.train.Enqueue(work); err != nil {
+		// Nothing was broadcast - free the UTXO and close out the request
+		s.db.UnlockUTXO(c.Context(), utxo.Outpoint)
+		s.db.UpdateRequestStatus(c.Context(), requestUUID, models.RequestStatusFailed, "", "", err.Error())
+		metrics.ObservePublish(clientID, tier, metrics.OutcomeQueueFull, submittedAt)
+		refundQuota(c)
+
+		if errors.Is(err, train.ErrClientBacklog) {
+			return c.Status(429).JSON(fiber.Map{
+				"error": "too many transactions in flight for this client, try again",
+			})
+		}
 		return c.Status(503).JSON(fiber.Map{
 			"error": "queue is full, try again",
 		})
@@ -211,7 +288,7 @@ func (s *Server) handlePublish(c *fiber.Ctx) error {
 
 		case <-time.After(timeout):
 			// Timeout - fall back to async response
-			log.Printf("⚠️  Sync wait timeout for %s, falling back to async", requestUUID)
+			slog.WarnContext(ctx, "Sync wait timed out, falling back to async", "timeout", timeout)
 			return c.Status(202).JSON(fiber.Map{
 				"success": true,
 				"uuid":    requestUUID,
@@ -229,34 +306,68 @@ func (s *Server) handlePublish(c *fiber.Ctx) error {
 	})
 }
 
-// createOPReturnTx constructs a raw OP_RETURN transaction
-func (s *Server) createOPReturnTx(utxo *models.UTXO, data []byte) (string, error) {
-	tx := transaction.NewTransaction()
+// PublishData queues data for broadcast in an OP_RETURN through the train,
+// like a publish with no client, returning the request UUID and txid
+func (s *Server) PublishData(ctx context.Context, data []byte) (string, string, error) {
+	utxo, err := s.reservations.FindAndLockUTXO(ctx, models.UTXOTypePublishing)
+	if err != nil {
+		return "", "", fmt.Errorf("no publishing UTXOs available: %w", err)
+	}
 
-	// Add input (the 100-sat publishing UTXO)
-	utxoScript, err := hex.DecodeString(utxo.ScriptPubKey)
+	rawHex, txid, err := s.createOPReturnTx(utxo, data)
 	if err != nil {
-		return "", fmt.Errorf("invalid script: %w", err)
+		s.db.UnlockUTXO(ctx, utxo.Outpoint)
+		return "", "", fmt.Errorf("failed to create transaction: %w", err)
 	}
 
-	// Create P2PKH unlocker
-	unlocker, err := p2pkh.Unlock(s.publishingKey.PrivateKey, nil)
+	requestUUID := uuid.New().String()
+	err = s.db.InsertBroadcastRequest(ctx, &models.BroadcastRequest{
+		UUID:     requestUUID,
+		RawTxHex: rawHex,
+		UTXOUsed: utxo.Outpoint,
+		Status:   models.RequestStatusPending,
+		Priority: string(train.PriorityNormal),
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to create unlocker: %w", err)
+		s.db.UnlockUTXO(ctx, utxo.Outpoint)
+		return "", "", fmt.Errorf("failed to save request: %w", err)
 	}
 
-	err = tx.AddInputFrom(
-		utxo.TxID,
-		utxo.Vout,
-		hex.EncodeToString(utxoScript),
-		utxo.Satoshis,
-		unlocker,
-	)
+	err = s.train.Enqueue(train.TxWork{
+		UUID:        requestUUID,
+		RawTxHex:    rawHex,
+		UTXOUsed:    utxo.Outpoint,
+		TxID:        txid,
+		Priority:    train.PriorityNormal,
+		SubmittedAt: time.Now(),
+		SpanContext: trace.SpanContextFromContext(ctx),
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to add input: %w", err)
+		s.db.UnlockUTXO(ctx, utxo.Outpoint)
+		s.db.UpdateRequestStatus(ctx, requestUUID, models.RequestStatusFailed, "", "", err.Error())
+		return "", "", fmt.Errorf("failed to queue transaction: %w", err)
 	}
 
-	// Add OP_RETURN output
+	return requestUUID, txid, nil
+}
+
+// requestPriority picks the train lane for a publish
+// The client's tier sets the default lane, and the ?priority= flag may move a
+// request to the same or a lower lane but never above what the tier allows
+func requestPriority(c *fiber.Ctx) train.Priority {
+	base := train.PriorityNormal
+	if client := clientFromContext(c); client != nil {
+		base = train.PriorityForTier(client.Tier)
+	}
+
+	if requested, ok := train.ParsePriority(c.Query("priority")); ok && requested.Rank() <= base.Rank() {
+		return requested
+	}
+	return base
+}
+
+// createOPReturnTx constructs a raw OP_RETURN transaction and returns it with its txid
+func (s *Server) createOPReturnTx(utxo *models.UTXO, data []byte) (string, string, error) {
 	// Construct OP_RETURN script manually: OP_FALSE OP_RETURN <data>
 	opReturnHex := "006a" // OP_FALSE OP_RETURN
 
@@ -277,9 +388,40 @@ func (s *Server) createOPReturnTx(utxo *models.UTXO, data []byte) (string, error
 	opReturnBytes, _ := hex.DecodeString(opReturnHex)
 	opReturnScript := script.Script(opReturnBytes)
 
+	return s.buildPublishTx(utxo, &opReturnScript)
+}
+
+// buildPublishTx spends a publishing UTXO into a single zero-value data output
+func (s *Server) buildPublishTx(utxo *models.UTXO, dataScript *script.Script) (string, string, error) {
+	tx := transaction.NewTransaction()
+
+	// Add input (the 100-sat publishing UTXO)
+	utxoScript, err := hex.DecodeString(utxo.ScriptPubKey)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid script: %w", err)
+	}
+
+	// Create P2PKH unlocker with the key that controls this UTXO
+	unlocker, err := s.publishingKey.UnlockerFor(utxo)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create unlocker: %w", err)
+	}
+
+	err = tx.AddInputFrom(
+		utxo.TxID,
+		utxo.Vout,
+		hex.EncodeToString(utxoScript),
+		utxo.Satoshis,
+		unlocker,
+	)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to add input: %w", err)
+	}
+
+	// Add OP_RETURN output
 	tx.AddOutput(&transaction.TransactionOutput{
 		Satoshis:      0,
-		LockingScript: &opReturnScript,
+		LockingScript: dataScript,
 	})
 
 	// Calculate fee (should be ~0.5 sats/byte)
@@ -288,22 +430,23 @@ func (s *Server) createOPReturnTx(utxo *models.UTXO, data []byte) (string, error
 
 	// Sign the transaction
 	if err := tx.Sign(); err != nil {
-		return "", fmt.Errorf("failed to sign transaction: %w", err)
+		return "", "", fmt.Errorf("failed to sign transaction: %w", err)
 	}
 
-	// Return raw hex
-	return tx.String(), nil
+	// Return raw hex; the train matches ARC's responses by this txid
+	return tx.String(), tx.TxID().String(), nil
 }
 
 // StatusResponse contains transaction status information
 type StatusResponse struct {
-	UUID      string `json:"uuid"`
-	Status    string `json:"status"`
-	TxID      string `json:"txid,omitempty"`
-	ARCStatus string `json:"arcStatus,omitempty"`
-	Error     string `json:"error,omitempty"`
-	CreatedAt string `json:"createdAt"`
-	UpdatedAt string `json:"updatedAt"`
+	UUID      string                    `json:"uuid"`
+	Status    string                    `json:"status"`
+	TxID      string                    `json:"txid,omitempty"`
+	ARCStatus string                    `json:"arcStatus,omitempty"`
+	Error     string                    `json:"error,omitempty"`
+	Attempts  []models.BroadcastAttempt `json:"attempts,omitempty"` // Failed ARC round trips
+	CreatedAt string                    `json:"createdAt"`
+	UpdatedAt string                    `json:"updatedAt"`
 }
 
 // handleStatus checks the status of a broadcast request
@@ -323,6 +466,7 @@ func (s *Server) handleStatus(c *fiber.Ctx) error {
 		TxID:      req.TxID,
 		ARCStatus: req.ARCStatus,
 		Error:     req.Error,
+		Attempts:  req.Attempts,
 		CreatedAt: req.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt: req.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	})
@@ -339,9 +483,13 @@ func (s *Server) handleHealth(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"status":     "healthy",
-		"queueDepth": s.train.QueueSize(),
-		"utxos":      stats,
+		"status":           "healthy",
+		"queueDepth":       s.train.QueueSize(),
+		"queueDepthByLane": s.train.LaneDepths(),
+		"batchesInFlight":  s.train.BatchesInFlight(),
+		"train":            s.train.SchedulerStats(),
+		"utxos":            stats,
+		"utxosReserved":    s.reservations.Len(),
 	})
 }
 
@@ -400,12 +548,23 @@ func (s *Server) handleStats(c *fiber.Ctx) error {
 		avgLatencyMs = totalLatency.Milliseconds() / int64(latencyCount)
 	}
 
+	// Instance running the singleton workers, if any replica holds a live lease
+	leaderID := ""
+	if lease, err := s.db.GetLeaderLease(c.Context(), leader.Singletons); err == nil && lease != nil && lease.ExpiresAt.After(time.Now()) {
+		leaderID = lease.Holder
+	}
+
 	return c.JSON(fiber.Map{
-		"utxos":         stats,
-		"queueDepth":    s.train.QueueSize(),
-		"broadcasts24h": successCount,
-		"avgLatencyMs":  avgLatencyMs,
-		"throughput":    throughput,
+		"utxos":            stats,
+		"utxosReserved":    s.reservations.Len(),
+		"queueDepth":       s.train.QueueSize(),
+		"queueDepthByLane": s.train.LaneDepths(),
+		"batchesInFlight":  s.train.BatchesInFlight(),
+		"train":            s.train.SchedulerStats(),
+		"broadcasts24h":    successCount,
+		"avgLatencyMs":     avgLatencyMs,
+		"throughput":       throughput,
+		"leader":           leaderID,
 	})
 }
 
@@ -414,6 +573,25 @@ type SplitRequest struct {
 	Count int `json:"count"`
 }
 
+// broadcastSplitTx sends one split transaction to ARC, failing unless ARC
+// accepted it (splits build on their parents, so an orphan is a failure)
+func (s *Server) broadcastSplitTx(ctx context.Context, rawHex string) (string, error) {
+	responses, err := s.arcClient.BroadcastBatch(ctx, []string{rawHex})
+	if err != nil {
+		return "", fmt.Errorf("ARC broadcast failed: %w", err)
+	}
+	if len(responses) == 0 {
+		return "", fmt.Errorf("ARC returned no responses")
+	}
+
+	resp := responses[0]
+	if err := arc.CheckAccepted(&resp); err != nil {
+		return "", err
+	}
+
+	return resp.TxID, nil
+}
+
 // handleSplit manually triggers UTXO splitting (admin only)
 func (s *Server) handleSplit(c *fiber.Ctx) error {
 	var req SplitRequest
@@ -429,58 +607,18 @@ func (s *Server) handleSplit(c *fiber.Ctx) error {
 
 	ctx := context.Background()
 
-	// Define ARC broadcast function for dependency injection
-	arcBroadcastFunc := func(ctx context.Context, rawHex string) (string, error) {
-		responses, err := s.arcClient.BroadcastBatch(ctx, []string{rawHex})
-		if err != nil {
-			return "", fmt.Errorf("ARC broadcast failed: %w", err)
-		}
-		if len(responses) == 0 {
-			return "", fmt.Errorf("ARC returned no responses")
-		}
-
-		// Check response status - only accept if truly successful
-		resp := responses[0]
-
-		// Reject if status code indicates error (400+)
-		if strings.Contains(resp.Title, "Malformed") || strings.Contains(resp.Title, "error") {
-			return "", fmt.Errorf("transaction rejected: %s - %s", resp.Title, resp.ExtraInfo)
-		}
-
-		// Only accept if transaction was received and stored (not orphaned)
-		if resp.TxStatus == arc.TxStatusRejected {
-			return "", fmt.Errorf("transaction rejected: %s", resp.ExtraInfo)
-		}
-
-		// Reject orphan mempool status - means parent tx not found
-		if strings.Contains(string(resp.TxStatus), "ORPHAN") {
-			return "", fmt.Errorf("transaction orphaned - parent transaction not found or not confirmed")
-		}
-
-		// Must be at least RECEIVED or STORED
-		if resp.TxStatus != arc.TxStatusReceived &&
-			resp.TxStatus != arc.TxStatusStored &&
-			resp.TxStatus != arc.TxStatusAnnounced &&
-			resp.TxStatus != arc.TxStatusSent &&
-			resp.TxStatus != arc.TxStatusSeenOnNetwork &&
-			resp.TxStatus != arc.TxStatusAccepted &&
-			resp.TxStatus != arc.TxStatusMined {
-			return "", fmt.Errorf("unexpected transaction status: %s", resp.TxStatus)
-		}
-
-		return resp.TxID, nil
-	}
-
 	// Execute Phase 1: Split into 50 branches
-	result, err := s.splitter.SplitIntoFiftyBranches(ctx, arcBroadcastFunc)
+	result, err := s.splitter.SplitIntoFiftyBranches(ctx, s.broadcastSplitTx)
 	if err != nil {
-		log.Printf("❌ Split failed: %v", err)
+		slog.ErrorContext(c.UserContext(), "Split failed", logging.Err(err))
 		return c.Status(500).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	log.Printf("✅ Phase 1 split complete: %d branch UTXOs created", result.TotalUTXOs)
+	slog.InfoContext(c.UserContext(), "Phase 1 split complete", "branch_utxos", result.TotalUTXOs)
+	auditDetail(c, "txids", strings.Join(result.BranchTxIDs, ","))
+	auditDetail(c, "total_utxos", strconv.Itoa(result.TotalUTXOs))
 
 	return c.JSON(fiber.Map{
 		"message":      "Phase 1 split complete",
@@ -493,56 +631,18 @@ func (s *Server) handleSplit(c *fiber.Ctx) error {
 func (s *Server) handleSplitPhase2(c *fiber.Ctx) error {
 	ctx := context.Background()
 
-	// Define ARC broadcast function
-	arcBroadcastFunc := func(ctx context.Context, rawHex string) (string, error) {
-		responses, err := s.arcClient.BroadcastBatch(ctx, []string{rawHex})
-		if err != nil {
-			return "", fmt.Errorf("ARC broadcast failed: %w", err)
-		}
-		if len(responses) == 0 {
-			return "", fmt.Errorf("ARC returned no responses")
-		}
-
-		resp := responses[0]
-
-		// Reject malformed transactions
-		if strings.Contains(resp.Title, "Malformed") || strings.Contains(resp.Title, "error") {
-			return "", fmt.Errorf("transaction rejected: %s - %s", resp.Title, resp.ExtraInfo)
-		}
-
-		if resp.TxStatus == arc.TxStatusRejected {
-			return "", fmt.Errorf("transaction rejected: %s", resp.ExtraInfo)
-		}
-
-		// Reject orphan mempool status
-		if strings.Contains(string(resp.TxStatus), "ORPHAN") {
-			return "", fmt.Errorf("transaction orphaned - parent transaction not found or not confirmed")
-		}
-
-		// Must be at least RECEIVED or STORED
-		if resp.TxStatus != arc.TxStatusReceived &&
-			resp.TxStatus != arc.TxStatusStored &&
-			resp.TxStatus != arc.TxStatusAnnounced &&
-			resp.TxStatus != arc.TxStatusSent &&
-			resp.TxStatus != arc.TxStatusSeenOnNetwork &&
-			resp.TxStatus != arc.TxStatusAccepted &&
-			resp.TxStatus != arc.TxStatusMined {
-			return "", fmt.Errorf("unexpected transaction status: %s", resp.TxStatus)
-		}
-
-		return resp.TxID, nil
-	}
-
 	// Execute Phase 2: Split branches into publishing leaves
-	result, err := s.splitter.SplitBranchesIntoLeaves(ctx, arcBroadcastFunc)
+	result, err := s.splitter.SplitBranchesIntoLeaves(ctx, s.broadcastSplitTx)
 	if err != nil {
-		log.Printf("❌ Phase 2 split failed: %v", err)
+		slog.ErrorContext(c.UserContext(), "Phase 2 split failed", logging.Err(err))
 		return c.Status(500).JSON(fiber.Map{
 			"error": err.Error(),
 		})
 	}
 
-	log.Printf("✅ Phase 2 split complete: %d publishing UTXOs created", result.TotalUTXOs)
+	slog.InfoContext(c.UserContext(), "Phase 2 split complete", "publishing_utxos", result.TotalUTXOs)
+	auditDetail(c, "txids", strings.Join(result.LeafTxIDs, ","))
+	auditDetail(c, "total_utxos", strconv.Itoa(result.TotalUTXOs))
 
 	return c.JSON(fiber.Map{
 		"message":      "Phase 2 split complete",
@@ -554,7 +654,7 @@ func (s *Server) handleSplitPhase2(c *fiber.Ctx) error {
 
 // Start begins serving HTTP requests
 func (s *Server) Start(addr string) error {
-	log.Printf("🌐 API server listening on %s", addr)
+	slog.Info("API server listening", "addr", addr)
 	return s.app.Listen(addr)
 }
 
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/admin"
+	"github.com/akua/bsv-broadcaster/internal/arc/arctest"
+	"github.com/akua/bsv-broadcaster/internal/auth"
+	"github.com/akua/bsv-broadcaster/internal/bsv"
+	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/models"
+	"github.com/akua/bsv-broadcaster/internal/train"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+	"github.com/bsv-blockchain/go-sdk/script"
+	"github.com/bsv-blockchain/go-sdk/transaction/template/p2pkh"
+)
+
+// testServer is a Server on an in-memory store, broadcasting to a fake ARC
+type testServer struct {
+	*Server
+	db      *database.MemoryStore
+	arc     *arctest.Server
+	clients *admin.ClientManager
+	mutual  *auth.MutualAuth
+}
+
+// newTestServer starts a server with utxos publishing UTXOs in its pool
+func newTestServer(t *testing.T, utxos int) *testServer {
+	t.Helper()
+
+	db := database.NewMemoryStore()
+	owner := database.NewLockOwner(time.Minute)
+	db.SetLockOwner(owner)
+
+	publishingKey, err := bsv.GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seedPublishingUTXOs(t, db, publishingKey, utxos)
+
+	identity, err := ec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutual, err := auth.NewMutualAuth(identity, db, nil, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := arctest.NewServer()
+	t.Cleanup(fake.Close)
+
+	trainWorker := train.NewTrain(db, fake.Client(), train.NewStaticScheduler(10*time.Millisecond, 50), 2, 3)
+	clients := admin.NewClientManager(db, db)
+	signatures := auth.NewRequestVerifier(auth.NewReplayGuard(db, 5*time.Minute), auth.SignatureV1)
+	reservations := database.NewReservationCache(db, models.UTXOTypePublishing, 10, owner.Lease)
+
+	s := NewServer(db, reservations, trainWorker, publishingKey, nil, fake.Client(), admin.NewAuditLog(db), clients, signatures, mutual)
+	trainWorker.Start()
+	t.Cleanup(trainWorker.Stop)
+
+	return &testServer{Server: s, db: db, arc: fake, clients: clients, mutual: mutual}
+}
+
+// seedPublishingUTXOs adds count available publishing UTXOs paying to kp
+func seedPublishingUTXOs(t *testing.T, db database.UTXOStore, kp *bsv.KeyPair, count int) {
+	t.Helper()
+
+	addr, err := script.NewAddressFromString(kp.Address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock, err := p2pkh.Lock(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < count; i++ {
+		txid := fmt.Sprintf("%064x", i+1)
+		err := db.InsertUTXO(context.Background(), &models.UTXO{
+			Outpoint:     fmt.Sprintf("%s:0", txid),
+			TxID:         txid,
+			Satoshis:     100,
+			ScriptPubKey: lock.String(),
+			Status:       models.UTXOStatusAvailable,
+			Type:         models.UTXOTypePublishing,
+			CreatedAt:    time.Now(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// registerClient creates an active pilot-tier client and returns its API key
+func (ts *testServer) registerClient(t *testing.T, publicKey string, maxDailyTx int) (string, *models.Client) {
+	t.Helper()
+
+	rawKey, client, err := ts.clients.RegisterClient(context.Background(), t.Name(), publicKey, "", maxDailyTx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ts.db.UpdateClientSecurity(context.Background(), client.ID, "pilot", false, nil, 24); err != nil {
+		t.Fatal(err)
+	}
+	client.Tier = "pilot"
+	// Keep the train's backlog cap out of the way of the quota under test
+	if err := ts.db.UpdateClientLimits(context.Background(), client.ID, maxDailyTx, 1000, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	return rawKey, client
+}
+
+// publishRequest builds a POST /publish for hex-encoded data
+func publishRequest(data string) *http.Request {
+	body, _ := json.Marshal(PublishRequest{Data: data})
+	req := httptest.NewRequest(http.MethodPost, "/publish", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// do runs a request through the app, returning the status and body
+func (ts *testServer) do(t *testing.T, req *http.Request) (*http.Response, []byte) {
+	t.Helper()
+
+	resp, err := ts.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, body
+}
+
+// dailyCount returns the client's counted transactions for today
+func (ts *testServer) dailyCount(t *testing.T, client *models.Client) int {
+	t.Helper()
+
+	stored, err := ts.db.GetClientByID(context.Background(), client.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.LastResetDate != time.Now().UTC().Format("2006-01-02") {
+		return 0
+	}
+	return stored.TxCount
+}
+
+var testData = hex.EncodeToString([]byte("hello"))
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/auth"
+	"github.com/akua/bsv-broadcaster/internal/metrics"
+	"github.com/akua/bsv-broadcaster/internal/models"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// signedClient is a client on a tier that requires request signatures
+type signedClient struct {
+	*models.Client
+	apiKey string
+	key    *ec.PrivateKey
+}
+
+func (ts *testServer) registerSignedClient(t *testing.T) *signedClient {
+	t.Helper()
+
+	key, err := ec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	apiKey, client := ts.registerClient(t, hex.EncodeToString(key.PubKey().Uncompressed()), 10)
+	if err := ts.db.UpdateClientSecurity(context.Background(), client.ID, "enterprise", true, nil, 24); err != nil {
+		t.Fatal(err)
+	}
+	return &signedClient{Client: client, apiKey: apiKey, key: key}
+}
+
+// signedPublish builds a POST /publish signed with version at signedAt,
+// using nonce
+func (sc *signedClient) signedPublish(t *testing.T, version int, signedAt time.Time, nonce string) *http.Request {
+	t.Helper()
+
+	req := publishRequest(testData)
+	timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+	req.Header.Set("X-API-Key", sc.apiKey)
+	req.Header.Set("X-Timestamp", timestamp)
+	req.Header.Set("X-Nonce", nonce)
+	req.Header.Set("X-Signature-Version", strconv.Itoa(version))
+
+	var message []byte
+	switch version {
+	case auth.SignatureV1:
+		var err error
+		if message, err = hex.DecodeString(timestamp + nonce + testData); err != nil {
+			t.Fatal(err)
+		}
+	default:
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		canonical, err := auth.CanonicalRequest(&auth.SignedRequest{
+			Method:      req.Method,
+			Path:        req.URL.Path,
+			RawQuery:    req.URL.RawQuery,
+			ContentType: req.Header.Get("Content-Type"),
+			APIKey:      sc.apiKey,
+			Nonce:       nonce,
+			Timestamp:   timestamp,
+			Body:        body,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		message = []byte(canonical)
+	}
+
+	hash1 := sha256.Sum256(message)
+	hash2 := sha256.Sum256(hash1[:])
+	sig, err := sc.key.Sign(hash2[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Signature", hex.EncodeToString(sig.Serialize()))
+	return req
+}
+
+// newNonce is a random hex nonce, which both signature versions accept
+func newNonce(t *testing.T) string {
+	t.Helper()
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func TestSignedPublishRefusesReplaysAndStaleTimestamps(t *testing.T) {
+	for _, version := range []int{auth.SignatureV1, auth.SignatureV2} {
+		t.Run("v"+strconv.Itoa(version), func(t *testing.T) {
+			ts := newTestServer(t, 2)
+			client := ts.registerSignedClient(t)
+			rejections := func(reason string) float64 {
+				return testutil.ToFloat64(metrics.SignatureRejections.WithLabelValues(client.ID.Hex(), reason))
+			}
+
+			nonce := newNonce(t)
+			signedAt := time.Now()
+			resp, body := ts.do(t, client.signedPublish(t, version, signedAt, nonce))
+			if resp.StatusCode != http.StatusAccepted {
+				t.Fatalf("signed publish status = %d, want %d: %s", resp.StatusCode, http.StatusAccepted, body)
+			}
+
+			// The same request captured and sent again
+			resp, body = ts.do(t, client.signedPublish(t, version, signedAt, nonce))
+			if resp.StatusCode != http.StatusUnauthorized || !strings.Contains(string(body), "replayed") {
+				t.Fatalf("replay status = %d (%s), want %d refusing the nonce", resp.StatusCode, body, http.StatusUnauthorized)
+			}
+			if got := rejections(metrics.RejectReplayedNonce); got != 1 {
+				t.Fatalf("replayed nonce rejections = %v, want 1", got)
+			}
+
+			// Correctly signed, with a fresh nonce, but outside the clock skew
+			for _, skew := range []time.Duration{-10 * time.Minute, 10 * time.Minute} {
+				resp, body = ts.do(t, client.signedPublish(t, version, time.Now().Add(skew), newNonce(t)))
+				if resp.StatusCode != http.StatusUnauthorized || !strings.Contains(string(body), "X-Timestamp") {
+					t.Fatalf("timestamp %v off status = %d (%s), want %d refusing the timestamp", skew, resp.StatusCode, body, http.StatusUnauthorized)
+				}
+			}
+			if got := rejections(metrics.RejectStaleTimestamp); got != 2 {
+				t.Fatalf("stale timestamp rejections = %v, want 2", got)
+			}
+
+			// Refused requests are not charged against the quota
+			if got := ts.dailyCount(t, client.Client); got != 1 {
+				t.Fatalf("daily count = %d, want only the accepted publish", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,409 @@
+// Package arctest provides an in-process fake ARC server for local runs and
+// tests of the train, splitter and sweeper
+//
+//	srv := arctest.NewServer()
+//	defer srv.Close()
+//	srv.FailNext(503, 1)                     // First batch gets a 503
+//	srv.DoubleSpend(txid, competingTxid)     // This tx loses its input
+//	client := srv.Client()                   // *arc.Client pointed at the fake
+//
+// Transactions are parsed for real, so txids match what the caller computed
+// and two transactions spending the same outpoint are reported as a double
+// spend without any scripting.
+package arctest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/arc"
+	"github.com/bsv-blockchain/go-sdk/transaction"
+)
+
+// Server is a fake ARC serving /v1/txs, /v1/tx/{txid}, /v1/policy and /v1/health
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	latency       time.Duration
+	failures      []int               // Status codes for the next /v1/txs calls
+	defaultStatus arc.TxStatus        // Status given to accepted transactions
+	rejects       map[string]string   // txid -> rejection reason
+	doubleSpends  map[string][]string // txid -> competing txids
+	orphans       map[string]bool     // txids reported as orphans
+	omit          map[string]bool     // txids processed but left out of batch responses
+	extra         []arc.TxResponse    // Stray answers added to the next batch response
+	txs           map[string]*arc.TxResponse
+	spentBy       map[string]string // outpoint -> first txid seen spending it
+	broadcasts    []string          // txids in arrival order, including rebroadcasts
+	batches       int
+	blockHeight   int64
+	healthy       bool
+	feeSatoshis   int
+	feeBytes      int
+}
+
+// NewServer starts a fake ARC that accepts every valid transaction as SEEN_ON_NETWORK
+func NewServer() *Server {
+	s := &Server{
+		defaultStatus: arc.TxStatusSeenOnNetwork,
+		rejects:       make(map[string]string),
+		doubleSpends:  make(map[string][]string),
+		orphans:       make(map[string]bool),
+		omit:          make(map[string]bool),
+		txs:           make(map[string]*arc.TxResponse),
+		spentBy:       make(map[string]string),
+		blockHeight:   800000,
+		healthy:       true,
+		feeSatoshis:   1,
+		feeBytes:      1000,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/txs", s.handleTxs)
+	mux.HandleFunc("GET /v1/tx/{txid}", s.handleTx)
+	mux.HandleFunc("GET /v1/policy", s.handlePolicy)
+	mux.HandleFunc("GET /v1/health", s.handleHealth)
+
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// Client returns an ARC client pointed at the fake
+func (s *Server) Client() *arc.Client {
+	return arc.NewClient(s.URL, "")
+}
+
+// SetLatency delays every response, honouring the caller's deadline
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// FailNext makes the next n /v1/txs calls answer with statusCode and process nothing
+func (s *Server) FailNext(statusCode, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, statusCode)
+	}
+}
+
+// SetDefaultStatus changes the status accepted transactions are given
+func (s *Server) SetDefaultStatus(status arc.TxStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.defaultStatus = status
+}
+
+// Reject makes ARC reject the transaction with the given reason
+func (s *Server) Reject(txid, reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejects[txid] = reason
+}
+
+// DoubleSpend reports the transaction as losing its inputs to competing
+func (s *Server) DoubleSpend(txid string, competing ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.doubleSpends[txid] = competing
+}
+
+// Orphan reports the transaction as waiting in the orphan mempool
+func (s *Server) Orphan(txid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orphans[txid] = true
+}
+
+// Omit processes the transaction but leaves it out of batch responses, as
+// ARC does when a batch times out part way through
+func (s *Server) Omit(txid string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.omit[txid] = true
+}
+
+// Extra adds resp to the next successful batch response after the real
+// answers, as a stray answer for a txid the batch didn't carry or a second
+// answer for one it did
+func (s *Server) Extra(resp arc.TxResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.extra = append(s.extra, resp)
+}
+
+// SetStatus overrides the status of a transaction the fake has already seen
+func (s *Server) SetStatus(txid string, status arc.TxStatus) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.txs[txid]
+	if !ok {
+		return false
+	}
+	rec.TxStatus = status
+	rec.Timestamp = now()
+	return true
+}
+
+// Mine moves the given transactions (all known ones if none are given) to MINED in a new block
+func (s *Server) Mine(txids ...string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(txids) == 0 {
+		for txid := range s.txs {
+			txids = append(txids, txid)
+		}
+	}
+
+	s.blockHeight++
+	blockHash := fmt.Sprintf("%064x", s.blockHeight)
+
+	mined := 0
+	for _, txid := range txids {
+		rec, ok := s.txs[txid]
+		if !ok || arc.Classify(rec.TxStatus) == arc.OutcomeRejected || rec.TxStatus == arc.TxStatusMined {
+			continue
+		}
+		rec.TxStatus = arc.TxStatusMined
+		rec.BlockHash = blockHash
+		rec.BlockHeight = s.blockHeight
+		rec.Timestamp = now()
+		mined++
+	}
+	return mined
+}
+
+// SetHealthy controls the /v1/health answer
+func (s *Server) SetHealthy(healthy bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.healthy = healthy
+}
+
+// SetFee sets the mining fee /v1/policy advertises
+func (s *Server) SetFee(satoshis, bytes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.feeSatoshis = satoshis
+	s.feeBytes = bytes
+}
+
+// Status returns what the fake holds for a transaction
+func (s *Server) Status(txid string) (arc.TxResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.txs[txid]
+	if !ok {
+		return arc.TxResponse{}, false
+	}
+	return *rec, true
+}
+
+// Broadcasts returns every txid submitted to /v1/txs in arrival order
+func (s *Server) Broadcasts() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.broadcasts...)
+}
+
+// BatchCount returns how many /v1/txs calls the fake has answered, failures included
+func (s *Server) BatchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.batches
+}
+
+// handleTxs processes a newline separated batch of raw transactions
+func (s *Server) handleTxs(w http.ResponseWriter, r *http.Request) {
+	if !s.wait(r.Context()) {
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad request", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batches++
+	if len(s.failures) > 0 {
+		code := s.failures[0]
+		s.failures = s.failures[1:]
+		writeError(w, code, http.StatusText(code), "scripted failure")
+		return
+	}
+
+	responses := []arc.TxResponse{}
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		resp := s.process(line)
+		if s.omit[resp.TxID] {
+			continue
+		}
+		responses = append(responses, resp)
+	}
+	responses = append(responses, s.extra...)
+	s.extra = nil
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+// process decides the fate of one raw transaction (caller holds mu)
+func (s *Server) process(rawHex string) arc.TxResponse {
+	tx, err := transaction.NewTransactionFromHex(rawHex)
+	if err != nil {
+		return arc.TxResponse{
+			Status: 461,
+			Title:  "Malformed transaction",
+			Detail: err.Error(),
+		}
+	}
+
+	txid := tx.TxID().String()
+	s.broadcasts = append(s.broadcasts, txid)
+
+	// Rebroadcasts are idempotent
+	if rec, ok := s.txs[txid]; ok {
+		return *rec
+	}
+
+	rec := &arc.TxResponse{
+		TxID:      txid,
+		TxStatus:  s.defaultStatus,
+		Timestamp: now(),
+	}
+
+	var conflicts, claims []string
+	for _, in := range tx.Inputs {
+		if in.SourceTXID == nil {
+			continue
+		}
+		outpoint := fmt.Sprintf("%s:%d", in.SourceTXID.String(), in.SourceTxOutIndex)
+		if other, ok := s.spentBy[outpoint]; ok {
+			conflicts = append(conflicts, other)
+		} else {
+			claims = append(claims, outpoint)
+		}
+	}
+
+	switch {
+	case s.rejects[txid] != "":
+		rec.TxStatus = arc.TxStatusRejected
+		rec.ExtraInfo = s.rejects[txid]
+	case s.doubleSpends[txid] != nil || len(conflicts) > 0:
+		rec.TxStatus = arc.TxStatusDoubleSpend
+		rec.CompetingTxs = append(conflicts, s.doubleSpends[txid]...)
+	case s.orphans[txid]:
+		rec.TxStatus = arc.TxStatusSeenInOrphanMempool
+		rec.ExtraInfo = "parent transaction not found"
+	}
+
+	// ARC forgets rejected transactions, so a fixed one can be resent and
+	// their inputs stay free
+	if rec.TxStatus == arc.TxStatusRejected {
+		return *rec
+	}
+	for _, outpoint := range claims {
+		s.spentBy[outpoint] = txid
+	}
+	s.txs[txid] = rec
+	return *rec
+}
+
+// handleTx answers a status lookup
+func (s *Server) handleTx(w http.ResponseWriter, r *http.Request) {
+	if !s.wait(r.Context()) {
+		return
+	}
+
+	resp, ok := s.Status(r.PathValue("txid"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "Not found", "transaction not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handlePolicy advertises the configured fee
+func (s *Server) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	var quote arc.PolicyQuote
+	quote.Policy.MaxScriptSizePolicy = 100000000
+	quote.Policy.MaxTxSigopsCountPolicy = 4294967295
+	quote.Policy.MaxTxSizePolicy = 100000000
+	quote.Policy.MiningFee.Satoshis = s.feeSatoshis
+	quote.Policy.MiningFee.Bytes = s.feeBytes
+	quote.Timestamp = now()
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, quote)
+}
+
+// handleHealth reports the configured health
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	healthy := s.healthy
+	s.mu.Unlock()
+
+	if !healthy {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"healthy": false})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"healthy": true})
+}
+
+// wait applies the configured latency, reporting false if the caller gave up first
+func (s *Server) wait(ctx context.Context) bool {
+	s.mu.Lock()
+	latency := s.latency
+	s.mu.Unlock()
+
+	if latency <= 0 {
+		return true
+	}
+
+	select {
+	case <-time.After(latency):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, title, detail string) {
+	writeJSON(w, status, arc.ErrorResponse{
+		Status: status,
+		Title:  title,
+		Detail: detail,
+	})
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
@@ -3,11 +3,16 @@ package arc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/metrics"
+	"github.com/akua/bsv-broadcaster/internal/tracing"
 )
 
 // Client handles communication with BSV ARC API
@@ -23,26 +28,12 @@ func NewClient(baseURL, apiKey string) *Client {
 		baseURL: strings.TrimSuffix(baseURL, "/"),
 		apiKey:  apiKey,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: tracing.InstrumentHTTP(metrics.InstrumentARC(http.DefaultTransport)),
 		},
 	}
 }
 
-// TxStatus represents the status of a transaction in ARC
-type TxStatus string
-
-const (
-	TxStatusReceived      TxStatus = "RECEIVED"
-	TxStatusStored        TxStatus = "STORED"
-	TxStatusAnnounced     TxStatus = "ANNOUNCED"
-	TxStatusSent          TxStatus = "SENT"
-	TxStatusSeenOnNetwork TxStatus = "SEEN_ON_NETWORK"
-	TxStatusAccepted      TxStatus = "ACCEPTED_BY_NETWORK"
-	TxStatusMined         TxStatus = "MINED"
-	TxStatusRejected      TxStatus = "REJECTED"
-	TxStatusDoubleSpend   TxStatus = "DOUBLE_SPEND_ATTEMPTED"
-)
-
 // TxResponse represents ARC's response for a single transaction
 type TxResponse struct {
 	TxID         string   `json:"txid"`
@@ -67,6 +58,56 @@ type ErrorResponse struct {
 	TxID   string `json:"txid,omitempty"`
 }
 
+// ErrTxNotFound is returned by GetTransactionStatus when ARC has never seen the txid
+var ErrTxNotFound = errors.New("transaction not found")
+
+// HTTPError is returned when ARC answers with an error status code
+type HTTPError struct {
+	StatusCode int
+	Title      string
+	Detail     string
+	Body       string // Raw body when it wasn't an ARC error document
+}
+
+func (e *HTTPError) Error() string {
+	if e.Title != "" || e.Detail != "" {
+		return fmt.Sprintf("ARC error %d: %s - %s", e.StatusCode, e.Title, e.Detail)
+	}
+	return fmt.Sprintf("ARC returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// maxErrorBody caps how much of an unexpected response body goes into an
+// error, which ends up in logs and request records
+const maxErrorBody = 512
+
+// truncateBody returns body as a string of at most maxErrorBody bytes
+func truncateBody(body []byte) string {
+	if len(body) <= maxErrorBody {
+		return string(body)
+	}
+	return fmt.Sprintf("%s... (%d bytes)", body[:maxErrorBody], len(body))
+}
+
+// IsRetriable classifies a BroadcastBatch error
+// ARC 5xx, 408 and 429 responses and transport failures (timeouts, resets,
+// unreadable responses) are retriable: the request may never have been
+// processed, or may have been processed without us seeing the answer.
+// Any other 4xx means ARC looked at the request and refused it.
+func IsRetriable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500 ||
+			httpErr.StatusCode == http.StatusTooManyRequests ||
+			httpErr.StatusCode == http.StatusRequestTimeout
+	}
+
+	return true
+}
+
 // BroadcastBatch submits multiple transactions to ARC in a single request
 // This is the key method for the "train" functionality
 func (c *Client) BroadcastBatch(ctx context.Context, hexes []string) ([]TxResponse, error) {
@@ -103,23 +144,25 @@ func (c *Client) BroadcastBatch(ctx context.Context, hexes []string) ([]TxRespon
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	// Debug logging
-	fmt.Printf("ARC Response Status: %d\n", resp.StatusCode)
-	fmt.Printf("ARC Response Body: %s\n", string(bodyBytes))
+	slog.DebugContext(ctx, "ARC batch response", "status", resp.StatusCode, "txs", len(hexes), "body_bytes", len(bodyBytes))
 
 	// Handle error responses
 	if resp.StatusCode >= 400 {
+		httpErr := &HTTPError{StatusCode: resp.StatusCode}
 		var errResp ErrorResponse
-		if err := json.Unmarshal(bodyBytes, &errResp); err == nil {
-			return nil, fmt.Errorf("ARC error %d: %s - %s", errResp.Status, errResp.Title, errResp.Detail)
+		if err := json.Unmarshal(bodyBytes, &errResp); err == nil && (errResp.Title != "" || errResp.Detail != "") {
+			httpErr.Title = errResp.Title
+			httpErr.Detail = errResp.Detail
+		} else {
+			httpErr.Body = truncateBody(bodyBytes)
 		}
-		return nil, fmt.Errorf("ARC returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, httpErr
 	}
 
 	// Parse successful response
 	var txResponses []TxResponse
 	if err := json.Unmarshal(bodyBytes, &txResponses); err != nil {
-		return nil, fmt.Errorf("failed to parse response (body: %s): %w", string(bodyBytes), err)
+		return nil, fmt.Errorf("failed to parse response (body: %s): %w", truncateBody(bodyBytes), err)
 	}
 
 	return txResponses, nil
@@ -157,12 +200,12 @@ func (c *Client) GetTransactionStatus(ctx context.Context, txid string) (*TxResp
 	defer resp.Body.Close()
 
 	if resp.StatusCode == 404 {
-		return nil, fmt.Errorf("transaction not found")
+		return nil, ErrTxNotFound
 	}
 
 	if resp.StatusCode >= 400 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("ARC returned status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Body: truncateBody(bodyBytes)}
 	}
 
 	var txResp TxResponse
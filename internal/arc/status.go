@@ -0,0 +1,147 @@
+package arc
+
+import "fmt"
+
+// TxStatus represents the status of a transaction in ARC
+type TxStatus string
+
+// ARC transaction statuses, in lifecycle order
+// See https://bitcoin-sv.github.io/arc/#/?id=transaction-statuses
+const (
+	TxStatusUnknown             TxStatus = "UNKNOWN"
+	TxStatusQueued              TxStatus = "QUEUED"
+	TxStatusReceived            TxStatus = "RECEIVED"
+	TxStatusStored              TxStatus = "STORED"
+	TxStatusAnnounced           TxStatus = "ANNOUNCED_TO_NETWORK"
+	TxStatusRequested           TxStatus = "REQUESTED_BY_NETWORK"
+	TxStatusSent                TxStatus = "SENT_TO_NETWORK"
+	TxStatusAccepted            TxStatus = "ACCEPTED_BY_NETWORK"
+	TxStatusSeenInOrphanMempool TxStatus = "SEEN_IN_ORPHAN_MEMPOOL"
+	TxStatusSeenOnNetwork       TxStatus = "SEEN_ON_NETWORK"
+	TxStatusDoubleSpend         TxStatus = "DOUBLE_SPEND_ATTEMPTED"
+	TxStatusRejected            TxStatus = "REJECTED"
+	TxStatusMinedInStaleBlock   TxStatus = "MINED_IN_STALE_BLOCK"
+	TxStatusMined               TxStatus = "MINED"
+)
+
+// statusRanks orders statuses along the lifecycle, matching ARC's numeric codes
+var statusRanks = map[TxStatus]int{
+	TxStatusUnknown:             0,
+	TxStatusQueued:              1,
+	TxStatusReceived:            2,
+	TxStatusStored:              3,
+	TxStatusAnnounced:           4,
+	TxStatusRequested:           5,
+	TxStatusSent:                6,
+	TxStatusAccepted:            7,
+	TxStatusSeenInOrphanMempool: 8,
+	TxStatusSeenOnNetwork:       9,
+	TxStatusDoubleSpend:         10,
+	TxStatusMined:               108,
+	TxStatusRejected:            109,
+	TxStatusMinedInStaleBlock:   115,
+}
+
+// Known reports whether the status is one ARC documents
+func (s TxStatus) Known() bool {
+	_, ok := statusRanks[s]
+	return ok
+}
+
+// Rank orders statuses so a later status can be told from an earlier one
+// Unrecognised statuses rank below UNKNOWN
+func (s TxStatus) Rank() int {
+	if rank, ok := statusRanks[s]; ok {
+		return rank
+	}
+	return -1
+}
+
+// IsTerminal reports whether ARC will never move the transaction on from this status
+// A double spend attempt isn't terminal: either side may still be mined
+func (s TxStatus) IsTerminal() bool {
+	return s == TxStatusMined || s == TxStatusRejected
+}
+
+// Outcome is what a status means for the inputs a transaction spends
+type Outcome int
+
+const (
+	OutcomeUnknown     Outcome = iota // Unrecognised status: nothing can be assumed
+	OutcomePending                    // ARC holds the tx but the network hasn't accepted it yet
+	OutcomeAccepted                   // Accepted by or seen on the network
+	OutcomeMined                      // In a block
+	OutcomeOrphan                     // Held back until a missing parent turns up
+	OutcomeDoubleSpend                // Inputs are contested by another transaction
+	OutcomeRejected                   // ARC refused it; its inputs are still unspent
+)
+
+// Classify maps an ARC status onto its outcome
+// This is the single place the train, split handlers and sweeper decide what
+// a status means
+func Classify(status TxStatus) Outcome {
+	switch status {
+	case TxStatusQueued, TxStatusReceived, TxStatusStored,
+		TxStatusAnnounced, TxStatusRequested, TxStatusSent:
+		return OutcomePending
+	case TxStatusMinedInStaleBlock:
+		// The block lost a reorg; ARC rebroadcasts the tx for the winning
+		// chain to mine, so it's still on its way
+		return OutcomePending
+	case TxStatusAccepted, TxStatusSeenOnNetwork:
+		return OutcomeAccepted
+	case TxStatusMined:
+		return OutcomeMined
+	case TxStatusSeenInOrphanMempool:
+		return OutcomeOrphan
+	case TxStatusDoubleSpend:
+		return OutcomeDoubleSpend
+	case TxStatusRejected:
+		return OutcomeRejected
+	default:
+		return OutcomeUnknown
+	}
+}
+
+// Outcome classifies a response, treating a per-transaction error (ARC
+// answers status 4xx for malformed or underpaying txs) as a rejection
+func (r *TxResponse) Outcome() Outcome {
+	if r.Status >= 400 {
+		return OutcomeRejected
+	}
+	return Classify(r.TxStatus)
+}
+
+// HoldsInputs reports whether ARC has a transaction spending the inputs, so
+// they must not be handed to another transaction
+// Unknown is treated as holding: releasing inputs ARC might broadcast risks a
+// double spend, while keeping them locked only costs a janitor cycle
+func (o Outcome) HoldsInputs() bool {
+	return o != OutcomeRejected
+}
+
+// Succeeded reports whether the transaction is on its way to being mined
+func (o Outcome) Succeeded() bool {
+	return o == OutcomePending || o == OutcomeAccepted || o == OutcomeMined
+}
+
+// CheckAccepted returns an error unless ARC's response shows the transaction
+// on its way to being mined
+func CheckAccepted(resp *TxResponse) error {
+	if resp.Status >= 400 {
+		return fmt.Errorf("transaction rejected: %s - %s", resp.Title, resp.ExtraInfo)
+	}
+
+	switch resp.Outcome() {
+	case OutcomePending, OutcomeAccepted, OutcomeMined:
+		return nil
+	case OutcomeRejected:
+		return fmt.Errorf("transaction rejected: %s", resp.ExtraInfo)
+	case OutcomeOrphan:
+		return fmt.Errorf("transaction orphaned - parent transaction not found or not confirmed")
+	case OutcomeDoubleSpend:
+		return fmt.Errorf("double spend detected: competing with %v", resp.CompetingTxs)
+	default:
+		return fmt.Errorf("unexpected transaction status: %s", resp.TxStatus)
+	}
+}
@@ -0,0 +1,44 @@
+package arc
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		status TxStatus
+		want   Outcome
+	}{
+		{TxStatusQueued, OutcomePending},
+		{TxStatusReceived, OutcomePending},
+		{TxStatusStored, OutcomePending},
+		{TxStatusAnnounced, OutcomePending},
+		{TxStatusRequested, OutcomePending},
+		{TxStatusSent, OutcomePending},
+		{TxStatusMinedInStaleBlock, OutcomePending},
+		{TxStatusAccepted, OutcomeAccepted},
+		{TxStatusSeenOnNetwork, OutcomeAccepted},
+		{TxStatusMined, OutcomeMined},
+		{TxStatusSeenInOrphanMempool, OutcomeOrphan},
+		{TxStatusDoubleSpend, OutcomeDoubleSpend},
+		{TxStatusRejected, OutcomeRejected},
+		{TxStatusUnknown, OutcomeUnknown},
+		{TxStatus("SOMETHING_NEW"), OutcomeUnknown},
+	}
+	for _, tt := range tests {
+		if got := Classify(tt.status); got != tt.want {
+			t.Errorf("Classify(%s) = %d, want %d", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestMinedInStaleBlockIsNotAFailure(t *testing.T) {
+	resp := &TxResponse{TxStatus: TxStatusMinedInStaleBlock}
+	if err := CheckAccepted(resp); err != nil {
+		t.Fatalf("CheckAccepted = %v, want nil", err)
+	}
+	if !resp.Outcome().Succeeded() || !resp.Outcome().HoldsInputs() {
+		t.Fatal("a tx mined in a stale block must keep its inputs and count as on its way")
+	}
+	if resp.TxStatus.IsTerminal() {
+		t.Fatal("MINED_IN_STALE_BLOCK is not terminal")
+	}
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the shortest admin password accepted
+const MinPasswordLength = 12
+
+// HashPassword returns the bcrypt hash stored for an admin password
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches a HashPassword hash
+// The comparison is constant-time.
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// dummyHash is compared against when an account doesn't exist, so a login for
+// an unknown username takes as long as one with a wrong password
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("no-such-admin-account"), bcrypt.DefaultCost)
+
+// WastePasswordCheck spends the time of a CheckPassword
+func WastePasswordCheck(password string) {
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+// ErrInvalidToken is returned for malformed, forged or expired admin tokens
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+const tokenVersion = "v1"
+
+// TokenSigner issues and verifies admin session tokens
+// A token is "v1.<claims>.<mac>": base64url JSON claims and their
+// HMAC-SHA256 under the signer's secret. Replicas must share the secret.
+type TokenSigner struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// tokenClaims is what a token asserts
+type tokenClaims struct {
+	Subject    string `json:"sub"`
+	IssuedAt   int64  `json:"iat"`
+	IssuedAtMs int64  `json:"iat_ms,omitempty"` // So a token issued just before a password change in the same second is still refused
+	ExpiresAt  int64  `json:"exp"`
+}
+
+// NewTokenSigner creates a signer whose tokens last ttl
+func NewTokenSigner(secret []byte, ttl time.Duration) *TokenSigner {
+	return &TokenSigner{secret: secret, ttl: ttl}
+}
+
+// GenerateTokenSecret returns a random signing secret
+func GenerateTokenSecret() ([]byte, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// Issue returns a token for username and when it expires
+func (s *TokenSigner) Issue(username string, now time.Time) (string, time.Time, error) {
+	expiresAt := now.Add(s.ttl)
+	claims, err := json.Marshal(tokenClaims{
+		Subject:    username,
+		IssuedAt:   now.Unix(),
+		IssuedAtMs: now.UnixMilli(),
+		ExpiresAt:  expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	signed := tokenVersion + "." + base64.RawURLEncoding.EncodeToString(claims)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(s.mac(signed)), expiresAt, nil
+}
+
+// Verify checks a token's signature and expiry, returning its username and
+// when it was issued
+func (s *TokenSigner) Verify(token string, now time.Time) (string, time.Time, error) {
+	i := strings.LastIndex(token, ".")
+	if i < 0 || !strings.HasPrefix(token, tokenVersion+".") {
+		return "", time.Time{}, ErrInvalidToken
+	}
+	signed, mac := token[:i], token[i+1:]
+
+	got, err := base64.RawURLEncoding.DecodeString(mac)
+	if err != nil || !hmac.Equal(got, s.mac(signed)) {
+		return "", time.Time{}, ErrInvalidToken
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(signed, tokenVersion+"."))
+	if err != nil {
+		return "", time.Time{}, ErrInvalidToken
+	}
+	var claims tokenClaims
+	if err := json.Unmarshal(raw, &claims); err != nil || claims.Subject == "" {
+		return "", time.Time{}, ErrInvalidToken
+	}
+	if now.Unix() >= claims.ExpiresAt {
+		return "", time.Time{}, ErrInvalidToken
+	}
+
+	if claims.IssuedAtMs != 0 {
+		return claims.Subject, time.UnixMilli(claims.IssuedAtMs), nil
+	}
+	return claims.Subject, time.Unix(claims.IssuedAt, 0), nil
+}
+
+func (s *TokenSigner) mac(signed string) []byte {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(signed))
+	return h.Sum(nil)
+}
@@ -0,0 +1,458 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/models"
+	sdkauth "github.com/bsv-blockchain/go-sdk/auth"
+	"github.com/bsv-blockchain/go-sdk/auth/authpayload"
+	"github.com/bsv-blockchain/go-sdk/auth/brc104"
+	"github.com/bsv-blockchain/go-sdk/auth/certificates"
+	"github.com/bsv-blockchain/go-sdk/auth/utils"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+	"github.com/bsv-blockchain/go-sdk/wallet"
+)
+
+// Mutual authentication errors; see also ErrInvalidSignature, ErrInvalidNonce
+// and ErrNonceReused
+var (
+	ErrInvalidAuthMessage   = errors.New("invalid auth message")
+	ErrUnknownIdentity      = errors.New("identity key is not registered to a client")
+	ErrClientDisabled       = errors.New("client account is disabled")
+	ErrAuthSessionNotFound  = errors.New("auth session not found or expired")
+	ErrCertificatesRequired = errors.New("required certificates not presented")
+)
+
+// MutualAuth is the server side of BRC-103 mutual authentication over HTTP
+// (BRC-104)
+// A client runs the handshake against /.well-known/auth with its identity
+// key, which must be a registered client's public key, then signs each request
+// in the x-bsv-auth-* headers; we sign each response back. Sessions are kept
+// in the store, so a handshake with one replica is good on all of them as
+// long as they share the server identity key.
+type MutualAuth struct {
+	wallet     *wallet.CompletedProtoWallet
+	identity   *ec.PublicKey
+	db         database.Store
+	requested  *utils.RequestedCertificateSet // nil when no certificates are needed
+	sessionTTL time.Duration
+}
+
+// NewMutualAuth creates the server side of mutual authentication, acting as
+// identityKey
+// Clients must present the certificates in requested, which may be nil, before
+// their session is good for requests. Sessions last sessionTTL from the
+// handshake.
+func NewMutualAuth(identityKey *ec.PrivateKey, db database.Store, requested *utils.RequestedCertificateSet, sessionTTL time.Duration) (*MutualAuth, error) {
+	w, err := wallet.NewCompletedProtoWallet(identityKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create identity wallet: %w", err)
+	}
+	return &MutualAuth{
+		wallet:     w,
+		identity:   identityKey.PubKey(),
+		db:         db,
+		requested:  requested,
+		sessionTTL: sessionTTL,
+	}, nil
+}
+
+// IdentityKey is the server's identity key, as compressed hex
+func (m *MutualAuth) IdentityKey() string {
+	return m.identity.ToDERHex()
+}
+
+// HandleMessage answers a handshake message POSTed to /.well-known/auth,
+// returning the reply and the session it concerns
+func (m *MutualAuth) HandleMessage(ctx context.Context, msg *sdkauth.AuthMessage, now time.Time) (*sdkauth.AuthMessage, *models.AuthSession, error) {
+	if msg.Version != sdkauth.AUTH_VERSION || msg.IdentityKey == nil {
+		return nil, nil, fmt.Errorf("%w: version must be %s with an identity key", ErrInvalidAuthMessage, sdkauth.AUTH_VERSION)
+	}
+
+	switch msg.MessageType {
+	case sdkauth.MessageTypeInitialRequest:
+		return m.handshake(ctx, msg, now)
+	case sdkauth.MessageTypeCertificateResponse:
+		return m.acceptCertificates(ctx, msg)
+	case sdkauth.MessageTypeCertificateRequest:
+		return m.answerCertificateRequest(ctx, msg)
+	}
+	return nil, nil, fmt.Errorf("%w: unexpected message type %q", ErrInvalidAuthMessage, msg.MessageType)
+}
+
+// handshake opens a session for a registered client
+func (m *MutualAuth) handshake(ctx context.Context, msg *sdkauth.AuthMessage, now time.Time) (*sdkauth.AuthMessage, *models.AuthSession, error) {
+	peerNonce, err := base64.StdEncoding.DecodeString(msg.InitialNonce)
+	if err != nil || len(peerNonce) == 0 {
+		return nil, nil, fmt.Errorf("%w: initialNonce must be base64", ErrInvalidAuthMessage)
+	}
+
+	client, err := m.db.GetClientByPublicKey(ctx, hex.EncodeToString(msg.IdentityKey.Uncompressed()))
+	if err != nil {
+		return nil, nil, ErrUnknownIdentity
+	}
+	if !client.IsActive {
+		return nil, nil, ErrClientDisabled
+	}
+
+	sessionNonce, err := utils.CreateNonce(ctx, m.wallet, wallet.Counterparty{Type: wallet.CounterpartyTypeSelf})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create session nonce: %w", err)
+	}
+	ourNonce, _ := base64.StdEncoding.DecodeString(sessionNonce)
+
+	signature, err := m.sign(ctx, append(peerNonce, ourNonce...), keyID(msg.InitialNonce, sessionNonce), msg.IdentityKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	session := &models.AuthSession{
+		SessionNonce:  sessionNonce,
+		PeerNonce:     msg.InitialNonce,
+		IdentityKey:   msg.IdentityKey.ToDERHex(),
+		ClientID:      client.ID.Hex(),
+		Authenticated: m.requested == nil,
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(m.sessionTTL),
+	}
+	if err := m.db.SaveAuthSession(ctx, session); err != nil {
+		return nil, nil, err
+	}
+
+	reply := &sdkauth.AuthMessage{
+		Version:      sdkauth.AUTH_VERSION,
+		MessageType:  sdkauth.MessageTypeInitialResponse,
+		IdentityKey:  m.identity,
+		Nonce:        sessionNonce,
+		YourNonce:    msg.InitialNonce,
+		InitialNonce: sessionNonce,
+		Signature:    signature,
+	}
+	if m.requested != nil {
+		reply.RequestedCertificates = *m.requested
+	}
+	return reply, session, nil
+}
+
+// acceptCertificates checks the certificates a client sent for its session,
+// which is good for requests once they satisfy what we asked for
+func (m *MutualAuth) acceptCertificates(ctx context.Context, msg *sdkauth.AuthMessage) (*sdkauth.AuthMessage, *models.AuthSession, error) {
+	session, err := m.session(ctx, msg.YourNonce, msg.IdentityKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certData, err := json.Marshal(msg.Certificates)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidAuthMessage, err)
+	}
+	if err := m.verify(ctx, certData, msg.Signature, keyID(msg.Nonce, session.SessionNonce), msg.IdentityKey); err != nil {
+		return nil, session, err
+	}
+
+	if m.requested != nil {
+		if err := m.checkCertificates(ctx, msg.Certificates, msg.IdentityKey); err != nil {
+			return nil, session, err
+		}
+		session.Authenticated = true
+		if err := m.db.SaveAuthSession(ctx, session); err != nil {
+			return nil, session, err
+		}
+	}
+
+	reply, err := m.certificateResponse(ctx, session)
+	return reply, session, err
+}
+
+// answerCertificateRequest answers a client asking for our certificates; we
+// hold none, so the answer is always empty
+func (m *MutualAuth) answerCertificateRequest(ctx context.Context, msg *sdkauth.AuthMessage) (*sdkauth.AuthMessage, *models.AuthSession, error) {
+	session, err := m.session(ctx, msg.YourNonce, msg.IdentityKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	requestData, err := json.Marshal(msg.RequestedCertificates)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidAuthMessage, err)
+	}
+	if err := m.verify(ctx, requestData, msg.Signature, keyID(msg.Nonce, session.SessionNonce), msg.IdentityKey); err != nil {
+		return nil, session, err
+	}
+
+	reply, err := m.certificateResponse(ctx, session)
+	return reply, session, err
+}
+
+// certificateResponse is our (empty) certificateResponse for a session; HTTP
+// clients need a signed message back for every one they send
+func (m *MutualAuth) certificateResponse(ctx context.Context, session *models.AuthSession) (*sdkauth.AuthMessage, error) {
+	nonce, err := utils.CreateNonce(ctx, m.wallet, wallet.Counterparty{Type: wallet.CounterpartyTypeSelf})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create nonce: %w", err)
+	}
+	peer, err := ec.PublicKeyFromString(session.IdentityKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session identity key: %w", err)
+	}
+
+	var none []*certificates.VerifiableCertificate
+	certData, _ := json.Marshal(none)
+	signature, err := m.sign(ctx, certData, keyID(nonce, session.PeerNonce), peer)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sdkauth.AuthMessage{
+		Version:     sdkauth.AUTH_VERSION,
+		MessageType: sdkauth.MessageTypeCertificateResponse,
+		IdentityKey: m.identity,
+		Nonce:       nonce,
+		YourNonce:   session.PeerNonce,
+		Signature:   signature,
+	}, nil
+}
+
+// checkCertificates validates certificates against what we request: the SDK
+// checks each one, and every requested type must be there, from a requested
+// certifier, revealing every requested field to us
+func (m *MutualAuth) checkCertificates(ctx context.Context, certs []*certificates.VerifiableCertificate, subject *ec.PublicKey) error {
+	if err := utils.ValidateCertificates(ctx, m.wallet, certs, subject, m.requested); err != nil {
+		return fmt.Errorf("%w: %v", ErrCertificatesRequired, err)
+	}
+
+	for certType, fields := range m.requested.CertificateTypes {
+		if !hasCertificate(certs, certType, m.requested.Certifiers, fields) {
+			return fmt.Errorf("%w: no valid certificate of type %s", ErrCertificatesRequired, base64.StdEncoding.EncodeToString(certType[:]))
+		}
+	}
+	return nil
+}
+
+// hasCertificate reports whether certs holds one of certType from one of
+// certifiers, revealing fields
+func hasCertificate(certs []*certificates.VerifiableCertificate, certType wallet.CertificateType, certifiers []*ec.PublicKey, fields []string) bool {
+	for _, cert := range certs {
+		if got, err := cert.Type.ToArray(); err != nil || got != certType {
+			continue
+		}
+		if !utils.CertifierInSlice(certifiers, &cert.Certifier) {
+			continue
+		}
+		revealed := true
+		for _, field := range fields {
+			if _, ok := cert.Keyring[wallet.CertificateFieldNameUnder50Bytes(field)]; !ok {
+				revealed = false
+				break
+			}
+		}
+		if revealed {
+			return true
+		}
+	}
+	return false
+}
+
+// MutualRequest is a request that passed VerifyRequest; SignResponse signs
+// the reply to it
+type MutualRequest struct {
+	Session   *models.AuthSession
+	requestID []byte
+	peer      *ec.PublicKey
+}
+
+// VerifyRequest checks a request signed under a mutual authentication session
+// The request's nonce is remembered until the session expires, so it can't
+// be replayed. The body is read and put back.
+func (m *MutualAuth) VerifyRequest(ctx context.Context, r *http.Request) (*MutualRequest, error) {
+	if r.Header.Get(brc104.HeaderVersion) != sdkauth.AUTH_VERSION {
+		return nil, fmt.Errorf("%w: %s must be %s", ErrInvalidAuthMessage, brc104.HeaderVersion, sdkauth.AUTH_VERSION)
+	}
+	if messageType := r.Header.Get(brc104.HeaderMessageType); messageType != "" && messageType != string(sdkauth.MessageTypeGeneral) {
+		return nil, fmt.Errorf("%w: %s must be %s", ErrInvalidAuthMessage, brc104.HeaderMessageType, sdkauth.MessageTypeGeneral)
+	}
+	peer, err := ec.PublicKeyFromString(r.Header.Get(brc104.HeaderIdentityKey))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid %s", ErrInvalidAuthMessage, brc104.HeaderIdentityKey)
+	}
+	requestID, err := base64.StdEncoding.DecodeString(r.Header.Get(brc104.HeaderRequestID))
+	if err != nil || len(requestID) != brc104.RequestIDLength {
+		return nil, fmt.Errorf("%w: invalid %s", ErrInvalidAuthMessage, brc104.HeaderRequestID)
+	}
+	signature, err := hex.DecodeString(r.Header.Get(brc104.HeaderSignature))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid %s", ErrInvalidAuthMessage, brc104.HeaderSignature)
+	}
+	nonce := r.Header.Get(brc104.HeaderNonce)
+	if len(nonce) < MinNonceLength || len(nonce) > MaxNonceLength {
+		return nil, ErrInvalidNonce
+	}
+
+	session, err := m.session(ctx, r.Header.Get(brc104.HeaderYourNonce), peer)
+	if err != nil {
+		return nil, err
+	}
+	if !session.Authenticated {
+		return nil, ErrCertificatesRequired
+	}
+
+	payload, err := authpayload.FromHTTPRequest(requestID, r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAuthMessage, err)
+	}
+	if err := m.verify(ctx, payload, signature, keyID(nonce, session.SessionNonce), peer); err != nil {
+		return nil, err
+	}
+
+	fresh, err := m.db.UseNonce(ctx, session.ClientID, nonce, session.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, ErrNonceReused
+	}
+	return &MutualRequest{Session: session, requestID: requestID, peer: peer}, nil
+}
+
+// SignResponse signs the response to req, returning the x-bsv-auth-* headers
+// to send with it
+// header must hold every x-bsv-* and Authorization header of the response.
+func (m *MutualAuth) SignResponse(ctx context.Context, req *MutualRequest, status int, header http.Header, body []byte) (http.Header, error) {
+	payload, err := authpayload.FromResponse(req.requestID, authpayload.SimplifiedHttpResponse{
+		StatusCode: status,
+		Header:     header,
+		Body:       body,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build response payload: %w", err)
+	}
+
+	nonce := string(utils.RandomBase64(32))
+	signature, err := m.sign(ctx, payload, keyID(nonce, req.Session.PeerNonce), req.peer)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := http.Header{}
+	signed.Set(brc104.HeaderVersion, sdkauth.AUTH_VERSION)
+	signed.Set(brc104.HeaderMessageType, string(sdkauth.MessageTypeGeneral))
+	signed.Set(brc104.HeaderIdentityKey, m.IdentityKey())
+	signed.Set(brc104.HeaderNonce, nonce)
+	signed.Set(brc104.HeaderYourNonce, req.Session.PeerNonce)
+	signed.Set(brc104.HeaderSignature, hex.EncodeToString(signature))
+	signed.Set(brc104.HeaderRequestID, base64.StdEncoding.EncodeToString(req.requestID))
+	return signed, nil
+}
+
+// session finds the live session we issued sessionNonce for, held by peer
+func (m *MutualAuth) session(ctx context.Context, sessionNonce string, peer *ec.PublicKey) (*models.AuthSession, error) {
+	// Our nonces carry an HMAC, so forged ones are refused without a lookup
+	valid, err := utils.VerifyNonce(ctx, sessionNonce, m.wallet, wallet.Counterparty{Type: wallet.CounterpartyTypeSelf})
+	if err != nil || !valid {
+		return nil, ErrAuthSessionNotFound
+	}
+
+	session, err := m.db.GetAuthSession(ctx, sessionNonce)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || session.IdentityKey != peer.ToDERHex() {
+		return nil, ErrAuthSessionNotFound
+	}
+	return session, nil
+}
+
+// sign signs data for peer under the BRC-103 message protocol
+func (m *MutualAuth) sign(ctx context.Context, data []byte, keyID string, peer *ec.PublicKey) ([]byte, error) {
+	result, err := m.wallet.CreateSignature(ctx, wallet.CreateSignatureArgs{
+		EncryptionArgs: messageEncryption(keyID, peer),
+		Data:           data,
+	}, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign auth message: %w", err)
+	}
+	return result.Signature.Serialize(), nil
+}
+
+// verify checks peer signed data under the BRC-103 message protocol
+func (m *MutualAuth) verify(ctx context.Context, data, signature []byte, keyID string, peer *ec.PublicKey) error {
+	sig, err := ec.ParseSignature(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	result, err := m.wallet.VerifySignature(ctx, wallet.VerifySignatureArgs{
+		EncryptionArgs: messageEncryption(keyID, peer),
+		Data:           data,
+		Signature:      sig,
+	}, "")
+	if err != nil || !result.Valid {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// messageEncryption is the key derivation every BRC-103 message signature uses
+func messageEncryption(keyID string, peer *ec.PublicKey) wallet.EncryptionArgs {
+	return wallet.EncryptionArgs{
+		ProtocolID: wallet.Protocol{
+			SecurityLevel: wallet.SecurityLevelEveryAppAndCounterparty,
+			Protocol:      sdkauth.AUTH_PROTOCOL_ID,
+		},
+		KeyID:        keyID,
+		Counterparty: wallet.Counterparty{Type: wallet.CounterpartyTypeOther, Counterparty: peer},
+	}
+}
+
+// keyID joins two nonces into a BRC-103 signing key ID
+func keyID(first, second string) string {
+	return first + " " + second
+}
+
+// ParseRequestedCertificates reads the certificates clients must present:
+// certifiers is a comma-separated list of hex public keys, and types is
+// semicolon-separated "<type base64>:<field>,<field>" entries
+// Both empty means no certificates are needed, and nil is returned.
+func ParseRequestedCertificates(certifiers, types string) (*utils.RequestedCertificateSet, error) {
+	certifiers, types = strings.TrimSpace(certifiers), strings.TrimSpace(types)
+	if certifiers == "" && types == "" {
+		return nil, nil
+	}
+	if certifiers == "" || types == "" {
+		return nil, errors.New("certificate types and certifiers must be given together")
+	}
+
+	requested := &utils.RequestedCertificateSet{
+		CertificateTypes: utils.RequestedCertificateTypeIDAndFieldList{},
+	}
+	for _, certifier := range strings.Split(certifiers, ",") {
+		key, err := ec.PublicKeyFromString(strings.TrimSpace(certifier))
+		if err != nil {
+			return nil, fmt.Errorf("invalid certifier %q: %w", certifier, err)
+		}
+		requested.Certifiers = append(requested.Certifiers, key)
+	}
+	for _, entry := range strings.Split(types, ";") {
+		typeID, fieldList, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		certType, err := wallet.CertificateTypeFromBase64(typeID)
+		if err != nil || typeID == "" {
+			return nil, fmt.Errorf("invalid certificate type %q", typeID)
+		}
+		var fields []string
+		for _, field := range strings.Split(fieldList, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, field)
+			}
+		}
+		requested.CertificateTypes[certType] = fields
+	}
+	return requested, nil
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/database"
+)
+
+// Nonce length limits; anything outside is refused before it is stored
+const (
+	MinNonceLength = 8
+	MaxNonceLength = 128
+)
+
+// Replay protection errors
+var (
+	ErrStaleTimestamp = errors.New("request timestamp is outside the allowed clock skew")
+	ErrInvalidNonce   = errors.New("nonce must be 8 to 128 characters")
+	ErrNonceReused    = errors.New("nonce has already been used")
+)
+
+// ReplayGuard refuses signed requests that are stale or repeat a nonce
+// A request's X-Timestamp must be within maxSkew of the server clock, and its
+// X-Nonce is remembered in the store until that timestamp goes stale, so a
+// captured request can be replayed neither later nor on another replica.
+type ReplayGuard struct {
+	nonces  database.NonceStore
+	maxSkew time.Duration
+}
+
+// NewReplayGuard creates a guard allowing maxSkew of clock difference
+func NewReplayGuard(nonces database.NonceStore, maxSkew time.Duration) *ReplayGuard {
+	return &ReplayGuard{nonces: nonces, maxSkew: maxSkew}
+}
+
+// CheckTimestamp parses an X-Timestamp (Unix seconds or milliseconds) and
+// checks it is fresh, returning when the request was signed
+func (g *ReplayGuard) CheckTimestamp(timestamp string, now time.Time) (time.Time, error) {
+	value, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || value <= 0 {
+		return time.Time{}, fmt.Errorf("%w: not a Unix timestamp", ErrStaleTimestamp)
+	}
+
+	// Millisecond timestamps are 13 digits until the year 2286; seconds
+	// won't reach 11 digits until 5138
+	signedAt := time.Unix(value, 0)
+	if value >= 1e11 {
+		signedAt = time.UnixMilli(value)
+	}
+
+	if skew := now.Sub(signedAt); skew > g.maxSkew || skew < -g.maxSkew {
+		return time.Time{}, ErrStaleTimestamp
+	}
+	return signedAt, nil
+}
+
+// UseNonce records a client's nonce, failing if it was seen before
+// Call it only once the signature has checked out, so forged requests can't
+// use up a client's nonces.
+func (g *ReplayGuard) UseNonce(ctx context.Context, clientID, nonce string, signedAt time.Time) error {
+	if len(nonce) < MinNonceLength || len(nonce) > MaxNonceLength {
+		return ErrInvalidNonce
+	}
+
+	// Once the timestamp is stale the request is refused anyway, so the nonce
+	// only has to be remembered until then
+	fresh, err := g.nonces.UseNonce(ctx, clientID, nonce, signedAt.Add(g.maxSkew))
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrNonceReused
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/database"
+)
+
+func TestCheckTimestampKnownAnswers(t *testing.T) {
+	guard := NewReplayGuard(database.NewMemoryStore(), 5*time.Minute)
+	now := time.Unix(1700000000, 0)
+
+	tests := []struct {
+		timestamp string
+		want      time.Time
+		err       error
+	}{
+		{"1700000000", time.Unix(1700000000, 0), nil},
+		{"1700000000123", time.UnixMilli(1700000000123), nil},
+		{"1700000300", time.Unix(1700000300, 0), nil},
+		{"1699999700", time.Unix(1699999700, 0), nil},
+		{"1700000301", time.Time{}, ErrStaleTimestamp},
+		{"1699999699", time.Time{}, ErrStaleTimestamp},
+		{"1699999699999", time.Time{}, ErrStaleTimestamp},
+		{"0", time.Time{}, ErrStaleTimestamp},
+		{"2023-11-14T22:13:20Z", time.Time{}, ErrStaleTimestamp},
+	}
+
+	for _, tt := range tests {
+		got, err := guard.CheckTimestamp(tt.timestamp, now)
+		if !errors.Is(err, tt.err) || !got.Equal(tt.want) {
+			t.Fatalf("CheckTimestamp(%q) = %v, %v, want %v, %v", tt.timestamp, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestUseNonceOncePerClient(t *testing.T) {
+	guard := NewReplayGuard(database.NewMemoryStore(), 5*time.Minute)
+	ctx := context.Background()
+	signedAt := time.Now()
+
+	if err := guard.UseNonce(ctx, "client-a", testNonce, signedAt); err != nil {
+		t.Fatal(err)
+	}
+	if err := guard.UseNonce(ctx, "client-a", testNonce, signedAt); !errors.Is(err, ErrNonceReused) {
+		t.Fatalf("reused nonce = %v, want ErrNonceReused", err)
+	}
+	// Nonces are per client
+	if err := guard.UseNonce(ctx, "client-b", testNonce, signedAt); err != nil {
+		t.Fatalf("another client's nonce = %v, want nil", err)
+	}
+	if err := guard.UseNonce(ctx, "client-a", "short", signedAt); !errors.Is(err, ErrInvalidNonce) {
+		t.Fatalf("short nonce = %v, want ErrInvalidNonce", err)
+	}
+}
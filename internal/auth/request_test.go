@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"testing"
+)
+
+// Known answers for both signature versions; a client library built from the
+// docs must produce exactly these strings. Signatures are RFC 6979, so they
+// are fixed too: the key is the private key 1.
+const (
+	testPublicKey = "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"
+	testTimestamp = "1700000000"
+	testNonce     = "0123456789abcdef"
+	testBody      = `{"data":"68656c6c6f"}`
+)
+
+func testSignedRequest() *SignedRequest {
+	return &SignedRequest{
+		Timestamp:   testTimestamp,
+		Nonce:       testNonce,
+		Method:      "post",
+		Path:        "/publish",
+		RawQuery:    "wait=true&a=1",
+		ContentType: " application/json ",
+		APIKey:      "bsv_test_key",
+		Body:        []byte(testBody),
+		LegacyData:  "68656c6c6f",
+	}
+}
+
+func TestCanonicalRequestKnownAnswers(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *SignedRequest
+		want string
+	}{
+		{
+			name: "publish",
+			req:  testSignedRequest(),
+			want: "BSV-BROADCASTER-SIG-V2\n" +
+				"POST\n" +
+				"/publish\n" +
+				"a=1&wait=true\n" +
+				"content-type:application/json\n" +
+				"x-api-key:bsv_test_key\n" +
+				"x-nonce:0123456789abcdef\n" +
+				"x-timestamp:1700000000\n" +
+				"d07cddaa0bc6d41c81db3bd6c055337f577846a8babf7d8f5a031c3ddadd66f4",
+		},
+		{
+			name: "no query, headers or body",
+			req:  &SignedRequest{Method: "GET", Path: "/auth/key-status", Nonce: testNonce, Timestamp: testTimestamp},
+			want: "BSV-BROADCASTER-SIG-V2\n" +
+				"GET\n" +
+				"/auth/key-status\n" +
+				"\n" +
+				"content-type:\n" +
+				"x-api-key:\n" +
+				"x-nonce:0123456789abcdef\n" +
+				"x-timestamp:1700000000\n" +
+				"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		},
+		{
+			name: "query values encoded",
+			req:  &SignedRequest{Method: "POST", Path: "/publish", RawQuery: "note=a b&note=c%2Fd", Nonce: testNonce, Timestamp: testTimestamp},
+			want: "BSV-BROADCASTER-SIG-V2\n" +
+				"POST\n" +
+				"/publish\n" +
+				"note=a+b&note=c%2Fd\n" +
+				"content-type:\n" +
+				"x-api-key:\n" +
+				"x-nonce:0123456789abcdef\n" +
+				"x-timestamp:1700000000\n" +
+				"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanonicalRequest(tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("canonical request =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := CanonicalRequest(&SignedRequest{RawQuery: "a=%zz"}); err == nil {
+		t.Fatal("malformed query accepted")
+	}
+}
+
+func TestLegacyMessageKnownAnswer(t *testing.T) {
+	// Version 1 covers only the timestamp, nonce and data field, read as hex
+	if got, want := legacyMessage(testSignedRequest()), "1700000000"+"0123456789abcdef"+"68656c6c6f"; got != want {
+		t.Fatalf("legacy message = %q, want %q", got, want)
+	}
+}
+
+func TestVerifyRequestKnownSignatures(t *testing.T) {
+	tests := []struct {
+		version   int
+		signature string
+	}{
+		{SignatureV1, "3045022100bf114a575196af400a60eac1d43bfca5503844257e93a1ce892c3456078ed956022059e4ad3104e87534b867f838eb2ae20072be22a9cb55ef2ae0579e32e96a17b1"},
+		{SignatureV2, "3045022100fd503fe78843417cddc3a5041327e9d8199ed6479fbf2224c3bb9ed94a502c670220162b5dba4fb2f0f86c812fddd7652ce41c1803f49bd4ff793e58287732f8bb09"},
+	}
+
+	for _, tt := range tests {
+		req := testSignedRequest()
+		req.Version = tt.version
+		req.Signature = tt.signature
+		signedBy, err := verifyRequest(req, []string{"", testPublicKey})
+		if err != nil || signedBy != testPublicKey {
+			t.Fatalf("v%d known signature = %q, %v, want it verified", tt.version, signedBy, err)
+		}
+
+		// Each version's signature covers only its own message
+		req.Version = SignatureV1 + SignatureV2 - tt.version
+		if _, err := verifyRequest(req, []string{testPublicKey}); err == nil {
+			t.Fatalf("v%d signature verified as v%d", tt.version, req.Version)
+		}
+	}
+}
@@ -11,6 +11,17 @@ import (
 // VerifySignature ensures the 'data' was signed by the 'pubKey'
 // Returns true if the signature is valid for the given data and public key
 func VerifySignature(pubKeyHex, dataHex, sigHex string) (bool, error) {
+	// Decode the data (Bitcoin signs its double SHA-256)
+	dataBytes, err := hex.DecodeString(dataHex)
+	if err != nil {
+		return false, fmt.Errorf("invalid data hex: %w", err)
+	}
+
+	return VerifyMessage(pubKeyHex, dataBytes, sigHex)
+}
+
+// VerifyMessage ensures message was signed by the 'pubKey', as raw bytes
+func VerifyMessage(pubKeyHex string, message []byte, sigHex string) (bool, error) {
 	// Decode public key
 	pubKeyBytes, err := hex.DecodeString(pubKeyHex)
 	if err != nil {
@@ -33,16 +44,24 @@ func VerifySignature(pubKeyHex, dataHex, sigHex string) (bool, error) {
 		return false, fmt.Errorf("failed to parse signature: %w", err)
 	}
 
-	// Decode and hash the data (Bitcoin uses double SHA-256)
-	dataBytes, err := hex.DecodeString(dataHex)
-	if err != nil {
-		return false, fmt.Errorf("invalid data hex: %w", err)
-	}
-
 	// Double SHA-256 hash
-	hash1 := sha256.Sum256(dataBytes)
+	hash1 := sha256.Sum256(message)
 	hash2 := sha256.Sum256(hash1[:])
 
 	// Verify signature
 	return sig.Verify(hash2[:], pubKey), nil
 }
+
+// NormalizePublicKey parses a hex public key, compressed or not, and returns
+// it the way client keys are stored: uncompressed lower-case hex
+func NormalizePublicKey(pubKeyHex string) (string, error) {
+	pubKeyBytes, err := hex.DecodeString(pubKeyHex)
+	if err != nil {
+		return "", fmt.Errorf("invalid public key hex: %w", err)
+	}
+	pubKey, err := ec.ParsePubKey(pubKeyBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse public key: %w", err)
+	}
+	return hex.EncodeToString(pubKey.Uncompressed()), nil
+}
@@ -0,0 +1,288 @@
+package bsv
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/akua/bsv-broadcaster/internal/models"
+	bip32 "github.com/bsv-blockchain/go-sdk/compat/bip32"
+	"github.com/bsv-blockchain/go-sdk/script"
+	"github.com/bsv-blockchain/go-sdk/transaction/template/p2pkh"
+)
+
+// DefaultHDAddressWindow is how many child addresses an HD keypair spreads
+// its UTXOs across when no explicit window is configured
+const DefaultHDAddressWindow = 20
+
+// defaultDerivationPaths keeps funding and publishing on separate branches
+// when both are derived from the same xprv
+// Paths belong to keys, not clients: every tenant's publishes spend from the
+// one publishing chain, and per-tenant derivation paths are out of scope.
+var defaultDerivationPaths = map[string]string{
+	"FUNDING_PRIVKEY":    "m/0'/0'",
+	"PUBLISHING_PRIVKEY": "m/0'/1'",
+}
+
+// hdChain holds the account-level extended key of an HD keypair and caches
+// derived children so signing a batch doesn't re-derive the same key
+type hdChain struct {
+	account  *bip32.ExtendedKey
+	window   uint32
+	previous []*KeyPair // Chains rotated away from; their UTXOs are still synced and signed for
+	mu       sync.RWMutex
+	children map[uint32]*KeyPair
+}
+
+// NewHDKeyPair derives a keypair rooted at path (e.g. "m/0'/1'") from an xprv
+// Child 0 becomes the keypair's own PrivateKey/Address so code that only
+// knows about a single address keeps working. previousPaths are the chains
+// the key used before a rotation, which it keeps signing for.
+func NewHDKeyPair(xprv, path string, window uint32, previousPaths ...string) (*KeyPair, error) {
+	master, err := bip32.NewKeyFromString(xprv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse xprv: %w", err)
+	}
+	if !master.IsPrivate() {
+		return nil, fmt.Errorf("extended key is not private")
+	}
+
+	if window == 0 {
+		window = DefaultHDAddressWindow
+	}
+
+	kp, err := newHDChain(master, path, window)
+	if err != nil {
+		return nil, err
+	}
+	for _, previous := range previousPaths {
+		if previous == path {
+			continue
+		}
+		chain, err := newHDChain(master, previous, window)
+		if err != nil {
+			return nil, err
+		}
+		kp.hd.previous = append(kp.hd.previous, chain)
+	}
+	return kp, nil
+}
+
+// newHDChain derives the keypair for one account path of master
+func newHDChain(master *bip32.ExtendedKey, path string, window uint32) (*KeyPair, error) {
+	account, err := master.DeriveChildFromPath(strings.TrimPrefix(strings.TrimPrefix(path, "m"), "/"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive %s: %w", path, err)
+	}
+
+	kp := &KeyPair{
+		DerivationPath: path,
+		hd: &hdChain{
+			account:  account,
+			window:   window,
+			children: make(map[uint32]*KeyPair),
+		},
+	}
+
+	first, err := kp.Child(0)
+	if err != nil {
+		return nil, err
+	}
+	kp.PrivateKey = first.PrivateKey
+	kp.PublicKey = first.PublicKey
+	kp.Address = first.Address
+	kp.WIF = first.WIF
+
+	return kp, nil
+}
+
+// loadHDKeyPair builds an HD keypair for envVarName using the
+// <NAME>_DERIVATION_PATH, <NAME>_PREVIOUS_DERIVATION_PATHS and
+// HD_ADDRESS_WINDOW environment variables
+func loadHDKeyPair(envVarName, xprv string) (*KeyPair, error) {
+	name := strings.TrimSuffix(envVarName, "_PRIVKEY")
+	path := os.Getenv(name + "_DERIVATION_PATH")
+	if path == "" {
+		path = defaultDerivationPaths[envVarName]
+	}
+	if path == "" {
+		path = "m/0'"
+	}
+
+	window := uint64(DefaultHDAddressWindow)
+	if v := os.Getenv("HD_ADDRESS_WINDOW"); v != "" {
+		parsed, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || parsed == 0 {
+			return nil, fmt.Errorf("invalid HD_ADDRESS_WINDOW: %q", v)
+		}
+		window = parsed
+	}
+
+	var previous []string
+	for _, p := range strings.Split(os.Getenv(name+"_PREVIOUS_DERIVATION_PATHS"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			previous = append(previous, p)
+		}
+	}
+
+	return NewHDKeyPair(xprv, path, uint32(window), previous...)
+}
+
+// IsHD reports whether the keypair is derived from an extended key
+func (kp *KeyPair) IsHD() bool {
+	return kp.hd != nil
+}
+
+// AddressWindow returns how many child addresses the keypair uses (1 for WIF keys)
+func (kp *KeyPair) AddressWindow() uint32 {
+	if kp.hd == nil {
+		return 1
+	}
+	return kp.hd.window
+}
+
+// Chains returns the keypair's current chain followed by the chains it was
+// rotated away from; a single-key keypair is its only chain
+func (kp *KeyPair) Chains() []*KeyPair {
+	if kp.hd == nil {
+		return []*KeyPair{kp}
+	}
+	return append([]*KeyPair{kp}, kp.hd.previous...)
+}
+
+// Child returns the keypair at the given derivation index
+// For single-key keypairs only index 0 exists and it is the keypair itself
+func (kp *KeyPair) Child(index uint32) (*KeyPair, error) {
+	if kp.hd == nil {
+		if index != 0 {
+			return nil, fmt.Errorf("keypair %s is not HD, cannot derive index %d", kp.Address, index)
+		}
+		return kp, nil
+	}
+
+	kp.hd.mu.RLock()
+	child, ok := kp.hd.children[index]
+	kp.hd.mu.RUnlock()
+	if ok {
+		return child, nil
+	}
+
+	ext, err := kp.hd.account.Child(index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive child %d: %w", index, err)
+	}
+
+	privKey, err := ext.ECPrivKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get child %d private key: %w", index, err)
+	}
+
+	pubKey := privKey.PubKey()
+	address, err := script.NewAddressFromPublicKey(pubKey, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create address: %w", err)
+	}
+
+	child = &KeyPair{
+		PrivateKey:     privKey,
+		PublicKey:      pubKey,
+		Address:        address.AddressString,
+		WIF:            privKey.Wif(),
+		DerivationPath: kp.ChildPath(index),
+	}
+
+	kp.hd.mu.Lock()
+	kp.hd.children[index] = child
+	kp.hd.mu.Unlock()
+
+	return child, nil
+}
+
+// ChildPath returns the derivation path of the child at index, which is
+// empty for single-key keypairs
+func (kp *KeyPair) ChildPath(index uint32) string {
+	if kp.hd == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s/%d", kp.DerivationPath, index)
+}
+
+// ReceiveAddress picks the address for the n-th output of a split so new
+// UTXOs are spread round-robin across the address window
+func (kp *KeyPair) ReceiveAddress(n int) (string, uint32, error) {
+	index := uint32(n) % kp.AddressWindow()
+	child, err := kp.Child(index)
+	if err != nil {
+		return "", 0, err
+	}
+	return child.Address, index, nil
+}
+
+// KeyFor returns the child key that controls the given UTXO
+// The UTXO's recorded derivation path and locking script are checked against
+// the derived key so a mismatched chain or index fails here rather than as an
+// invalid signature at ARC. A path on a chain the key was rotated away from is
+// signed for by that chain. Rows stored before paths were recorded have none
+// and are checked by script alone.
+func (kp *KeyPair) KeyFor(utxo *models.UTXO) (*KeyPair, error) {
+	if utxo.ScriptPubKey == "" {
+		return nil, fmt.Errorf("UTXO %s has no locking script to check its key against", utxo.Outpoint)
+	}
+
+	chain := kp.chainFor(utxo)
+	if chain == nil {
+		return nil, fmt.Errorf("UTXO %s was derived at %s, not under %s", utxo.Outpoint, utxo.DerivationPath, kp.Address)
+	}
+
+	child, err := chain.Child(utxo.DerivationIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	if createP2PKHScriptFromAddress(child.Address) != utxo.ScriptPubKey {
+		return nil, fmt.Errorf("UTXO %s is not controlled by %s", utxo.Outpoint, child.Address)
+	}
+
+	return child, nil
+}
+
+// chainFor finds which of the keypair's chains the UTXO's recorded path is on
+func (kp *KeyPair) chainFor(utxo *models.UTXO) *KeyPair {
+	if utxo.DerivationPath == "" {
+		return kp
+	}
+	for _, chain := range kp.Chains() {
+		if utxo.DerivationPath == chain.ChildPath(utxo.DerivationIndex) {
+			return chain
+		}
+	}
+	return nil
+}
+
+// UnlockerFor creates a P2PKH unlocker signing with the key that controls the UTXO
+func (kp *KeyPair) UnlockerFor(utxo *models.UTXO) (*p2pkh.P2PKH, error) {
+	child, err := kp.KeyFor(utxo)
+	if err != nil {
+		return nil, err
+	}
+	return p2pkh.Unlock(child.PrivateKey, nil)
+}
+
+// ResolveUnlocker finds which of the given keypairs controls the UTXO and
+// returns an unlocker for it
+func ResolveUnlocker(utxo *models.UTXO, keys ...*KeyPair) (*p2pkh.P2PKH, error) {
+	for _, kp := range keys {
+		if kp == nil {
+			continue
+		}
+		if unlocker, err := kp.UnlockerFor(utxo); err == nil {
+			return unlocker, nil
+		}
+	}
+	if utxo.ScriptPubKey == "" {
+		return nil, fmt.Errorf("UTXO %s has no locking script, can't tell which key controls it", utxo.Outpoint)
+	}
+	return nil, fmt.Errorf("no key controls UTXO %s (derivation path %q, index %d)", utxo.Outpoint, utxo.DerivationPath, utxo.DerivationIndex)
+}
@@ -0,0 +1,166 @@
+package bsv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/akua/bsv-broadcaster/internal/models"
+	bip32 "github.com/bsv-blockchain/go-sdk/compat/bip32"
+)
+
+// newTestHDKeys derives funding and publishing keypairs from one xprv, the
+// way LoadOrGenerateKeyPair does with the default paths
+func newTestHDKeys(t *testing.T) (funding, publishing *KeyPair) {
+	t.Helper()
+
+	xprv, _, err := bip32.GenerateHDKeyPair(bip32.RecommendedSeedLen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	funding, err = NewHDKeyPair(xprv, defaultDerivationPaths["FUNDING_PRIVKEY"], 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishing, err = NewHDKeyPair(xprv, defaultDerivationPaths["PUBLISHING_PRIVKEY"], 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return funding, publishing
+}
+
+// paidTo is a UTXO paying to kp's child at index, recorded the way the
+// splitter records one
+func paidTo(kp *KeyPair, index uint32) *models.UTXO {
+	child, _ := kp.Child(index)
+	return &models.UTXO{
+		Outpoint:        "00:0",
+		ScriptPubKey:    createP2PKHScriptFromAddress(child.Address),
+		DerivationIndex: index,
+		DerivationPath:  kp.ChildPath(index),
+	}
+}
+
+func TestChildPath(t *testing.T) {
+	_, publishing := newTestHDKeys(t)
+	if got := publishing.ChildPath(3); got != "m/0'/1'/3" {
+		t.Fatalf("ChildPath(3) = %q, want m/0'/1'/3", got)
+	}
+	child, err := publishing.Child(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if child.DerivationPath != publishing.ChildPath(3) {
+		t.Fatalf("child path = %q, want %q", child.DerivationPath, publishing.ChildPath(3))
+	}
+
+	single, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := single.ChildPath(0); got != "" {
+		t.Fatalf("single-key ChildPath(0) = %q, want empty", got)
+	}
+}
+
+func TestResolveUnlockerPicksTheRecordedChain(t *testing.T) {
+	funding, publishing := newTestHDKeys(t)
+
+	utxo := paidTo(publishing, 3)
+	if _, err := ResolveUnlocker(utxo, funding, publishing); err != nil {
+		t.Fatalf("ResolveUnlocker: %v", err)
+	}
+	if _, err := funding.KeyFor(utxo); err == nil {
+		t.Fatal("funding key accepted a UTXO derived on the publishing chain")
+	}
+
+	key, err := publishing.KeyFor(utxo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.DerivationPath != utxo.DerivationPath {
+		t.Fatalf("signing key path = %q, want %q", key.DerivationPath, utxo.DerivationPath)
+	}
+}
+
+func TestKeyForRejectsMismatchedPath(t *testing.T) {
+	funding, publishing := newTestHDKeys(t)
+
+	// The script is publishing's, but the row claims the funding chain
+	utxo := paidTo(publishing, 2)
+	utxo.DerivationPath = funding.ChildPath(2)
+
+	if _, err := publishing.KeyFor(utxo); err == nil || !strings.Contains(err.Error(), "was derived at") {
+		t.Fatalf("KeyFor error = %v, want a derivation path mismatch", err)
+	}
+	if _, err := ResolveUnlocker(utxo, funding, publishing); err == nil {
+		t.Fatal("ResolveUnlocker accepted a UTXO whose path and script disagree")
+	}
+}
+
+func TestKeyForChecksScriptWithoutPath(t *testing.T) {
+	funding, publishing := newTestHDKeys(t)
+
+	// Rows stored before paths were recorded
+	utxo := paidTo(publishing, 4)
+	utxo.DerivationPath = ""
+
+	if _, err := publishing.KeyFor(utxo); err != nil {
+		t.Fatalf("KeyFor: %v", err)
+	}
+	if _, err := funding.KeyFor(utxo); err == nil {
+		t.Fatal("funding key accepted a UTXO whose script it doesn't control")
+	}
+}
+
+func TestResolveUnlockerRequiresScript(t *testing.T) {
+	funding, publishing := newTestHDKeys(t)
+	single, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, utxo := range []*models.UTXO{
+		{Outpoint: "00:0"},
+		{Outpoint: "00:1", DerivationPath: publishing.ChildPath(0)},
+	} {
+		if _, err := ResolveUnlocker(utxo, single, funding, publishing); err == nil || !strings.Contains(err.Error(), "no locking script") {
+			t.Fatalf("ResolveUnlocker(%s) error = %v, want a missing script error", utxo.Outpoint, err)
+		}
+	}
+}
+
+func TestRotatedKeyStillSignsForPreviousChain(t *testing.T) {
+	xprv, _, err := bip32.GenerateHDKeyPair(bip32.RecommendedSeedLen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := NewHDKeyPair(xprv, "m/0'/1'", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxo := paidTo(before, 3)
+
+	rotated, err := NewHDKeyPair(xprv, "m/0'/2'", 5, "m/0'/1'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := rotated.KeyFor(utxo)
+	if err != nil {
+		t.Fatalf("rotated key can't sign for its previous chain: %v", err)
+	}
+	if key.DerivationPath != "m/0'/1'/3" {
+		t.Fatalf("signing key path = %q, want m/0'/1'/3", key.DerivationPath)
+	}
+	if rotated.Address == before.Address {
+		t.Fatal("rotated key still receives on the previous chain")
+	}
+
+	// Without the previous path, the old UTXOs are refused
+	forgotten, err := NewHDKeyPair(xprv, "m/0'/2'", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := forgotten.KeyFor(utxo); err == nil {
+		t.Fatal("key signed for a chain it was never configured with")
+	}
+}
@@ -3,7 +3,9 @@ package bsv
 import (
 	"encoding/hex"
 	"fmt"
+	"log/slog"
 	"os"
+	"strings"
 
 	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
 	"github.com/bsv-blockchain/go-sdk/script"
@@ -15,6 +17,10 @@ type KeyPair struct {
 	PublicKey  *ec.PublicKey
 	Address    string
 	WIF        string
+
+	// HD DERIVATION (empty for single-key WIF keypairs)
+	DerivationPath string
+	hd             *hdChain
 }
 
 // GenerateKeyPair creates a new random BSV keypair
@@ -56,17 +62,23 @@ func LoadOrGenerateKeyPair(envVarName string) (*KeyPair, error) {
 			return nil, err
 		}
 
-		// Print warning to set it in .env
-		fmt.Printf("\n⚠️  WARNING: No %s found in environment!\n", envVarName)
-		fmt.Printf("Generated new keypair:\n")
-		fmt.Printf("  Address: %s\n", kp.Address)
-		fmt.Printf("  Private Key (WIF): %s\n", kp.WIF)
-		fmt.Printf("\nAdd this to your .env file:\n")
-		fmt.Printf("%s=%s\n\n", envVarName, kp.WIF)
+		// The key itself is never logged; the caller persists it
+		slog.Warn("No key in environment, generated a new keypair", "env", envVarName, "address", kp.Address)
 
 		return kp, nil
 	}
 
+	// Extended private keys switch the keypair into HD mode
+	if strings.HasPrefix(privKeyWIF, "xprv") || strings.HasPrefix(privKeyWIF, "tprv") {
+		kp, err := loadHDKeyPair(envVarName, privKeyWIF)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s: %w", envVarName, err)
+		}
+
+		slog.Info("Loaded HD key", "env", envVarName, "path", kp.DerivationPath, "addresses", kp.AddressWindow(), "address", kp.Address)
+		return kp, nil
+	}
+
 	// Load existing private key from WIF
 	privKey, err := ec.PrivateKeyFromWif(privKeyWIF)
 	if err != nil {
@@ -86,7 +98,7 @@ func LoadOrGenerateKeyPair(envVarName string) (*KeyPair, error) {
 
 	wifStr := privKey.Wif()
 
-	fmt.Printf("✓ Loaded %s: %s\n", envVarName, address.AddressString)
+	slog.Info("Loaded key", "env", envVarName, "address", address.AddressString)
 
 	return &KeyPair{
 		PrivateKey: privKey,
@@ -96,6 +108,11 @@ func LoadOrGenerateKeyPair(envVarName string) (*KeyPair, error) {
 	}, nil
 }
 
+// LogValue logs a keypair as its address so the private key can't leak into logs
+func (kp *KeyPair) LogValue() slog.Value {
+	return slog.StringValue(kp.Address)
+}
+
 // Sign signs a transaction hash with the private key
 func (kp *KeyPair) Sign(hash []byte) ([]byte, error) {
 	sig, err := kp.PrivateKey.Sign(hash)
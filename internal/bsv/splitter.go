@@ -4,11 +4,13 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
+	"log/slog"
 
 	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/logging"
+	"github.com/akua/bsv-broadcaster/internal/metrics"
 	"github.com/akua/bsv-broadcaster/internal/models"
 	"github.com/bsv-blockchain/go-sdk/transaction"
-	"github.com/bsv-blockchain/go-sdk/transaction/template/p2pkh"
 )
 
 // SimpleFeeModel implements transaction.FeeModel for sat-per-byte fee calculation
@@ -25,19 +27,20 @@ func (fm *SimpleFeeModel) ComputeFee(tx *transaction.Transaction) (uint64, error
 
 // Splitter handles splitting funding UTXOs into publishing UTXOs
 type Splitter struct {
-	db             *database.Database
-	fundingKey     *KeyPair
-	publishingAddr string
-	feeRate        float64 // sats per byte
+	db            database.Store
+	fundingKey    *KeyPair
+	publishingKey *KeyPair
+	feeRate       float64 // sats per byte
 }
 
 // NewSplitter creates a new UTXO splitter
-func NewSplitter(db *database.Database, fundingKey *KeyPair, publishingAddr string, feeRate float64) *Splitter {
+// Outputs are spread across the keypairs' HD address windows when they are HD
+func NewSplitter(db database.Store, fundingKey, publishingKey *KeyPair, feeRate float64) *Splitter {
 	return &Splitter{
-		db:             db,
-		fundingKey:     fundingKey,
-		publishingAddr: publishingAddr,
-		feeRate:        feeRate,
+		db:            db,
+		fundingKey:    fundingKey,
+		publishingKey: publishingKey,
+		feeRate:       feeRate,
 	}
 }
 
@@ -52,13 +55,13 @@ type SplitResult struct {
 // Phase 1: Split funding UTXO into N branch UTXOs
 // Phase 2: Split each branch into 1000 leaf UTXOs (publishing)
 func (s *Splitter) CreatePublishingUTXOs(ctx context.Context, targetCount int) (*SplitResult, error) {
-	fmt.Printf("🌳 Starting UTXO split to create %d publishing UTXOs...\n", targetCount)
+	slog.InfoContext(ctx, "Starting UTXO split", "target", targetCount)
 
 	// Calculate how many branches we need
 	leavesPerBranch := 1000
 	branchCount := (targetCount + leavesPerBranch - 1) / leavesPerBranch // Round up
 
-	fmt.Printf("   Strategy: %d branches × %d leaves = %d UTXOs\n", branchCount, leavesPerBranch, branchCount*leavesPerBranch)
+	slog.InfoContext(ctx, "Split strategy", "branches", branchCount, "leaves_per_branch", leavesPerBranch)
 
 	// Phase 1: Create branches
 	branchUTXOs, branchTxIDs, err := s.createBranches(ctx, branchCount)
@@ -66,7 +69,7 @@ func (s *Splitter) CreatePublishingUTXOs(ctx context.Context, targetCount int) (
 		return nil, fmt.Errorf("failed to create branches: %w", err)
 	}
 
-	fmt.Printf("✓ Phase 1 complete: Created %d branch UTXOs\n", len(branchUTXOs))
+	slog.InfoContext(ctx, "Phase 1 complete", "branch_utxos", len(branchUTXOs))
 
 	// Phase 2: Create leaves from each branch (can be parallelized)
 	leafTxIDs, err := s.createLeaves(ctx, branchUTXOs, leavesPerBranch)
@@ -75,7 +78,7 @@ func (s *Splitter) CreatePublishingUTXOs(ctx context.Context, targetCount int) (
 	}
 
 	totalUTXOs := len(leafTxIDs) * leavesPerBranch
-	fmt.Printf("✓ Phase 2 complete: Created %d leaf transactions (%d UTXOs)\n", len(leafTxIDs), totalUTXOs)
+	slog.InfoContext(ctx, "Phase 2 complete", "leaf_txs", len(leafTxIDs), "publishing_utxos", totalUTXOs)
 
 	return &SplitResult{
 		BranchTxIDs: branchTxIDs,
@@ -111,7 +114,7 @@ func (s *Splitter) createBranches(ctx context.Context, branchCount int) ([]*mode
 	}
 
 	// Create unlocker for P2PKH
-	unlocker, err := p2pkh.Unlock(s.fundingKey.PrivateKey, nil)
+	unlocker, err := ResolveUnlocker(fundingUTXO, s.fundingKey, s.publishingKey)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create unlocker: %w", err)
 	}
@@ -128,11 +131,16 @@ func (s *Splitter) createBranches(ctx context.Context, branchCount int) ([]*mode
 	}
 
 	// Add outputs (branches)
+	branchIndices := make([]uint32, branchCount)
 	for i := 0; i < branchCount; i++ {
-		err = tx.PayToAddress(s.publishingAddr, satsPerBranch)
+		addr, index, err := s.publishingKey.ReceiveAddress(i)
 		if err != nil {
+			return nil, nil, fmt.Errorf("failed to derive address for output %d: %w", i, err)
+		}
+		if err := tx.PayToAddress(addr, satsPerBranch); err != nil {
 			return nil, nil, fmt.Errorf("failed to add output %d: %w", i, err)
 		}
+		branchIndices[i] = index
 	}
 
 	// Add change output manually
@@ -163,18 +171,21 @@ func (s *Splitter) createBranches(ctx context.Context, branchCount int) ([]*mode
 	for i := 0; i < branchCount; i++ {
 		scriptHex := hex.EncodeToString(*tx.Outputs[i].LockingScript)
 		utxo := &models.UTXO{
-			Outpoint:     fmt.Sprintf("%s:%d", txid, i),
-			TxID:         txid,
-			Vout:         uint32(i),
-			Satoshis:     satsPerBranch,
-			ScriptPubKey: scriptHex,
-			Status:       models.UTXOStatusAvailable,
-			Type:         models.UTXOTypeFunding, // These are still "funding" tier
+			Outpoint:        fmt.Sprintf("%s:%d", txid, i),
+			TxID:            txid,
+			Vout:            uint32(i),
+			Satoshis:        satsPerBranch,
+			ScriptPubKey:    scriptHex,
+			Status:          models.UTXOStatusAvailable,
+			Type:            models.UTXOTypeFunding, // These are still "funding" tier
+			DerivationIndex: branchIndices[i],
+			DerivationPath:  s.publishingKey.ChildPath(branchIndices[i]),
 		}
 
 		if err := s.db.InsertUTXO(ctx, utxo); err != nil {
 			return nil, nil, err
 		}
+		metrics.SplitOutputs.WithLabelValues(string(utxo.Type)).Inc()
 
 		branchUTXOs = append(branchUTXOs, utxo)
 	}
@@ -198,7 +209,7 @@ func (s *Splitter) createLeaves(ctx context.Context, branchUTXOs []*models.UTXO,
 			return nil, err
 		}
 
-		unlocker, err := p2pkh.Unlock(s.fundingKey.PrivateKey, nil)
+		unlocker, err := ResolveUnlocker(branch, s.fundingKey, s.publishingKey)
 		if err != nil {
 			return nil, err
 		}
@@ -215,10 +226,16 @@ func (s *Splitter) createLeaves(ctx context.Context, branchUTXOs []*models.UTXO,
 		}
 
 		// Add 1000 outputs of 100 sats each
+		leafIndices := make([]uint32, leavesPerBranch)
 		for i := 0; i < leavesPerBranch; i++ {
-			if err := tx.PayToAddress(s.publishingAddr, 100); err != nil {
+			addr, index, err := s.publishingKey.ReceiveAddress(i)
+			if err != nil {
+				return nil, err
+			}
+			if err := tx.PayToAddress(addr, 100); err != nil {
 				return nil, err
 			}
+			leafIndices[i] = index
 		}
 
 		// Sign
@@ -233,18 +250,21 @@ func (s *Splitter) createLeaves(ctx context.Context, branchUTXOs []*models.UTXO,
 			out := tx.Outputs[i]
 			scriptHex := hex.EncodeToString(*out.LockingScript)
 			utxo := &models.UTXO{
-				Outpoint:     fmt.Sprintf("%s:%d", txid, i),
-				TxID:         txid,
-				Vout:         uint32(i),
-				Satoshis:     100,
-				ScriptPubKey: scriptHex,
-				Status:       models.UTXOStatusAvailable,
-				Type:         models.UTXOTypePublishing,
+				Outpoint:        fmt.Sprintf("%s:%d", txid, i),
+				TxID:            txid,
+				Vout:            uint32(i),
+				Satoshis:        100,
+				ScriptPubKey:    scriptHex,
+				Status:          models.UTXOStatusAvailable,
+				Type:            models.UTXOTypePublishing,
+				DerivationIndex: leafIndices[i],
+				DerivationPath:  s.publishingKey.ChildPath(leafIndices[i]),
 			}
 
 			if err := s.db.InsertUTXO(ctx, utxo); err != nil {
 				return nil, err
 			}
+			metrics.SplitOutputs.WithLabelValues(string(utxo.Type)).Inc()
 		}
 
 		leafTxIDs = append(leafTxIDs, txid)
@@ -260,7 +280,7 @@ func (s *Splitter) createLeaves(ctx context.Context, branchUTXOs []*models.UTXO,
 // Each branch (~2M sats) is split into 1,000 publishing UTXOs of 100 sats each
 // This is Phase 2 of the tree split
 func (s *Splitter) SplitBranchesIntoLeaves(ctx context.Context, arcBroadcastFunc func(context.Context, string) (string, error)) (*SplitResult, error) {
-	fmt.Println("🍃 Phase 2: Splitting branch UTXOs into publishing leaves...")
+	slog.InfoContext(ctx, "Phase 2: splitting branch UTXOs into publishing leaves")
 
 	// Find all available funding UTXOs (branches from Phase 1)
 	fundingUTXOs, err := s.db.FindUTXOsByType(ctx, models.UTXOTypeFunding, models.UTXOStatusAvailable)
@@ -272,18 +292,18 @@ func (s *Splitter) SplitBranchesIntoLeaves(ctx context.Context, arcBroadcastFunc
 		return nil, fmt.Errorf("no funding UTXOs available for splitting")
 	}
 
-	fmt.Printf("   Found %d branch UTXOs to split\n", len(fundingUTXOs))
+	slog.InfoContext(ctx, "Found branch UTXOs to split", "count", len(fundingUTXOs))
 
 	leafTxIDs := make([]string, 0, len(fundingUTXOs))
 	totalPublishingUTXOs := 0
 
 	// Process each branch
 	for idx, branch := range fundingUTXOs {
-		fmt.Printf("   Processing branch %d/%d: %s (%d sats)\n", idx+1, len(fundingUTXOs), branch.Outpoint, branch.Satoshis)
+		slog.DebugContext(ctx, "Processing branch", "index", idx+1, "of", len(fundingUTXOs), logging.KeyUTXO, branch.Outpoint, "satoshis", branch.Satoshis)
 
 		// Lock the UTXO
 		if err := s.db.LockUTXO(ctx, branch.Outpoint); err != nil {
-			fmt.Printf("   ⚠️  Warning: failed to lock UTXO %s: %v\n", branch.Outpoint, err)
+			slog.WarnContext(ctx, "Failed to lock branch UTXO", logging.KeyUTXO, branch.Outpoint, logging.Err(err))
 			continue
 		}
 
@@ -295,7 +315,7 @@ func (s *Splitter) SplitBranchesIntoLeaves(ctx context.Context, arcBroadcastFunc
 		availableForLeaves := branch.Satoshis - estimatedFee
 
 		if availableForLeaves < publishingSats {
-			fmt.Printf("   ⚠️  Skipping: not enough sats for even one leaf\n")
+			slog.WarnContext(ctx, "Skipping branch: not enough sats for even one leaf", logging.KeyUTXO, branch.Outpoint)
 			s.db.UnlockUTXO(ctx, branch.Outpoint)
 			continue
 		}
@@ -305,13 +325,13 @@ func (s *Splitter) SplitBranchesIntoLeaves(ctx context.Context, arcBroadcastFunc
 			totalLeavesCount = 500 // Cap at 500 to keep tx size reasonable (~17KB)
 		}
 
-		fmt.Printf("   Creating %d publishing UTXOs (100 sats each)\n", totalLeavesCount)
+		slog.DebugContext(ctx, "Creating publishing UTXOs", "count", totalLeavesCount)
 
 		// Create transaction
 		tx := transaction.NewTransaction()
 
 		// Add input from branch UTXO
-		unlocker, err := p2pkh.Unlock(s.fundingKey.PrivateKey, nil)
+		unlocker, err := ResolveUnlocker(branch, s.fundingKey, s.publishingKey)
 		if err != nil {
 			s.db.UnlockUTXO(ctx, branch.Outpoint)
 			return nil, fmt.Errorf("failed to create unlocker: %w", err)
@@ -323,11 +343,18 @@ func (s *Splitter) SplitBranchesIntoLeaves(ctx context.Context, arcBroadcastFunc
 		}
 
 		// Add publishing outputs
+		leafIndices := make([]uint32, totalLeavesCount)
 		for i := 0; i < totalLeavesCount; i++ {
-			if err := tx.PayToAddress(s.publishingAddr, publishingSats); err != nil {
+			addr, index, err := s.publishingKey.ReceiveAddress(i)
+			if err != nil {
+				s.db.UnlockUTXO(ctx, branch.Outpoint)
+				return nil, fmt.Errorf("failed to derive address for output %d: %w", i, err)
+			}
+			if err := tx.PayToAddress(addr, publishingSats); err != nil {
 				s.db.UnlockUTXO(ctx, branch.Outpoint)
 				return nil, fmt.Errorf("failed to add output %d: %w", i, err)
 			}
+			leafIndices[i] = index
 		}
 
 		// Calculate change manually and add it
@@ -362,17 +389,16 @@ func (s *Splitter) SplitBranchesIntoLeaves(ctx context.Context, arcBroadcastFunc
 		}
 
 		txid := tx.TxID().String()
-		fmt.Printf("   Transaction: %s (%d bytes)\n", txid, len(rawHex)/2)
+		slog.DebugContext(ctx, "Broadcasting leaf transaction", logging.KeyTxID, txid, "bytes", len(rawHex)/2)
 
 		// Broadcast via ARC
-		fmt.Println("   📡 Broadcasting...")
 		broadcastedTxID, err := arcBroadcastFunc(ctx, rawHex)
 		if err != nil {
 			s.db.UnlockUTXO(ctx, branch.Outpoint)
 			return nil, fmt.Errorf("ARC broadcast failed for branch %s: %w", branch.Outpoint, err)
 		}
 
-		fmt.Printf("   ✅ Broadcast successful: %s\n", broadcastedTxID)
+		slog.DebugContext(ctx, "Leaf transaction broadcast", logging.KeyTxID, broadcastedTxID)
 
 		// Mark branch as spent
 		if err := s.db.MarkUTXOSpent(ctx, branch.Outpoint, txid); err != nil {
@@ -385,25 +411,28 @@ func (s *Splitter) SplitBranchesIntoLeaves(ctx context.Context, arcBroadcastFunc
 			scriptHex := hex.EncodeToString(*out.LockingScript)
 
 			utxo := &models.UTXO{
-				Outpoint:     fmt.Sprintf("%s:%d", txid, i),
-				TxID:         txid,
-				Vout:         uint32(i),
-				Satoshis:     publishingSats,
-				ScriptPubKey: scriptHex,
-				Status:       models.UTXOStatusAvailable,
-				Type:         models.UTXOTypePublishing,
+				Outpoint:        fmt.Sprintf("%s:%d", txid, i),
+				TxID:            txid,
+				Vout:            uint32(i),
+				Satoshis:        publishingSats,
+				ScriptPubKey:    scriptHex,
+				Status:          models.UTXOStatusAvailable,
+				Type:            models.UTXOTypePublishing,
+				DerivationIndex: leafIndices[i],
+				DerivationPath:  s.publishingKey.ChildPath(leafIndices[i]),
 			}
 
 			if err := s.db.InsertUTXO(ctx, utxo); err != nil {
 				return nil, fmt.Errorf("failed to insert publishing UTXO: %w", err)
 			}
+			metrics.SplitOutputs.WithLabelValues(string(utxo.Type)).Inc()
 		}
 
 		leafTxIDs = append(leafTxIDs, txid)
 		totalPublishingUTXOs += totalLeavesCount
 	}
 
-	fmt.Printf("✓ Phase 2 complete: Created %d publishing UTXOs across %d transactions\n", totalPublishingUTXOs, len(leafTxIDs))
+	slog.InfoContext(ctx, "Phase 2 complete", "publishing_utxos", totalPublishingUTXOs, "leaf_txs", len(leafTxIDs))
 
 	return &SplitResult{
 		LeafTxIDs:  leafTxIDs,
@@ -415,7 +444,7 @@ func (s *Splitter) SplitBranchesIntoLeaves(ctx context.Context, arcBroadcastFunc
 // Each branch will be ~2,000,000 sats (adjustable based on input amount and fees)
 // This is Phase 1 of the tree split - Phase 2 will split each branch into 1,000 leaves
 func (s *Splitter) SplitIntoFiftyBranches(ctx context.Context, arcBroadcastFunc func(context.Context, string) (string, error)) (*SplitResult, error) {
-	fmt.Println("🌳 Phase 1: Splitting funding UTXO into 50 branches...")
+	slog.InfoContext(ctx, "Phase 1: splitting funding UTXO into 50 branches")
 
 	// Find a funding UTXO
 	fundingUTXO, err := s.db.FindAndLockUTXO(ctx, models.UTXOTypeFunding)
@@ -423,7 +452,7 @@ func (s *Splitter) SplitIntoFiftyBranches(ctx context.Context, arcBroadcastFunc
 		return nil, fmt.Errorf("no funding UTXO available: %w", err)
 	}
 
-	fmt.Printf("   Using funding UTXO: %s (%d sats)\n", fundingUTXO.Outpoint, fundingUTXO.Satoshis)
+	slog.InfoContext(ctx, "Using funding UTXO", logging.KeyUTXO, fundingUTXO.Outpoint, "satoshis", fundingUTXO.Satoshis)
 
 	// Calculate branch size
 	const branchCount = 50
@@ -440,15 +469,13 @@ func (s *Splitter) SplitIntoFiftyBranches(ctx context.Context, arcBroadcastFunc
 	// Each branch gets equal amount
 	branchAmount := availableForBranches / branchCount
 
-	fmt.Printf("   Total input: %d sats\n", totalInput)
-	fmt.Printf("   Estimated fee: %d sats\n", estimatedFee)
-	fmt.Printf("   Each branch: %d sats\n", branchAmount)
+	slog.DebugContext(ctx, "Branch amounts", "total_input", totalInput, "estimated_fee", estimatedFee, "each_branch", branchAmount)
 
 	// Create transaction
 	tx := transaction.NewTransaction()
 
 	// Add input from funding UTXO
-	unlocker, err := p2pkh.Unlock(s.fundingKey.PrivateKey, nil)
+	unlocker, err := ResolveUnlocker(fundingUTXO, s.fundingKey, s.publishingKey)
 	if err != nil {
 		s.db.UnlockUTXO(ctx, fundingUTXO.Outpoint)
 		return nil, fmt.Errorf("failed to create unlocker: %w", err)
@@ -467,11 +494,18 @@ func (s *Splitter) SplitIntoFiftyBranches(ctx context.Context, arcBroadcastFunc
 	}
 
 	// Add 50 branch outputs to funding address
+	branchIndices := make([]uint32, branchCount)
 	for i := 0; i < branchCount; i++ {
-		if err := tx.PayToAddress(s.fundingKey.Address, branchAmount); err != nil {
+		addr, index, err := s.fundingKey.ReceiveAddress(i)
+		if err != nil {
+			s.db.UnlockUTXO(ctx, fundingUTXO.Outpoint)
+			return nil, fmt.Errorf("failed to derive address for output %d: %w", i, err)
+		}
+		if err := tx.PayToAddress(addr, branchAmount); err != nil {
 			s.db.UnlockUTXO(ctx, fundingUTXO.Outpoint)
 			return nil, fmt.Errorf("failed to add output %d: %w", i, err)
 		}
+		branchIndices[i] = index
 	}
 
 	// Sign transaction
@@ -485,26 +519,24 @@ func (s *Splitter) SplitIntoFiftyBranches(ctx context.Context, arcBroadcastFunc
 	ef, err := tx.EF()
 	if err == nil {
 		rawHex = hex.EncodeToString(ef)
-		fmt.Println("   Using Extended Format (EF)")
+		slog.DebugContext(ctx, "Using Extended Format (EF)")
 	} else {
 		rawHex = tx.String()
-		fmt.Println("   Using standard format")
+		slog.DebugContext(ctx, "Using standard format")
 	}
 
 	txid := tx.TxID().String()
 
-	fmt.Printf("   Transaction created: %s\n", txid)
-	fmt.Printf("   Size: %d bytes\n", len(rawHex)/2)
+	slog.InfoContext(ctx, "Broadcasting branch transaction", logging.KeyTxID, txid, "bytes", len(rawHex)/2)
 
 	// Broadcast via ARC
-	fmt.Println("   📡 Broadcasting to ARC...")
 	broadcastedTxID, err := arcBroadcastFunc(ctx, rawHex)
 	if err != nil {
 		s.db.UnlockUTXO(ctx, fundingUTXO.Outpoint)
 		return nil, fmt.Errorf("ARC broadcast failed: %w", err)
 	}
 
-	fmt.Printf("   ✓ Broadcast successful: %s\n", broadcastedTxID)
+	slog.InfoContext(ctx, "Branch transaction broadcast", logging.KeyTxID, broadcastedTxID)
 
 	// Mark original UTXO as spent
 	if err := s.db.MarkUTXOSpent(ctx, fundingUTXO.Outpoint, txid); err != nil {
@@ -517,21 +549,24 @@ func (s *Splitter) SplitIntoFiftyBranches(ctx context.Context, arcBroadcastFunc
 		scriptHex := hex.EncodeToString(*out.LockingScript)
 
 		branchUTXO := &models.UTXO{
-			Outpoint:     fmt.Sprintf("%s:%d", txid, i),
-			TxID:         txid,
-			Vout:         uint32(i),
-			Satoshis:     branchAmount,
-			ScriptPubKey: scriptHex,
-			Status:       models.UTXOStatusAvailable,
-			Type:         models.UTXOTypeFunding, // Still funding type, will split further
+			Outpoint:        fmt.Sprintf("%s:%d", txid, i),
+			TxID:            txid,
+			Vout:            uint32(i),
+			Satoshis:        branchAmount,
+			ScriptPubKey:    scriptHex,
+			Status:          models.UTXOStatusAvailable,
+			Type:            models.UTXOTypeFunding, // Still funding type, will split further
+			DerivationIndex: branchIndices[i],
+			DerivationPath:  s.fundingKey.ChildPath(branchIndices[i]),
 		}
 
 		if err := s.db.InsertUTXO(ctx, branchUTXO); err != nil {
 			return nil, fmt.Errorf("failed to insert branch UTXO %d: %w", i, err)
 		}
+		metrics.SplitOutputs.WithLabelValues(string(branchUTXO.Type)).Inc()
 	}
 
-	fmt.Printf("✓ Phase 1 complete: Created %d branch UTXOs\n", branchCount)
+	slog.InfoContext(ctx, "Phase 1 complete", "branch_utxos", branchCount)
 
 	return &SplitResult{
 		BranchTxIDs: []string{txid},
@@ -550,14 +585,13 @@ func (s *Splitter) CheckAndRefill(ctx context.Context, minCount int) error {
 	availablePublishing := stats["publishing_available"]
 
 	if availablePublishing < int64(minCount) {
-		fmt.Printf("⚠️  Low on publishing UTXOs (%d available, need %d)\n", availablePublishing, minCount)
-		fmt.Println("   Triggering refill...")
+		slog.WarnContext(ctx, "Low on publishing UTXOs, triggering refill", "available", availablePublishing, "min", minCount)
 
 		needed := minCount - int(availablePublishing)
 		_, err := s.CreatePublishingUTXOs(ctx, needed)
 		return err
 	}
 
-	fmt.Printf("✓ Publishing UTXO pool healthy: %d available\n", availablePublishing)
+	slog.InfoContext(ctx, "Publishing UTXO pool healthy", "available", availablePublishing)
 	return nil
 }
@@ -0,0 +1,162 @@
+package bsv
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/arc"
+	"github.com/akua/bsv-broadcaster/internal/arc/arctest"
+	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/models"
+)
+
+const (
+	testFundingSats = 1_000_000
+	testFeeRate     = 0.05
+)
+
+// newTestSplitter is a splitter over a memory store holding one funding UTXO
+func newTestSplitter(t *testing.T) (*Splitter, *database.MemoryStore, *models.UTXO) {
+	t.Helper()
+
+	db := database.NewMemoryStore()
+	db.SetLockOwner(database.NewLockOwner(time.Minute))
+	funding, publishing := newTestHDKeys(t)
+
+	utxo := paidTo(funding, 0)
+	utxo.TxID = fmt.Sprintf("%064x", 1)
+	utxo.Outpoint = utxo.TxID + ":0"
+	utxo.Satoshis = testFundingSats
+	utxo.Status = models.UTXOStatusAvailable
+	utxo.Type = models.UTXOTypeFunding
+	if err := db.InsertUTXO(context.Background(), utxo); err != nil {
+		t.Fatal(err)
+	}
+	return NewSplitter(db, funding, publishing, testFeeRate), db, utxo
+}
+
+// broadcastVia sends split transactions to ARC the way the admin split
+// handler does, failing anything ARC doesn't accept
+func broadcastVia(client *arc.Client) func(context.Context, string) (string, error) {
+	return func(ctx context.Context, rawHex string) (string, error) {
+		resp, err := client.BroadcastSingle(ctx, rawHex)
+		if err != nil {
+			return "", err
+		}
+		if err := arc.CheckAccepted(resp); err != nil {
+			return "", err
+		}
+		return resp.TxID, nil
+	}
+}
+
+func utxosOf(t *testing.T, db *database.MemoryStore, utxoType models.UTXOType, status models.UTXOStatus) []*models.UTXO {
+	t.Helper()
+
+	utxos, err := db.FindUTXOsByType(context.Background(), utxoType, status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return utxos
+}
+
+func TestSplitterBuildsTreeThroughARC(t *testing.T) {
+	splitter, db, funding := newTestSplitter(t)
+	fake := arctest.NewServer()
+	defer fake.Close()
+	fake.SetLatency(5 * time.Millisecond)
+	broadcast := broadcastVia(fake.Client())
+	ctx := context.Background()
+
+	branches, err := splitter.SplitIntoFiftyBranches(ctx, broadcast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spent := utxosOf(t, db, models.UTXOTypeFunding, models.UTXOStatusSpent); len(spent) != 1 || spent[0].Outpoint != funding.Outpoint {
+		t.Fatalf("spent funding = %v, want the original UTXO", spent)
+	}
+	if got := utxosOf(t, db, models.UTXOTypeFunding, models.UTXOStatusAvailable); len(got) != 50 {
+		t.Fatalf("%d branches available, want 50", len(got))
+	}
+
+	// Leaves spend the branches once their parent is in a block
+	if mined := fake.Mine(branches.BranchTxIDs...); mined != 1 {
+		t.Fatalf("mined %d branch transactions, want 1", mined)
+	}
+	leaves, err := splitter.SplitBranchesIntoLeaves(ctx, broadcast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leaves.LeafTxIDs) != 50 {
+		t.Fatalf("%d leaf transactions, want 50", len(leaves.LeafTxIDs))
+	}
+	for _, txid := range leaves.LeafTxIDs {
+		resp, ok := fake.Status(txid)
+		if !ok || !resp.Outcome().Succeeded() {
+			t.Fatalf("ARC status of leaf tx %s = %+v, want accepted", txid, resp)
+		}
+	}
+
+	publishing := utxosOf(t, db, models.UTXOTypePublishing, models.UTXOStatusAvailable)
+	if len(publishing) != leaves.TotalUTXOs || len(publishing) == 0 {
+		t.Fatalf("%d publishing UTXOs stored, want %d", len(publishing), leaves.TotalUTXOs)
+	}
+	for _, utxo := range publishing {
+		if _, err := ResolveUnlocker(utxo, splitter.fundingKey, splitter.publishingKey); err != nil {
+			t.Fatalf("publishing UTXO %s can't be signed for: %v", utxo.Outpoint, err)
+		}
+	}
+	if got := utxosOf(t, db, models.UTXOTypeFunding, models.UTXOStatusAvailable); len(got) != 0 {
+		t.Fatalf("%d branches left available, want 0", len(got))
+	}
+}
+
+func TestSplitterReleasesFundingWhenARCRefuses(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(fake *arctest.Server)
+		ctx   func() (context.Context, context.CancelFunc)
+	}{
+		{
+			name:  "rejected",
+			setup: func(fake *arctest.Server) { fake.SetDefaultStatus(arc.TxStatusRejected) },
+		},
+		{
+			name:  "server error",
+			setup: func(fake *arctest.Server) { fake.FailNext(503, 1) },
+		},
+		{
+			name:  "timeout",
+			setup: func(fake *arctest.Server) { fake.SetLatency(time.Second) },
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 20*time.Millisecond)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			splitter, db, funding := newTestSplitter(t)
+			fake := arctest.NewServer()
+			defer fake.Close()
+			tt.setup(fake)
+
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tt.ctx != nil {
+				ctx, cancel = tt.ctx()
+			}
+			defer cancel()
+
+			if _, err := splitter.SplitIntoFiftyBranches(ctx, broadcastVia(fake.Client())); err == nil {
+				t.Fatal("split succeeded, want the ARC failure")
+			}
+
+			available := utxosOf(t, db, models.UTXOTypeFunding, models.UTXOStatusAvailable)
+			if len(available) != 1 || available[0].Outpoint != funding.Outpoint {
+				t.Fatalf("available funding = %v, want the original UTXO back", available)
+			}
+		})
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/logging"
 	"github.com/akua/bsv-broadcaster/internal/models"
 	"github.com/bsv-blockchain/go-sdk/script"
 	"github.com/bsv-blockchain/go-sdk/transaction/template/p2pkh"
@@ -30,39 +32,53 @@ type BitailsResponse struct {
 	Unspent    []BitailsUTXO `json:"unspent"`
 }
 
+// bitailsURL is the Bitails API the sync reads unspent outputs from
+const bitailsURL = "https://api.bitails.io"
+
+// bitailsIndexGrace is how old an available row must be before the sync
+// believes Bitails that it's gone; a fresh split output may not be indexed yet
+const bitailsIndexGrace = 10 * time.Minute
+
 // SyncService handles syncing local UTXO database with blockchain state
 type SyncService struct {
-	db             *database.Database
-	fundingAddr    string
-	publishingAddr string
+	db            database.Store
+	baseURL       string
+	indexGrace    time.Duration
+	fundingKey    *KeyPair
+	publishingKey *KeyPair
 }
 
 // NewSyncService creates a new blockchain sync service
-func NewSyncService(db *database.Database, fundingAddr, publishingAddr string) *SyncService {
+func NewSyncService(db database.Store, fundingKey, publishingKey *KeyPair) *SyncService {
 	return &SyncService{
-		db:             db,
-		fundingAddr:    fundingAddr,
-		publishingAddr: publishingAddr,
+		db:            db,
+		baseURL:       bitailsURL,
+		indexGrace:    bitailsIndexGrace,
+		fundingKey:    fundingKey,
+		publishingKey: publishingKey,
 	}
 }
 
 // SyncUTXOs queries the blockchain and syncs the local database
-// This is called on startup to ensure consistency
+// This is called on startup to ensure consistency. UTXOs are upserted by
+// outpoint, so locked and spent rows keep their status and lock - another
+// replica may hold them, and Bitails can lag a spend ARC already accepted.
+// Available rows Bitails no longer lists were spent elsewhere and are marked
+// spent.
 func (s *SyncService) SyncUTXOs(ctx context.Context) error {
-	fmt.Println("🔄 Starting UTXO sync with blockchain...")
+	slog.InfoContext(ctx, "Starting UTXO sync with blockchain")
 
-	// Clear all existing UTXOs to start fresh from blockchain state
-	fmt.Println("   Clearing existing UTXO database...")
-	if err := s.db.ClearAllUTXOs(ctx); err != nil {
-		fmt.Printf("⚠️  Warning: failed to clear UTXOs: %v\n", err)
+	available, err := s.availableByScript(ctx)
+	if err != nil {
+		return err
 	}
 
-	// Sync both addresses
-	if err := s.syncAddress(ctx, s.fundingAddr); err != nil {
+	// Sync both keys (every address in the window for HD keys)
+	if err := s.syncKey(ctx, s.fundingKey, available); err != nil {
 		return fmt.Errorf("failed to sync funding address: %w", err)
 	}
 
-	if err := s.syncAddress(ctx, s.publishingAddr); err != nil {
+	if err := s.syncKey(ctx, s.publishingKey, available); err != nil {
 		return fmt.Errorf("failed to sync publishing address: %w", err)
 	}
 
@@ -72,19 +88,56 @@ func (s *SyncService) SyncUTXOs(ctx context.Context) error {
 		return fmt.Errorf("failed to get stats: %w", err)
 	}
 
-	fmt.Printf("✓ Sync complete: %d funding, %d publishing, %d change UTXOs\n",
-		stats["funding_available"],
-		stats["publishing_available"],
-		stats["change_available"],
+	slog.InfoContext(ctx, "Sync complete",
+		"funding", stats["funding_available"],
+		"publishing", stats["publishing_available"],
+		"change", stats["change_available"],
 	)
 
 	return nil
 }
 
+// availableByScript returns the available rows old enough for Bitails to
+// have indexed, keyed by locking script
+func (s *SyncService) availableByScript(ctx context.Context) (map[string][]*models.UTXO, error) {
+	cutoff := time.Now().Add(-s.indexGrace)
+	available := make(map[string][]*models.UTXO)
+	for _, utxoType := range []models.UTXOType{models.UTXOTypeFunding, models.UTXOTypePublishing, models.UTXOTypeChange} {
+		utxos, err := s.db.FindUTXOsByType(ctx, utxoType, models.UTXOStatusAvailable)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load available %s UTXOs: %w", utxoType, err)
+		}
+		for _, utxo := range utxos {
+			if utxo.CreatedAt.Before(cutoff) {
+				available[utxo.ScriptPubKey] = append(available[utxo.ScriptPubKey], utxo)
+			}
+		}
+	}
+	return available, nil
+}
+
+// syncKey syncs every address in the address window of each of a
+// keypair's chains, so UTXOs left on a chain it was rotated away from are
+// still found
+func (s *SyncService) syncKey(ctx context.Context, kp *KeyPair, available map[string][]*models.UTXO) error {
+	for _, chain := range kp.Chains() {
+		for index := uint32(0); index < chain.AddressWindow(); index++ {
+			child, err := chain.Child(index)
+			if err != nil {
+				return err
+			}
+			if err := s.syncAddress(ctx, child.Address, index, child.DerivationPath, available); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // syncAddress fetches UTXOs for a specific address from Bitails
-func (s *SyncService) syncAddress(ctx context.Context, address string) error {
+func (s *SyncService) syncAddress(ctx context.Context, address string, derivationIndex uint32, derivationPath string, available map[string][]*models.UTXO) error {
 	// Build URL with high limit to get all UTXOs in one request
-	url := fmt.Sprintf("https://api.bitails.io/address/%s/unspent?limit=100000", address)
+	url := fmt.Sprintf("%s/address/%s/unspent?limit=100000", s.baseURL, address)
 
 	// Create HTTP request with context
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -110,39 +163,54 @@ func (s *SyncService) syncAddress(ctx context.Context, address string) error {
 	}
 
 	utxos := response.Unspent
-	fmt.Printf("   Found %d UTXOs for %s\n", len(utxos), address)
+	scriptPubKey := createP2PKHScriptFromAddress(address)
+	unspent := make(map[string]struct{}, len(utxos))
 
 	// Insert/update each UTXO in database
 	for _, u := range utxos {
 		outpoint := fmt.Sprintf("%s:%d", u.TxID, u.Vout)
+		unspent[outpoint] = struct{}{}
 
 		// Determine category
 		utxoType := CategorizeUTXO(u.Satoshis)
 
-		// Get script pubkey
-		scriptPubKey := createP2PKHScriptFromAddress(address)
-
 		// Create UTXO model
 		utxo := &models.UTXO{
-			Outpoint:     outpoint,
-			TxID:         u.TxID,
-			Vout:         uint32(u.Vout),
-			Satoshis:     u.Satoshis,
-			ScriptPubKey: scriptPubKey,
-			Status:       models.UTXOStatusAvailable,
-			Type:         utxoType,
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
+			Outpoint:        outpoint,
+			TxID:            u.TxID,
+			Vout:            uint32(u.Vout),
+			Satoshis:        u.Satoshis,
+			ScriptPubKey:    scriptPubKey,
+			Status:          models.UTXOStatusAvailable,
+			Type:            utxoType,
+			DerivationIndex: derivationIndex,
+			DerivationPath:  derivationPath,
+			CreatedAt:       time.Now(),
+			UpdatedAt:       time.Now(),
 		}
 
-		// Upsert to database (won't duplicate if already exists)
+		// Upsert to database (won't duplicate or change the status of an existing row)
 		if err := s.db.UpsertUTXO(ctx, utxo); err != nil {
-			fmt.Printf("⚠️  Warning: failed to upsert UTXO %s: %v\n", outpoint, err)
+			slog.WarnContext(ctx, "Failed to upsert UTXO", logging.KeyUTXO, outpoint, logging.Err(err))
+			continue
+		}
+	}
+
+	// Available rows Bitails no longer lists were spent outside this
+	// service; one locked meanwhile fails the lock check and is left alone
+	spent := 0
+	for _, utxo := range available[scriptPubKey] {
+		if _, ok := unspent[utxo.Outpoint]; ok {
+			continue
+		}
+		if err := s.db.MarkUTXOSpent(ctx, utxo.Outpoint, ""); err != nil {
+			slog.WarnContext(ctx, "Failed to mark UTXO spent", logging.KeyUTXO, utxo.Outpoint, logging.Err(err))
 			continue
 		}
+		spent++
 	}
 
-	fmt.Printf("✓ Synced %d UTXOs for %s\n", len(utxos), address)
+	slog.DebugContext(ctx, "Synced address", "address", address, "utxos", len(utxos), "spent", spent)
 	return nil
 }
 
@@ -0,0 +1,241 @@
+package bsv
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/models"
+	bip32 "github.com/bsv-blockchain/go-sdk/compat/bip32"
+)
+
+// fakeBitails serves unspent outputs per address the way Bitails does
+func fakeBitails(t *testing.T, unspent map[string][]BitailsUTXO) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		address := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/address/"), "/unspent")
+		json.NewEncoder(w).Encode(BitailsResponse{Address: address, Unspent: unspent[address]})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSyncLeavesLockedAndSpentUTXOsAlone(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryStore()
+	owner := database.NewLockOwner(time.Minute)
+	db.SetLockOwner(owner)
+	funding, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishing, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txid := func(i int) string { return fmt.Sprintf("%064x", i) }
+	for i := 1; i <= 2; i++ {
+		err := db.InsertUTXO(ctx, &models.UTXO{
+			Outpoint:     txid(i) + ":0",
+			TxID:         txid(i),
+			Satoshis:     100,
+			ScriptPubKey: createP2PKHScriptFromAddress(publishing.Address),
+			Status:       models.UTXOStatusAvailable,
+			Type:         models.UTXOTypePublishing,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A broadcast in flight, and a spend Bitails hasn't indexed yet
+	locked, spent := txid(1)+":0", txid(2)+":0"
+	if err := db.LockUTXO(ctx, locked); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.MarkUTXOSpent(ctx, spent, "spender"); err != nil {
+		t.Fatal(err)
+	}
+
+	bitails := fakeBitails(t, map[string][]BitailsUTXO{
+		publishing.Address: {
+			{TxID: txid(1), Vout: 0, Satoshis: 100},
+			{TxID: txid(2), Vout: 0, Satoshis: 100},
+			{TxID: txid(3), Vout: 0, Satoshis: 100},
+		},
+		funding.Address: {
+			{TxID: txid(4), Vout: 1, Satoshis: 50_000},
+		},
+	})
+	sync := NewSyncService(db, funding, publishing)
+	sync.baseURL = bitails.URL
+
+	if err := sync.SyncUTXOs(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	byOutpoint := make(map[string]*models.UTXO)
+	for _, utxoType := range []models.UTXOType{models.UTXOTypeFunding, models.UTXOTypePublishing} {
+		for _, status := range []models.UTXOStatus{models.UTXOStatusAvailable, models.UTXOStatusLocked, models.UTXOStatusSpent} {
+			utxos, err := db.FindUTXOsByType(ctx, utxoType, status)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, utxo := range utxos {
+				byOutpoint[utxo.Outpoint] = utxo
+			}
+		}
+	}
+
+	if got := byOutpoint[locked]; got == nil || got.Status != models.UTXOStatusLocked || got.LockOwner != owner.ID {
+		t.Fatalf("locked UTXO after sync = %+v, want still locked by %s", got, owner.ID)
+	}
+	if got := byOutpoint[spent]; got == nil || got.Status != models.UTXOStatusSpent {
+		t.Fatalf("spent UTXO after sync = %+v, want still spent", got)
+	}
+	if got := byOutpoint[txid(3)+":0"]; got == nil || got.Status != models.UTXOStatusAvailable || got.Type != models.UTXOTypePublishing {
+		t.Fatalf("new publishing UTXO = %+v, want available", got)
+	}
+	if got := byOutpoint[txid(4)+":1"]; got == nil || got.Status != models.UTXOStatusAvailable || got.Type != models.UTXOTypeFunding {
+		t.Fatalf("new funding UTXO = %+v, want available", got)
+	}
+
+	// The lock survives for its owner to settle
+	if err := db.UnlockUTXO(ctx, locked); err != nil {
+		t.Fatalf("unlock after sync: %v", err)
+	}
+}
+
+func TestSyncMarksVanishedUTXOsSpent(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryStore()
+	db.SetLockOwner(database.NewLockOwner(time.Minute))
+	var keys [3]*KeyPair
+	for i := range keys {
+		kp, err := GenerateKeyPair()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = kp
+	}
+	funding, publishing, foreign := keys[0], keys[1], keys[2]
+
+	txid := func(i int) string { return fmt.Sprintf("%064x", i) }
+	insert := func(i int, kp *KeyPair) string {
+		err := db.InsertUTXO(ctx, &models.UTXO{
+			Outpoint:     txid(i) + ":0",
+			TxID:         txid(i),
+			Satoshis:     100,
+			ScriptPubKey: createP2PKHScriptFromAddress(kp.Address),
+			Status:       models.UTXOStatusAvailable,
+			Type:         models.UTXOTypePublishing,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return txid(i) + ":0"
+	}
+	listed := insert(1, publishing)
+	vanished := insert(2, publishing)
+	locked := insert(3, publishing)
+	elsewhere := insert(4, foreign)
+	if err := db.LockUTXO(ctx, locked); err != nil {
+		t.Fatal(err)
+	}
+
+	bitails := fakeBitails(t, map[string][]BitailsUTXO{
+		publishing.Address: {{TxID: txid(1), Vout: 0, Satoshis: 100}},
+	})
+	sync := NewSyncService(db, funding, publishing)
+	sync.baseURL = bitails.URL
+	status := func(outpoint string) models.UTXOStatus {
+		for _, s := range []models.UTXOStatus{models.UTXOStatusAvailable, models.UTXOStatusLocked, models.UTXOStatusSpent} {
+			utxos, err := db.FindUTXOsByType(ctx, models.UTXOTypePublishing, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, utxo := range utxos {
+				if utxo.Outpoint == outpoint {
+					return s
+				}
+			}
+		}
+		t.Fatalf("UTXO %s not found", outpoint)
+		return ""
+	}
+
+	// Rows this fresh may just not be indexed yet
+	if err := sync.SyncUTXOs(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := status(vanished); got != models.UTXOStatusAvailable {
+		t.Fatalf("fresh unlisted UTXO = %s, want still available", got)
+	}
+
+	sync.indexGrace = 0
+	if err := sync.SyncUTXOs(ctx); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]models.UTXOStatus{
+		listed:    models.UTXOStatusAvailable,
+		vanished:  models.UTXOStatusSpent,
+		locked:    models.UTXOStatusLocked,
+		elsewhere: models.UTXOStatusAvailable,
+	}
+	for outpoint, wantStatus := range want {
+		if got := status(outpoint); got != wantStatus {
+			t.Errorf("UTXO %s = %s, want %s", outpoint, got, wantStatus)
+		}
+	}
+}
+
+func TestSyncFindsUTXOsOnPreviousChains(t *testing.T) {
+	ctx := context.Background()
+	db := database.NewMemoryStore()
+	db.SetLockOwner(database.NewLockOwner(time.Minute))
+
+	xprv, _, err := bip32.GenerateHDKeyPair(bip32.RecommendedSeedLen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	funding, err := NewHDKeyPair(xprv, "m/0'/2'", 2, "m/0'/0'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	publishing, err := NewHDKeyPair(xprv, "m/0'/1'", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := funding.Chains()[1].Child(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txid := fmt.Sprintf("%064x", 7)
+	bitails := fakeBitails(t, map[string][]BitailsUTXO{
+		old.Address: {{TxID: txid, Vout: 0, Satoshis: 50_000}},
+	})
+	sync := NewSyncService(db, funding, publishing)
+	sync.baseURL = bitails.URL
+	if err := sync.SyncUTXOs(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	utxos, err := db.FindUTXOsByType(ctx, models.UTXOTypeFunding, models.UTXOStatusAvailable)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(utxos) != 1 || utxos[0].DerivationPath != "m/0'/0'/1" {
+		t.Fatalf("synced funding UTXOs = %+v, want the one on m/0'/0'/1", utxos)
+	}
+	if _, err := ResolveUnlocker(utxos[0], funding, publishing); err != nil {
+		t.Fatalf("UTXO on the previous chain can't be spent: %v", err)
+	}
+}
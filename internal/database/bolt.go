@@ -0,0 +1,1398 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/models"
+	"go.etcd.io/bbolt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Bucket names
+// Records are stored BSON encoded, the same shape as the Mongo documents.
+// The index buckets hold keys only.
+var (
+	bucketUTXOs          = []byte("utxos")              // outpoint -> UTXO
+	bucketUTXOsAvailable = []byte("utxos_available")    // type | created | outpoint
+	bucketRequests       = []byte("broadcast_requests") // uuid -> request
+	bucketRequestsByTime = []byte("requests_by_time")   // created | uuid
+	bucketRequestsByUTXO = []byte("requests_by_utxo")   // outpoint -> newest uuid
+	bucketClients        = []byte("clients")            // id hex -> client
+	bucketClientsByKey   = []byte("clients_by_key")     // api key hash -> id hex
+	bucketDoubleSpends   = []byte("double_spend_incidents")
+	bucketLeaderLeases   = []byte("leader_leases")  // name -> lease
+	bucketAdminAccounts  = []byte("admin_accounts") // username -> account
+	bucketAuditLog       = []byte("audit_log")      // at | id -> entry
+	bucketAuditChain     = []byte("audit_chain")    // seq -> audit_log key
+	bucketUsedNonces     = []byte("used_nonces")    // client id | nonce -> expiry
+	bucketAuthSessions   = []byte("auth_sessions")  // session nonce -> session
+	bucketRateCounters   = []byte("rate_counters")  // key -> expiry | count
+)
+
+// BoltStore is a Store in a single bbolt file for embedded single-node installs
+// bbolt runs one write transaction at a time, so FindAndLockUTXO never hands
+// the same UTXO to two callers. Only one process may open the file.
+type BoltStore struct {
+	db    *bbolt.DB
+	owner LockOwner
+}
+
+// OpenBolt opens (creating if needed) the store at path
+func OpenBolt(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{
+			bucketUTXOs, bucketUTXOsAvailable, bucketRequests, bucketRequestsByTime,
+			bucketRequestsByUTXO, bucketClients, bucketClientsByKey, bucketDoubleSpends,
+			bucketLeaderLeases, bucketAdminAccounts, bucketAuditLog, bucketAuditChain,
+			bucketUsedNonces, bucketAuthSessions, bucketRateCounters,
+		} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create buckets: %w", err)
+	}
+
+	return &BoltStore{db: db, owner: NewLockOwner(DefaultLease)}, nil
+}
+
+// SetLockOwner sets the owner and lease stamped on locks this store takes
+func (b *BoltStore) SetLockOwner(owner LockOwner) {
+	b.owner = owner
+}
+
+// timeKey encodes t so byte order is time order
+// Millisecond precision matches what BSON keeps
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixMilli()))
+	return key
+}
+
+// availableKey orders available UTXOs by type, then oldest first
+func availableKey(utxo *models.UTXO) []byte {
+	key := append([]byte(utxo.Type), 0)
+	key = append(key, timeKey(utxo.CreatedAt)...)
+	return append(key, utxo.Outpoint...)
+}
+
+func getUTXO(tx *bbolt.Tx, outpoint string) (*models.UTXO, error) {
+	data := tx.Bucket(bucketUTXOs).Get([]byte(outpoint))
+	if data == nil {
+		return nil, nil
+	}
+	var utxo models.UTXO
+	if err := bson.Unmarshal(data, &utxo); err != nil {
+		return nil, fmt.Errorf("failed to decode UTXO %s: %w", outpoint, err)
+	}
+	return &utxo, nil
+}
+
+// putUTXO stores utxo and keeps the available index in step with old, the
+// previously stored version (nil for a new UTXO)
+func putUTXO(tx *bbolt.Tx, old, utxo *models.UTXO) error {
+	data, err := bson.Marshal(utxo)
+	if err != nil {
+		return err
+	}
+	// Decode again so the index key uses the stored timestamp precision
+	var stored models.UTXO
+	if err := bson.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+
+	available := tx.Bucket(bucketUTXOsAvailable)
+	if old != nil && old.Status == models.UTXOStatusAvailable {
+		if err := available.Delete(availableKey(old)); err != nil {
+			return err
+		}
+	}
+	if stored.Status == models.UTXOStatusAvailable {
+		if err := available.Put(availableKey(&stored), nil); err != nil {
+			return err
+		}
+	}
+	return tx.Bucket(bucketUTXOs).Put([]byte(utxo.Outpoint), data)
+}
+
+// updateUTXO applies fn to a stored UTXO, reporting false if it doesn't exist
+func updateUTXO(tx *bbolt.Tx, outpoint string, fn func(*models.UTXO)) (bool, error) {
+	old, err := getUTXO(tx, outpoint)
+	if err != nil || old == nil {
+		return false, err
+	}
+	utxo := copyUTXO(old)
+	fn(utxo)
+	return true, putUTXO(tx, old, utxo)
+}
+
+// lockOldest locks up to count of the oldest available UTXOs of a type
+func lockOldest(tx *bbolt.Tx, utxoType models.UTXOType, count int, owner LockOwner) ([]*models.UTXO, error) {
+	prefix := append([]byte(utxoType), 0)
+
+	// Collect first: deleting index keys under a live cursor skips entries
+	var outpoints []string
+	c := tx.Bucket(bucketUTXOsAvailable).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix) && len(outpoints) < count; k, _ = c.Next() {
+		outpoints = append(outpoints, string(k[len(prefix)+8:]))
+	}
+
+	utxos := make([]*models.UTXO, 0, len(outpoints))
+	for _, outpoint := range outpoints {
+		old, err := getUTXO(tx, outpoint)
+		if err != nil {
+			return nil, err
+		}
+		if old == nil {
+			continue
+		}
+		utxo := copyUTXO(old)
+		lock(utxo, owner)
+		if err := putUTXO(tx, old, utxo); err != nil {
+			return nil, err
+		}
+		utxos = append(utxos, utxo)
+	}
+	return utxos, nil
+}
+
+// FindAndLockUTXO atomically locks the oldest available UTXO of a type
+func (b *BoltStore) FindAndLockUTXO(ctx context.Context, utxoType models.UTXOType) (*models.UTXO, error) {
+	utxos, err := b.FindAndLockBatch(ctx, utxoType, 1)
+	if err != nil {
+		return nil, err
+	}
+	return utxos[0], nil
+}
+
+// FindAndLockBatch locks up to count UTXOs in one transaction, returning what it could get
+func (b *BoltStore) FindAndLockBatch(ctx context.Context, utxoType models.UTXOType, count int) ([]*models.UTXO, error) {
+	var utxos []*models.UTXO
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		var err error
+		utxos, err = lockOldest(tx, utxoType, count, b.owner)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock UTXO: %w", err)
+	}
+	if len(utxos) == 0 {
+		return nil, fmt.Errorf("no available %s UTXOs", utxoType)
+	}
+	return utxos, nil
+}
+
+// forEachUTXO calls fn for every stored UTXO
+func forEachUTXO(tx *bbolt.Tx, fn func(*models.UTXO) error) error {
+	return tx.Bucket(bucketUTXOs).ForEach(func(k, v []byte) error {
+		var utxo models.UTXO
+		if err := bson.Unmarshal(v, &utxo); err != nil {
+			return fmt.Errorf("failed to decode UTXO %s: %w", k, err)
+		}
+		return fn(&utxo)
+	})
+}
+
+// FindUTXOsByType returns all UTXOs of a type and status without locking
+func (b *BoltStore) FindUTXOsByType(ctx context.Context, utxoType models.UTXOType, status models.UTXOStatus) ([]*models.UTXO, error) {
+	var utxos []*models.UTXO
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		return forEachUTXO(tx, func(utxo *models.UTXO) error {
+			if utxo.Type == utxoType && utxo.Status == status {
+				utxos = append(utxos, utxo)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find UTXOs: %w", err)
+	}
+	sortUTXOs(utxos)
+	return utxos, nil
+}
+
+// GetAvailableUTXOs returns up to limit available UTXOs of a type (0 = no limit)
+func (b *BoltStore) GetAvailableUTXOs(ctx context.Context, utxoType models.UTXOType, limit int) ([]*models.UTXO, error) {
+	var utxos []*models.UTXO
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		prefix := append([]byte(utxoType), 0)
+		c := tx.Bucket(bucketUTXOsAvailable).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			if limit > 0 && len(utxos) >= limit {
+				break
+			}
+			utxo, err := getUTXO(tx, string(k[len(prefix)+8:]))
+			if err != nil {
+				return err
+			}
+			if utxo != nil {
+				utxos = append(utxos, utxo)
+			}
+		}
+		return nil
+	})
+	return utxos, err
+}
+
+// MarkUTXOSpent marks a UTXO as spent
+func (b *BoltStore) MarkUTXOSpent(ctx context.Context, outpoint string, txid string) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		stored, err := getUTXO(tx, outpoint)
+		if err != nil || stored == nil {
+			return err
+		}
+		if stored.Status == models.UTXOStatusLocked && stored.LockOwner != b.owner.ID {
+			return fmt.Errorf("failed to mark %s spent: %w", outpoint, ErrLockNotHeld)
+		}
+		_, err = updateUTXO(tx, outpoint, markSpent)
+		return err
+	})
+}
+
+// LockUTXO locks a specific UTXO by outpoint
+func (b *BoltStore) LockUTXO(ctx context.Context, outpoint string) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		found, err := updateUTXO(tx, outpoint, func(utxo *models.UTXO) { lock(utxo, b.owner) })
+		if err != nil {
+			return fmt.Errorf("failed to lock UTXO: %w", err)
+		}
+		if !found {
+			return fmt.Errorf("UTXO not found: %s", outpoint)
+		}
+		return nil
+	})
+}
+
+// UnlockUTXO releases a locked UTXO back to available status
+func (b *BoltStore) UnlockUTXO(ctx context.Context, outpoint string) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		stored, err := getUTXO(tx, outpoint)
+		if err != nil || stored == nil {
+			return err
+		}
+		if stored.Status != models.UTXOStatusLocked || stored.LockOwner != b.owner.ID {
+			return fmt.Errorf("failed to unlock %s: %w", outpoint, ErrLockNotHeld)
+		}
+		_, err = updateUTXO(tx, outpoint, unlock)
+		return err
+	})
+}
+
+// unlock marks a UTXO available again
+func unlock(utxo *models.UTXO) {
+	utxo.Status = models.UTXOStatusAvailable
+	utxo.LockedAt = nil
+	utxo.LockOwner = ""
+	utxo.LeaseExpiresAt = nil
+	utxo.UpdatedAt = time.Now()
+}
+
+// InsertUTXO adds a new UTXO, ignoring outpoints that already exist
+func (b *BoltStore) InsertUTXO(ctx context.Context, utxo *models.UTXO) error {
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		existing, err := getUTXO(tx, utxo.Outpoint)
+		if err != nil || existing != nil {
+			return err
+		}
+
+		utxo.CreatedAt = time.Now()
+		utxo.UpdatedAt = time.Now()
+		if utxo.ID.IsZero() {
+			utxo.ID = primitive.NewObjectID()
+		}
+		return putUTXO(tx, nil, utxo)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to insert UTXO: %w", err)
+	}
+	return nil
+}
+
+// UpsertUTXO inserts a UTXO as available or refreshes an existing one's
+// details without touching its status
+func (b *BoltStore) UpsertUTXO(ctx context.Context, utxo *models.UTXO) error {
+	now := time.Now()
+	utxo.UpdatedAt = now
+
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		found, err := updateUTXO(tx, utxo.Outpoint, func(existing *models.UTXO) {
+			existing.TxID = utxo.TxID
+			existing.Vout = utxo.Vout
+			existing.Satoshis = utxo.Satoshis
+			existing.ScriptPubKey = utxo.ScriptPubKey
+			existing.Type = utxo.Type
+			existing.DerivationIndex = utxo.DerivationIndex
+			existing.DerivationPath = utxo.DerivationPath
+			existing.UpdatedAt = now
+		})
+		if err != nil || found {
+			return err
+		}
+
+		stored := copyUTXO(utxo)
+		stored.ID = primitive.NewObjectID()
+		stored.Status = models.UTXOStatusAvailable
+		stored.LockedAt = nil
+		stored.SpentAt = nil
+		stored.CreatedAt = now
+		return putUTXO(tx, nil, stored)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upsert UTXO: %w", err)
+	}
+	return nil
+}
+
+// RenewLeases extends every lease held by this store's owner
+func (b *BoltStore) RenewLeases(ctx context.Context) (int64, error) {
+	now := time.Now()
+	expires := now.Add(b.owner.Lease)
+	var renewed int64
+
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		var held []string
+		err := forEachUTXO(tx, func(utxo *models.UTXO) error {
+			if utxo.Status == models.UTXOStatusLocked && utxo.LockOwner == b.owner.ID {
+				held = append(held, utxo.Outpoint)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, outpoint := range held {
+			_, err := updateUTXO(tx, outpoint, func(utxo *models.UTXO) {
+				utxo.LeaseExpiresAt = &expires
+				utxo.UpdatedAt = now
+			})
+			if err != nil {
+				return err
+			}
+		}
+		renewed = int64(len(held))
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to renew leases: %w", err)
+	}
+	return renewed, nil
+}
+
+// ExpireLease gives up a lock without unlocking it, leaving the UTXO for the janitor
+func (b *BoltStore) ExpireLease(ctx context.Context, outpoint string) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		_, err := updateUTXO(tx, outpoint, func(utxo *models.UTXO) {
+			if utxo.Status != models.UTXOStatusLocked || utxo.LockOwner != b.owner.ID {
+				return
+			}
+			now := time.Now()
+			utxo.LockOwner = ""
+			utxo.LeaseExpiresAt = &now
+			utxo.UpdatedAt = now
+		})
+		return err
+	})
+}
+
+// FindExpiredLeases returns up to limit locked UTXOs whose lease has run out
+func (b *BoltStore) FindExpiredLeases(ctx context.Context, limit int) ([]*models.UTXO, error) {
+	now := time.Now()
+	var utxos []*models.UTXO
+
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		return forEachUTXO(tx, func(utxo *models.UTXO) error {
+			if b.owner.leaseExpired(utxo, now) {
+				utxos = append(utxos, utxo)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find expired leases: %w", err)
+	}
+
+	b.owner.sortByLeaseExpiry(utxos)
+	if limit > 0 && len(utxos) > limit {
+		utxos = utxos[:limit]
+	}
+	return utxos, nil
+}
+
+// ReclaimUTXO unlocks a UTXO returned by FindExpiredLeases unless its lock
+// was renewed, released or retaken since
+func (b *BoltStore) ReclaimUTXO(ctx context.Context, utxo *models.UTXO) (bool, error) {
+	reclaimed := false
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		stored, err := getUTXO(tx, utxo.Outpoint)
+		if err != nil || stored == nil || !sameLock(stored, utxo) {
+			return err
+		}
+		reclaimed = true
+		_, err = updateUTXO(tx, utxo.Outpoint, unlock)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to reclaim UTXO: %w", err)
+	}
+	return reclaimed, nil
+}
+
+// MarkExpiredUTXOSpent marks a UTXO returned by FindExpiredLeases spent
+// unless its lock was renewed, released or retaken since
+func (b *BoltStore) MarkExpiredUTXOSpent(ctx context.Context, utxo *models.UTXO, txid string) (bool, error) {
+	spent := false
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		stored, err := getUTXO(tx, utxo.Outpoint)
+		if err != nil || stored == nil || !sameLock(stored, utxo) {
+			return err
+		}
+		spent = true
+		_, err = updateUTXO(tx, utxo.Outpoint, markSpent)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to mark UTXO spent: %w", err)
+	}
+	return spent, nil
+}
+
+// DeferExpiredLease pushes an expired lease out to until unless its lock
+// was renewed, released or retaken since
+func (b *BoltStore) DeferExpiredLease(ctx context.Context, utxo *models.UTXO, until time.Time) (bool, error) {
+	deferred := false
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		stored, err := getUTXO(tx, utxo.Outpoint)
+		if err != nil || stored == nil || !sameLock(stored, utxo) {
+			return err
+		}
+		deferred = true
+		_, err = updateUTXO(tx, utxo.Outpoint, func(utxo *models.UTXO) {
+			utxo.LeaseExpiresAt = &until
+			utxo.UpdatedAt = time.Now()
+		})
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to defer lease: %w", err)
+	}
+	return deferred, nil
+}
+
+// GetUTXOStats returns counts of UTXOs keyed "<type>_<status>"
+func (b *BoltStore) GetUTXOStats(ctx context.Context) (map[string]int64, error) {
+	stats := make(map[string]int64)
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		return forEachUTXO(tx, func(utxo *models.UTXO) error {
+			stats[fmt.Sprintf("%s_%s", utxo.Type, utxo.Status)]++
+			return nil
+		})
+	})
+	return stats, err
+}
+
+func getRequest(tx *bbolt.Tx, uuid string) (*models.BroadcastRequest, error) {
+	data := tx.Bucket(bucketRequests).Get([]byte(uuid))
+	if data == nil {
+		return nil, nil
+	}
+	var req models.BroadcastRequest
+	if err := bson.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("failed to decode request %s: %w", uuid, err)
+	}
+	return &req, nil
+}
+
+func putRequest(tx *bbolt.Tx, req *models.BroadcastRequest) error {
+	data, err := bson.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucketRequests).Put([]byte(req.UUID), data)
+}
+
+// updateRequest applies fn to a stored request; missing requests are ignored like Mongo's UpdateOne
+func (b *BoltStore) updateRequest(uuid string, fn func(*models.BroadcastRequest)) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		req, err := getRequest(tx, uuid)
+		if err != nil || req == nil {
+			return err
+		}
+		fn(req)
+		req.UpdatedAt = time.Now()
+		return putRequest(tx, req)
+	})
+}
+
+// InsertBroadcastRequest creates a new broadcast request record
+func (b *BoltStore) InsertBroadcastRequest(ctx context.Context, req *models.BroadcastRequest) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		if tx.Bucket(bucketRequests).Get([]byte(req.UUID)) != nil {
+			return fmt.Errorf("duplicate request UUID: %s", req.UUID)
+		}
+
+		req.CreatedAt = time.Now()
+		req.UpdatedAt = time.Now()
+		if req.ID.IsZero() {
+			req.ID = primitive.NewObjectID()
+		}
+
+		if err := putRequest(tx, req); err != nil {
+			return err
+		}
+		if req.UTXOUsed != "" {
+			if err := tx.Bucket(bucketRequestsByUTXO).Put([]byte(req.UTXOUsed), []byte(req.UUID)); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(bucketRequestsByTime).Put(append(timeKey(req.CreatedAt), req.UUID...), nil)
+	})
+}
+
+// UpdateRequestStatus updates the status of a broadcast request
+// Empty txid, arcStatus and errorMsg leave the stored values alone
+func (b *BoltStore) UpdateRequestStatus(ctx context.Context, uuid string, status models.RequestStatus, txid, arcStatus, errorMsg string) error {
+	return b.updateRequest(uuid, func(req *models.BroadcastRequest) {
+		req.Status = status
+		if txid != "" {
+			req.TxID = txid
+		}
+		if arcStatus != "" {
+			req.ARCStatus = arcStatus
+		}
+		if errorMsg != "" {
+			req.Error = errorMsg
+		}
+	})
+}
+
+// AppendRequestAttempt records a failed ARC round trip on a broadcast request
+func (b *BoltStore) AppendRequestAttempt(ctx context.Context, uuid string, attempt models.BroadcastAttempt) error {
+	return b.updateRequest(uuid, func(req *models.BroadcastRequest) {
+		req.Attempts = append(req.Attempts, attempt)
+	})
+}
+
+// GetRequestByUUID retrieves a broadcast request by UUID
+func (b *BoltStore) GetRequestByUUID(ctx context.Context, uuid string) (*models.BroadcastRequest, error) {
+	var req *models.BroadcastRequest
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		req, err = getRequest(tx, uuid)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if req == nil {
+		return nil, fmt.Errorf("request not found")
+	}
+	return req, nil
+}
+
+// GetRequestByUTXO returns the newest request that spent the outpoint, or nil
+func (b *BoltStore) GetRequestByUTXO(ctx context.Context, outpoint string) (*models.BroadcastRequest, error) {
+	var req *models.BroadcastRequest
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		uuid := tx.Bucket(bucketRequestsByUTXO).Get([]byte(outpoint))
+		if uuid == nil {
+			return nil
+		}
+		var err error
+		req, err = getRequest(tx, string(uuid))
+		return err
+	})
+	return req, err
+}
+
+// GetBroadcastRequestsSince returns broadcast requests created at or after since
+func (b *BoltStore) GetBroadcastRequestsSince(ctx context.Context, since time.Time) ([]models.BroadcastRequest, error) {
+	var requests []models.BroadcastRequest
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(bucketRequestsByTime).Cursor()
+		for k, _ := c.Seek(timeKey(since)); k != nil; k, _ = c.Next() {
+			req, err := getRequest(tx, string(k[8:]))
+			if err != nil {
+				return err
+			}
+			if req != nil {
+				requests = append(requests, *req)
+			}
+		}
+		return nil
+	})
+	return requests, err
+}
+
+// incidentKey orders incidents oldest first
+func incidentKey(incident *models.DoubleSpendIncident) []byte {
+	return append(timeKey(incident.DetectedAt), incident.ID[:]...)
+}
+
+// RecordDoubleSpend stores a double-spend incident and the competing txids on its request
+func (b *BoltStore) RecordDoubleSpend(ctx context.Context, incident *models.DoubleSpendIncident) error {
+	incident.ID = primitive.NewObjectID()
+	incident.DetectedAt = time.Now()
+	if incident.CompetingTxs == nil {
+		incident.CompetingTxs = []string{}
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		data, err := bson.Marshal(incident)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(bucketDoubleSpends).Put(incidentKey(incident), data); err != nil {
+			return fmt.Errorf("failed to insert double spend incident: %w", err)
+		}
+
+		req, err := getRequest(tx, incident.RequestUUID)
+		if err != nil || req == nil {
+			return err
+		}
+		req.CompetingTxs = incident.CompetingTxs
+		req.UpdatedAt = time.Now()
+		return putRequest(tx, req)
+	})
+}
+
+// MarkDoubleSpendRepublished records the outcome of reissuing a double-spent payload
+func (b *BoltStore) MarkDoubleSpendRepublished(ctx context.Context, incident *models.DoubleSpendIncident, newUUID, errorMsg string) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketDoubleSpends)
+		key := incidentKey(incident)
+		if data := bucket.Get(key); data != nil {
+			var stored models.DoubleSpendIncident
+			if err := bson.Unmarshal(data, &stored); err != nil {
+				return err
+			}
+			stored.Republished = newUUID != ""
+			stored.RepublishUUID = newUUID
+			stored.RepublishError = errorMsg
+			data, err := bson.Marshal(&stored)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(key, data); err != nil {
+				return err
+			}
+		}
+
+		if newUUID == "" {
+			return nil
+		}
+		req, err := getRequest(tx, incident.RequestUUID)
+		if err != nil || req == nil {
+			return err
+		}
+		req.RepublishedAs = newUUID
+		req.UpdatedAt = time.Now()
+		return putRequest(tx, req)
+	})
+}
+
+// ListDoubleSpendIncidents returns the newest incidents, optionally for one client
+func (b *BoltStore) ListDoubleSpendIncidents(ctx context.Context, clientID string, limit int) ([]models.DoubleSpendIncident, error) {
+	incidents := []models.DoubleSpendIncident{}
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(bucketDoubleSpends).Cursor()
+		for k, v := c.Last(); k != nil && (limit <= 0 || len(incidents) < limit); k, v = c.Prev() {
+			var incident models.DoubleSpendIncident
+			if err := bson.Unmarshal(v, &incident); err != nil {
+				return err
+			}
+			if clientID != "" && incident.ClientID != clientID {
+				continue
+			}
+			incidents = append(incidents, incident)
+		}
+		return nil
+	})
+	return incidents, err
+}
+
+func getClient(tx *bbolt.Tx, id string) (*models.Client, error) {
+	data := tx.Bucket(bucketClients).Get([]byte(id))
+	if data == nil {
+		return nil, nil
+	}
+	var client models.Client
+	if err := bson.Unmarshal(data, &client); err != nil {
+		return nil, fmt.Errorf("failed to decode client %s: %w", id, err)
+	}
+	return &client, nil
+}
+
+func getClientByKey(tx *bbolt.Tx, apiKeyHash string) (*models.Client, error) {
+	id := tx.Bucket(bucketClientsByKey).Get([]byte(apiKeyHash))
+	if id == nil {
+		return nil, nil
+	}
+	return getClient(tx, string(id))
+}
+
+func putClient(tx *bbolt.Tx, client *models.Client) error {
+	data, err := bson.Marshal(client)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucketClients).Put([]byte(client.ID.Hex()), data)
+}
+
+// CreateClient creates a new API client
+func (b *BoltStore) CreateClient(ctx context.Context, client *models.Client) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		byKey := tx.Bucket(bucketClientsByKey)
+		if byKey.Get([]byte(client.APIKeyHash)) != nil {
+			return fmt.Errorf("duplicate API key")
+		}
+		if client.ID.IsZero() {
+			client.ID = primitive.NewObjectID()
+		}
+
+		if err := putClient(tx, client); err != nil {
+			return err
+		}
+		return byKey.Put([]byte(client.APIKeyHash), []byte(client.ID.Hex()))
+	})
+}
+
+// viewClient runs a read-only client lookup, turning a miss into notFound
+func (b *BoltStore) viewClient(lookup func(*bbolt.Tx) (*models.Client, error), notFound string) (*models.Client, error) {
+	var client *models.Client
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		client, err = lookup(tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		return nil, fmt.Errorf("%s", notFound)
+	}
+	return client, nil
+}
+
+// GetClientByID retrieves a client by ID
+func (b *BoltStore) GetClientByID(ctx context.Context, clientID primitive.ObjectID) (*models.Client, error) {
+	return b.viewClient(func(tx *bbolt.Tx) (*models.Client, error) {
+		return getClient(tx, clientID.Hex())
+	}, "client not found")
+}
+
+// GetClientByAPIKey retrieves a client by their API key hash
+func (b *BoltStore) GetClientByAPIKey(ctx context.Context, apiKeyHash string) (*models.Client, error) {
+	return b.viewClient(func(tx *bbolt.Tx) (*models.Client, error) {
+		return getClientByKey(tx, apiKeyHash)
+	}, "client not found")
+}
+
+// GetClientByAPIKeyHash retrieves a client by their hashed API key
+func (b *BoltStore) GetClientByAPIKeyHash(ctx context.Context, apiKeyHash string) (*models.Client, error) {
+	return b.viewClient(func(tx *bbolt.Tx) (*models.Client, error) {
+		return getClientByKey(tx, apiKeyHash)
+	}, "invalid API key")
+}
+
+// GetClientByPublicKey retrieves a client by their current public key
+func (b *BoltStore) GetClientByPublicKey(ctx context.Context, publicKey string) (*models.Client, error) {
+	return b.viewClient(func(tx *bbolt.Tx) (*models.Client, error) {
+		var found *models.Client
+		err := tx.Bucket(bucketClients).ForEach(func(k, v []byte) error {
+			if found != nil {
+				return nil
+			}
+			var client models.Client
+			if err := bson.Unmarshal(v, &client); err != nil {
+				return fmt.Errorf("failed to decode client %s: %w", k, err)
+			}
+			if client.PublicKey == publicKey {
+				found = &client
+			}
+			return nil
+		})
+		return found, err
+	}, "client not found")
+}
+
+// ListClients returns all registered clients, oldest first
+func (b *BoltStore) ListClients(ctx context.Context) ([]*models.Client, error) {
+	var clients []*models.Client
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		// ObjectID hex keys sort by creation time
+		return tx.Bucket(bucketClients).ForEach(func(k, v []byte) error {
+			var client models.Client
+			if err := bson.Unmarshal(v, &client); err != nil {
+				return fmt.Errorf("failed to decode client %s: %w", k, err)
+			}
+			clients = append(clients, &client)
+			return nil
+		})
+	})
+	return clients, err
+}
+
+// ReserveClientTx counts a transaction against the client's daily quota,
+// starting a new count on a new day
+func (b *BoltStore) ReserveClientTx(ctx context.Context, clientID interface{}, today string, maxDailyTx int) (int, error) {
+	id, ok := clientID.(primitive.ObjectID)
+	if !ok {
+		return 0, fmt.Errorf("client ID must be a primitive.ObjectID, got %T", clientID)
+	}
+
+	count := 0
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		client, err := getClient(tx, id.Hex())
+		if err != nil {
+			return err
+		}
+		if client == nil {
+			return fmt.Errorf("client not found")
+		}
+
+		if client.LastResetDate == today {
+			count = client.TxCount
+		}
+		if count >= maxDailyTx {
+			return ErrQuotaExceeded
+		}
+		count++
+		client.TxCount = count
+		client.LastResetDate = today
+		client.UpdatedAt = time.Now()
+		return putClient(tx, client)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// RefundClientTx gives back a transaction counted on day
+func (b *BoltStore) RefundClientTx(ctx context.Context, clientID interface{}, day string) error {
+	return b.updateClient(clientID, func(client *models.Client) {
+		if client.LastResetDate == day && client.TxCount > 0 {
+			client.TxCount--
+		}
+	})
+}
+
+// UpdateClientStatus activates or deactivates a client
+func (b *BoltStore) UpdateClientStatus(ctx context.Context, clientID interface{}, isActive bool) error {
+	return b.updateClient(clientID, func(client *models.Client) {
+		client.IsActive = isActive
+	})
+}
+
+// BindPublicKeyToClient sets a client's public key if none is registered yet
+func (b *BoltStore) BindPublicKeyToClient(ctx context.Context, apiKeyHash, publicKey string) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		client, err := getClientByKey(tx, apiKeyHash)
+		if err != nil {
+			return err
+		}
+		if client == nil || client.PublicKey != "" {
+			return fmt.Errorf("client not found or public key already registered")
+		}
+
+		client.PublicKey = publicKey
+		client.RequireSignature = true // Auto-enable signature requirement
+		client.UpdatedAt = time.Now()
+		return putClient(tx, client)
+	})
+}
+
+// RotateClientPublicKey replaces a client's public key, keeping the old one for the grace period
+func (b *BoltStore) RotateClientPublicKey(ctx context.Context, apiKeyHash, newPublicKey string) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		client, err := getClientByKey(tx, apiKeyHash)
+		if err != nil {
+			return err
+		}
+		if client == nil {
+			return fmt.Errorf("client not found: %s", apiKeyHash)
+		}
+
+		now := time.Now()
+		client.OldPublicKey = client.PublicKey
+		client.PublicKey = newPublicKey
+		client.KeyRotatedAt = &now
+		client.UpdatedAt = now
+		return putClient(tx, client)
+	})
+}
+
+// UpdateClientSecurity updates a client's security tier and settings
+func (b *BoltStore) UpdateClientSecurity(ctx context.Context, clientID interface{}, tier string, requireSignature bool, allowedIPs []string, gracePeriodHours int) error {
+	return b.updateClient(clientID, func(client *models.Client) {
+		client.Tier = tier
+		client.RequireSignature = requireSignature
+		client.AllowedIPs = allowedIPs
+		client.GracePeriodHours = gracePeriodHours
+	})
+}
+
+// UpdateClientLimits updates a client's daily quota, in-flight cap and rate limits
+func (b *BoltStore) UpdateClientLimits(ctx context.Context, clientID interface{}, maxDailyTx, maxInFlight, maxPerSecond, maxPerMinute int) error {
+	return b.updateClient(clientID, func(client *models.Client) {
+		client.MaxDailyTx = maxDailyTx
+		client.MaxInFlight = maxInFlight
+		client.MaxPerSecond = maxPerSecond
+		client.MaxPerMinute = maxPerMinute
+	})
+}
+
+// UpdateClientAutoRepublish sets whether a client's double-spent payloads are reissued
+func (b *BoltStore) UpdateClientAutoRepublish(ctx context.Context, clientID interface{}, autoRepublish bool) error {
+	return b.updateClient(clientID, func(client *models.Client) {
+		client.AutoRepublish = autoRepublish
+	})
+}
+
+// updateClient applies fn to a stored client in one write transaction
+func (b *BoltStore) updateClient(clientID interface{}, fn func(*models.Client)) error {
+	id, ok := clientID.(primitive.ObjectID)
+	if !ok {
+		return fmt.Errorf("client ID must be a primitive.ObjectID, got %T", clientID)
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		client, err := getClient(tx, id.Hex())
+		if err != nil {
+			return err
+		}
+		if client == nil {
+			return fmt.Errorf("client not found")
+		}
+		fn(client)
+		client.UpdatedAt = time.Now()
+		return putClient(tx, client)
+	})
+}
+
+// AcquireLeadership records holder as leader
+// Only one process can open the file, so it always wins; a lease left by a
+// previous run is taken over rather than waited out
+func (b *BoltStore) AcquireLeadership(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	data, err := bson.Marshal(&models.LeaderLease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl), RenewedAt: now})
+	if err != nil {
+		return false, err
+	}
+
+	err = b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketLeaderLeases).Put([]byte(name), data)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire leadership: %w", err)
+	}
+	return true, nil
+}
+
+// ReleaseLeadership gives up the named lease if holder has it
+func (b *BoltStore) ReleaseLeadership(ctx context.Context, name, holder string) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		lease, err := getLeaderLease(tx, name)
+		if err != nil || lease == nil || lease.Holder != holder {
+			return err
+		}
+		return tx.Bucket(bucketLeaderLeases).Delete([]byte(name))
+	})
+}
+
+// GetLeaderLease returns the named lease, or nil if nobody has taken it
+func (b *BoltStore) GetLeaderLease(ctx context.Context, name string) (*models.LeaderLease, error) {
+	var lease *models.LeaderLease
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		var err error
+		lease, err = getLeaderLease(tx, name)
+		return err
+	})
+	return lease, err
+}
+
+func getLeaderLease(tx *bbolt.Tx, name string) (*models.LeaderLease, error) {
+	data := tx.Bucket(bucketLeaderLeases).Get([]byte(name))
+	if data == nil {
+		return nil, nil
+	}
+	var lease models.LeaderLease
+	if err := bson.Unmarshal(data, &lease); err != nil {
+		return nil, fmt.Errorf("failed to decode leader lease %s: %w", name, err)
+	}
+	return &lease, nil
+}
+
+// CreateAdminAccount creates a named admin account
+func (b *BoltStore) CreateAdminAccount(ctx context.Context, account *models.AdminAccount) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketAdminAccounts)
+		if bucket.Get([]byte(account.Username)) != nil {
+			return fmt.Errorf("admin account %q already exists", account.Username)
+		}
+		return putAdminAccount(tx, account)
+	})
+}
+
+// GetAdminAccount returns the named admin account, or nil
+func (b *BoltStore) GetAdminAccount(ctx context.Context, username string) (*models.AdminAccount, error) {
+	var account *models.AdminAccount
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(bucketAdminAccounts).Get([]byte(username))
+		if data == nil {
+			return nil
+		}
+		account = &models.AdminAccount{}
+		return bson.Unmarshal(data, account)
+	})
+	return account, err
+}
+
+// ListAdminAccounts returns every admin account by username
+func (b *BoltStore) ListAdminAccounts(ctx context.Context) ([]*models.AdminAccount, error) {
+	accounts := []*models.AdminAccount{}
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketAdminAccounts).ForEach(func(k, v []byte) error {
+			var account models.AdminAccount
+			if err := bson.Unmarshal(v, &account); err != nil {
+				return err
+			}
+			accounts = append(accounts, &account)
+			return nil
+		})
+	})
+	return accounts, err
+}
+
+// UpdateAdminAccount saves an existing account's role, status and password
+func (b *BoltStore) UpdateAdminAccount(ctx context.Context, account *models.AdminAccount) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(bucketAdminAccounts).Get([]byte(account.Username))
+		if data == nil {
+			return fmt.Errorf("admin account not found")
+		}
+		var stored models.AdminAccount
+		if err := bson.Unmarshal(data, &stored); err != nil {
+			return err
+		}
+		stored.PasswordHash = account.PasswordHash
+		stored.Role = account.Role
+		stored.IsActive = account.IsActive
+		stored.PasswordChangedAt = account.PasswordChangedAt
+		stored.UpdatedAt = account.UpdatedAt
+		return putAdminAccount(tx, &stored)
+	})
+}
+
+func putAdminAccount(tx *bbolt.Tx, account *models.AdminAccount) error {
+	data, err := bson.Marshal(account)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucketAdminAccounts).Put([]byte(account.Username), data)
+}
+
+// AppendAuditEntry adds an entry to the audit log
+func (b *BoltStore) AppendAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	entry.ID = primitive.NewObjectID()
+	if entry.At.IsZero() {
+		entry.At = time.Now()
+	}
+
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		data, err := bson.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		key := append(timeKey(entry.At), entry.ID[:]...)
+		if entry.Seq > 0 {
+			chain := tx.Bucket(bucketAuditChain)
+			if chain.Get(seqKey(entry.Seq)) != nil {
+				return ErrAuditSeqTaken
+			}
+			if err := chain.Put(seqKey(entry.Seq), key); err != nil {
+				return fmt.Errorf("failed to insert audit entry: %w", err)
+			}
+		}
+		if err := tx.Bucket(bucketAuditLog).Put(key, data); err != nil {
+			return fmt.Errorf("failed to insert audit entry: %w", err)
+		}
+		return nil
+	})
+}
+
+// seqKey orders audit chain entries by Seq
+func seqKey(seq int64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(seq))
+	return key
+}
+
+// ListAuditEntries returns the newest audit entries, optionally for one actor
+func (b *BoltStore) ListAuditEntries(ctx context.Context, actor string, limit int) ([]models.AuditEntry, error) {
+	entries := []models.AuditEntry{}
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		c := tx.Bucket(bucketAuditLog).Cursor()
+		for k, v := c.Last(); k != nil && (limit <= 0 || len(entries) < limit); k, v = c.Prev() {
+			var entry models.AuditEntry
+			if err := bson.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			if actor != "" && entry.Actor != actor {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// LastAuditEntry returns the chained audit entry with the highest Seq
+func (b *BoltStore) LastAuditEntry(ctx context.Context) (*models.AuditEntry, error) {
+	var entry *models.AuditEntry
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		_, key := tx.Bucket(bucketAuditChain).Cursor().Last()
+		if key == nil {
+			return nil
+		}
+		entry = &models.AuditEntry{}
+		return bson.Unmarshal(tx.Bucket(bucketAuditLog).Get(key), entry)
+	})
+	return entry, err
+}
+
+// ListAuditChain returns chained audit entries after afterSeq in Seq order
+func (b *BoltStore) ListAuditChain(ctx context.Context, afterSeq int64, limit int) ([]models.AuditEntry, error) {
+	entries := []models.AuditEntry{}
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		log := tx.Bucket(bucketAuditLog)
+		c := tx.Bucket(bucketAuditChain).Cursor()
+		for _, key := c.Seek(seqKey(afterSeq + 1)); key != nil && (limit <= 0 || len(entries) < limit); _, key = c.Next() {
+			var entry models.AuditEntry
+			if err := bson.Unmarshal(log.Get(key), &entry); err != nil {
+				return err
+			}
+			entries = append(entries, entry)
+		}
+		return nil
+	})
+	return entries, err
+}
+
+// UseNonce records a client's nonce, reporting false if it is still live
+func (b *BoltStore) UseNonce(ctx context.Context, clientID, nonce string, expiresAt time.Time) (bool, error) {
+	fresh := false
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketUsedNonces)
+		key := []byte(clientID + "|" + nonce)
+		if expiry := bucket.Get(key); expiry != nil && time.Now().UnixMilli() < int64(binary.BigEndian.Uint64(expiry)) {
+			return nil
+		}
+		fresh = true
+		return bucket.Put(key, timeKey(expiresAt))
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to record nonce: %w", err)
+	}
+	return fresh, nil
+}
+
+// PurgeExpiredNonces deletes expired nonces
+func (b *BoltStore) PurgeExpiredNonces(ctx context.Context) (int64, error) {
+	var purged int64
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		now := time.Now().UnixMilli()
+		bucket := tx.Bucket(bucketUsedNonces)
+
+		var expired [][]byte
+		bucket.ForEach(func(k, v []byte) error {
+			if int64(binary.BigEndian.Uint64(v)) <= now {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		purged = int64(len(expired))
+		return nil
+	})
+	return purged, err
+}
+
+// SaveAuthSession creates or replaces a mutual authentication session
+func (b *BoltStore) SaveAuthSession(ctx context.Context, session *models.AuthSession) error {
+	data, err := bson.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode auth session: %w", err)
+	}
+	err = b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucketAuthSessions).Put([]byte(session.SessionNonce), data)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save auth session: %w", err)
+	}
+	return nil
+}
+
+// GetAuthSession returns the unexpired session with this nonce, or nil
+func (b *BoltStore) GetAuthSession(ctx context.Context, sessionNonce string) (*models.AuthSession, error) {
+	var session *models.AuthSession
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		data := tx.Bucket(bucketAuthSessions).Get([]byte(sessionNonce))
+		if data == nil {
+			return nil
+		}
+		var found models.AuthSession
+		if err := bson.Unmarshal(data, &found); err != nil {
+			return fmt.Errorf("failed to decode auth session: %w", err)
+		}
+		if time.Now().Before(found.ExpiresAt) {
+			session = &found
+		}
+		return nil
+	})
+	return session, err
+}
+
+// PurgeExpiredAuthSessions deletes expired sessions
+func (b *BoltStore) PurgeExpiredAuthSessions(ctx context.Context) (int64, error) {
+	var purged int64
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		now := time.Now()
+		bucket := tx.Bucket(bucketAuthSessions)
+
+		var expired [][]byte
+		err := bucket.ForEach(func(k, v []byte) error {
+			var session models.AuthSession
+			if err := bson.Unmarshal(v, &session); err != nil || !now.Before(session.ExpiresAt) {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		purged = int64(len(expired))
+		return nil
+	})
+	return purged, err
+}
+
+// IncrementRateCounter adds one to the counter at key unless it has reached
+// limit; an expired counter starts over
+func (b *BoltStore) IncrementRateCounter(ctx context.Context, key string, limit int, expiresAt time.Time) (int, error) {
+	count := 0
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketRateCounters)
+		expiry := expiresAt.UnixMilli()
+		if v := bucket.Get([]byte(key)); v != nil && time.Now().UnixMilli() < int64(binary.BigEndian.Uint64(v)) {
+			expiry = int64(binary.BigEndian.Uint64(v))
+			count = int(binary.BigEndian.Uint64(v[8:]))
+		}
+		if count >= limit {
+			return ErrQuotaExceeded
+		}
+		count++
+		return bucket.Put([]byte(key), rateCounterValue(expiry, count))
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetRateCounter returns the count at key, 0 if there is none
+func (b *BoltStore) GetRateCounter(ctx context.Context, key string) (int, error) {
+	count := 0
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		if v := tx.Bucket(bucketRateCounters).Get([]byte(key)); v != nil && time.Now().UnixMilli() < int64(binary.BigEndian.Uint64(v)) {
+			count = int(binary.BigEndian.Uint64(v[8:]))
+		}
+		return nil
+	})
+	return count, err
+}
+
+// DecrementRateCounter takes one off the count at key, if above 0
+func (b *BoltStore) DecrementRateCounter(ctx context.Context, key string) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketRateCounters)
+		v := bucket.Get([]byte(key))
+		if v == nil {
+			return nil
+		}
+		count := int(binary.BigEndian.Uint64(v[8:]))
+		if count == 0 {
+			return nil
+		}
+		return bucket.Put([]byte(key), rateCounterValue(int64(binary.BigEndian.Uint64(v)), count-1))
+	})
+}
+
+// PurgeExpiredRateCounters deletes expired counters
+func (b *BoltStore) PurgeExpiredRateCounters(ctx context.Context) (int64, error) {
+	var purged int64
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		now := time.Now().UnixMilli()
+		bucket := tx.Bucket(bucketRateCounters)
+
+		var expired [][]byte
+		bucket.ForEach(func(k, v []byte) error {
+			if int64(binary.BigEndian.Uint64(v)) <= now {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		purged = int64(len(expired))
+		return nil
+	})
+	return purged, err
+}
+
+// rateCounterValue encodes a rate counter's expiry (Unix ms) and count
+func rateCounterValue(expiry int64, count int) []byte {
+	value := make([]byte, 16)
+	binary.BigEndian.PutUint64(value, uint64(expiry))
+	binary.BigEndian.PutUint64(value[8:], uint64(count))
+	return value
+}
+
+// Close flushes and closes the file
+func (b *BoltStore) Close(ctx context.Context) error {
+	return b.db.Close()
+}
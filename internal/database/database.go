@@ -17,11 +17,19 @@ const (
 	CollectionUTXOs             = "utxos"
 	CollectionBroadcastRequests = "broadcast_requests"
 	CollectionClients           = "clients"
+	CollectionDoubleSpends      = "double_spend_incidents"
+	CollectionLeaderLeases      = "leader_leases"
+	CollectionAdminAccounts     = "admin_accounts"
+	CollectionAuditLog          = "audit_log"
+	CollectionUsedNonces        = "used_nonces"
+	CollectionAuthSessions      = "auth_sessions"
+	CollectionRateCounters      = "rate_counters"
 )
 
 type Database struct {
 	client *mongo.Client
 	db     *mongo.Database
+	owner  LockOwner
 }
 
 // Connect establishes a connection to MongoDB
@@ -39,6 +47,7 @@ func Connect(ctx context.Context, uri string) (*Database, error) {
 	db := &Database{
 		client: client,
 		db:     client.Database(DatabaseName),
+		owner:  NewLockOwner(DefaultLease),
 	}
 
 	// Create indexes
@@ -72,6 +81,23 @@ func (d *Database) createIndexes(ctx context.Context) error {
 				{Key: "locked_at", Value: 1},
 			},
 		},
+		{
+			// Janitor scan for expired leases
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "lease_expires_at", Value: 1},
+			},
+		},
+		{
+			// Lease renewal
+			Keys:    bson.D{{Key: "lock_owner", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
+		{
+			// Read-back after a contested FindAndLockBatch
+			Keys:    bson.D{{Key: "lock_token", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
 	}
 
 	_, err := utxosCollection.Indexes().CreateMany(ctx, indexes)
@@ -81,9 +107,15 @@ func (d *Database) createIndexes(ctx context.Context) error {
 
 	// Index for broadcast requests
 	requestsCollection := d.db.Collection(CollectionBroadcastRequests)
-	_, err = requestsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys:    bson.D{{Key: "uuid", Value: 1}},
-		Options: options.Index().SetUnique(true),
+	_, err = requestsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "uuid", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// Janitor lookup of the request holding an expired lease
+			Keys: bson.D{{Key: "utxo_used", Value: 1}, {Key: "created_at", Value: -1}},
+		},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create request indexes: %w", err)
@@ -91,17 +123,94 @@ func (d *Database) createIndexes(ctx context.Context) error {
 
 	// Index for clients
 	clientsCollection := d.db.Collection(CollectionClients)
-	_, err = clientsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys:    bson.D{{Key: "api_key_hash", Value: 1}},
-		Options: options.Index().SetUnique(true),
+	_, err = clientsCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "api_key_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			// Mutual authentication finds clients by identity key
+			Keys: bson.D{{Key: "public_key", Value: 1}},
+		},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create client indexes: %w", err)
 	}
 
+	// Index for double-spend incidents (newest first, optionally per client)
+	doubleSpendsCollection := d.db.Collection(CollectionDoubleSpends)
+	_, err = doubleSpendsCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "client_id", Value: 1}, {Key: "detected_at", Value: -1}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create double spend indexes: %w", err)
+	}
+
+	// Index for the audit log (newest first, optionally per actor)
+	auditCollection := d.db.Collection(CollectionAuditLog)
+	_, err = auditCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "actor", Value: 1}, {Key: "at", Value: -1}},
+		},
+		{
+			// One entry per chain position; entries from before chaining have no seq
+			Keys:    bson.D{{Key: "seq", Value: 1}},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create audit log indexes: %w", err)
+	}
+
+	// Used nonces expire on their own
+	_, err = d.db.Collection(CollectionUsedNonces).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create used nonce indexes: %w", err)
+	}
+
+	// So do mutual authentication sessions
+	_, err = d.db.Collection(CollectionAuthSessions).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create auth session indexes: %w", err)
+	}
+
+	// And rate limit counters
+	_, err = d.db.Collection(CollectionRateCounters).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create rate counter indexes: %w", err)
+	}
+
 	return nil
 }
 
+// SetLockOwner sets the owner and lease stamped on locks this store takes
+func (d *Database) SetLockOwner(owner LockOwner) {
+	d.owner = owner
+}
+
+// lockFields are the fields set when this store takes a lock at now
+func (d *Database) lockFields(now time.Time) bson.M {
+	return bson.M{
+		"status":           models.UTXOStatusLocked,
+		"locked_at":        now,
+		"lock_owner":       d.owner.ID,
+		"lease_expires_at": now.Add(d.owner.Lease),
+		"updated_at":       now,
+	}
+}
+
+// unsetLease clears the lock's owner and lease
+var unsetLease = bson.M{"lock_owner": "", "lease_expires_at": ""}
+
 // FindAndLockUTXO atomically finds an available UTXO and locks it
 // This is the critical thread-safe operation for high concurrency
 func (d *Database) FindAndLockUTXO(ctx context.Context, utxoType models.UTXOType) (*models.UTXO, error) {
@@ -112,14 +221,7 @@ func (d *Database) FindAndLockUTXO(ctx context.Context, utxoType models.UTXOType
 		"type":   utxoType,
 	}
 
-	now := time.Now()
-	update := bson.M{
-		"$set": bson.M{
-			"status":     models.UTXOStatusLocked,
-			"locked_at":  now,
-			"updated_at": now,
-		},
-	}
+	update := bson.M{"$set": d.lockFields(time.Now())}
 
 	opts := options.FindOneAndUpdate().
 		SetReturnDocument(options.After).
@@ -160,22 +262,79 @@ func (d *Database) FindUTXOsByType(ctx context.Context, utxoType models.UTXOType
 	return utxos, nil
 }
 
-// FindAndLockBatch atomically locks multiple UTXOs for batch operations
+// FindAndLockBatch atomically locks up to count UTXOs, returning what it could get
+// It takes a fixed number of round trips whatever the count: find the oldest
+// candidates, lock the ones still available with one UpdateMany, and only if
+// another caller won some of them, read back which ones carry our lock token
 func (d *Database) FindAndLockBatch(ctx context.Context, utxoType models.UTXOType, count int) ([]*models.UTXO, error) {
-	utxos := make([]*models.UTXO, 0, count)
-
-	for i := 0; i < count; i++ {
-		utxo, err := d.FindAndLockUTXO(ctx, utxoType)
-		if err != nil {
-			// If we can't get all requested, return what we have
-			if len(utxos) > 0 {
-				return utxos, nil
-			}
-			return nil, err
+	collection := d.db.Collection(CollectionUTXOs)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}). // FIFO
+		SetLimit(int64(count))
+
+	cursor, err := collection.Find(ctx, bson.M{
+		"status": models.UTXOStatusAvailable,
+		"type":   utxoType,
+	}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find UTXOs: %w", err)
+	}
+
+	var candidates []*models.UTXO
+	if err := cursor.All(ctx, &candidates); err != nil {
+		return nil, fmt.Errorf("failed to decode UTXOs: %w", err)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no available %s UTXOs", utxoType)
+	}
+
+	ids := make([]primitive.ObjectID, len(candidates))
+	for i, utxo := range candidates {
+		ids[i] = utxo.ID
+	}
+
+	now := time.Now()
+	token := primitive.NewObjectID().Hex()
+	set := d.lockFields(now)
+	set["lock_token"] = token
+	result, err := collection.UpdateMany(ctx,
+		bson.M{
+			"_id":    bson.M{"$in": ids},
+			"status": models.UTXOStatusAvailable,
+		},
+		bson.M{"$set": set},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock UTXOs: %w", err)
+	}
+
+	if result.ModifiedCount == int64(len(candidates)) {
+		expires := now.Add(d.owner.Lease)
+		for _, utxo := range candidates {
+			utxo.Status = models.UTXOStatusLocked
+			utxo.LockedAt = &now
+			utxo.LockOwner = d.owner.ID
+			utxo.LeaseExpiresAt = &expires
+			utxo.UpdatedAt = now
 		}
-		utxos = append(utxos, utxo)
+		return candidates, nil
+	}
+
+	// Lost some candidates to a concurrent lock
+	cursor, err = collection.Find(ctx, bson.M{"lock_token": token, "status": models.UTXOStatusLocked},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read locked UTXOs: %w", err)
 	}
 
+	var utxos []*models.UTXO
+	if err := cursor.All(ctx, &utxos); err != nil {
+		return nil, fmt.Errorf("failed to decode UTXOs: %w", err)
+	}
+	if len(utxos) == 0 {
+		return nil, fmt.Errorf("no available %s UTXOs", utxoType)
+	}
 	return utxos, nil
 }
 
@@ -190,24 +349,33 @@ func (d *Database) MarkUTXOSpent(ctx context.Context, outpoint string, txid stri
 			"spent_at":   now,
 			"updated_at": now,
 		},
+		"$unset": unsetLease,
 	}
 
-	_, err := collection.UpdateOne(ctx, bson.M{"outpoint": outpoint}, update)
-	return err
+	filter := bson.M{
+		"outpoint": outpoint,
+		"$or": bson.A{
+			bson.M{"status": bson.M{"$ne": models.UTXOStatusLocked}},
+			bson.M{"lock_owner": d.owner.ID},
+		},
+	}
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if err := d.lockMismatch(ctx, outpoint); err != nil {
+			return fmt.Errorf("failed to mark %s spent: %w", outpoint, err)
+		}
+	}
+	return nil
 }
 
 // LockUTXO locks a specific UTXO by outpoint
 func (d *Database) LockUTXO(ctx context.Context, outpoint string) error {
 	collection := d.db.Collection(CollectionUTXOs)
 
-	now := time.Now()
-	update := bson.M{
-		"$set": bson.M{
-			"status":     models.UTXOStatusLocked,
-			"locked_at":  now,
-			"updated_at": now,
-		},
-	}
+	update := bson.M{"$set": d.lockFields(time.Now())}
 
 	result, err := collection.UpdateOne(ctx, bson.M{"outpoint": outpoint}, update)
 	if err != nil {
@@ -229,24 +397,33 @@ func (d *Database) UnlockUTXO(ctx context.Context, outpoint string) error {
 			"locked_at":  nil,
 			"updated_at": time.Now(),
 		},
+		"$unset": unsetLease,
 	}
 
-	_, err := collection.UpdateOne(ctx, bson.M{"outpoint": outpoint}, update)
-	return err
+	filter := bson.M{"outpoint": outpoint, "status": models.UTXOStatusLocked, "lock_owner": d.owner.ID}
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		if err := d.lockMismatch(ctx, outpoint); err != nil {
+			return fmt.Errorf("failed to unlock %s: %w", outpoint, err)
+		}
+	}
+	return nil
 }
 
-// ClearAllUTXOs removes all UTXOs from the database
-// Used during blockchain sync to start fresh
-func (d *Database) ClearAllUTXOs(ctx context.Context) error {
-	collection := d.db.Collection(CollectionUTXOs)
-	result, err := collection.DeleteMany(ctx, bson.M{})
+// lockMismatch explains an owner-filtered update that matched nothing:
+// ErrLockNotHeld if the outpoint exists, nil if it doesn't
+func (d *Database) lockMismatch(ctx context.Context, outpoint string) error {
+	count, err := d.db.Collection(CollectionUTXOs).CountDocuments(ctx, bson.M{"outpoint": outpoint})
 	if err != nil {
-		return fmt.Errorf("failed to clear UTXOs: %w", err)
+		return err
 	}
-	if result.DeletedCount > 0 {
-		fmt.Printf("   Cleared %d existing UTXOs\n", result.DeletedCount)
+	if count == 0 {
+		return nil
 	}
-	return nil
+	return ErrLockNotHeld
 }
 
 // InsertUTXO adds a new UTXO to the database
@@ -280,12 +457,14 @@ func (d *Database) UpsertUTXO(ctx context.Context, utxo *models.UTXO) error {
 
 	update := bson.M{
 		"$set": bson.M{
-			"txid":           utxo.TxID,
-			"vout":           utxo.Vout,
-			"satoshis":       utxo.Satoshis,
-			"script_pub_key": utxo.ScriptPubKey,
-			"type":           utxo.Type,
-			"updated_at":     now,
+			"txid":             utxo.TxID,
+			"vout":             utxo.Vout,
+			"satoshis":         utxo.Satoshis,
+			"script_pub_key":   utxo.ScriptPubKey,
+			"type":             utxo.Type,
+			"derivation_index": utxo.DerivationIndex,
+			"derivation_path":  utxo.DerivationPath,
+			"updated_at":       now,
 		},
 		"$setOnInsert": bson.M{
 			"outpoint":   utxo.Outpoint,
@@ -341,6 +520,19 @@ func (d *Database) UpdateRequestStatus(ctx context.Context, uuid string, status
 	return err
 }
 
+// AppendRequestAttempt records a failed ARC round trip on a broadcast request
+func (d *Database) AppendRequestAttempt(ctx context.Context, uuid string, attempt models.BroadcastAttempt) error {
+	collection := d.db.Collection(CollectionBroadcastRequests)
+
+	update := bson.M{
+		"$push": bson.M{"attempts": attempt},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	_, err := collection.UpdateOne(ctx, bson.M{"uuid": uuid}, update)
+	return err
+}
+
 // GetRequestByUUID retrieves a broadcast request by UUID
 func (d *Database) GetRequestByUUID(ctx context.Context, uuid string) (*models.BroadcastRequest, error) {
 	collection := d.db.Collection(CollectionBroadcastRequests)
@@ -357,31 +549,163 @@ func (d *Database) GetRequestByUUID(ctx context.Context, uuid string) (*models.B
 	return &req, nil
 }
 
-// RecoverStuckUTXOs finds UTXOs locked for more than the specified duration and unlocks them
-func (d *Database) RecoverStuckUTXOs(ctx context.Context, maxAge time.Duration) (int64, error) {
+// GetRequestByUTXO returns the newest request that spent the outpoint, or nil
+func (d *Database) GetRequestByUTXO(ctx context.Context, outpoint string) (*models.BroadcastRequest, error) {
+	collection := d.db.Collection(CollectionBroadcastRequests)
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	var req models.BroadcastRequest
+	err := collection.FindOne(ctx, bson.M{"utxo_used": outpoint}, opts).Decode(&req)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+// RenewLeases extends every lease held by this store's owner
+func (d *Database) RenewLeases(ctx context.Context) (int64, error) {
 	collection := d.db.Collection(CollectionUTXOs)
 
-	threshold := time.Now().Add(-maxAge)
+	now := time.Now()
+	result, err := collection.UpdateMany(ctx,
+		bson.M{"status": models.UTXOStatusLocked, "lock_owner": d.owner.ID},
+		bson.M{"$set": bson.M{"lease_expires_at": now.Add(d.owner.Lease), "updated_at": now}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to renew leases: %w", err)
+	}
+	return result.ModifiedCount, nil
+}
+
+// ExpireLease gives up a lock without unlocking it, leaving the UTXO for the janitor
+func (d *Database) ExpireLease(ctx context.Context, outpoint string) error {
+	collection := d.db.Collection(CollectionUTXOs)
+
+	now := time.Now()
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"outpoint": outpoint, "status": models.UTXOStatusLocked, "lock_owner": d.owner.ID},
+		bson.M{
+			"$set":   bson.M{"lease_expires_at": now, "updated_at": now},
+			"$unset": bson.M{"lock_owner": ""},
+		},
+	)
+	return err
+}
+
+// FindExpiredLeases returns up to limit locked UTXOs whose lease has run out
+// Locks from before leases existed count as expired once older than a lease
+func (d *Database) FindExpiredLeases(ctx context.Context, limit int) ([]*models.UTXO, error) {
+	collection := d.db.Collection(CollectionUTXOs)
 
+	now := time.Now()
 	filter := bson.M{
-		"status":    models.UTXOStatusLocked,
-		"locked_at": bson.M{"$lt": threshold},
+		"status": models.UTXOStatusLocked,
+		"$or": bson.A{
+			bson.M{"lease_expires_at": bson.M{"$lt": now}},
+			bson.M{
+				"lease_expires_at": bson.M{"$exists": false},
+				"locked_at":        bson.M{"$lt": now.Add(-d.owner.Lease)},
+			},
+		},
 	}
 
-	update := bson.M{
+	// A missing lease_expires_at sorts first, and those locks are the oldest
+	opts := options.Find().
+		SetSort(bson.D{{Key: "lease_expires_at", Value: 1}, {Key: "locked_at", Value: 1}}).
+		SetLimit(int64(limit))
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find expired leases: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var utxos []*models.UTXO
+	if err := cursor.All(ctx, &utxos); err != nil {
+		return nil, fmt.Errorf("failed to decode UTXOs: %w", err)
+	}
+	return utxos, nil
+}
+
+// ReclaimUTXO unlocks a UTXO returned by FindExpiredLeases unless its lock
+// was renewed, released or retaken since
+func (d *Database) ReclaimUTXO(ctx context.Context, utxo *models.UTXO) (bool, error) {
+	collection := d.db.Collection(CollectionUTXOs)
+
+	result, err := collection.UpdateOne(ctx, sameLockFilter(utxo), bson.M{
 		"$set": bson.M{
 			"status":     models.UTXOStatusAvailable,
 			"locked_at":  nil,
 			"updated_at": time.Now(),
 		},
+		"$unset": unsetLease,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to reclaim UTXO: %w", err)
 	}
+	return result.ModifiedCount == 1, nil
+}
+
+// MarkExpiredUTXOSpent marks a UTXO returned by FindExpiredLeases spent
+// unless its lock was renewed, released or retaken since
+func (d *Database) MarkExpiredUTXOSpent(ctx context.Context, utxo *models.UTXO, txid string) (bool, error) {
+	collection := d.db.Collection(CollectionUTXOs)
 
-	result, err := collection.UpdateMany(ctx, filter, update)
+	now := time.Now()
+	result, err := collection.UpdateOne(ctx, sameLockFilter(utxo), bson.M{
+		"$set": bson.M{
+			"status":     models.UTXOStatusSpent,
+			"spent_at":   now,
+			"updated_at": now,
+		},
+		"$unset": unsetLease,
+	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to recover stuck UTXOs: %w", err)
+		return false, fmt.Errorf("failed to mark UTXO spent: %w", err)
 	}
+	return result.ModifiedCount == 1, nil
+}
 
-	return result.ModifiedCount, nil
+// DeferExpiredLease pushes an expired lease out to until unless its lock
+// was renewed, released or retaken since
+func (d *Database) DeferExpiredLease(ctx context.Context, utxo *models.UTXO, until time.Time) (bool, error) {
+	collection := d.db.Collection(CollectionUTXOs)
+
+	result, err := collection.UpdateOne(ctx, sameLockFilter(utxo), bson.M{
+		"$set": bson.M{"lease_expires_at": until, "updated_at": time.Now()},
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to defer lease: %w", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// sameLockFilter matches utxo only while it holds the lock it was read with
+func sameLockFilter(utxo *models.UTXO) bson.M {
+	filter := bson.M{
+		"outpoint":         utxo.Outpoint,
+		"status":           models.UTXOStatusLocked,
+		"locked_at":        optionalTime(utxo.LockedAt),
+		"lease_expires_at": optionalTime(utxo.LeaseExpiresAt),
+	}
+	if utxo.LockOwner != "" {
+		filter["lock_owner"] = utxo.LockOwner
+	} else {
+		filter["lock_owner"] = bson.M{"$exists": false}
+	}
+	return filter
+}
+
+// optionalTime matches a time field exactly, or its absence when t is nil
+func optionalTime(t *time.Time) interface{} {
+	if t == nil {
+		return bson.M{"$exists": false}
+	}
+	return *t
 }
 
 // GetUTXOStats returns counts of UTXOs by type and status
@@ -500,33 +824,65 @@ func (d *Database) GetClientByAPIKeyHash(ctx context.Context, apiKeyHash string)
 	return &client, nil
 }
 
-// IncrementClientTxCount increments the transaction count for a client
-// Resets the counter if it's a new day
-func (d *Database) IncrementClientTxCount(ctx context.Context, clientID interface{}, today string) error {
+// GetClientByPublicKey retrieves a client by their current public key
+func (d *Database) GetClientByPublicKey(ctx context.Context, publicKey string) (*models.Client, error) {
 	collection := d.db.Collection(CollectionClients)
 
-	// First, check if we need to reset the counter
 	var client models.Client
-	err := collection.FindOne(ctx, bson.M{"_id": clientID}).Decode(&client)
+	err := collection.FindOne(ctx, bson.M{"public_key": publicKey}).Decode(&client)
 	if err != nil {
-		return err
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("client not found")
+		}
+		return nil, err
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"updated_at": time.Now(),
-		},
+	return &client, nil
+}
+
+// ReserveClientTx counts a transaction against the client's daily quota in
+// one conditional update, so concurrent requests can't overshoot it
+func (d *Database) ReserveClientTx(ctx context.Context, clientID interface{}, today string, maxDailyTx int) (int, error) {
+	collection := d.db.Collection(CollectionClients)
+	countToday := bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$last_reset_date", today}}, "$tx_count", 0}}
+
+	filter := bson.M{
+		"_id":   clientID,
+		"$expr": bson.M{"$lt": bson.A{countToday, maxDailyTx}},
 	}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"tx_count":        bson.M{"$add": bson.A{countToday, 1}},
+		"last_reset_date": today,
+		"updated_at":      time.Now(),
+	}}}}
 
-	// If it's a new day, reset the counter
-	if client.LastResetDate != today {
-		update["$set"].(bson.M)["tx_count"] = 1
-		update["$set"].(bson.M)["last_reset_date"] = today
-	} else {
-		update["$inc"] = bson.M{"tx_count": 1}
+	var client models.Client
+	err := collection.FindOneAndUpdate(ctx, filter, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&client)
+	if err == nil {
+		return client.TxCount, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return 0, err
+	}
+
+	// Either the quota is used up or there is no such client
+	found, err := collection.CountDocuments(ctx, bson.M{"_id": clientID})
+	if err != nil {
+		return 0, err
+	}
+	if found == 0 {
+		return 0, fmt.Errorf("client not found")
 	}
+	return 0, ErrQuotaExceeded
+}
 
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": clientID}, update)
+// RefundClientTx gives back a transaction counted on day
+func (d *Database) RefundClientTx(ctx context.Context, clientID interface{}, day string) error {
+	_, err := d.db.Collection(CollectionClients).UpdateOne(ctx,
+		bson.M{"_id": clientID, "last_reset_date": day, "tx_count": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"tx_count": -1}, "$set": bson.M{"updated_at": time.Now()}},
+	)
 	return err
 }
 
@@ -673,6 +1029,422 @@ func (d *Database) UpdateClientSecurity(ctx context.Context, clientID interface{
 	return err
 }
 
+// UpdateClientLimits updates a client's daily quota, in-flight cap and rate limits
+func (d *Database) UpdateClientLimits(ctx context.Context, clientID interface{}, maxDailyTx, maxInFlight, maxPerSecond, maxPerMinute int) error {
+	collection := d.db.Collection(CollectionClients)
+
+	update := bson.M{
+		"$set": bson.M{
+			"max_daily_tx":   maxDailyTx,
+			"max_in_flight":  maxInFlight,
+			"max_per_second": maxPerSecond,
+			"max_per_minute": maxPerMinute,
+			"updated_at":     time.Now(),
+		},
+	}
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": clientID}, update)
+	return err
+}
+
+// UpdateClientAutoRepublish sets whether a client's double-spent payloads are reissued
+func (d *Database) UpdateClientAutoRepublish(ctx context.Context, clientID interface{}, autoRepublish bool) error {
+	collection := d.db.Collection(CollectionClients)
+
+	update := bson.M{
+		"$set": bson.M{
+			"auto_republish": autoRepublish,
+			"updated_at":     time.Now(),
+		},
+	}
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": clientID}, update)
+	return err
+}
+
+// RecordDoubleSpend stores a double-spend incident and the competing txids on its request
+func (d *Database) RecordDoubleSpend(ctx context.Context, incident *models.DoubleSpendIncident) error {
+	incident.DetectedAt = time.Now()
+	if incident.CompetingTxs == nil {
+		incident.CompetingTxs = []string{}
+	}
+
+	result, err := d.db.Collection(CollectionDoubleSpends).InsertOne(ctx, incident)
+	if err != nil {
+		return fmt.Errorf("failed to insert double spend incident: %w", err)
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		incident.ID = id
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"competing_txs": incident.CompetingTxs,
+			"updated_at":    time.Now(),
+		},
+	}
+	_, err = d.db.Collection(CollectionBroadcastRequests).UpdateOne(ctx, bson.M{"uuid": incident.RequestUUID}, update)
+	return err
+}
+
+// MarkDoubleSpendRepublished records the outcome of reissuing a double-spent payload
+func (d *Database) MarkDoubleSpendRepublished(ctx context.Context, incident *models.DoubleSpendIncident, newUUID, errorMsg string) error {
+	_, err := d.db.Collection(CollectionDoubleSpends).UpdateOne(ctx, bson.M{"_id": incident.ID}, bson.M{
+		"$set": bson.M{
+			"republished":     newUUID != "",
+			"republish_uuid":  newUUID,
+			"republish_error": errorMsg,
+		},
+	})
+	if err != nil || newUUID == "" {
+		return err
+	}
+
+	_, err = d.db.Collection(CollectionBroadcastRequests).UpdateOne(ctx, bson.M{"uuid": incident.RequestUUID}, bson.M{
+		"$set": bson.M{
+			"republished_as": newUUID,
+			"updated_at":     time.Now(),
+		},
+	})
+	return err
+}
+
+// ListDoubleSpendIncidents returns the newest incidents, optionally for one client
+func (d *Database) ListDoubleSpendIncidents(ctx context.Context, clientID string, limit int) ([]models.DoubleSpendIncident, error) {
+	collection := d.db.Collection(CollectionDoubleSpends)
+
+	filter := bson.M{}
+	if clientID != "" {
+		filter["client_id"] = clientID
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "detected_at", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	incidents := []models.DoubleSpendIncident{}
+	if err := cursor.All(ctx, &incidents); err != nil {
+		return nil, err
+	}
+
+	return incidents, nil
+}
+
+// AcquireLeadership takes or renews the named lease for holder
+// The filter only matches a lease holder already has or one that has run out;
+// when another holder's live lease exists the upsert collides with it on _id
+func (d *Database) AcquireLeadership(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	collection := d.db.Collection(CollectionLeaderLeases)
+
+	now := time.Now()
+	filter := bson.M{
+		"_id": name,
+		"$or": bson.A{
+			bson.M{"holder": holder},
+			bson.M{"expires_at": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"holder":     holder,
+			"expires_at": now.Add(ttl),
+			"renewed_at": now,
+		},
+	}
+
+	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire leadership: %w", err)
+	}
+	return true, nil
+}
+
+// ReleaseLeadership gives up the named lease if holder has it
+func (d *Database) ReleaseLeadership(ctx context.Context, name, holder string) error {
+	_, err := d.db.Collection(CollectionLeaderLeases).DeleteOne(ctx, bson.M{"_id": name, "holder": holder})
+	return err
+}
+
+// GetLeaderLease returns the named lease, or nil if nobody has taken it
+func (d *Database) GetLeaderLease(ctx context.Context, name string) (*models.LeaderLease, error) {
+	var lease models.LeaderLease
+	err := d.db.Collection(CollectionLeaderLeases).FindOne(ctx, bson.M{"_id": name}).Decode(&lease)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// CreateAdminAccount creates a named admin account
+func (d *Database) CreateAdminAccount(ctx context.Context, account *models.AdminAccount) error {
+	_, err := d.db.Collection(CollectionAdminAccounts).InsertOne(ctx, account)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("admin account %q already exists", account.Username)
+	}
+	return err
+}
+
+// GetAdminAccount returns the named admin account, or nil
+func (d *Database) GetAdminAccount(ctx context.Context, username string) (*models.AdminAccount, error) {
+	var account models.AdminAccount
+	err := d.db.Collection(CollectionAdminAccounts).FindOne(ctx, bson.M{"_id": username}).Decode(&account)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// ListAdminAccounts returns every admin account by username
+func (d *Database) ListAdminAccounts(ctx context.Context) ([]*models.AdminAccount, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := d.db.Collection(CollectionAdminAccounts).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	accounts := []*models.AdminAccount{}
+	if err := cursor.All(ctx, &accounts); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// UpdateAdminAccount saves an existing account's role, status and password
+func (d *Database) UpdateAdminAccount(ctx context.Context, account *models.AdminAccount) error {
+	result, err := d.db.Collection(CollectionAdminAccounts).UpdateOne(ctx, bson.M{"_id": account.Username}, bson.M{
+		"$set": bson.M{
+			"password_hash":       account.PasswordHash,
+			"role":                account.Role,
+			"is_active":           account.IsActive,
+			"password_changed_at": account.PasswordChangedAt,
+			"updated_at":          account.UpdatedAt,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("admin account not found")
+	}
+	return nil
+}
+
+// AppendAuditEntry adds an entry to the audit log
+func (d *Database) AppendAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	entry.ID = primitive.NewObjectID()
+	if entry.At.IsZero() {
+		entry.At = time.Now()
+	}
+
+	_, err := d.db.Collection(CollectionAuditLog).InsertOne(ctx, entry)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrAuditSeqTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// ListAuditEntries returns the newest audit entries, optionally for one actor
+func (d *Database) ListAuditEntries(ctx context.Context, actor string, limit int) ([]models.AuditEntry, error) {
+	filter := bson.M{}
+	if actor != "" {
+		filter["actor"] = actor
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := d.db.Collection(CollectionAuditLog).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// LastAuditEntry returns the chained audit entry with the highest Seq
+func (d *Database) LastAuditEntry(ctx context.Context) (*models.AuditEntry, error) {
+	var entry models.AuditEntry
+	err := d.db.Collection(CollectionAuditLog).FindOne(ctx,
+		bson.M{"seq": bson.M{"$gt": 0}},
+		options.FindOne().SetSort(bson.D{{Key: "seq", Value: -1}}),
+	).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// ListAuditChain returns chained audit entries after afterSeq in Seq order
+func (d *Database) ListAuditChain(ctx context.Context, afterSeq int64, limit int) ([]models.AuditEntry, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "seq", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := d.db.Collection(CollectionAuditLog).Find(ctx, bson.M{"seq": bson.M{"$gt": afterSeq}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// UseNonce records a client's nonce, reporting false if it is still live
+// The TTL monitor deletes expired nonces about once a minute; one it hasn't
+// reached yet is replaced in place.
+func (d *Database) UseNonce(ctx context.Context, clientID, nonce string, expiresAt time.Time) (bool, error) {
+	collection := d.db.Collection(CollectionUsedNonces)
+	id := clientID + "|" + nonce
+
+	_, err := collection.InsertOne(ctx, bson.M{"_id": id, "expires_at": expiresAt})
+	if err == nil {
+		return true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return false, fmt.Errorf("failed to record nonce: %w", err)
+	}
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, "expires_at": bson.M{"$lte": time.Now()}},
+		bson.M{"$set": bson.M{"expires_at": expiresAt}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to record nonce: %w", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// PurgeExpiredNonces is a no-op; the TTL index expires nonces
+func (d *Database) PurgeExpiredNonces(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+// SaveAuthSession creates or replaces a mutual authentication session
+func (d *Database) SaveAuthSession(ctx context.Context, session *models.AuthSession) error {
+	_, err := d.db.Collection(CollectionAuthSessions).ReplaceOne(ctx,
+		bson.M{"_id": session.SessionNonce}, session, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save auth session: %w", err)
+	}
+	return nil
+}
+
+// GetAuthSession returns the unexpired session with this nonce, or nil
+func (d *Database) GetAuthSession(ctx context.Context, sessionNonce string) (*models.AuthSession, error) {
+	var session models.AuthSession
+	err := d.db.Collection(CollectionAuthSessions).FindOne(ctx, bson.M{
+		"_id":        sessionNonce,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(&session)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// PurgeExpiredAuthSessions is a no-op; the TTL index expires sessions
+func (d *Database) PurgeExpiredAuthSessions(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
+// IncrementRateCounter adds one to the counter at key unless it has reached
+// limit
+func (d *Database) IncrementRateCounter(ctx context.Context, key string, limit int, expiresAt time.Time) (int, error) {
+	if limit <= 0 {
+		return 0, ErrQuotaExceeded
+	}
+
+	collection := d.db.Collection(CollectionRateCounters)
+	filter := bson.M{"_id": key, "count": bson.M{"$lt": limit}}
+	update := bson.M{"$inc": bson.M{"count": 1}, "$setOnInsert": bson.M{"expires_at": expiresAt}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	// A full counter fails the filter, so the upsert collides with it; so
+	// can a racing first increment, hence the one retry
+	for attempt := 0; ; attempt++ {
+		var counter struct {
+			Count int `bson:"count"`
+		}
+		err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&counter)
+		if err == nil {
+			return counter.Count, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return 0, fmt.Errorf("failed to count request: %w", err)
+		}
+		if attempt == 1 {
+			return 0, ErrQuotaExceeded
+		}
+	}
+}
+
+// GetRateCounter returns the count at key, 0 if there is none
+func (d *Database) GetRateCounter(ctx context.Context, key string) (int, error) {
+	var counter struct {
+		Count int `bson:"count"`
+	}
+	err := d.db.Collection(CollectionRateCounters).FindOne(ctx, bson.M{"_id": key}).Decode(&counter)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read rate counter: %w", err)
+	}
+	return counter.Count, nil
+}
+
+// DecrementRateCounter takes one off the count at key, if above 0
+func (d *Database) DecrementRateCounter(ctx context.Context, key string) error {
+	_, err := d.db.Collection(CollectionRateCounters).UpdateOne(ctx,
+		bson.M{"_id": key, "count": bson.M{"$gt": 0}},
+		bson.M{"$inc": bson.M{"count": -1}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to refund rate counter: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpiredRateCounters is a no-op; the TTL index expires counters
+func (d *Database) PurgeExpiredRateCounters(ctx context.Context) (int64, error) {
+	return 0, nil
+}
+
 // Close closes the database connection
 func (d *Database) Close(ctx context.Context) error {
 	return d.client.Disconnect(ctx)
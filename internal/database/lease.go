@@ -0,0 +1,87 @@
+package database
+
+import (
+	"os"
+	"sort"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/models"
+	"github.com/google/uuid"
+)
+
+// DefaultLease is how long a UTXO lock lasts without renewal
+const DefaultLease = 2 * time.Minute
+
+// LockOwner identifies the instance taking UTXO locks and how long its leases last
+//
+// Every lock a store takes is stamped with the owner and an expiry. The
+// owner's janitor renews its leases while the process is alive; only expired
+// leases are reclaimed, so a slow train or a second replica can't have a
+// UTXO pulled out from under an in-flight transaction.
+type LockOwner struct {
+	ID    string
+	Lease time.Duration
+}
+
+// NewLockOwner names this process
+// The random suffix means a restarted instance never renews locks its
+// predecessor left behind; those expire and get reconciled instead
+func NewLockOwner(lease time.Duration) LockOwner {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "broadcaster"
+	}
+	if lease <= 0 {
+		lease = DefaultLease
+	}
+	return LockOwner{
+		ID:    host + "-" + uuid.New().String()[:8],
+		Lease: lease,
+	}
+}
+
+// leaseExpired reports whether a locked UTXO can be reclaimed at now
+// Locks taken before leases existed fall back to their age
+func (o LockOwner) leaseExpired(utxo *models.UTXO, now time.Time) bool {
+	if utxo.Status != models.UTXOStatusLocked {
+		return false
+	}
+	if utxo.LeaseExpiresAt != nil {
+		return utxo.LeaseExpiresAt.Before(now)
+	}
+	return utxo.LockedAt == nil || utxo.LockedAt.Before(now.Add(-o.Lease))
+}
+
+// sortByLeaseExpiry orders expired leases longest expired first
+func (o LockOwner) sortByLeaseExpiry(utxos []*models.UTXO) {
+	expiry := func(utxo *models.UTXO) time.Time {
+		switch {
+		case utxo.LeaseExpiresAt != nil:
+			return *utxo.LeaseExpiresAt
+		case utxo.LockedAt != nil:
+			return utxo.LockedAt.Add(o.Lease)
+		}
+		return time.Time{}
+	}
+	sort.Slice(utxos, func(i, j int) bool {
+		a, b := expiry(utxos[i]), expiry(utxos[j])
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return utxos[i].Outpoint < utxos[j].Outpoint
+	})
+}
+
+// sameLock reports whether two reads of a UTXO saw the same lock, i.e.
+// nobody renewed, released or retook it in between
+func sameLock(a, b *models.UTXO) bool {
+	return a.Status == b.Status && a.LockOwner == b.LockOwner &&
+		timesEqual(a.LockedAt, b.LockedAt) && timesEqual(a.LeaseExpiresAt, b.LeaseExpiresAt)
+}
+
+func timesEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
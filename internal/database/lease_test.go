@@ -0,0 +1,232 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/models"
+)
+
+// forEachUTXOStore runs fn against the embedded backends, each holding one
+// available funding UTXO at 01..:0
+func forEachUTXOStore(t *testing.T, fn func(t *testing.T, store UTXOStore, outpoint string)) {
+	stores := map[string]func(t *testing.T) UTXOStore{
+		"memory": func(t *testing.T) UTXOStore { return NewMemoryStore() },
+		"bolt": func(t *testing.T) UTXOStore {
+			store, err := OpenBolt(filepath.Join(t.TempDir(), "test.db"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { store.Close(context.Background()) })
+			return store
+		},
+	}
+
+	for name, open := range stores {
+		t.Run(name, func(t *testing.T) {
+			store := open(t)
+			store.SetLockOwner(NewLockOwner(time.Minute))
+
+			txid := fmt.Sprintf("%064x", 1)
+			err := store.InsertUTXO(context.Background(), &models.UTXO{
+				Outpoint: txid + ":0",
+				TxID:     txid,
+				Satoshis: 1000,
+				Status:   models.UTXOStatusAvailable,
+				Type:     models.UTXOTypeFunding,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			fn(t, store, txid+":0")
+		})
+	}
+}
+
+// storedUTXO reads outpoint back whatever its status
+func storedUTXO(t *testing.T, store UTXOStore, outpoint string) *models.UTXO {
+	t.Helper()
+
+	for _, status := range []models.UTXOStatus{models.UTXOStatusAvailable, models.UTXOStatusLocked, models.UTXOStatusSpent} {
+		utxos, err := store.FindUTXOsByType(context.Background(), models.UTXOTypeFunding, status)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, utxo := range utxos {
+			if utxo.Outpoint == outpoint {
+				return utxo
+			}
+		}
+	}
+	t.Fatalf("UTXO %s not stored", outpoint)
+	return nil
+}
+
+func TestUnlockUTXORequiresTheLockOwner(t *testing.T) {
+	forEachUTXOStore(t, func(t *testing.T, store UTXOStore, outpoint string) {
+		ctx := context.Background()
+		mine := NewLockOwner(time.Minute)
+		store.SetLockOwner(mine)
+		if err := store.LockUTXO(ctx, outpoint); err != nil {
+			t.Fatal(err)
+		}
+
+		// Another instance sharing the store
+		store.SetLockOwner(NewLockOwner(time.Minute))
+		if err := store.UnlockUTXO(ctx, outpoint); !errors.Is(err, ErrLockNotHeld) {
+			t.Fatalf("unlock by another owner = %v, want ErrLockNotHeld", err)
+		}
+		if err := store.MarkUTXOSpent(ctx, outpoint, "other"); !errors.Is(err, ErrLockNotHeld) {
+			t.Fatalf("spend by another owner = %v, want ErrLockNotHeld", err)
+		}
+		if got := storedUTXO(t, store, outpoint); got.Status != models.UTXOStatusLocked || got.LockOwner != mine.ID {
+			t.Fatalf("UTXO = %s by %q, want still locked by %s", got.Status, got.LockOwner, mine.ID)
+		}
+
+		store.SetLockOwner(mine)
+		if err := store.UnlockUTXO(ctx, outpoint); err != nil {
+			t.Fatalf("unlock by owner: %v", err)
+		}
+		if got := storedUTXO(t, store, outpoint); got.Status != models.UTXOStatusAvailable {
+			t.Fatalf("UTXO = %s, want available", got.Status)
+		}
+
+		// Nothing left to unlock
+		if err := store.UnlockUTXO(ctx, outpoint); !errors.Is(err, ErrLockNotHeld) {
+			t.Fatalf("second unlock = %v, want ErrLockNotHeld", err)
+		}
+		if err := store.UnlockUTXO(ctx, "missing:0"); err != nil {
+			t.Fatalf("unlock of unknown outpoint = %v, want nil", err)
+		}
+	})
+}
+
+func TestLockOwnerLosesAnExpiredLease(t *testing.T) {
+	forEachUTXOStore(t, func(t *testing.T, store UTXOStore, outpoint string) {
+		ctx := context.Background()
+		if err := store.LockUTXO(ctx, outpoint); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.ExpireLease(ctx, outpoint); err != nil {
+			t.Fatal(err)
+		}
+
+		// Given up to the janitor, so its former owner can't settle it
+		if err := store.UnlockUTXO(ctx, outpoint); !errors.Is(err, ErrLockNotHeld) {
+			t.Fatalf("unlock after expiry = %v, want ErrLockNotHeld", err)
+		}
+		if err := store.MarkUTXOSpent(ctx, outpoint, "late"); !errors.Is(err, ErrLockNotHeld) {
+			t.Fatalf("spend after expiry = %v, want ErrLockNotHeld", err)
+		}
+		if got := storedUTXO(t, store, outpoint); got.Status != models.UTXOStatusLocked {
+			t.Fatalf("UTXO = %s, want locked for the janitor", got.Status)
+		}
+	})
+}
+
+func TestMarkUTXOSpentAcceptsUnlockedUTXOs(t *testing.T) {
+	forEachUTXOStore(t, func(t *testing.T, store UTXOStore, outpoint string) {
+		// The sweeper spends available UTXOs without locking them
+		if err := store.MarkUTXOSpent(context.Background(), outpoint, "sweep"); err != nil {
+			t.Fatal(err)
+		}
+		if got := storedUTXO(t, store, outpoint); got.Status != models.UTXOStatusSpent {
+			t.Fatalf("UTXO = %s, want spent", got.Status)
+		}
+	})
+}
+
+func TestMarkExpiredUTXOSpentChecksTheLock(t *testing.T) {
+	forEachUTXOStore(t, func(t *testing.T, store UTXOStore, outpoint string) {
+		ctx := context.Background()
+		if err := store.LockUTXO(ctx, outpoint); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.ExpireLease(ctx, outpoint); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+
+		expired, err := store.FindExpiredLeases(ctx, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(expired) != 1 {
+			t.Fatalf("%d expired leases, want 1", len(expired))
+		}
+
+		// Relocked between the janitor's read and its write
+		stale := *expired[0]
+		if ok, err := store.ReclaimUTXO(ctx, &stale); err != nil || !ok {
+			t.Fatalf("reclaim = %v, %v", ok, err)
+		}
+		if err := store.LockUTXO(ctx, outpoint); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := store.MarkExpiredUTXOSpent(ctx, &stale, "janitor"); err != nil || ok {
+			t.Fatalf("spend with a stale lock = %v, %v, want false", ok, err)
+		}
+		if got := storedUTXO(t, store, outpoint); got.Status != models.UTXOStatusLocked {
+			t.Fatalf("UTXO = %s, want locked by its new holder", got.Status)
+		}
+
+		if err := store.ExpireLease(ctx, outpoint); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+		expired, err = store.FindExpiredLeases(ctx, 10)
+		if err != nil || len(expired) != 1 {
+			t.Fatalf("expired leases = %d, %v, want 1", len(expired), err)
+		}
+		if ok, err := store.MarkExpiredUTXOSpent(ctx, expired[0], "janitor"); err != nil || !ok {
+			t.Fatalf("spend = %v, %v, want true", ok, err)
+		}
+		if got := storedUTXO(t, store, outpoint); got.Status != models.UTXOStatusSpent {
+			t.Fatalf("UTXO = %s, want spent", got.Status)
+		}
+	})
+}
+
+func TestFindExpiredLeasesLongestExpiredFirst(t *testing.T) {
+	forEachUTXOStore(t, func(t *testing.T, store UTXOStore, _ string) {
+		ctx := context.Background()
+		now := time.Now()
+
+		// Inserted newest expiry first, so insertion order can't pass for sorting
+		for i, ago := range []time.Duration{time.Minute, 10 * time.Minute, 5 * time.Minute} {
+			txid := fmt.Sprintf("%064x", 100+i)
+			lockedAt := now.Add(-ago - time.Minute)
+			expires := now.Add(-ago)
+			err := store.InsertUTXO(ctx, &models.UTXO{
+				Outpoint:       txid + ":0",
+				TxID:           txid,
+				Status:         models.UTXOStatusLocked,
+				Type:           models.UTXOTypeFunding,
+				LockedAt:       &lockedAt,
+				LockOwner:      "dead-instance",
+				LeaseExpiresAt: &expires,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		expired, err := store.FindExpiredLeases(ctx, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []string{fmt.Sprintf("%064x:0", 101), fmt.Sprintf("%064x:0", 102)}
+		if len(expired) != len(want) {
+			t.Fatalf("%d expired leases, want %d", len(expired), len(want))
+		}
+		for i, utxo := range expired {
+			if utxo.Outpoint != want[i] {
+				t.Fatalf("expired[%d] = %s, want %s", i, utxo.Outpoint, want[i])
+			}
+		}
+	})
+}
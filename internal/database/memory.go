@@ -0,0 +1,1125 @@
+package database
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MemoryStore is an in-process Store for tests and single-binary dev mode
+// Everything is lost on restart. A single mutex makes every method atomic,
+// so FindAndLockUTXO never hands the same UTXO to two callers.
+type MemoryStore struct {
+	mu        sync.Mutex
+	seq       uint64
+	utxos     map[string]*models.UTXO             // outpoint -> UTXO
+	available map[models.UTXOType]*utxoQueue      // FIFO candidates for FindAndLockUTXO
+	requests  map[string]*models.BroadcastRequest // UUID -> request
+	clients   map[primitive.ObjectID]*models.Client
+	incidents []*models.DoubleSpendIncident
+	leaders   map[string]*models.LeaderLease
+	admins    map[string]*models.AdminAccount
+	audit     []models.AuditEntry
+	nonces    map[string]time.Time // client ID | nonce -> expiry
+	sessions  map[string]*models.AuthSession
+	counters  map[string]*rateCounter
+	owner     LockOwner
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		utxos:     make(map[string]*models.UTXO),
+		available: make(map[models.UTXOType]*utxoQueue),
+		requests:  make(map[string]*models.BroadcastRequest),
+		clients:   make(map[primitive.ObjectID]*models.Client),
+		leaders:   make(map[string]*models.LeaderLease),
+		admins:    make(map[string]*models.AdminAccount),
+		nonces:    make(map[string]time.Time),
+		sessions:  make(map[string]*models.AuthSession),
+		counters:  make(map[string]*rateCounter),
+		owner:     NewLockOwner(DefaultLease),
+	}
+}
+
+// SetLockOwner sets the owner and lease stamped on locks this store takes
+func (m *MemoryStore) SetLockOwner(owner LockOwner) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.owner = owner
+}
+
+// utxoQueue orders available UTXOs oldest first
+// Entries are not removed when a UTXO is locked or spent; FindAndLockUTXO
+// skips entries whose UTXO is no longer available
+type utxoQueue []utxoEntry
+
+type utxoEntry struct {
+	createdAt time.Time
+	seq       uint64
+	outpoint  string
+}
+
+func (q utxoQueue) Len() int { return len(q) }
+func (q utxoQueue) Less(i, j int) bool {
+	if !q[i].createdAt.Equal(q[j].createdAt) {
+		return q[i].createdAt.Before(q[j].createdAt)
+	}
+	return q[i].seq < q[j].seq
+}
+func (q utxoQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *utxoQueue) Push(x interface{}) { *q = append(*q, x.(utxoEntry)) }
+func (q *utxoQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	e := old[n-1]
+	*q = old[:n-1]
+	return e
+}
+
+// makeAvailable marks a UTXO available and queues it for locking (caller holds mu)
+func (m *MemoryStore) makeAvailable(utxo *models.UTXO) {
+	utxo.Status = models.UTXOStatusAvailable
+	utxo.LockedAt = nil
+	utxo.LockOwner = ""
+	utxo.LeaseExpiresAt = nil
+
+	q, ok := m.available[utxo.Type]
+	if !ok {
+		q = &utxoQueue{}
+		m.available[utxo.Type] = q
+	}
+	m.seq++
+	heap.Push(q, utxoEntry{createdAt: utxo.CreatedAt, seq: m.seq, outpoint: utxo.Outpoint})
+}
+
+// lock marks a UTXO locked under owner's lease
+func lock(utxo *models.UTXO, owner LockOwner) {
+	now := time.Now()
+	expires := now.Add(owner.Lease)
+	utxo.Status = models.UTXOStatusLocked
+	utxo.LockedAt = &now
+	utxo.LockOwner = owner.ID
+	utxo.LeaseExpiresAt = &expires
+	utxo.UpdatedAt = now
+}
+
+// FindAndLockUTXO atomically locks the oldest available UTXO of a type
+func (m *MemoryStore) FindAndLockUTXO(ctx context.Context, utxoType models.UTXOType) (*models.UTXO, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	q := m.available[utxoType]
+	for q != nil && q.Len() > 0 {
+		e := heap.Pop(q).(utxoEntry)
+		utxo, ok := m.utxos[e.outpoint]
+		if !ok || utxo.Status != models.UTXOStatusAvailable || utxo.Type != utxoType {
+			continue // Stale entry
+		}
+		lock(utxo, m.owner)
+		return copyUTXO(utxo), nil
+	}
+
+	return nil, fmt.Errorf("no available %s UTXOs", utxoType)
+}
+
+// FindAndLockBatch locks up to count UTXOs, returning what it could get
+func (m *MemoryStore) FindAndLockBatch(ctx context.Context, utxoType models.UTXOType, count int) ([]*models.UTXO, error) {
+	utxos := make([]*models.UTXO, 0, count)
+
+	for i := 0; i < count; i++ {
+		utxo, err := m.FindAndLockUTXO(ctx, utxoType)
+		if err != nil {
+			if len(utxos) > 0 {
+				return utxos, nil
+			}
+			return nil, err
+		}
+		utxos = append(utxos, utxo)
+	}
+
+	return utxos, nil
+}
+
+// FindUTXOsByType returns all UTXOs of a type and status without locking
+func (m *MemoryStore) FindUTXOsByType(ctx context.Context, utxoType models.UTXOType, status models.UTXOStatus) ([]*models.UTXO, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var utxos []*models.UTXO
+	for _, utxo := range m.utxos {
+		if utxo.Type == utxoType && utxo.Status == status {
+			utxos = append(utxos, copyUTXO(utxo))
+		}
+	}
+	sortUTXOs(utxos)
+	return utxos, nil
+}
+
+// GetAvailableUTXOs returns up to limit available UTXOs of a type (0 = no limit)
+func (m *MemoryStore) GetAvailableUTXOs(ctx context.Context, utxoType models.UTXOType, limit int) ([]*models.UTXO, error) {
+	utxos, err := m.FindUTXOsByType(ctx, utxoType, models.UTXOStatusAvailable)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(utxos) > limit {
+		utxos = utxos[:limit]
+	}
+	return utxos, nil
+}
+
+// MarkUTXOSpent marks a UTXO as spent
+func (m *MemoryStore) MarkUTXOSpent(ctx context.Context, outpoint string, txid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	utxo, ok := m.utxos[outpoint]
+	if !ok {
+		return nil
+	}
+	if utxo.Status == models.UTXOStatusLocked && utxo.LockOwner != m.owner.ID {
+		return fmt.Errorf("failed to mark %s spent: %w", outpoint, ErrLockNotHeld)
+	}
+	markSpent(utxo)
+	return nil
+}
+
+// markSpent records a UTXO as spent, dropping any lock
+func markSpent(utxo *models.UTXO) {
+	now := time.Now()
+	utxo.Status = models.UTXOStatusSpent
+	utxo.SpentAt = &now
+	utxo.LockOwner = ""
+	utxo.LeaseExpiresAt = nil
+	utxo.UpdatedAt = now
+}
+
+// LockUTXO locks a specific UTXO by outpoint
+func (m *MemoryStore) LockUTXO(ctx context.Context, outpoint string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	utxo, ok := m.utxos[outpoint]
+	if !ok {
+		return fmt.Errorf("UTXO not found: %s", outpoint)
+	}
+	lock(utxo, m.owner)
+	return nil
+}
+
+// UnlockUTXO releases a locked UTXO back to available status
+func (m *MemoryStore) UnlockUTXO(ctx context.Context, outpoint string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	utxo, ok := m.utxos[outpoint]
+	if !ok {
+		return nil
+	}
+	if utxo.Status != models.UTXOStatusLocked || utxo.LockOwner != m.owner.ID {
+		return fmt.Errorf("failed to unlock %s: %w", outpoint, ErrLockNotHeld)
+	}
+	utxo.UpdatedAt = time.Now()
+	m.makeAvailable(utxo)
+	return nil
+}
+
+// InsertUTXO adds a new UTXO, ignoring outpoints that already exist
+func (m *MemoryStore) InsertUTXO(ctx context.Context, utxo *models.UTXO) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.utxos[utxo.Outpoint]; ok {
+		return nil
+	}
+
+	utxo.CreatedAt = time.Now()
+	utxo.UpdatedAt = time.Now()
+	if utxo.ID.IsZero() {
+		utxo.ID = primitive.NewObjectID()
+	}
+
+	stored := copyUTXO(utxo)
+	m.utxos[utxo.Outpoint] = stored
+	if stored.Status == models.UTXOStatusAvailable {
+		m.makeAvailable(stored)
+	}
+	return nil
+}
+
+// UpsertUTXO inserts a UTXO as available or refreshes an existing one's
+// details without touching its status
+func (m *MemoryStore) UpsertUTXO(ctx context.Context, utxo *models.UTXO) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	utxo.UpdatedAt = now
+
+	if existing, ok := m.utxos[utxo.Outpoint]; ok {
+		typeChanged := existing.Type != utxo.Type
+		existing.TxID = utxo.TxID
+		existing.Vout = utxo.Vout
+		existing.Satoshis = utxo.Satoshis
+		existing.ScriptPubKey = utxo.ScriptPubKey
+		existing.Type = utxo.Type
+		existing.DerivationIndex = utxo.DerivationIndex
+		existing.DerivationPath = utxo.DerivationPath
+		existing.UpdatedAt = now
+		if typeChanged && existing.Status == models.UTXOStatusAvailable {
+			m.makeAvailable(existing) // Queue under the new type
+		}
+		return nil
+	}
+
+	stored := copyUTXO(utxo)
+	stored.ID = primitive.NewObjectID()
+	stored.SpentAt = nil
+	stored.CreatedAt = now
+	m.utxos[stored.Outpoint] = stored
+	m.makeAvailable(stored)
+	return nil
+}
+
+// RenewLeases extends every lease held by this store's owner
+func (m *MemoryStore) RenewLeases(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	expires := now.Add(m.owner.Lease)
+	var renewed int64
+	for _, utxo := range m.utxos {
+		if utxo.Status == models.UTXOStatusLocked && utxo.LockOwner == m.owner.ID {
+			t := expires
+			utxo.LeaseExpiresAt = &t
+			utxo.UpdatedAt = now
+			renewed++
+		}
+	}
+	return renewed, nil
+}
+
+// ExpireLease gives up a lock without unlocking it, leaving the UTXO for the janitor
+func (m *MemoryStore) ExpireLease(ctx context.Context, outpoint string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if utxo, ok := m.utxos[outpoint]; ok && utxo.Status == models.UTXOStatusLocked && utxo.LockOwner == m.owner.ID {
+		now := time.Now()
+		utxo.LockOwner = ""
+		utxo.LeaseExpiresAt = &now
+		utxo.UpdatedAt = now
+	}
+	return nil
+}
+
+// FindExpiredLeases returns up to limit locked UTXOs whose lease has run out
+func (m *MemoryStore) FindExpiredLeases(ctx context.Context, limit int) ([]*models.UTXO, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var utxos []*models.UTXO
+	for _, utxo := range m.utxos {
+		if m.owner.leaseExpired(utxo, now) {
+			utxos = append(utxos, copyUTXO(utxo))
+		}
+	}
+	m.owner.sortByLeaseExpiry(utxos)
+	if limit > 0 && len(utxos) > limit {
+		utxos = utxos[:limit]
+	}
+	return utxos, nil
+}
+
+// ReclaimUTXO unlocks a UTXO returned by FindExpiredLeases unless its lock
+// was renewed, released or retaken since
+func (m *MemoryStore) ReclaimUTXO(ctx context.Context, utxo *models.UTXO) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.utxos[utxo.Outpoint]
+	if !ok || !sameLock(stored, utxo) {
+		return false, nil
+	}
+	stored.UpdatedAt = time.Now()
+	m.makeAvailable(stored)
+	return true, nil
+}
+
+// MarkExpiredUTXOSpent marks a UTXO returned by FindExpiredLeases spent
+// unless its lock was renewed, released or retaken since
+func (m *MemoryStore) MarkExpiredUTXOSpent(ctx context.Context, utxo *models.UTXO, txid string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.utxos[utxo.Outpoint]
+	if !ok || !sameLock(stored, utxo) {
+		return false, nil
+	}
+	markSpent(stored)
+	return true, nil
+}
+
+// DeferExpiredLease pushes an expired lease out to until unless its lock
+// was renewed, released or retaken since
+func (m *MemoryStore) DeferExpiredLease(ctx context.Context, utxo *models.UTXO, until time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.utxos[utxo.Outpoint]
+	if !ok || !sameLock(stored, utxo) {
+		return false, nil
+	}
+	stored.LeaseExpiresAt = &until
+	stored.UpdatedAt = time.Now()
+	return true, nil
+}
+
+// GetUTXOStats returns counts of UTXOs keyed "<type>_<status>"
+func (m *MemoryStore) GetUTXOStats(ctx context.Context) (map[string]int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make(map[string]int64)
+	for _, utxo := range m.utxos {
+		stats[fmt.Sprintf("%s_%s", utxo.Type, utxo.Status)]++
+	}
+	return stats, nil
+}
+
+// InsertBroadcastRequest creates a new broadcast request record
+func (m *MemoryStore) InsertBroadcastRequest(ctx context.Context, req *models.BroadcastRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.requests[req.UUID]; ok {
+		return fmt.Errorf("duplicate request UUID: %s", req.UUID)
+	}
+
+	req.CreatedAt = time.Now()
+	req.UpdatedAt = time.Now()
+	if req.ID.IsZero() {
+		req.ID = primitive.NewObjectID()
+	}
+
+	m.requests[req.UUID] = copyRequest(req)
+	return nil
+}
+
+// UpdateRequestStatus updates the status of a broadcast request
+// Empty txid, arcStatus and errorMsg leave the stored values alone
+func (m *MemoryStore) UpdateRequestStatus(ctx context.Context, uuid string, status models.RequestStatus, txid, arcStatus, errorMsg string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	req, ok := m.requests[uuid]
+	if !ok {
+		return nil
+	}
+
+	req.Status = status
+	req.UpdatedAt = time.Now()
+	if txid != "" {
+		req.TxID = txid
+	}
+	if arcStatus != "" {
+		req.ARCStatus = arcStatus
+	}
+	if errorMsg != "" {
+		req.Error = errorMsg
+	}
+	return nil
+}
+
+// AppendRequestAttempt records a failed ARC round trip on a broadcast request
+func (m *MemoryStore) AppendRequestAttempt(ctx context.Context, uuid string, attempt models.BroadcastAttempt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if req, ok := m.requests[uuid]; ok {
+		req.Attempts = append(req.Attempts, attempt)
+		req.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+// GetRequestByUUID retrieves a broadcast request by UUID
+func (m *MemoryStore) GetRequestByUUID(ctx context.Context, uuid string) (*models.BroadcastRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	req, ok := m.requests[uuid]
+	if !ok {
+		return nil, fmt.Errorf("request not found")
+	}
+	return copyRequest(req), nil
+}
+
+// GetRequestByUTXO returns the newest request that spent the outpoint, or nil
+func (m *MemoryStore) GetRequestByUTXO(ctx context.Context, outpoint string) (*models.BroadcastRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var newest *models.BroadcastRequest
+	for _, req := range m.requests {
+		if req.UTXOUsed == outpoint && (newest == nil || req.CreatedAt.After(newest.CreatedAt)) {
+			newest = req
+		}
+	}
+	if newest == nil {
+		return nil, nil
+	}
+	return copyRequest(newest), nil
+}
+
+// GetBroadcastRequestsSince returns broadcast requests created at or after since
+func (m *MemoryStore) GetBroadcastRequestsSince(ctx context.Context, since time.Time) ([]models.BroadcastRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var requests []models.BroadcastRequest
+	for _, req := range m.requests {
+		if !req.CreatedAt.Before(since) {
+			requests = append(requests, *copyRequest(req))
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].CreatedAt.Before(requests[j].CreatedAt) })
+	return requests, nil
+}
+
+// RecordDoubleSpend stores a double-spend incident and the competing txids on its request
+func (m *MemoryStore) RecordDoubleSpend(ctx context.Context, incident *models.DoubleSpendIncident) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	incident.ID = primitive.NewObjectID()
+	incident.DetectedAt = time.Now()
+	if incident.CompetingTxs == nil {
+		incident.CompetingTxs = []string{}
+	}
+
+	stored := *incident
+	stored.CompetingTxs = append([]string(nil), incident.CompetingTxs...)
+	m.incidents = append(m.incidents, &stored)
+
+	if req, ok := m.requests[incident.RequestUUID]; ok {
+		req.CompetingTxs = append([]string(nil), incident.CompetingTxs...)
+		req.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+// MarkDoubleSpendRepublished records the outcome of reissuing a double-spent payload
+func (m *MemoryStore) MarkDoubleSpendRepublished(ctx context.Context, incident *models.DoubleSpendIncident, newUUID, errorMsg string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, stored := range m.incidents {
+		if stored.ID == incident.ID {
+			stored.Republished = newUUID != ""
+			stored.RepublishUUID = newUUID
+			stored.RepublishError = errorMsg
+		}
+	}
+
+	if req, ok := m.requests[incident.RequestUUID]; ok && newUUID != "" {
+		req.RepublishedAs = newUUID
+		req.UpdatedAt = time.Now()
+	}
+	return nil
+}
+
+// ListDoubleSpendIncidents returns the newest incidents, optionally for one client
+func (m *MemoryStore) ListDoubleSpendIncidents(ctx context.Context, clientID string, limit int) ([]models.DoubleSpendIncident, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	incidents := []models.DoubleSpendIncident{}
+	for i := len(m.incidents) - 1; i >= 0 && (limit <= 0 || len(incidents) < limit); i-- {
+		incident := *m.incidents[i]
+		if clientID != "" && incident.ClientID != clientID {
+			continue
+		}
+		incident.CompetingTxs = append([]string(nil), incident.CompetingTxs...)
+		incidents = append(incidents, incident)
+	}
+	return incidents, nil
+}
+
+// CreateClient creates a new API client
+func (m *MemoryStore) CreateClient(ctx context.Context, client *models.Client) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, existing := range m.clients {
+		if existing.APIKeyHash == client.APIKeyHash {
+			return fmt.Errorf("duplicate API key")
+		}
+	}
+	if client.ID.IsZero() {
+		client.ID = primitive.NewObjectID()
+	}
+
+	m.clients[client.ID] = copyClient(client)
+	return nil
+}
+
+// GetClientByID retrieves a client by ID
+func (m *MemoryStore) GetClientByID(ctx context.Context, clientID primitive.ObjectID) (*models.Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client, ok := m.clients[clientID]
+	if !ok {
+		return nil, fmt.Errorf("client not found")
+	}
+	return copyClient(client), nil
+}
+
+// GetClientByAPIKey retrieves a client by their API key hash
+func (m *MemoryStore) GetClientByAPIKey(ctx context.Context, apiKeyHash string) (*models.Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client := m.clientByKey(apiKeyHash)
+	if client == nil {
+		return nil, fmt.Errorf("client not found")
+	}
+	return copyClient(client), nil
+}
+
+// GetClientByAPIKeyHash retrieves a client by their hashed API key
+func (m *MemoryStore) GetClientByAPIKeyHash(ctx context.Context, apiKeyHash string) (*models.Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client := m.clientByKey(apiKeyHash)
+	if client == nil {
+		return nil, fmt.Errorf("invalid API key")
+	}
+	return copyClient(client), nil
+}
+
+// GetClientByPublicKey retrieves a client by their current public key
+func (m *MemoryStore) GetClientByPublicKey(ctx context.Context, publicKey string) (*models.Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, client := range m.clients {
+		if client.PublicKey == publicKey {
+			return copyClient(client), nil
+		}
+	}
+	return nil, fmt.Errorf("client not found")
+}
+
+// ListClients returns all registered clients, oldest first
+func (m *MemoryStore) ListClients(ctx context.Context) ([]*models.Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	clients := make([]*models.Client, 0, len(m.clients))
+	for _, client := range m.clients {
+		clients = append(clients, copyClient(client))
+	}
+	sort.Slice(clients, func(i, j int) bool { return clients[i].CreatedAt.Before(clients[j].CreatedAt) })
+	return clients, nil
+}
+
+// ReserveClientTx counts a transaction against the client's daily quota,
+// starting a new count on a new day
+func (m *MemoryStore) ReserveClientTx(ctx context.Context, clientID interface{}, today string, maxDailyTx int) (int, error) {
+	count := 0
+	err := m.updateClient(clientID, func(client *models.Client) {
+		if client.LastResetDate == today {
+			count = client.TxCount
+		}
+		if count >= maxDailyTx {
+			count = -1
+			return
+		}
+		count++
+		client.TxCount = count
+		client.LastResetDate = today
+	})
+	if err != nil {
+		return 0, err
+	}
+	if count < 0 {
+		return 0, ErrQuotaExceeded
+	}
+	return count, nil
+}
+
+// RefundClientTx gives back a transaction counted on day
+func (m *MemoryStore) RefundClientTx(ctx context.Context, clientID interface{}, day string) error {
+	return m.updateClient(clientID, func(client *models.Client) {
+		if client.LastResetDate == day && client.TxCount > 0 {
+			client.TxCount--
+		}
+	})
+}
+
+// UpdateClientStatus activates or deactivates a client
+func (m *MemoryStore) UpdateClientStatus(ctx context.Context, clientID interface{}, isActive bool) error {
+	return m.updateClient(clientID, func(client *models.Client) {
+		client.IsActive = isActive
+	})
+}
+
+// BindPublicKeyToClient sets a client's public key if none is registered yet
+func (m *MemoryStore) BindPublicKeyToClient(ctx context.Context, apiKeyHash, publicKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client := m.clientByKey(apiKeyHash)
+	if client == nil || client.PublicKey != "" {
+		return fmt.Errorf("client not found or public key already registered")
+	}
+
+	client.PublicKey = publicKey
+	client.RequireSignature = true // Auto-enable signature requirement
+	client.UpdatedAt = time.Now()
+	return nil
+}
+
+// RotateClientPublicKey replaces a client's public key, keeping the old one for the grace period
+func (m *MemoryStore) RotateClientPublicKey(ctx context.Context, apiKeyHash, newPublicKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client := m.clientByKey(apiKeyHash)
+	if client == nil {
+		return fmt.Errorf("client not found: %s", apiKeyHash)
+	}
+
+	now := time.Now()
+	client.OldPublicKey = client.PublicKey
+	client.PublicKey = newPublicKey
+	client.KeyRotatedAt = &now
+	client.UpdatedAt = now
+	return nil
+}
+
+// UpdateClientSecurity updates a client's security tier and settings
+func (m *MemoryStore) UpdateClientSecurity(ctx context.Context, clientID interface{}, tier string, requireSignature bool, allowedIPs []string, gracePeriodHours int) error {
+	return m.updateClient(clientID, func(client *models.Client) {
+		client.Tier = tier
+		client.RequireSignature = requireSignature
+		client.AllowedIPs = append([]string(nil), allowedIPs...)
+		client.GracePeriodHours = gracePeriodHours
+	})
+}
+
+// UpdateClientLimits updates a client's daily quota, in-flight cap and rate limits
+func (m *MemoryStore) UpdateClientLimits(ctx context.Context, clientID interface{}, maxDailyTx, maxInFlight, maxPerSecond, maxPerMinute int) error {
+	return m.updateClient(clientID, func(client *models.Client) {
+		client.MaxDailyTx = maxDailyTx
+		client.MaxInFlight = maxInFlight
+		client.MaxPerSecond = maxPerSecond
+		client.MaxPerMinute = maxPerMinute
+	})
+}
+
+// UpdateClientAutoRepublish sets whether a client's double-spent payloads are reissued
+func (m *MemoryStore) UpdateClientAutoRepublish(ctx context.Context, clientID interface{}, autoRepublish bool) error {
+	return m.updateClient(clientID, func(client *models.Client) {
+		client.AutoRepublish = autoRepublish
+	})
+}
+
+// AcquireLeadership takes or renews the named lease for holder
+func (m *MemoryStore) AcquireLeadership(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if lease, ok := m.leaders[name]; ok && lease.Holder != holder && lease.ExpiresAt.After(now) {
+		return false, nil
+	}
+	m.leaders[name] = &models.LeaderLease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl), RenewedAt: now}
+	return true, nil
+}
+
+// ReleaseLeadership gives up the named lease if holder has it
+func (m *MemoryStore) ReleaseLeadership(ctx context.Context, name, holder string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if lease, ok := m.leaders[name]; ok && lease.Holder == holder {
+		delete(m.leaders, name)
+	}
+	return nil
+}
+
+// GetLeaderLease returns the named lease, or nil if nobody has taken it
+func (m *MemoryStore) GetLeaderLease(ctx context.Context, name string) (*models.LeaderLease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	lease, ok := m.leaders[name]
+	if !ok {
+		return nil, nil
+	}
+	copied := *lease
+	return &copied, nil
+}
+
+// CreateAdminAccount creates a named admin account
+func (m *MemoryStore) CreateAdminAccount(ctx context.Context, account *models.AdminAccount) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.admins[account.Username]; ok {
+		return fmt.Errorf("admin account %q already exists", account.Username)
+	}
+	stored := *account
+	m.admins[account.Username] = &stored
+	return nil
+}
+
+// GetAdminAccount returns the named admin account, or nil
+func (m *MemoryStore) GetAdminAccount(ctx context.Context, username string) (*models.AdminAccount, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	account, ok := m.admins[username]
+	if !ok {
+		return nil, nil
+	}
+	c := *account
+	return &c, nil
+}
+
+// ListAdminAccounts returns every admin account by username
+func (m *MemoryStore) ListAdminAccounts(ctx context.Context) ([]*models.AdminAccount, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	accounts := []*models.AdminAccount{}
+	for _, account := range m.admins {
+		c := *account
+		accounts = append(accounts, &c)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Username < accounts[j].Username })
+	return accounts, nil
+}
+
+// UpdateAdminAccount saves an existing account's role, status and password
+func (m *MemoryStore) UpdateAdminAccount(ctx context.Context, account *models.AdminAccount) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stored, ok := m.admins[account.Username]
+	if !ok {
+		return fmt.Errorf("admin account not found")
+	}
+	stored.PasswordHash = account.PasswordHash
+	stored.Role = account.Role
+	stored.IsActive = account.IsActive
+	stored.PasswordChangedAt = account.PasswordChangedAt
+	stored.UpdatedAt = account.UpdatedAt
+	return nil
+}
+
+// AppendAuditEntry adds an entry to the audit log
+func (m *MemoryStore) AppendAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry.Seq > 0 {
+		for _, existing := range m.audit {
+			if existing.Seq == entry.Seq {
+				return ErrAuditSeqTaken
+			}
+		}
+	}
+
+	entry.ID = primitive.NewObjectID()
+	if entry.At.IsZero() {
+		entry.At = time.Now()
+	}
+	stored := *entry
+	stored.Details = copyDetails(entry.Details)
+	m.audit = append(m.audit, stored)
+	return nil
+}
+
+// ListAuditEntries returns the newest audit entries, optionally for one actor
+func (m *MemoryStore) ListAuditEntries(ctx context.Context, actor string, limit int) ([]models.AuditEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := []models.AuditEntry{}
+	for i := len(m.audit) - 1; i >= 0 && (limit <= 0 || len(entries) < limit); i-- {
+		entry := m.audit[i]
+		if actor != "" && entry.Actor != actor {
+			continue
+		}
+		entry.Details = copyDetails(entry.Details)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// LastAuditEntry returns the chained audit entry with the highest Seq
+func (m *MemoryStore) LastAuditEntry(ctx context.Context) (*models.AuditEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var last *models.AuditEntry
+	for i := range m.audit {
+		if m.audit[i].Seq > 0 && (last == nil || m.audit[i].Seq > last.Seq) {
+			last = &m.audit[i]
+		}
+	}
+	if last == nil {
+		return nil, nil
+	}
+	entry := *last
+	entry.Details = copyDetails(last.Details)
+	return &entry, nil
+}
+
+// ListAuditChain returns chained audit entries after afterSeq in Seq order
+func (m *MemoryStore) ListAuditChain(ctx context.Context, afterSeq int64, limit int) ([]models.AuditEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := []models.AuditEntry{}
+	for _, entry := range m.audit {
+		if entry.Seq > afterSeq {
+			entry.Details = copyDetails(entry.Details)
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// UseNonce records a client's nonce, reporting false if it is still live
+func (m *MemoryStore) UseNonce(ctx context.Context, clientID, nonce string, expiresAt time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := clientID + "|" + nonce
+	if expiry, ok := m.nonces[key]; ok && time.Now().Before(expiry) {
+		return false, nil
+	}
+	m.nonces[key] = expiresAt
+	return true, nil
+}
+
+// PurgeExpiredNonces drops expired nonces
+func (m *MemoryStore) PurgeExpiredNonces(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var purged int64
+	for key, expiry := range m.nonces {
+		if !now.Before(expiry) {
+			delete(m.nonces, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// SaveAuthSession creates or replaces a mutual authentication session
+func (m *MemoryStore) SaveAuthSession(ctx context.Context, session *models.AuthSession) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved := *session
+	m.sessions[session.SessionNonce] = &saved
+	return nil
+}
+
+// GetAuthSession returns the unexpired session with this nonce, or nil
+func (m *MemoryStore) GetAuthSession(ctx context.Context, sessionNonce string) (*models.AuthSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[sessionNonce]
+	if !ok || !time.Now().Before(session.ExpiresAt) {
+		return nil, nil
+	}
+	found := *session
+	return &found, nil
+}
+
+// PurgeExpiredAuthSessions drops expired sessions
+func (m *MemoryStore) PurgeExpiredAuthSessions(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var purged int64
+	for nonce, session := range m.sessions {
+		if !now.Before(session.ExpiresAt) {
+			delete(m.sessions, nonce)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// rateCounter is a rate limit window's count
+type rateCounter struct {
+	count     int
+	expiresAt time.Time
+}
+
+// IncrementRateCounter adds one to the counter at key unless it has reached
+// limit
+func (m *MemoryStore) IncrementRateCounter(ctx context.Context, key string, limit int, expiresAt time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counter, ok := m.counters[key]
+	if !ok || !time.Now().Before(counter.expiresAt) {
+		counter = &rateCounter{expiresAt: expiresAt}
+		m.counters[key] = counter
+	}
+	if counter.count >= limit {
+		return 0, ErrQuotaExceeded
+	}
+	counter.count++
+	return counter.count, nil
+}
+
+// GetRateCounter returns the count at key, 0 if there is none
+func (m *MemoryStore) GetRateCounter(ctx context.Context, key string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if counter, ok := m.counters[key]; ok && time.Now().Before(counter.expiresAt) {
+		return counter.count, nil
+	}
+	return 0, nil
+}
+
+// DecrementRateCounter takes one off the count at key, if above 0
+func (m *MemoryStore) DecrementRateCounter(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if counter, ok := m.counters[key]; ok && counter.count > 0 {
+		counter.count--
+	}
+	return nil
+}
+
+// PurgeExpiredRateCounters drops expired counters
+func (m *MemoryStore) PurgeExpiredRateCounters(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var purged int64
+	for key, counter := range m.counters {
+		if !now.Before(counter.expiresAt) {
+			delete(m.counters, key)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// Close is a no-op; the data goes away with the process
+func (m *MemoryStore) Close(ctx context.Context) error {
+	return nil
+}
+
+// updateClient applies fn to a stored client under the lock
+func (m *MemoryStore) updateClient(clientID interface{}, fn func(*models.Client)) error {
+	id, ok := clientID.(primitive.ObjectID)
+	if !ok {
+		return fmt.Errorf("client ID must be a primitive.ObjectID, got %T", clientID)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	client, ok := m.clients[id]
+	if !ok {
+		return fmt.Errorf("client not found")
+	}
+	fn(client)
+	client.UpdatedAt = time.Now()
+	return nil
+}
+
+// clientByKey finds a client by API key hash (caller holds mu)
+func (m *MemoryStore) clientByKey(apiKeyHash string) *models.Client {
+	for _, client := range m.clients {
+		if client.APIKeyHash == apiKeyHash {
+			return client
+		}
+	}
+	return nil
+}
+
+// sortUTXOs orders UTXOs oldest first, like the Mongo FIFO
+func sortUTXOs(utxos []*models.UTXO) {
+	sort.Slice(utxos, func(i, j int) bool {
+		if !utxos[i].CreatedAt.Equal(utxos[j].CreatedAt) {
+			return utxos[i].CreatedAt.Before(utxos[j].CreatedAt)
+		}
+		return utxos[i].Outpoint < utxos[j].Outpoint
+	})
+}
+
+func copyUTXO(u *models.UTXO) *models.UTXO {
+	c := *u
+	c.LockedAt = copyTime(u.LockedAt)
+	c.LeaseExpiresAt = copyTime(u.LeaseExpiresAt)
+	c.SpentAt = copyTime(u.SpentAt)
+	return &c
+}
+
+func copyRequest(r *models.BroadcastRequest) *models.BroadcastRequest {
+	c := *r
+	c.Attempts = append([]models.BroadcastAttempt(nil), r.Attempts...)
+	c.CompetingTxs = append([]string(nil), r.CompetingTxs...)
+	c.ResponseChan = nil // Not persisted
+	return &c
+}
+
+func copyClient(cl *models.Client) *models.Client {
+	c := *cl
+	c.AllowedIPs = append([]string(nil), cl.AllowedIPs...)
+	c.KeyRotatedAt = copyTime(cl.KeyRotatedAt)
+	return &c
+}
+
+func copyDetails(details map[string]string) map[string]string {
+	if details == nil {
+		return nil
+	}
+	c := make(map[string]string, len(details))
+	for k, v := range details {
+		c[k] = v
+	}
+	return c
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/models"
+)
+
+// Run with -race: the publish handlers and the train lock from many goroutines
+func TestMemoryStoreConcurrentLocksNeverShareAUTXO(t *testing.T) {
+	ctx := context.Background()
+	db := NewMemoryStore()
+	db.SetLockOwner(NewLockOwner(time.Minute))
+
+	const total = 500
+	for i := 0; i < total; i++ {
+		txid := fmt.Sprintf("%064x", i)
+		err := db.InsertUTXO(ctx, &models.UTXO{
+			Outpoint: txid + ":0",
+			TxID:     txid,
+			Satoshis: 100,
+			Status:   models.UTXOStatusAvailable,
+			Type:     models.UTXOTypePublishing,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Single locks and batches race until the pool runs dry
+	var mu sync.Mutex
+	lockedBy := make(map[string]int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < 16; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				var utxos []*models.UTXO
+				var err error
+				if worker%2 == 0 {
+					var utxo *models.UTXO
+					if utxo, err = db.FindAndLockUTXO(ctx, models.UTXOTypePublishing); err == nil {
+						utxos = []*models.UTXO{utxo}
+					}
+				} else {
+					utxos, err = db.FindAndLockBatch(ctx, models.UTXOTypePublishing, 7)
+				}
+				if err != nil {
+					return
+				}
+
+				mu.Lock()
+				for _, utxo := range utxos {
+					lockedBy[utxo.Outpoint]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for outpoint, n := range lockedBy {
+		if n != 1 {
+			t.Fatalf("UTXO %s handed out %d times", outpoint, n)
+		}
+	}
+	if len(lockedBy) != total {
+		t.Fatalf("%d UTXOs handed out, want all %d", len(lockedBy), total)
+	}
+	locked, err := db.FindUTXOsByType(ctx, models.UTXOTypePublishing, models.UTXOStatusLocked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locked) != total {
+		t.Fatalf("%d UTXOs locked, want %d", len(locked), total)
+	}
+}
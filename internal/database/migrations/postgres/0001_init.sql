@@ -0,0 +1,86 @@
+-- Initial schema: mirrors the MongoDB collections and Database.createIndexes
+-- IDs are ObjectID hex strings so models stay backend-neutral
+
+CREATE TABLE utxos (
+    id               TEXT PRIMARY KEY,
+    outpoint         TEXT NOT NULL,
+    txid             TEXT NOT NULL DEFAULT '',
+    vout             BIGINT NOT NULL DEFAULT 0,
+    satoshis         BIGINT NOT NULL DEFAULT 0,
+    script_pub_key   TEXT NOT NULL DEFAULT '',
+    status           TEXT NOT NULL,
+    type             TEXT NOT NULL,
+    derivation_index BIGINT NOT NULL DEFAULT 0,
+    locked_at        TIMESTAMPTZ,
+    spent_at         TIMESTAMPTZ,
+    created_at       TIMESTAMPTZ NOT NULL,
+    updated_at       TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX utxos_outpoint_idx ON utxos (outpoint);
+CREATE INDEX utxos_status_type_idx ON utxos (status, type);
+CREATE INDEX utxos_status_locked_at_idx ON utxos (status, locked_at);
+-- FIFO order for FindAndLockUTXO
+CREATE INDEX utxos_available_fifo_idx ON utxos (type, created_at) WHERE status = 'available';
+
+CREATE TABLE broadcast_requests (
+    id             TEXT PRIMARY KEY,
+    uuid           TEXT NOT NULL,
+    raw_tx_hex     TEXT NOT NULL DEFAULT '',
+    txid           TEXT NOT NULL DEFAULT '',
+    utxo_used      TEXT NOT NULL DEFAULT '',
+    status         TEXT NOT NULL,
+    arc_status     TEXT NOT NULL DEFAULT '',
+    priority       TEXT NOT NULL DEFAULT '',
+    error          TEXT NOT NULL DEFAULT '',
+    attempts       JSONB NOT NULL DEFAULT '[]',
+    client_id      TEXT NOT NULL DEFAULT '',
+    competing_txs  TEXT[] NOT NULL DEFAULT '{}',
+    republished_as TEXT NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL,
+    updated_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX broadcast_requests_uuid_idx ON broadcast_requests (uuid);
+CREATE INDEX broadcast_requests_created_at_idx ON broadcast_requests (created_at);
+
+CREATE TABLE clients (
+    id                 TEXT PRIMARY KEY,
+    name               TEXT NOT NULL,
+    api_key_hash       TEXT NOT NULL,
+    public_key         TEXT NOT NULL DEFAULT '',
+    old_public_key     TEXT NOT NULL DEFAULT '',
+    key_rotated_at     TIMESTAMPTZ,
+    grace_period_hours INTEGER NOT NULL DEFAULT 0,
+    tier               TEXT NOT NULL DEFAULT '',
+    require_signature  BOOLEAN NOT NULL DEFAULT FALSE,
+    allowed_ips        TEXT[] NOT NULL DEFAULT '{}',
+    is_active          BOOLEAN NOT NULL DEFAULT TRUE,
+    site_origin        TEXT NOT NULL DEFAULT '',
+    max_daily_tx       INTEGER NOT NULL DEFAULT 0,
+    max_in_flight      INTEGER NOT NULL DEFAULT 0,
+    auto_republish     BOOLEAN NOT NULL DEFAULT FALSE,
+    tx_count           INTEGER NOT NULL DEFAULT 0,
+    last_reset_date    TEXT NOT NULL DEFAULT '',
+    created_at         TIMESTAMPTZ NOT NULL,
+    updated_at         TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX clients_api_key_hash_idx ON clients (api_key_hash);
+
+CREATE TABLE double_spend_incidents (
+    id              TEXT PRIMARY KEY,
+    request_uuid    TEXT NOT NULL,
+    client_id       TEXT NOT NULL DEFAULT '',
+    utxo            TEXT NOT NULL,
+    losing_txid     TEXT NOT NULL DEFAULT '',
+    competing_txs   TEXT[] NOT NULL DEFAULT '{}',
+    arc_status      TEXT NOT NULL DEFAULT '',
+    extra_info      TEXT NOT NULL DEFAULT '',
+    republished     BOOLEAN NOT NULL DEFAULT FALSE,
+    republish_uuid  TEXT NOT NULL DEFAULT '',
+    republish_error TEXT NOT NULL DEFAULT '',
+    detected_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX double_spend_incidents_client_idx ON double_spend_incidents (client_id, detected_at DESC);
@@ -0,0 +1,12 @@
+-- Lease-based UTXO locks: the owner instance and when its lease runs out
+
+ALTER TABLE utxos ADD COLUMN lock_owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE utxos ADD COLUMN lease_expires_at TIMESTAMPTZ;
+
+-- Janitor scan for expired leases
+CREATE INDEX utxos_status_lease_idx ON utxos (status, lease_expires_at);
+-- Lease renewal
+CREATE INDEX utxos_lock_owner_idx ON utxos (lock_owner) WHERE status = 'locked';
+
+-- Janitor lookup of the request holding an expired lease
+CREATE INDEX broadcast_requests_utxo_used_idx ON broadcast_requests (utxo_used, created_at DESC);
@@ -0,0 +1,9 @@
+-- Leader election: one row per named lease, held by the instance running that
+-- group of singleton workers
+
+CREATE TABLE leader_leases (
+    name       TEXT PRIMARY KEY,
+    holder     TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    renewed_at TIMESTAMPTZ NOT NULL
+);
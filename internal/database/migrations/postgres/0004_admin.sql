@@ -0,0 +1,36 @@
+-- Named admin accounts and the append-only audit log of their actions
+
+CREATE TABLE admin_accounts (
+    username            TEXT PRIMARY KEY,
+    password_hash       TEXT NOT NULL,
+    role                TEXT NOT NULL,
+    is_active           BOOLEAN NOT NULL DEFAULT TRUE,
+    password_changed_at TIMESTAMPTZ NOT NULL,
+    created_at          TIMESTAMPTZ NOT NULL,
+    updated_at          TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE audit_log (
+    id        TEXT PRIMARY KEY,
+    at        TIMESTAMPTZ NOT NULL,
+    actor     TEXT NOT NULL,
+    role      TEXT NOT NULL DEFAULT '',
+    action    TEXT NOT NULL,
+    target    TEXT NOT NULL DEFAULT '',
+    status    INTEGER NOT NULL,
+    remote_ip TEXT NOT NULL DEFAULT '',
+    details   JSONB NOT NULL DEFAULT '{}'
+);
+CREATE INDEX audit_log_actor_idx ON audit_log (actor, at DESC);
+CREATE INDEX audit_log_at_idx ON audit_log (at DESC);
+
+-- Entries can be added but never changed or removed
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,8 @@
+-- Hash chain over the audit log; entries written before it have no seq
+
+ALTER TABLE audit_log
+    ADD COLUMN seq       BIGINT,
+    ADD COLUMN prev_hash TEXT NOT NULL DEFAULT '',
+    ADD COLUMN hash      TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX audit_log_seq_idx ON audit_log (seq);
@@ -0,0 +1,10 @@
+-- Nonces of signed client requests, kept until their timestamp goes stale so
+-- a captured request can't be replayed on any replica
+
+CREATE TABLE used_nonces (
+    client_id  TEXT NOT NULL,
+    nonce      TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (client_id, nonce)
+);
+CREATE INDEX used_nonces_expires_idx ON used_nonces (expires_at);
@@ -0,0 +1,15 @@
+-- BRC-103 mutual authentication sessions, shared by every replica, and the
+-- lookup of a client by the identity key it authenticates with
+
+CREATE TABLE auth_sessions (
+    session_nonce TEXT PRIMARY KEY,
+    peer_nonce    TEXT NOT NULL,
+    identity_key  TEXT NOT NULL,
+    client_id     TEXT NOT NULL,
+    authenticated BOOLEAN NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL,
+    expires_at    TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX auth_sessions_expires_idx ON auth_sessions (expires_at);
+
+CREATE INDEX clients_public_key_idx ON clients (public_key);
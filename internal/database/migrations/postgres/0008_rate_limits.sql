@@ -0,0 +1,13 @@
+-- Per-second and per-minute client rate limits, and the sliding window
+-- counters behind them, shared by every replica
+
+ALTER TABLE clients
+    ADD COLUMN max_per_second INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN max_per_minute INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE rate_counters (
+    key        TEXT PRIMARY KEY,
+    count      INTEGER NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX rate_counters_expires_idx ON rate_counters (expires_at);
@@ -0,0 +1,5 @@
+-- The full derivation path of the key controlling each UTXO, so a UTXO is
+-- only ever signed for by the chain it was paid to
+
+ALTER TABLE utxos
+    ADD COLUMN derivation_path TEXT NOT NULL DEFAULT '';
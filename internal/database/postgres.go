@@ -0,0 +1,1225 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//go:embed migrations/postgres/*.sql
+var postgresMigrations embed.FS
+
+// postgresMigrationLock is the advisory lock key serialising migrations
+// across instances starting at the same time
+const postgresMigrationLock = 0x62737662 // "bsvb"
+
+// PostgresStore is a Store backed by PostgreSQL
+type PostgresStore struct {
+	pool  *pgxpool.Pool
+	owner LockOwner
+}
+
+// ConnectPostgres connects to PostgreSQL and applies pending migrations
+func ConnectPostgres(ctx context.Context, uri string) (*PostgresStore, error) {
+	pool, err := pgxpool.New(ctx, uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping PostgreSQL: %w", err)
+	}
+
+	p := &PostgresStore{pool: pool, owner: NewLockOwner(DefaultLease)}
+	if err := p.migrate(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return p, nil
+}
+
+// migrate applies embedded migrations that haven't run yet, in filename order
+func (p *PostgresStore) migrate(ctx context.Context) error {
+	tx, err := p.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, postgresMigrationLock); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	files, err := fs.Glob(postgresMigrations, "migrations/postgres/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		version := strings.TrimSuffix(file[strings.LastIndex(file, "/")+1:], ".sql")
+
+		var applied bool
+		err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, version).Scan(&applied)
+		if err != nil {
+			return err
+		}
+		if applied {
+			continue
+		}
+
+		sql, err := postgresMigrations.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, string(sql)); err != nil {
+			return fmt.Errorf("migration %s failed: %w", version, err)
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, applied_at) VALUES ($1, $2)`, version, time.Now()); err != nil {
+			return err
+		}
+		slog.Info("Applied migration", "version", version)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// SetLockOwner sets the owner and lease stamped on locks this store takes
+func (p *PostgresStore) SetLockOwner(owner LockOwner) {
+	p.owner = owner
+}
+
+const utxoColumns = `id, outpoint, txid, vout, satoshis, script_pub_key, status, type,
+	derivation_index, derivation_path, locked_at, lock_owner, lease_expires_at, spent_at, created_at, updated_at`
+
+// scanUTXO reads a row selected with utxoColumns
+func scanUTXO(row pgx.Row) (*models.UTXO, error) {
+	var (
+		utxo                  models.UTXO
+		id                    string
+		vout, derivationIndex int64
+		satoshis              int64
+	)
+	err := row.Scan(&id, &utxo.Outpoint, &utxo.TxID, &vout, &satoshis, &utxo.ScriptPubKey,
+		&utxo.Status, &utxo.Type, &derivationIndex, &utxo.DerivationPath, &utxo.LockedAt, &utxo.LockOwner,
+		&utxo.LeaseExpiresAt, &utxo.SpentAt, &utxo.CreatedAt, &utxo.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	utxo.ID, _ = primitive.ObjectIDFromHex(id)
+	utxo.Vout = uint32(vout)
+	utxo.Satoshis = uint64(satoshis)
+	utxo.DerivationIndex = uint32(derivationIndex)
+	return &utxo, nil
+}
+
+// queryUTXOs runs a query returning utxoColumns rows
+func (p *PostgresStore) queryUTXOs(ctx context.Context, sql string, args ...interface{}) ([]*models.UTXO, error) {
+	rows, err := p.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var utxos []*models.UTXO
+	for rows.Next() {
+		utxo, err := scanUTXO(rows)
+		if err != nil {
+			return nil, err
+		}
+		utxos = append(utxos, utxo)
+	}
+	return utxos, rows.Err()
+}
+
+// FindAndLockUTXO atomically locks the oldest available UTXO of a type
+// SKIP LOCKED lets concurrent callers each take a different row instead of
+// queueing behind one another
+func (p *PostgresStore) FindAndLockUTXO(ctx context.Context, utxoType models.UTXOType) (*models.UTXO, error) {
+	utxos, err := p.lockUTXOs(ctx, utxoType, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(utxos) == 0 {
+		return nil, fmt.Errorf("no available %s UTXOs", utxoType)
+	}
+	return utxos[0], nil
+}
+
+// FindAndLockBatch atomically locks up to count UTXOs in one statement
+func (p *PostgresStore) FindAndLockBatch(ctx context.Context, utxoType models.UTXOType, count int) ([]*models.UTXO, error) {
+	utxos, err := p.lockUTXOs(ctx, utxoType, count)
+	if err != nil {
+		return nil, err
+	}
+	if len(utxos) == 0 {
+		return nil, fmt.Errorf("no available %s UTXOs", utxoType)
+	}
+	return utxos, nil
+}
+
+// lockUTXOs claims up to count available UTXOs, oldest first
+func (p *PostgresStore) lockUTXOs(ctx context.Context, utxoType models.UTXOType, count int) ([]*models.UTXO, error) {
+	now := time.Now()
+	utxos, err := p.queryUTXOs(ctx, `
+		UPDATE utxos SET status = $1, locked_at = $2, updated_at = $2, lock_owner = $6, lease_expires_at = $7
+		WHERE id IN (
+			SELECT id FROM utxos
+			WHERE status = $3 AND type = $4
+			ORDER BY created_at
+			LIMIT $5
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+utxoColumns,
+		models.UTXOStatusLocked, now, models.UTXOStatusAvailable, utxoType, count, p.owner.ID, now.Add(p.owner.Lease))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock UTXO: %w", err)
+	}
+
+	// RETURNING doesn't preserve the subquery's order
+	sortUTXOs(utxos)
+	return utxos, nil
+}
+
+// FindUTXOsByType returns all UTXOs of a type and status without locking
+func (p *PostgresStore) FindUTXOsByType(ctx context.Context, utxoType models.UTXOType, status models.UTXOStatus) ([]*models.UTXO, error) {
+	utxos, err := p.queryUTXOs(ctx,
+		`SELECT `+utxoColumns+` FROM utxos WHERE type = $1 AND status = $2 ORDER BY created_at`,
+		utxoType, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find UTXOs: %w", err)
+	}
+	return utxos, nil
+}
+
+// GetAvailableUTXOs returns up to limit available UTXOs of a type (0 = no limit)
+func (p *PostgresStore) GetAvailableUTXOs(ctx context.Context, utxoType models.UTXOType, limit int) ([]*models.UTXO, error) {
+	sql := `SELECT ` + utxoColumns + ` FROM utxos WHERE status = $1 AND type = $2 ORDER BY created_at`
+	args := []interface{}{models.UTXOStatusAvailable, utxoType}
+	if limit > 0 {
+		sql += ` LIMIT $3`
+		args = append(args, limit)
+	}
+	return p.queryUTXOs(ctx, sql, args...)
+}
+
+// MarkUTXOSpent marks a UTXO as spent
+func (p *PostgresStore) MarkUTXOSpent(ctx context.Context, outpoint string, txid string) error {
+	now := time.Now()
+	tag, err := p.pool.Exec(ctx,
+		`UPDATE utxos SET status = $1, spent_at = $2, updated_at = $2, lock_owner = '', lease_expires_at = NULL
+		 WHERE outpoint = $3 AND (status <> $4 OR lock_owner = $5)`,
+		models.UTXOStatusSpent, now, outpoint, models.UTXOStatusLocked, p.owner.ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		if err := p.lockMismatch(ctx, outpoint); err != nil {
+			return fmt.Errorf("failed to mark %s spent: %w", outpoint, err)
+		}
+	}
+	return nil
+}
+
+// LockUTXO locks a specific UTXO by outpoint
+func (p *PostgresStore) LockUTXO(ctx context.Context, outpoint string) error {
+	now := time.Now()
+	tag, err := p.pool.Exec(ctx,
+		`UPDATE utxos SET status = $1, locked_at = $2, updated_at = $2, lock_owner = $4, lease_expires_at = $5
+		 WHERE outpoint = $3`,
+		models.UTXOStatusLocked, now, outpoint, p.owner.ID, now.Add(p.owner.Lease))
+	if err != nil {
+		return fmt.Errorf("failed to lock UTXO: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("UTXO not found: %s", outpoint)
+	}
+	return nil
+}
+
+// UnlockUTXO releases a locked UTXO back to available status
+func (p *PostgresStore) UnlockUTXO(ctx context.Context, outpoint string) error {
+	tag, err := p.pool.Exec(ctx,
+		`UPDATE utxos SET status = $1, locked_at = NULL, lock_owner = '', lease_expires_at = NULL, updated_at = $2
+		 WHERE outpoint = $3 AND status = $4 AND lock_owner = $5`,
+		models.UTXOStatusAvailable, time.Now(), outpoint, models.UTXOStatusLocked, p.owner.ID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		if err := p.lockMismatch(ctx, outpoint); err != nil {
+			return fmt.Errorf("failed to unlock %s: %w", outpoint, err)
+		}
+	}
+	return nil
+}
+
+// lockMismatch explains an owner-filtered update that matched nothing:
+// ErrLockNotHeld if the outpoint exists, nil if it doesn't
+func (p *PostgresStore) lockMismatch(ctx context.Context, outpoint string) error {
+	var exists bool
+	if err := p.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM utxos WHERE outpoint = $1)`, outpoint).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return nil
+	}
+	return ErrLockNotHeld
+}
+
+// InsertUTXO adds a new UTXO, ignoring outpoints that already exist
+func (p *PostgresStore) InsertUTXO(ctx context.Context, utxo *models.UTXO) error {
+	utxo.CreatedAt = time.Now()
+	utxo.UpdatedAt = time.Now()
+	if utxo.ID.IsZero() {
+		utxo.ID = primitive.NewObjectID()
+	}
+
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO utxos (`+utxoColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		ON CONFLICT (outpoint) DO NOTHING`,
+		utxo.ID.Hex(), utxo.Outpoint, utxo.TxID, int64(utxo.Vout), int64(utxo.Satoshis), utxo.ScriptPubKey,
+		utxo.Status, utxo.Type, int64(utxo.DerivationIndex), utxo.DerivationPath, utxo.LockedAt, utxo.LockOwner,
+		utxo.LeaseExpiresAt, utxo.SpentAt, utxo.CreatedAt, utxo.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert UTXO: %w", err)
+	}
+	return nil
+}
+
+// UpsertUTXO inserts a UTXO as available or refreshes an existing one's
+// details without touching its status
+func (p *PostgresStore) UpsertUTXO(ctx context.Context, utxo *models.UTXO) error {
+	now := time.Now()
+	utxo.UpdatedAt = now
+
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO utxos (`+utxoColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULL, '', NULL, NULL, $11, $11)
+		ON CONFLICT (outpoint) DO UPDATE SET
+			txid = EXCLUDED.txid,
+			vout = EXCLUDED.vout,
+			satoshis = EXCLUDED.satoshis,
+			script_pub_key = EXCLUDED.script_pub_key,
+			type = EXCLUDED.type,
+			derivation_index = EXCLUDED.derivation_index,
+			derivation_path = EXCLUDED.derivation_path,
+			updated_at = EXCLUDED.updated_at`,
+		primitive.NewObjectID().Hex(), utxo.Outpoint, utxo.TxID, int64(utxo.Vout), int64(utxo.Satoshis),
+		utxo.ScriptPubKey, models.UTXOStatusAvailable, utxo.Type, int64(utxo.DerivationIndex), utxo.DerivationPath, now)
+	if err != nil {
+		return fmt.Errorf("failed to upsert UTXO: %w", err)
+	}
+	return nil
+}
+
+// RenewLeases extends every lease held by this store's owner
+func (p *PostgresStore) RenewLeases(ctx context.Context) (int64, error) {
+	now := time.Now()
+	tag, err := p.pool.Exec(ctx,
+		`UPDATE utxos SET lease_expires_at = $1, updated_at = $2 WHERE status = $3 AND lock_owner = $4`,
+		now.Add(p.owner.Lease), now, models.UTXOStatusLocked, p.owner.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to renew leases: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ExpireLease gives up a lock without unlocking it, leaving the UTXO for the janitor
+func (p *PostgresStore) ExpireLease(ctx context.Context, outpoint string) error {
+	now := time.Now()
+	_, err := p.pool.Exec(ctx,
+		`UPDATE utxos SET lock_owner = '', lease_expires_at = $1, updated_at = $1 WHERE outpoint = $2 AND status = $3 AND lock_owner = $4`,
+		now, outpoint, models.UTXOStatusLocked, p.owner.ID)
+	return err
+}
+
+// FindExpiredLeases returns up to limit locked UTXOs whose lease has run out
+// Locks from before leases existed count as expired once older than a lease
+func (p *PostgresStore) FindExpiredLeases(ctx context.Context, limit int) ([]*models.UTXO, error) {
+	now := time.Now()
+	utxos, err := p.queryUTXOs(ctx, `
+		SELECT `+utxoColumns+` FROM utxos
+		WHERE status = $1 AND (lease_expires_at < $2 OR (lease_expires_at IS NULL AND locked_at < $3))
+		ORDER BY lease_expires_at NULLS FIRST, locked_at, outpoint
+		LIMIT $4`,
+		models.UTXOStatusLocked, now, now.Add(-p.owner.Lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find expired leases: %w", err)
+	}
+	return utxos, nil
+}
+
+// ReclaimUTXO unlocks a UTXO returned by FindExpiredLeases unless its lock
+// was renewed, released or retaken since
+func (p *PostgresStore) ReclaimUTXO(ctx context.Context, utxo *models.UTXO) (bool, error) {
+	tag, err := p.pool.Exec(ctx, `
+		UPDATE utxos SET status = $1, locked_at = NULL, lock_owner = '', lease_expires_at = NULL, updated_at = $2
+		WHERE outpoint = $3 AND status = $4 AND lock_owner = $5
+			AND locked_at IS NOT DISTINCT FROM $6
+			AND lease_expires_at IS NOT DISTINCT FROM $7`,
+		models.UTXOStatusAvailable, time.Now(), utxo.Outpoint, models.UTXOStatusLocked, utxo.LockOwner,
+		utxo.LockedAt, utxo.LeaseExpiresAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to reclaim UTXO: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// MarkExpiredUTXOSpent marks a UTXO returned by FindExpiredLeases spent
+// unless its lock was renewed, released or retaken since
+func (p *PostgresStore) MarkExpiredUTXOSpent(ctx context.Context, utxo *models.UTXO, txid string) (bool, error) {
+	now := time.Now()
+	tag, err := p.pool.Exec(ctx, `
+		UPDATE utxos SET status = $1, spent_at = $2, updated_at = $2, lock_owner = '', lease_expires_at = NULL
+		WHERE outpoint = $3 AND status = $4 AND lock_owner = $5
+			AND locked_at IS NOT DISTINCT FROM $6
+			AND lease_expires_at IS NOT DISTINCT FROM $7`,
+		models.UTXOStatusSpent, now, utxo.Outpoint, models.UTXOStatusLocked, utxo.LockOwner,
+		utxo.LockedAt, utxo.LeaseExpiresAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark UTXO spent: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// DeferExpiredLease pushes an expired lease out to until unless its lock
+// was renewed, released or retaken since
+func (p *PostgresStore) DeferExpiredLease(ctx context.Context, utxo *models.UTXO, until time.Time) (bool, error) {
+	tag, err := p.pool.Exec(ctx, `
+		UPDATE utxos SET lease_expires_at = $1, updated_at = $2
+		WHERE outpoint = $3 AND status = $4 AND lock_owner = $5
+			AND locked_at IS NOT DISTINCT FROM $6
+			AND lease_expires_at IS NOT DISTINCT FROM $7`,
+		until, time.Now(), utxo.Outpoint, models.UTXOStatusLocked, utxo.LockOwner,
+		utxo.LockedAt, utxo.LeaseExpiresAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to defer lease: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// GetUTXOStats returns counts of UTXOs keyed "<type>_<status>"
+func (p *PostgresStore) GetUTXOStats(ctx context.Context) (map[string]int64, error) {
+	rows, err := p.pool.Query(ctx, `SELECT type, status, COUNT(*) FROM utxos GROUP BY type, status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make(map[string]int64)
+	for rows.Next() {
+		var utxoType, status string
+		var count int64
+		if err := rows.Scan(&utxoType, &status, &count); err != nil {
+			continue
+		}
+		stats[fmt.Sprintf("%s_%s", utxoType, status)] = count
+	}
+	return stats, rows.Err()
+}
+
+const requestColumns = `id, uuid, raw_tx_hex, txid, utxo_used, status, arc_status, priority,
+	error, attempts, client_id, competing_txs, republished_as, created_at, updated_at`
+
+// scanRequest reads a row selected with requestColumns
+func scanRequest(row pgx.Row) (*models.BroadcastRequest, error) {
+	var (
+		req      models.BroadcastRequest
+		id       string
+		attempts []byte
+	)
+	err := row.Scan(&id, &req.UUID, &req.RawTxHex, &req.TxID, &req.UTXOUsed, &req.Status,
+		&req.ARCStatus, &req.Priority, &req.Error, &attempts, &req.ClientID, &req.CompetingTxs,
+		&req.RepublishedAs, &req.CreatedAt, &req.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	req.ID, _ = primitive.ObjectIDFromHex(id)
+	if err := json.Unmarshal(attempts, &req.Attempts); err != nil {
+		return nil, fmt.Errorf("failed to decode attempts: %w", err)
+	}
+	if len(req.CompetingTxs) == 0 {
+		req.CompetingTxs = nil
+	}
+	return &req, nil
+}
+
+// InsertBroadcastRequest creates a new broadcast request record
+func (p *PostgresStore) InsertBroadcastRequest(ctx context.Context, req *models.BroadcastRequest) error {
+	req.CreatedAt = time.Now()
+	req.UpdatedAt = time.Now()
+	if req.ID.IsZero() {
+		req.ID = primitive.NewObjectID()
+	}
+
+	attempts, err := json.Marshal(nonNilAttempts(req.Attempts))
+	if err != nil {
+		return err
+	}
+
+	_, err = p.pool.Exec(ctx, `
+		INSERT INTO broadcast_requests (`+requestColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+		req.ID.Hex(), req.UUID, req.RawTxHex, req.TxID, req.UTXOUsed, req.Status, req.ARCStatus,
+		req.Priority, req.Error, attempts, req.ClientID, nonNilStrings(req.CompetingTxs),
+		req.RepublishedAs, req.CreatedAt, req.UpdatedAt)
+	return err
+}
+
+// UpdateRequestStatus updates the status of a broadcast request
+// Empty txid, arcStatus and errorMsg leave the stored values alone
+func (p *PostgresStore) UpdateRequestStatus(ctx context.Context, uuid string, status models.RequestStatus, txid, arcStatus, errorMsg string) error {
+	_, err := p.pool.Exec(ctx, `
+		UPDATE broadcast_requests SET
+			status = $1,
+			txid = COALESCE(NULLIF($2, ''), txid),
+			arc_status = COALESCE(NULLIF($3, ''), arc_status),
+			error = COALESCE(NULLIF($4, ''), error),
+			updated_at = $5
+		WHERE uuid = $6`,
+		status, txid, arcStatus, errorMsg, time.Now(), uuid)
+	return err
+}
+
+// AppendRequestAttempt records a failed ARC round trip on a broadcast request
+func (p *PostgresStore) AppendRequestAttempt(ctx context.Context, uuid string, attempt models.BroadcastAttempt) error {
+	entry, err := json.Marshal([]models.BroadcastAttempt{attempt})
+	if err != nil {
+		return err
+	}
+
+	_, err = p.pool.Exec(ctx,
+		`UPDATE broadcast_requests SET attempts = attempts || $1::jsonb, updated_at = $2 WHERE uuid = $3`,
+		entry, time.Now(), uuid)
+	return err
+}
+
+// GetRequestByUUID retrieves a broadcast request by UUID
+func (p *PostgresStore) GetRequestByUUID(ctx context.Context, uuid string) (*models.BroadcastRequest, error) {
+	req, err := scanRequest(p.pool.QueryRow(ctx,
+		`SELECT `+requestColumns+` FROM broadcast_requests WHERE uuid = $1`, uuid))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("request not found")
+		}
+		return nil, err
+	}
+	return req, nil
+}
+
+// GetRequestByUTXO returns the newest request that spent the outpoint, or nil
+func (p *PostgresStore) GetRequestByUTXO(ctx context.Context, outpoint string) (*models.BroadcastRequest, error) {
+	req, err := scanRequest(p.pool.QueryRow(ctx,
+		`SELECT `+requestColumns+` FROM broadcast_requests WHERE utxo_used = $1 ORDER BY created_at DESC LIMIT 1`,
+		outpoint))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return req, nil
+}
+
+// GetBroadcastRequestsSince returns the status and timestamps of requests created at or after since
+func (p *PostgresStore) GetBroadcastRequestsSince(ctx context.Context, since time.Time) ([]models.BroadcastRequest, error) {
+	rows, err := p.pool.Query(ctx,
+		`SELECT status, created_at, updated_at FROM broadcast_requests WHERE created_at >= $1 ORDER BY created_at`,
+		since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var requests []models.BroadcastRequest
+	for rows.Next() {
+		var req models.BroadcastRequest
+		if err := rows.Scan(&req.Status, &req.CreatedAt, &req.UpdatedAt); err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, rows.Err()
+}
+
+// RecordDoubleSpend stores a double-spend incident and the competing txids on its request
+func (p *PostgresStore) RecordDoubleSpend(ctx context.Context, incident *models.DoubleSpendIncident) error {
+	incident.ID = primitive.NewObjectID()
+	incident.DetectedAt = time.Now()
+	if incident.CompetingTxs == nil {
+		incident.CompetingTxs = []string{}
+	}
+
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO double_spend_incidents
+				(id, request_uuid, client_id, utxo, losing_txid, competing_txs, arc_status, extra_info, detected_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+			incident.ID.Hex(), incident.RequestUUID, incident.ClientID, incident.UTXO, incident.LosingTxID,
+			incident.CompetingTxs, incident.ARCStatus, incident.ExtraInfo, incident.DetectedAt)
+		if err != nil {
+			return fmt.Errorf("failed to insert double spend incident: %w", err)
+		}
+
+		_, err = tx.Exec(ctx,
+			`UPDATE broadcast_requests SET competing_txs = $1, updated_at = $2 WHERE uuid = $3`,
+			incident.CompetingTxs, time.Now(), incident.RequestUUID)
+		return err
+	})
+}
+
+// MarkDoubleSpendRepublished records the outcome of reissuing a double-spent payload
+func (p *PostgresStore) MarkDoubleSpendRepublished(ctx context.Context, incident *models.DoubleSpendIncident, newUUID, errorMsg string) error {
+	return pgx.BeginFunc(ctx, p.pool, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx,
+			`UPDATE double_spend_incidents SET republished = $1, republish_uuid = $2, republish_error = $3 WHERE id = $4`,
+			newUUID != "", newUUID, errorMsg, incident.ID.Hex())
+		if err != nil || newUUID == "" {
+			return err
+		}
+
+		_, err = tx.Exec(ctx,
+			`UPDATE broadcast_requests SET republished_as = $1, updated_at = $2 WHERE uuid = $3`,
+			newUUID, time.Now(), incident.RequestUUID)
+		return err
+	})
+}
+
+// ListDoubleSpendIncidents returns the newest incidents, optionally for one client
+func (p *PostgresStore) ListDoubleSpendIncidents(ctx context.Context, clientID string, limit int) ([]models.DoubleSpendIncident, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT id, request_uuid, client_id, utxo, losing_txid, competing_txs, arc_status, extra_info,
+			republished, republish_uuid, republish_error, detected_at
+		FROM double_spend_incidents
+		WHERE $1 = '' OR client_id = $1
+		ORDER BY detected_at DESC
+		LIMIT $2`,
+		clientID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	incidents := []models.DoubleSpendIncident{}
+	for rows.Next() {
+		var incident models.DoubleSpendIncident
+		var id string
+		err := rows.Scan(&id, &incident.RequestUUID, &incident.ClientID, &incident.UTXO, &incident.LosingTxID,
+			&incident.CompetingTxs, &incident.ARCStatus, &incident.ExtraInfo, &incident.Republished,
+			&incident.RepublishUUID, &incident.RepublishError, &incident.DetectedAt)
+		if err != nil {
+			return nil, err
+		}
+		incident.ID, _ = primitive.ObjectIDFromHex(id)
+		incidents = append(incidents, incident)
+	}
+	return incidents, rows.Err()
+}
+
+const clientColumns = `id, name, api_key_hash, public_key, old_public_key, key_rotated_at,
+	grace_period_hours, tier, require_signature, allowed_ips, is_active, site_origin,
+	max_daily_tx, max_in_flight, auto_republish, tx_count, last_reset_date, created_at, updated_at,
+	max_per_second, max_per_minute`
+
+// scanClient reads a row selected with clientColumns
+func scanClient(row pgx.Row) (*models.Client, error) {
+	var client models.Client
+	var id string
+	err := row.Scan(&id, &client.Name, &client.APIKeyHash, &client.PublicKey, &client.OldPublicKey,
+		&client.KeyRotatedAt, &client.GracePeriodHours, &client.Tier, &client.RequireSignature,
+		&client.AllowedIPs, &client.IsActive, &client.SiteOrigin, &client.MaxDailyTx, &client.MaxInFlight,
+		&client.AutoRepublish, &client.TxCount, &client.LastResetDate, &client.CreatedAt, &client.UpdatedAt,
+		&client.MaxPerSecond, &client.MaxPerMinute)
+	if err != nil {
+		return nil, err
+	}
+
+	client.ID, _ = primitive.ObjectIDFromHex(id)
+	if len(client.AllowedIPs) == 0 {
+		client.AllowedIPs = nil
+	}
+	return &client, nil
+}
+
+// CreateClient creates a new API client
+func (p *PostgresStore) CreateClient(ctx context.Context, client *models.Client) error {
+	if client.ID.IsZero() {
+		client.ID = primitive.NewObjectID()
+	}
+
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO clients (`+clientColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)`,
+		client.ID.Hex(), client.Name, client.APIKeyHash, client.PublicKey, client.OldPublicKey,
+		client.KeyRotatedAt, client.GracePeriodHours, client.Tier, client.RequireSignature,
+		nonNilStrings(client.AllowedIPs), client.IsActive, client.SiteOrigin, client.MaxDailyTx,
+		client.MaxInFlight, client.AutoRepublish, client.TxCount, client.LastResetDate,
+		client.CreatedAt, client.UpdatedAt, client.MaxPerSecond, client.MaxPerMinute)
+	return err
+}
+
+// getClient fetches one client by an indexed column
+func (p *PostgresStore) getClient(ctx context.Context, column string, value interface{}, notFound string) (*models.Client, error) {
+	client, err := scanClient(p.pool.QueryRow(ctx,
+		`SELECT `+clientColumns+` FROM clients WHERE `+column+` = $1`, value))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errors.New(notFound)
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+// GetClientByID retrieves a client by ID
+func (p *PostgresStore) GetClientByID(ctx context.Context, clientID primitive.ObjectID) (*models.Client, error) {
+	return p.getClient(ctx, "id", clientID.Hex(), "client not found")
+}
+
+// GetClientByAPIKey retrieves a client by their API key hash
+func (p *PostgresStore) GetClientByAPIKey(ctx context.Context, apiKeyHash string) (*models.Client, error) {
+	return p.getClient(ctx, "api_key_hash", apiKeyHash, "client not found")
+}
+
+// GetClientByAPIKeyHash retrieves a client by their hashed API key
+func (p *PostgresStore) GetClientByAPIKeyHash(ctx context.Context, apiKeyHash string) (*models.Client, error) {
+	return p.getClient(ctx, "api_key_hash", apiKeyHash, "invalid API key")
+}
+
+// GetClientByPublicKey retrieves a client by their current public key
+func (p *PostgresStore) GetClientByPublicKey(ctx context.Context, publicKey string) (*models.Client, error) {
+	return p.getClient(ctx, "public_key", publicKey, "client not found")
+}
+
+// ListClients returns all registered clients, oldest first
+func (p *PostgresStore) ListClients(ctx context.Context) ([]*models.Client, error) {
+	rows, err := p.pool.Query(ctx, `SELECT `+clientColumns+` FROM clients ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clients []*models.Client
+	for rows.Next() {
+		client, err := scanClient(rows)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, rows.Err()
+}
+
+// ReserveClientTx counts a transaction against the client's daily quota in
+// one conditional update, so concurrent requests can't overshoot it
+func (p *PostgresStore) ReserveClientTx(ctx context.Context, clientID interface{}, today string, maxDailyTx int) (int, error) {
+	id, ok := clientID.(primitive.ObjectID)
+	if !ok {
+		return 0, fmt.Errorf("client ID must be a primitive.ObjectID, got %T", clientID)
+	}
+
+	var count int
+	err := p.pool.QueryRow(ctx, `
+		UPDATE clients SET
+			tx_count = CASE WHEN last_reset_date = $2 THEN tx_count + 1 ELSE 1 END,
+			last_reset_date = $2, updated_at = $4
+		WHERE id = $1 AND CASE WHEN last_reset_date = $2 THEN tx_count ELSE 0 END < $3
+		RETURNING tx_count`,
+		id.Hex(), today, maxDailyTx, time.Now()).Scan(&count)
+	if err == nil {
+		return count, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, err
+	}
+
+	// Either the quota is used up or there is no such client
+	var found bool
+	if err := p.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM clients WHERE id = $1)`, id.Hex()).Scan(&found); err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("client not found")
+	}
+	return 0, ErrQuotaExceeded
+}
+
+// RefundClientTx gives back a transaction counted on day
+func (p *PostgresStore) RefundClientTx(ctx context.Context, clientID interface{}, day string) error {
+	id, ok := clientID.(primitive.ObjectID)
+	if !ok {
+		return fmt.Errorf("client ID must be a primitive.ObjectID, got %T", clientID)
+	}
+
+	_, err := p.pool.Exec(ctx, `
+		UPDATE clients SET tx_count = tx_count - 1, updated_at = $3
+		WHERE id = $1 AND last_reset_date = $2 AND tx_count > 0`,
+		id.Hex(), day, time.Now())
+	return err
+}
+
+// UpdateClientStatus activates or deactivates a client
+func (p *PostgresStore) UpdateClientStatus(ctx context.Context, clientID interface{}, isActive bool) error {
+	return p.updateClient(ctx, clientID, `is_active = $2`, isActive)
+}
+
+// BindPublicKeyToClient sets a client's public key if none is registered yet
+func (p *PostgresStore) BindPublicKeyToClient(ctx context.Context, apiKeyHash, publicKey string) error {
+	tag, err := p.pool.Exec(ctx, `
+		UPDATE clients SET public_key = $1, require_signature = TRUE, updated_at = $2
+		WHERE api_key_hash = $3 AND public_key = ''`,
+		publicKey, time.Now(), apiKeyHash)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("client not found or public key already registered")
+	}
+	return nil
+}
+
+// RotateClientPublicKey replaces a client's public key, keeping the old one for the grace period
+func (p *PostgresStore) RotateClientPublicKey(ctx context.Context, apiKeyHash, newPublicKey string) error {
+	now := time.Now()
+	tag, err := p.pool.Exec(ctx, `
+		UPDATE clients SET old_public_key = public_key, public_key = $1, key_rotated_at = $2, updated_at = $2
+		WHERE api_key_hash = $3`,
+		newPublicKey, now, apiKeyHash)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("client not found: %s", apiKeyHash)
+	}
+	return nil
+}
+
+// UpdateClientSecurity updates a client's security tier and settings
+func (p *PostgresStore) UpdateClientSecurity(ctx context.Context, clientID interface{}, tier string, requireSignature bool, allowedIPs []string, gracePeriodHours int) error {
+	return p.updateClient(ctx, clientID,
+		`tier = $2, require_signature = $3, allowed_ips = $4, grace_period_hours = $5`,
+		tier, requireSignature, nonNilStrings(allowedIPs), gracePeriodHours)
+}
+
+// UpdateClientLimits updates a client's daily quota, in-flight cap and rate limits
+func (p *PostgresStore) UpdateClientLimits(ctx context.Context, clientID interface{}, maxDailyTx, maxInFlight, maxPerSecond, maxPerMinute int) error {
+	return p.updateClient(ctx, clientID, `max_daily_tx = $2, max_in_flight = $3, max_per_second = $4, max_per_minute = $5`,
+		maxDailyTx, maxInFlight, maxPerSecond, maxPerMinute)
+}
+
+// UpdateClientAutoRepublish sets whether a client's double-spent payloads are reissued
+func (p *PostgresStore) UpdateClientAutoRepublish(ctx context.Context, clientID interface{}, autoRepublish bool) error {
+	return p.updateClient(ctx, clientID, `auto_republish = $2`, autoRepublish)
+}
+
+// updateClient applies a SET clause to one client; $1 is the client ID and
+// the clause's own parameters start at $2
+func (p *PostgresStore) updateClient(ctx context.Context, clientID interface{}, set string, args ...interface{}) error {
+	id, ok := clientID.(primitive.ObjectID)
+	if !ok {
+		return fmt.Errorf("client ID must be a primitive.ObjectID, got %T", clientID)
+	}
+
+	args = append([]interface{}{id.Hex()}, args...)
+	args = append(args, time.Now())
+	sql := fmt.Sprintf(`UPDATE clients SET %s, updated_at = $%d WHERE id = $1`, set, len(args))
+
+	tag, err := p.pool.Exec(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("client not found")
+	}
+	return nil
+}
+
+// AcquireLeadership takes or renews the named lease for holder
+// The conflict update only applies to a lease holder already has or one that
+// has run out, so no row is affected while another holder's lease is live
+func (p *PostgresStore) AcquireLeadership(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	tag, err := p.pool.Exec(ctx, `
+		INSERT INTO leader_leases (name, holder, expires_at, renewed_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE SET holder = EXCLUDED.holder, expires_at = EXCLUDED.expires_at, renewed_at = EXCLUDED.renewed_at
+		WHERE leader_leases.holder = EXCLUDED.holder OR leader_leases.expires_at < EXCLUDED.renewed_at`,
+		name, holder, now.Add(ttl), now)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire leadership: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// ReleaseLeadership gives up the named lease if holder has it
+func (p *PostgresStore) ReleaseLeadership(ctx context.Context, name, holder string) error {
+	_, err := p.pool.Exec(ctx, `DELETE FROM leader_leases WHERE name = $1 AND holder = $2`, name, holder)
+	return err
+}
+
+// GetLeaderLease returns the named lease, or nil if nobody has taken it
+func (p *PostgresStore) GetLeaderLease(ctx context.Context, name string) (*models.LeaderLease, error) {
+	var lease models.LeaderLease
+	err := p.pool.QueryRow(ctx,
+		`SELECT name, holder, expires_at, renewed_at FROM leader_leases WHERE name = $1`, name,
+	).Scan(&lease.Name, &lease.Holder, &lease.ExpiresAt, &lease.RenewedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// CreateAdminAccount creates a named admin account
+func (p *PostgresStore) CreateAdminAccount(ctx context.Context, account *models.AdminAccount) error {
+	tag, err := p.pool.Exec(ctx, `
+		INSERT INTO admin_accounts
+			(username, password_hash, role, is_active, password_changed_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (username) DO NOTHING`,
+		account.Username, account.PasswordHash, account.Role, account.IsActive,
+		account.PasswordChangedAt, account.CreatedAt, account.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("admin account %q already exists", account.Username)
+	}
+	return nil
+}
+
+const adminAccountColumns = `username, password_hash, role, is_active, password_changed_at, created_at, updated_at`
+
+// scanAdminAccount reads a row selected with adminAccountColumns
+func scanAdminAccount(row pgx.Row) (*models.AdminAccount, error) {
+	var account models.AdminAccount
+	err := row.Scan(&account.Username, &account.PasswordHash, &account.Role, &account.IsActive,
+		&account.PasswordChangedAt, &account.CreatedAt, &account.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// GetAdminAccount returns the named admin account, or nil
+func (p *PostgresStore) GetAdminAccount(ctx context.Context, username string) (*models.AdminAccount, error) {
+	account, err := scanAdminAccount(p.pool.QueryRow(ctx,
+		`SELECT `+adminAccountColumns+` FROM admin_accounts WHERE username = $1`, username))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return account, err
+}
+
+// ListAdminAccounts returns every admin account by username
+func (p *PostgresStore) ListAdminAccounts(ctx context.Context) ([]*models.AdminAccount, error) {
+	rows, err := p.pool.Query(ctx, `SELECT `+adminAccountColumns+` FROM admin_accounts ORDER BY username`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []*models.AdminAccount{}
+	for rows.Next() {
+		account, err := scanAdminAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// UpdateAdminAccount saves an existing account's role, status and password
+func (p *PostgresStore) UpdateAdminAccount(ctx context.Context, account *models.AdminAccount) error {
+	tag, err := p.pool.Exec(ctx, `
+		UPDATE admin_accounts
+		SET password_hash = $1, role = $2, is_active = $3, password_changed_at = $4, updated_at = $5
+		WHERE username = $6`,
+		account.PasswordHash, account.Role, account.IsActive, account.PasswordChangedAt,
+		account.UpdatedAt, account.Username)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("admin account not found")
+	}
+	return nil
+}
+
+// AppendAuditEntry adds an entry to the audit log
+func (p *PostgresStore) AppendAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	entry.ID = primitive.NewObjectID()
+	if entry.At.IsZero() {
+		entry.At = time.Now()
+	}
+
+	details, err := json.Marshal(nonNilDetails(entry.Details))
+	if err != nil {
+		return err
+	}
+
+	tag, err := p.pool.Exec(ctx, `
+		INSERT INTO audit_log (id, seq, prev_hash, hash, at, actor, role, action, target, status, remote_ip, details)
+		VALUES ($1, NULLIF($2::bigint, 0), $3, $4, $5, $6, $7, $8, $9, $10, $11, $12::jsonb)
+		ON CONFLICT (seq) DO NOTHING`,
+		entry.ID.Hex(), entry.Seq, entry.PrevHash, entry.Hash, entry.At, entry.Actor, entry.Role,
+		entry.Action, entry.Target, entry.Status, entry.RemoteIP, details)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAuditSeqTaken
+	}
+	return nil
+}
+
+// auditEntryColumns are scanned by scanAuditEntries, in order
+const auditEntryColumns = `id, COALESCE(seq, 0), prev_hash, hash, at, actor, role, action, target, status, remote_ip, details`
+
+// ListAuditEntries returns the newest audit entries, optionally for one actor
+func (p *PostgresStore) ListAuditEntries(ctx context.Context, actor string, limit int) ([]models.AuditEntry, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT `+auditEntryColumns+`
+		FROM audit_log
+		WHERE $1 = '' OR actor = $1
+		ORDER BY at DESC, id DESC
+		LIMIT $2`,
+		actor, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanAuditEntries(rows)
+}
+
+// LastAuditEntry returns the chained audit entry with the highest Seq
+func (p *PostgresStore) LastAuditEntry(ctx context.Context) (*models.AuditEntry, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT `+auditEntryColumns+`
+		FROM audit_log
+		WHERE seq IS NOT NULL
+		ORDER BY seq DESC
+		LIMIT 1`)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := scanAuditEntries(rows)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// ListAuditChain returns chained audit entries after afterSeq in Seq order
+func (p *PostgresStore) ListAuditChain(ctx context.Context, afterSeq int64, limit int) ([]models.AuditEntry, error) {
+	rows, err := p.pool.Query(ctx, `
+		SELECT `+auditEntryColumns+`
+		FROM audit_log
+		WHERE seq > $1
+		ORDER BY seq
+		LIMIT $2`,
+		afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanAuditEntries(rows)
+}
+
+// scanAuditEntries reads auditEntryColumns rows and closes them
+func scanAuditEntries(rows pgx.Rows) ([]models.AuditEntry, error) {
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var id string
+		var details []byte
+		err := rows.Scan(&id, &entry.Seq, &entry.PrevHash, &entry.Hash, &entry.At, &entry.Actor, &entry.Role,
+			&entry.Action, &entry.Target, &entry.Status, &entry.RemoteIP, &details)
+		if err != nil {
+			return nil, err
+		}
+		entry.ID, _ = primitive.ObjectIDFromHex(id)
+		if err := json.Unmarshal(details, &entry.Details); err != nil {
+			return nil, fmt.Errorf("failed to decode audit entry %s details: %w", id, err)
+		}
+		if len(entry.Details) == 0 {
+			entry.Details = nil
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// UseNonce records a client's nonce, reporting false if it is still live
+func (p *PostgresStore) UseNonce(ctx context.Context, clientID, nonce string, expiresAt time.Time) (bool, error) {
+	tag, err := p.pool.Exec(ctx, `
+		INSERT INTO used_nonces (client_id, nonce, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (client_id, nonce) DO UPDATE SET expires_at = EXCLUDED.expires_at
+		WHERE used_nonces.expires_at <= now()`,
+		clientID, nonce, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to record nonce: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// PurgeExpiredNonces deletes expired nonces
+func (p *PostgresStore) PurgeExpiredNonces(ctx context.Context) (int64, error) {
+	tag, err := p.pool.Exec(ctx, `DELETE FROM used_nonces WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge nonces: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// SaveAuthSession creates or replaces a mutual authentication session
+func (p *PostgresStore) SaveAuthSession(ctx context.Context, session *models.AuthSession) error {
+	_, err := p.pool.Exec(ctx, `
+		INSERT INTO auth_sessions (session_nonce, peer_nonce, identity_key, client_id, authenticated, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (session_nonce) DO UPDATE SET
+			peer_nonce = EXCLUDED.peer_nonce, identity_key = EXCLUDED.identity_key,
+			client_id = EXCLUDED.client_id, authenticated = EXCLUDED.authenticated,
+			created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at`,
+		session.SessionNonce, session.PeerNonce, session.IdentityKey, session.ClientID,
+		session.Authenticated, session.CreatedAt, session.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to save auth session: %w", err)
+	}
+	return nil
+}
+
+// GetAuthSession returns the unexpired session with this nonce, or nil
+func (p *PostgresStore) GetAuthSession(ctx context.Context, sessionNonce string) (*models.AuthSession, error) {
+	var session models.AuthSession
+	err := p.pool.QueryRow(ctx, `
+		SELECT session_nonce, peer_nonce, identity_key, client_id, authenticated, created_at, expires_at
+		FROM auth_sessions WHERE session_nonce = $1 AND expires_at > now()`, sessionNonce).
+		Scan(&session.SessionNonce, &session.PeerNonce, &session.IdentityKey, &session.ClientID,
+			&session.Authenticated, &session.CreatedAt, &session.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// PurgeExpiredAuthSessions deletes expired sessions
+func (p *PostgresStore) PurgeExpiredAuthSessions(ctx context.Context) (int64, error) {
+	tag, err := p.pool.Exec(ctx, `DELETE FROM auth_sessions WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge auth sessions: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// IncrementRateCounter adds one to the counter at key unless it has reached
+// limit; an expired counter starts over
+func (p *PostgresStore) IncrementRateCounter(ctx context.Context, key string, limit int, expiresAt time.Time) (int, error) {
+	var count int
+	err := p.pool.QueryRow(ctx, `
+		INSERT INTO rate_counters (key, count, expires_at)
+		SELECT $1, 1, $3 WHERE $2 > 0
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN rate_counters.expires_at <= now() THEN 1 ELSE rate_counters.count + 1 END,
+			expires_at = CASE WHEN rate_counters.expires_at <= now() THEN EXCLUDED.expires_at ELSE rate_counters.expires_at END
+		WHERE rate_counters.expires_at <= now() OR rate_counters.count < $2
+		RETURNING count`,
+		key, limit, expiresAt).Scan(&count)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrQuotaExceeded
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to count request: %w", err)
+	}
+	return count, nil
+}
+
+// GetRateCounter returns the count at key, 0 if there is none
+func (p *PostgresStore) GetRateCounter(ctx context.Context, key string) (int, error) {
+	var count int
+	err := p.pool.QueryRow(ctx, `SELECT count FROM rate_counters WHERE key = $1 AND expires_at > now()`, key).Scan(&count)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read rate counter: %w", err)
+	}
+	return count, nil
+}
+
+// DecrementRateCounter takes one off the count at key, if above 0
+func (p *PostgresStore) DecrementRateCounter(ctx context.Context, key string) error {
+	_, err := p.pool.Exec(ctx, `UPDATE rate_counters SET count = count - 1 WHERE key = $1 AND count > 0`, key)
+	if err != nil {
+		return fmt.Errorf("failed to refund rate counter: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpiredRateCounters deletes expired counters
+func (p *PostgresStore) PurgeExpiredRateCounters(ctx context.Context) (int64, error) {
+	tag, err := p.pool.Exec(ctx, `DELETE FROM rate_counters WHERE expires_at <= now()`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge rate counters: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Close closes the connection pool
+func (p *PostgresStore) Close(ctx context.Context) error {
+	p.pool.Close()
+	return nil
+}
+
+func nonNilDetails(details map[string]string) map[string]string {
+	if details == nil {
+		return map[string]string{}
+	}
+	return details
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func nonNilAttempts(a []models.BroadcastAttempt) []models.BroadcastAttempt {
+	if a == nil {
+		return []models.BroadcastAttempt{}
+	}
+	return a
+}
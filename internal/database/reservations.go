@@ -0,0 +1,157 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/logging"
+	"github.com/akua/bsv-broadcaster/internal/models"
+)
+
+// ReservationCache hands out UTXOs of one type from a block locked in bulk,
+// so the publish path costs one store round trip per batchSize requests
+// instead of one per request
+//
+// Reserved UTXOs are leased from the moment the block is taken, and a
+// reservation is only handed out with at least half a lease left, so whoever
+// receives it has time to broadcast. When one gets closer than that to
+// lapsing the cache renews the owner's leases itself, alongside the janitor's
+// renewals, rather than wasting the block. Reservations whose lease already
+// lapsed, or that can't be renewed, give up their lease and the janitor
+// reclaims them - unlocking them here could race a janitor that already has.
+type ReservationCache struct {
+	store     UTXOStore
+	utxoType  models.UTXOType
+	batchSize int
+	lease     time.Duration // The store owner's lease length
+
+	mu       sync.Mutex
+	reserved []*models.UTXO // Oldest first
+	closed   bool
+}
+
+// NewReservationCache creates a cache that locks batchSize UTXOs of utxoType
+// at a time; lease must match the store's LockOwner
+func NewReservationCache(store UTXOStore, utxoType models.UTXOType, batchSize int, lease time.Duration) *ReservationCache {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	return &ReservationCache{
+		store:     store,
+		utxoType:  utxoType,
+		batchSize: batchSize,
+		lease:     lease,
+	}
+}
+
+// FindAndLockUTXO hands out the oldest reservation, locking a new block when
+// none are left; other types go straight to the store
+func (r *ReservationCache) FindAndLockUTXO(ctx context.Context, utxoType models.UTXOType) (*models.UTXO, error) {
+	if utxoType != r.utxoType {
+		return r.store.FindAndLockUTXO(ctx, utxoType)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, fmt.Errorf("UTXO reservations released")
+	}
+
+	r.renewLeases(ctx)
+	if len(r.reserved) == 0 {
+		utxos, err := r.store.FindAndLockBatch(ctx, utxoType, r.batchSize)
+		if err != nil {
+			return nil, err
+		}
+		r.reserved = utxos
+	}
+
+	utxo := r.reserved[0]
+	r.reserved[0] = nil
+	r.reserved = r.reserved[1:]
+	return utxo, nil
+}
+
+// renewLeases keeps every reservation at least half a lease from lapsing,
+// renewing them in one round trip once any gets closer (caller holds mu)
+func (r *ReservationCache) renewLeases(ctx context.Context) {
+	now := time.Now()
+	renewBy := now.Add(r.lease / 2)
+
+	lapsing := false
+	for _, utxo := range r.reserved {
+		if utxo.LeaseExpiresAt != nil && utxo.LeaseExpiresAt.Before(renewBy) {
+			lapsing = true
+			break
+		}
+	}
+	if !lapsing {
+		return
+	}
+
+	if _, err := r.store.RenewLeases(ctx); err != nil {
+		slog.Warn("Failed to renew UTXO reservations", "type", r.utxoType, logging.Err(err))
+		r.drop(ctx, renewBy)
+		return
+	}
+
+	// A lease that lapsed before the renewal may already be the janitor's,
+	// so only those still running were extended
+	r.drop(ctx, now)
+	for _, utxo := range r.reserved {
+		expires := now.Add(r.lease)
+		utxo.LeaseExpiresAt = &expires
+	}
+}
+
+// drop discards reservations whose lease lapses before cutoff, leaving them
+// to the janitor (caller holds mu)
+func (r *ReservationCache) drop(ctx context.Context, cutoff time.Time) {
+	kept := r.reserved[:0]
+	dropped := 0
+	for _, utxo := range r.reserved {
+		if utxo.LeaseExpiresAt != nil && utxo.LeaseExpiresAt.Before(cutoff) {
+			r.store.ExpireLease(ctx, utxo.Outpoint)
+			dropped++
+			continue
+		}
+		kept = append(kept, utxo)
+	}
+	r.reserved = kept
+
+	if dropped > 0 {
+		slog.Warn("Dropped lapsing UTXO reservations, janitor will reclaim them", "count", dropped, "type", r.utxoType)
+	}
+}
+
+// Len returns how many reservations are waiting to be handed out
+func (r *ReservationCache) Len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.reserved)
+}
+
+// Release unlocks every unexpired reservation and stops handing out more
+// Call it on shutdown once nothing else can ask for a UTXO
+func (r *ReservationCache) Release(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+	r.drop(ctx, time.Now())
+
+	released := 0
+	for _, utxo := range r.reserved {
+		if err := r.store.UnlockUTXO(ctx, utxo.Outpoint); err != nil {
+			r.reserved = r.reserved[released:]
+			return released, fmt.Errorf("failed to release %s: %w", utxo.Outpoint, err)
+		}
+		released++
+	}
+	r.reserved = nil
+	return released, nil
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/models"
+)
+
+const testLease = 80 * time.Millisecond
+
+// newTestReservations is a cache of 3 over a memory store holding count
+// publishing UTXOs
+func newTestReservations(t *testing.T, count int) (*ReservationCache, *MemoryStore) {
+	t.Helper()
+
+	db := NewMemoryStore()
+	db.SetLockOwner(NewLockOwner(testLease))
+	for i := 1; i <= count; i++ {
+		txid := fmt.Sprintf("%064x", i)
+		err := db.InsertUTXO(context.Background(), &models.UTXO{
+			Outpoint: txid + ":0",
+			TxID:     txid,
+			Satoshis: 100,
+			Status:   models.UTXOStatusAvailable,
+			Type:     models.UTXOTypePublishing,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewReservationCache(db, models.UTXOTypePublishing, 3, testLease), db
+}
+
+func take(t *testing.T, r *ReservationCache) *models.UTXO {
+	t.Helper()
+
+	utxo, err := r.FindAndLockUTXO(context.Background(), models.UTXOTypePublishing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return utxo
+}
+
+func stored(t *testing.T, db *MemoryStore, outpoint string) *models.UTXO {
+	t.Helper()
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	utxo, ok := db.utxos[outpoint]
+	if !ok {
+		t.Fatalf("UTXO %s not stored", outpoint)
+	}
+	return copyUTXO(utxo)
+}
+
+func TestReservationCacheRenewsLeasesBeforeHandingOut(t *testing.T) {
+	r, db := newTestReservations(t, 6)
+	first := take(t, r)
+
+	// Past half the lease but not lapsed: renewed, not dropped
+	time.Sleep(testLease * 5 / 8)
+	second := take(t, r)
+	if second.TxID != fmt.Sprintf("%064x", 2) {
+		t.Fatalf("second reservation = %s, want the next one from the first block", second.Outpoint)
+	}
+	if r.Len() != 1 {
+		t.Fatalf("%d reservations left, want 1", r.Len())
+	}
+
+	row := stored(t, db, second.Outpoint)
+	if row.Status != models.UTXOStatusLocked || row.LockOwner != db.owner.ID {
+		t.Fatalf("stored reservation = %s by %q, want locked by us", row.Status, row.LockOwner)
+	}
+	if time.Until(*row.LeaseExpiresAt) < testLease/2 {
+		t.Fatalf("lease expires in %v, want it renewed", time.Until(*row.LeaseExpiresAt))
+	}
+	if row := stored(t, db, first.Outpoint); time.Until(*row.LeaseExpiresAt) < testLease/2 {
+		t.Fatal("renewal missed a UTXO already handed out")
+	}
+}
+
+func TestReservationCacheDropsLapsedLeases(t *testing.T) {
+	r, db := newTestReservations(t, 6)
+	first := take(t, r)
+
+	// Renewal stalled long enough for the rest of the block to lapse
+	time.Sleep(testLease + 20*time.Millisecond)
+	next := take(t, r)
+	if next.TxID != fmt.Sprintf("%064x", 4) {
+		t.Fatalf("reservation after lapse = %s, want one from a new block", next.Outpoint)
+	}
+
+	for i := 2; i <= 3; i++ {
+		row := stored(t, db, fmt.Sprintf("%064x:0", i))
+		if row.Status != models.UTXOStatusLocked || row.LockOwner != "" {
+			t.Fatalf("dropped reservation = %s by %q, want a lease left for the janitor", row.Status, row.LockOwner)
+		}
+	}
+	if row := stored(t, db, first.Outpoint); row.LockOwner != db.owner.ID {
+		t.Fatal("dropping reservations touched one already handed out")
+	}
+}
+
+func TestReservationCacheReleaseUnlocksReservations(t *testing.T) {
+	r, db := newTestReservations(t, 3)
+	take(t, r)
+
+	released, err := r.Release(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released != 2 {
+		t.Fatalf("released %d, want 2", released)
+	}
+	for i := 2; i <= 3; i++ {
+		if row := stored(t, db, fmt.Sprintf("%064x:0", i)); row.Status != models.UTXOStatusAvailable {
+			t.Fatalf("released reservation is %s, want available", row.Status)
+		}
+	}
+	if _, err := r.FindAndLockUTXO(context.Background(), models.UTXOTypePublishing); err == nil {
+		t.Fatal("cache handed out a UTXO after Release")
+	}
+}
@@ -0,0 +1,214 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UTXOStore tracks the UTXO pool and its available -> locked -> spent lifecycle
+// Locks are leases held by the store's LockOwner (see lease.go)
+type UTXOStore interface {
+	SetLockOwner(owner LockOwner)
+	// FindAndLockUTXO atomically claims the oldest available UTXO of a type;
+	// concurrent callers never receive the same UTXO
+	FindAndLockUTXO(ctx context.Context, utxoType models.UTXOType) (*models.UTXO, error)
+	FindAndLockBatch(ctx context.Context, utxoType models.UTXOType, count int) ([]*models.UTXO, error)
+	FindUTXOsByType(ctx context.Context, utxoType models.UTXOType, status models.UTXOStatus) ([]*models.UTXO, error)
+	GetAvailableUTXOs(ctx context.Context, utxoType models.UTXOType, limit int) ([]*models.UTXO, error)
+	// MarkUTXOSpent marks a UTXO spent; a locked one only while this store's
+	// owner holds the lock, failing with ErrLockNotHeld otherwise
+	MarkUTXOSpent(ctx context.Context, outpoint string, txid string) error
+	LockUTXO(ctx context.Context, outpoint string) error
+	// UnlockUTXO releases a lock this store's owner holds, failing with
+	// ErrLockNotHeld if it gave the lock up or lost it to the janitor
+	UnlockUTXO(ctx context.Context, outpoint string) error
+	InsertUTXO(ctx context.Context, utxo *models.UTXO) error
+	UpsertUTXO(ctx context.Context, utxo *models.UTXO) error
+	// RenewLeases extends every lease held by this store's owner
+	RenewLeases(ctx context.Context) (int64, error)
+	// ExpireLease gives up a lock this owner holds without unlocking it,
+	// leaving the UTXO for the janitor to reconcile against ARC
+	ExpireLease(ctx context.Context, outpoint string) error
+	// FindExpiredLeases returns up to limit expired leases, longest expired first
+	FindExpiredLeases(ctx context.Context, limit int) ([]*models.UTXO, error)
+	// ReclaimUTXO unlocks a UTXO returned by FindExpiredLeases, unless its
+	// lock changed since; reports whether it did
+	ReclaimUTXO(ctx context.Context, utxo *models.UTXO) (bool, error)
+	// MarkExpiredUTXOSpent marks a UTXO returned by FindExpiredLeases spent,
+	// unless its lock changed since; reports whether it did
+	MarkExpiredUTXOSpent(ctx context.Context, utxo *models.UTXO, txid string) (bool, error)
+	// DeferExpiredLease pushes the expiry of a lease returned by
+	// FindExpiredLeases out to until, unless its lock changed since, so
+	// FindExpiredLeases moves past it; reports whether it did
+	DeferExpiredLease(ctx context.Context, utxo *models.UTXO, until time.Time) (bool, error)
+	GetUTXOStats(ctx context.Context) (map[string]int64, error)
+}
+
+// ErrLockNotHeld means a UTXO's lock belongs to another owner, or to none
+var ErrLockNotHeld = errors.New("UTXO lock not held")
+
+// RequestStore tracks publish requests and what happened to them on the way to ARC
+type RequestStore interface {
+	InsertBroadcastRequest(ctx context.Context, req *models.BroadcastRequest) error
+	UpdateRequestStatus(ctx context.Context, uuid string, status models.RequestStatus, txid, arcStatus, errorMsg string) error
+	AppendRequestAttempt(ctx context.Context, uuid string, attempt models.BroadcastAttempt) error
+	GetRequestByUUID(ctx context.Context, uuid string) (*models.BroadcastRequest, error)
+	// GetRequestByUTXO returns the newest request that spent the outpoint, or nil
+	GetRequestByUTXO(ctx context.Context, outpoint string) (*models.BroadcastRequest, error)
+	GetBroadcastRequestsSince(ctx context.Context, since time.Time) ([]models.BroadcastRequest, error)
+	RecordDoubleSpend(ctx context.Context, incident *models.DoubleSpendIncident) error
+	MarkDoubleSpendRepublished(ctx context.Context, incident *models.DoubleSpendIncident, newUUID, errorMsg string) error
+	ListDoubleSpendIncidents(ctx context.Context, clientID string, limit int) ([]models.DoubleSpendIncident, error)
+}
+
+// ClientStore holds API clients, their keys, tiers and quotas
+// Client IDs are primitive.ObjectIDs
+type ClientStore interface {
+	CreateClient(ctx context.Context, client *models.Client) error
+	GetClientByID(ctx context.Context, clientID primitive.ObjectID) (*models.Client, error)
+	GetClientByAPIKey(ctx context.Context, apiKeyHash string) (*models.Client, error)
+	GetClientByAPIKeyHash(ctx context.Context, apiKeyHash string) (*models.Client, error)
+	// GetClientByPublicKey finds the client whose current key is publicKey,
+	// as uncompressed lower-case hex
+	GetClientByPublicKey(ctx context.Context, publicKey string) (*models.Client, error)
+	ListClients(ctx context.Context) ([]*models.Client, error)
+	// ReserveClientTx atomically counts a transaction against the client's
+	// daily quota, starting a new count on a new day, and returns the day's
+	// count; it fails with ErrQuotaExceeded, counting nothing, once
+	// maxDailyTx transactions were counted on today
+	ReserveClientTx(ctx context.Context, clientID interface{}, today string, maxDailyTx int) (int, error)
+	// RefundClientTx gives back a transaction counted on day; it does nothing
+	// once a new day's count has started
+	RefundClientTx(ctx context.Context, clientID interface{}, day string) error
+	UpdateClientStatus(ctx context.Context, clientID interface{}, isActive bool) error
+	BindPublicKeyToClient(ctx context.Context, apiKeyHash, publicKey string) error
+	RotateClientPublicKey(ctx context.Context, apiKeyHash, newPublicKey string) error
+	UpdateClientSecurity(ctx context.Context, clientID interface{}, tier string, requireSignature bool, allowedIPs []string, gracePeriodHours int) error
+	UpdateClientLimits(ctx context.Context, clientID interface{}, maxDailyTx, maxInFlight, maxPerSecond, maxPerMinute int) error
+	UpdateClientAutoRepublish(ctx context.Context, clientID interface{}, autoRepublish bool) error
+}
+
+// LeaderStore backs leader election with named leases
+type LeaderStore interface {
+	// AcquireLeadership takes or renews the named lease for holder unless
+	// another holder's lease is still live; reports whether holder has it
+	AcquireLeadership(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	// ReleaseLeadership gives up the named lease if holder has it
+	ReleaseLeadership(ctx context.Context, name, holder string) error
+	// GetLeaderLease returns the named lease, or nil if nobody has taken it
+	GetLeaderLease(ctx context.Context, name string) (*models.LeaderLease, error)
+}
+
+// AdminStore holds named admin accounts and the audit log of their actions
+// Audit entries are append-only: nothing updates or deletes them
+type AdminStore interface {
+	// CreateAdminAccount fails if the username is taken
+	CreateAdminAccount(ctx context.Context, account *models.AdminAccount) error
+	// GetAdminAccount returns the account, or nil if there is none
+	GetAdminAccount(ctx context.Context, username string) (*models.AdminAccount, error)
+	ListAdminAccounts(ctx context.Context) ([]*models.AdminAccount, error)
+	// UpdateAdminAccount saves an existing account's role, status and password
+	UpdateAdminAccount(ctx context.Context, account *models.AdminAccount) error
+	// AppendAuditEntry fails with ErrAuditSeqTaken if entry.Seq is in the log
+	AppendAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+	// ListAuditEntries returns the newest entries, optionally for one actor
+	ListAuditEntries(ctx context.Context, actor string, limit int) ([]models.AuditEntry, error)
+	// LastAuditEntry returns the chained entry with the highest Seq, or nil
+	LastAuditEntry(ctx context.Context) (*models.AuditEntry, error)
+	// ListAuditChain returns chained entries with Seq above afterSeq, lowest first
+	ListAuditChain(ctx context.Context, afterSeq int64, limit int) ([]models.AuditEntry, error)
+}
+
+// ErrAuditSeqTaken means another writer appended the audit entry at that Seq
+// first; the caller re-reads the head and tries again
+var ErrAuditSeqTaken = errors.New("audit sequence number already taken")
+
+// NonceStore remembers the nonces of signed client requests until they
+// expire, so a captured request can't be replayed on any replica
+type NonceStore interface {
+	// UseNonce records the client's nonce until expiresAt; it reports false
+	// if the nonce is already recorded and unexpired
+	UseNonce(ctx context.Context, clientID, nonce string, expiresAt time.Time) (bool, error)
+	// PurgeExpiredNonces deletes expired nonces, returning how many
+	PurgeExpiredNonces(ctx context.Context) (int64, error)
+}
+
+// RateCounterStore keeps the per-window request counts behind client rate
+// limits, so every replica counts against the same limit
+type RateCounterStore interface {
+	// IncrementRateCounter atomically adds one to the counter at key, kept
+	// until expiresAt, and returns the new count; it fails with
+	// ErrQuotaExceeded, counting nothing, if the count has reached limit
+	IncrementRateCounter(ctx context.Context, key string, limit int, expiresAt time.Time) (int, error)
+	// GetRateCounter returns the count at key, 0 if there is none
+	GetRateCounter(ctx context.Context, key string) (int, error)
+	// DecrementRateCounter takes one off the count at key, if above 0
+	DecrementRateCounter(ctx context.Context, key string) error
+	// PurgeExpiredRateCounters deletes expired counters, returning how many
+	PurgeExpiredRateCounters(ctx context.Context) (int64, error)
+}
+
+// ErrQuotaExceeded means a client's quota or rate limit is used up
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// AuthSessionStore holds BRC-103 mutual authentication sessions, so any
+// replica can serve a session another one set up
+type AuthSessionStore interface {
+	// SaveAuthSession creates or replaces a session
+	SaveAuthSession(ctx context.Context, session *models.AuthSession) error
+	// GetAuthSession returns the unexpired session with this nonce, or nil
+	GetAuthSession(ctx context.Context, sessionNonce string) (*models.AuthSession, error)
+	// PurgeExpiredAuthSessions deletes expired sessions, returning how many
+	PurgeExpiredAuthSessions(ctx context.Context) (int64, error)
+}
+
+// Store is everything the broadcaster persists
+type Store interface {
+	UTXOStore
+	RequestStore
+	ClientStore
+	LeaderStore
+	AdminStore
+	NonceStore
+	AuthSessionStore
+	RateCounterStore
+	Close(ctx context.Context) error
+}
+
+var (
+	_ Store = (*Database)(nil)
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*PostgresStore)(nil)
+	_ Store = (*BoltStore)(nil)
+)
+
+// Open connects to the store named by uri
+//
+//	memory://                  in-process store, lost on restart (dev mode)
+//	mongodb://, mongodb+srv:// MongoDB
+//	postgres://, postgresql:// PostgreSQL, migrated on connect
+//	bolt://<path>              embedded bbolt file (single node)
+func Open(ctx context.Context, uri string) (Store, error) {
+	scheme, rest, _ := strings.Cut(uri, "://")
+	switch scheme {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "mongodb", "mongodb+srv":
+		return Connect(ctx, uri)
+	case "postgres", "postgresql":
+		return ConnectPostgres(ctx, uri)
+	case "bolt":
+		if rest == "" {
+			return nil, fmt.Errorf("bolt:// URI needs a file path")
+		}
+		return OpenBolt(rest)
+	default:
+		return nil, fmt.Errorf("unsupported database URI scheme %q", scheme)
+	}
+}
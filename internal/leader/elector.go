@@ -0,0 +1,138 @@
+package leader
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/logging"
+)
+
+// Singletons names the lease held by the replica that runs the singleton
+// background workers (startup sync, expired lease reclaiming)
+const Singletons = "singletons"
+
+// Elector campaigns for a named lease so only one replica runs a group of
+// background workers
+//
+// The holder renews the lease every third of its TTL. IsLeader only reports
+// true until the last successful renewal's TTL runs out, counted from before
+// the renewal was sent, so a replica that can't reach the store stops acting
+// as leader no later than another replica can take over.
+type Elector struct {
+	store  database.LeaderStore
+	name   string
+	holder string
+	ttl    time.Duration
+
+	mu         sync.Mutex
+	validUntil time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewElector creates an elector campaigning for name as holder
+func NewElector(store database.LeaderStore, name, holder string, ttl time.Duration) *Elector {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Elector{
+		store:  store,
+		name:   name,
+		holder: holder,
+		ttl:    ttl,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start campaigns once, so IsLeader is settled when it returns, then keeps
+// campaigning in the background
+func (e *Elector) Start() {
+	e.campaign()
+
+	e.wg.Add(1)
+	go e.run()
+}
+
+// Stop stops campaigning and hands the lease back so another replica can
+// take over without waiting for it to expire
+func (e *Elector) Stop() {
+	e.cancel()
+	e.wg.Wait()
+
+	wasLeader := e.IsLeader()
+	e.mu.Lock()
+	e.validUntil = time.Time{}
+	e.mu.Unlock()
+
+	if !wasLeader {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.store.ReleaseLeadership(ctx, e.name, e.holder); err != nil {
+		slog.Warn("Failed to release leadership", "lease", e.name, logging.Err(err))
+		return
+	}
+	slog.Info("Released leadership", "lease", e.name)
+}
+
+// IsLeader reports whether this replica currently holds the lease
+func (e *Elector) IsLeader() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return time.Now().Before(e.validUntil)
+}
+
+// run is the main campaign loop
+func (e *Elector) run() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			e.campaign()
+		case <-e.ctx.Done():
+			return
+		}
+	}
+}
+
+// campaign takes or renews the lease
+func (e *Elector) campaign() {
+	wasLeader := e.IsLeader()
+	sent := time.Now()
+
+	ctx, cancel := context.WithTimeout(e.ctx, e.ttl/3)
+	defer cancel()
+
+	won, err := e.store.AcquireLeadership(ctx, e.name, e.holder, e.ttl)
+	if err != nil {
+		// Keep whatever validity the last renewal bought; it runs out on its own
+		slog.Warn("Leader election failed", "lease", e.name, logging.Err(err))
+		return
+	}
+
+	e.mu.Lock()
+	if won {
+		e.validUntil = sent.Add(e.ttl)
+	} else {
+		e.validUntil = time.Time{}
+	}
+	e.mu.Unlock()
+
+	switch {
+	case won && !wasLeader:
+		slog.Info("Elected leader", "lease", e.name, "holder", e.holder)
+	case !won && wasLeader:
+		slog.Warn("Lost leadership", "lease", e.name)
+	}
+}
@@ -0,0 +1,177 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Field keys used across packages
+const (
+	KeyRequestUUID = "request_uuid"
+	KeyClientID    = "client_id"
+	KeyTxID        = "txid"
+	KeyUTXO        = "utxo"
+	KeyError       = "error"
+)
+
+// Redacted replaces secrets in log output
+const Redacted = "[REDACTED]"
+
+// Setup installs the default slog logger
+// level is debug, info, warn or error; format is json or text. The standard
+// log package is routed through it too, so stray log.Printf calls are still
+// levelled and redacted.
+func Setup(level, format string) error {
+	return setup(os.Stdout, level, format)
+}
+
+func setup(w io.Writer, level, format string) error {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+
+	opts := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redact}
+
+	var handler slog.Handler
+	switch format {
+	case "json", "":
+		handler = slog.NewJSONHandler(w, opts)
+	case "text":
+		handler = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("invalid log format %q (want json or text)", format)
+	}
+
+	slog.SetDefault(slog.New(contextHandler{handler}))
+	return nil
+}
+
+// Err is the attribute for an error
+func Err(err error) slog.Attr {
+	return slog.Any(KeyError, err)
+}
+
+type ctxKey struct{}
+
+// With returns a context whose log records carry attrs
+// Use it once the request UUID or client is known so every record logged
+// with the context is correlated.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(ctxKey{}).([]slog.Attr)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	merged = append(merged, existing...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, ctxKey{}, merged)
+}
+
+// contextHandler adds the context's attributes and trace IDs to each record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(ctxKey{}).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// sensitiveKeys are attribute keys whose values are never logged, compared
+// lowercased with - and _ removed
+var sensitiveKeys = map[string]bool{
+	"wif":           true,
+	"privatekey":    true,
+	"privkey":       true,
+	"apikey":        true,
+	"xapikey":       true,
+	"password":      true,
+	"adminpassword": true,
+	"secret":        true,
+	"token":         true,
+	"authorization": true,
+	"rawtx":         true,
+	"rawtxhex":      true,
+	"txhex":         true,
+	"hex":           true,
+}
+
+// secretPatterns catch secrets embedded in messages and error strings
+var secretPatterns = []*regexp.Regexp{
+	// WIF private keys (mainnet and testnet, compressed or not)
+	regexp.MustCompile(`\b[5KLc9][1-9A-HJ-NP-Za-km-z]{50,51}\b`),
+	// BIP32 extended private keys
+	regexp.MustCompile(`\b[xt]prv[1-9A-HJ-NP-Za-km-z]{100,112}\b`),
+	// API keys as issued by auth.GenerateAPIKey
+	regexp.MustCompile(`gh_[A-Za-z0-9_\-]{40,}=*`),
+	// Raw transaction hex: longer than any txid, public key or signature
+	regexp.MustCompile(`\b[0-9a-fA-F]{150,}\b`),
+}
+
+// credentialPatterns catch passwords in key=value pairs and connection
+// strings; the key is kept so the record still reads
+var credentialPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)(password|passwd|pwd|secret)(\s*[=:]\s*)[^\s,;&"']+`), "${1}${2}" + Redacted},
+	{regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+(@)`), "${1}" + Redacted + "${2}"},
+}
+
+// redact is the ReplaceAttr hook applied to every attribute and the message
+func redact(groups []string, a slog.Attr) slog.Attr {
+	if a.Key != slog.MessageKey && sensitiveKeys[normalizeKey(a.Key)] {
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, scrub(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, scrub(err.Error()))
+		}
+		if s, ok := a.Value.Any().(fmt.Stringer); ok {
+			return slog.String(a.Key, scrub(s.String()))
+		}
+	}
+	return a
+}
+
+func normalizeKey(key string) string {
+	key = strings.ToLower(key)
+	key = strings.ReplaceAll(key, "_", "")
+	return strings.ReplaceAll(key, "-", "")
+}
+
+// scrub replaces anything that looks like a secret in s
+func scrub(s string) string {
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllString(s, Redacted)
+	}
+	for _, c := range credentialPatterns {
+		s = c.pattern.ReplaceAllString(s, c.replacement)
+	}
+	return s
+}
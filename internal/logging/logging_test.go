@@ -0,0 +1,181 @@
+package logging
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	bip32 "github.com/bsv-blockchain/go-sdk/compat/bip32"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+)
+
+// capture installs a JSON logger writing to a buffer for the test
+func capture(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	var buf bytes.Buffer
+	if err := setup(&buf, "debug", "json"); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+// record decodes the single JSON record in buf
+func record(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+
+	var rec map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("log output %q is not one JSON record: %v", buf.String(), err)
+	}
+	return rec
+}
+
+// secrets generates one of each kind of secret the scrub looks for
+func secrets(t *testing.T) (wif, xprv, apiKey, rawHex string) {
+	t.Helper()
+
+	key, err := ec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	xprv, _, err = bip32.GenerateHDKeyPair(bip32.RecommendedSeedLen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	rawHex = strings.Repeat("0100000001", 20)
+	return key.Wif(), xprv, "gh_" + base64.URLEncoding.EncodeToString(b), rawHex
+}
+
+func TestSensitiveKeysAreRedacted(t *testing.T) {
+	buf := capture(t)
+	txid := strings.Repeat("ab", 32)
+
+	slog.Info("Configured",
+		"wif", "anything",
+		"X-API-Key", "anything",
+		"admin_password", "anything",
+		"Authorization", "Bearer anything",
+		"rawTxHex", "anything",
+		slog.Group("client", "api_key", "anything"),
+		KeyTxID, txid,
+	)
+
+	rec := record(t, buf)
+	for _, key := range []string{"wif", "X-API-Key", "admin_password", "Authorization", "rawTxHex"} {
+		if rec[key] != Redacted {
+			t.Errorf("%s = %v, want %s", key, rec[key], Redacted)
+		}
+	}
+	if group, _ := rec["client"].(map[string]any); group["api_key"] != Redacted {
+		t.Errorf("client.api_key = %v, want %s", group["api_key"], Redacted)
+	}
+	if rec[KeyTxID] != txid {
+		t.Errorf("txid = %v, want it logged as is", rec[KeyTxID])
+	}
+	if strings.Contains(buf.String(), "anything") {
+		t.Fatalf("secret value leaked: %s", buf.String())
+	}
+}
+
+func TestSecretsAreScrubbedFromMessagesAndErrors(t *testing.T) {
+	wif, xprv, apiKey, rawHex := secrets(t)
+	txid := strings.Repeat("cd", 32)
+
+	tests := []struct {
+		name   string
+		log    func()
+		secret string
+		kept   string
+	}{
+		{
+			name:   "WIF in the message",
+			log:    func() { slog.Info("Loaded key " + wif) },
+			secret: wif,
+			kept:   "Loaded key",
+		},
+		{
+			name:   "xprv in an error",
+			log:    func() { slog.Error("Bad key", Err(fmt.Errorf("invalid path for %s", xprv))) },
+			secret: xprv,
+			kept:   "invalid path for",
+		},
+		{
+			name:   "API key in a string value",
+			log:    func() { slog.Warn("Rejected", "header", "X-API-Key: "+apiKey) },
+			secret: apiKey,
+			kept:   "X-API-Key",
+		},
+		{
+			name:   "raw transaction in an error",
+			log:    func() { slog.Error("Broadcast failed", Err(errors.New("ARC refused "+rawHex))) },
+			secret: rawHex,
+			kept:   "ARC refused",
+		},
+		{
+			name:   "password in a key=value string",
+			log:    func() { slog.Error("Login failed", "detail", "user=admin password=hunter2 role=viewer") },
+			secret: "hunter2",
+			kept:   "role=viewer",
+		},
+		{
+			name:   "password in a connection string",
+			log:    func() { slog.Error("Connect failed", Err(errors.New("dial mongodb://root:hunter2@db:27017 refused"))) },
+			secret: "hunter2",
+			kept:   "mongodb://root:" + Redacted + "@db:27017",
+		},
+		{
+			name:   "txids are not secrets",
+			log:    func() { slog.Info("Broadcast " + txid) },
+			secret: "",
+			kept:   txid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := capture(t)
+			tt.log()
+
+			out := buf.String()
+			if tt.secret != "" && strings.Contains(out, tt.secret) {
+				t.Fatalf("secret leaked: %s", out)
+			}
+			if tt.secret != "" && !strings.Contains(out, Redacted) {
+				t.Fatalf("no redaction marker in %s", out)
+			}
+			if !strings.Contains(out, tt.kept) {
+				t.Fatalf("log lost %q: %s", tt.kept, out)
+			}
+		})
+	}
+}
+
+func TestTextFormatIsRedactedToo(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	var buf bytes.Buffer
+	if err := setup(&buf, "info", "text"); err != nil {
+		t.Fatal(err)
+	}
+	wif, _, _, _ := secrets(t)
+	slog.Info("Loaded", "key", wif, "password", "hunter2")
+
+	if out := buf.String(); strings.Contains(out, wif) || strings.Contains(out, "hunter2") {
+		t.Fatalf("secret leaked: %s", out)
+	}
+}
@@ -0,0 +1,271 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "broadcaster"
+
+// Publish outcomes
+const (
+	OutcomeSuccess   = "success"    // ARC accepted or mined the transaction
+	OutcomeFailed    = "failed"     // ARC rejected it or it ran out of retries
+	OutcomeNoUTXO    = "no_utxo"    // Refused: no publishing UTXO free
+	OutcomeQueueFull = "queue_full" // Refused: train lane or client backlog full
+	OutcomeError     = "error"      // Refused: the server couldn't build or save it
+)
+
+// Reasons a signed client request is refused
+const (
+	RejectInvalidSignature   = "invalid_signature"   // Signature doesn't match the client's key
+	RejectStaleTimestamp     = "stale_timestamp"     // X-Timestamp outside the allowed clock skew
+	RejectInvalidNonce       = "invalid_nonce"       // X-Nonce too short or too long
+	RejectReplayedNonce      = "replayed_nonce"      // X-Nonce already used: a replay
+	RejectUnsupportedVersion = "unsupported_version" // X-Signature-Version not accepted
+
+	// Mutual authentication only
+	RejectInvalidAuthMessage = "invalid_auth_message"  // Malformed handshake message or x-bsv-auth-* headers
+	RejectUnknownIdentity    = "unknown_identity"      // Identity key isn't a registered client's public key
+	RejectClientDisabled     = "client_disabled"       // Handshake from a disabled client
+	RejectNoSession          = "no_session"            // Session unknown, expired or for a rotated key
+	RejectCertificates       = "certificates_required" // Requested certificates missing or invalid
+)
+
+// VersionMutualAuth labels SignedRequests signed under a BRC-103 session
+const VersionMutualAuth = "brc103"
+
+var (
+	// Publishes counts publish requests by client, client tier and outcome
+	// The client is the authenticated client's ID
+	Publishes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "publishes_total",
+		Help:      "Publish requests by client, tier and outcome.",
+	}, []string{"client", "tier", "outcome"})
+
+	// PublishLatency measures a publish from request arrival to ARC's final answer
+	PublishLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "publish_latency_seconds",
+		Help:      "Time from publish request to ARC's final answer.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"outcome"})
+
+	// TrainBatchSize measures how many transactions each departing train carries
+	TrainBatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "train_batch_size",
+		Help:      "Transactions per batch sent to ARC.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12), // 1 to 2048
+	})
+
+	// ARCLatency measures ARC round trips by HTTP method and response code
+	// POST is a broadcast, GET a status or policy lookup
+	ARCLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "arc_request_duration_seconds",
+		Help:      "ARC HTTP round trips by method and status code.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"method", "code"})
+
+	// JanitorRecoveries counts expired UTXO leases the janitor settled, by what it did
+	JanitorRecoveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "janitor_recoveries_total",
+		Help:      "Expired UTXO leases reclaimed (unlocked) or found broadcast (spent).",
+	}, []string{"action"})
+
+	// SplitOutputs counts UTXOs created by the splitter, by the type created
+	SplitOutputs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "split_outputs_total",
+		Help:      "UTXOs created by splitting, by type.",
+	}, []string{"type"})
+
+	// SignatureRejections counts signed client requests refused, by client and reason
+	SignatureRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "signature_rejections_total",
+		Help:      "Signed client requests refused, by client and reason.",
+	}, []string{"client", "reason"})
+
+	// SignedRequests counts signed client requests accepted, by signature
+	// version, showing when legacy signatures can be turned off
+	SignedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "signed_requests_total",
+		Help:      "Signed client requests accepted, by signature version.",
+	}, []string{"version"})
+
+	// RateLimited counts client requests refused for a used up rate limit, by
+	// client and window (second, minute or day)
+	RateLimited = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_total",
+		Help:      "Client requests refused for a used up rate limit, by client and window.",
+	}, []string{"client", "window"})
+
+	// MutualAuthSessions counts BRC-103 mutual authentication handshakes completed
+	MutualAuthSessions = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mutual_auth_sessions_total",
+		Help:      "BRC-103 mutual authentication sessions opened.",
+	})
+
+	// AuditWriteFailures counts admin audit entries that couldn't be written,
+	// by phase: an intent failure refuses the action, a result failure means
+	// an action ran without its outcome on record - alert on any increase
+	AuditWriteFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_write_failures_total",
+		Help:      "Admin audit entries that could not be written, by phase (intent or result).",
+	}, []string{"phase"})
+
+	// Sweeps counts sweep transactions and SweptSatoshis what they moved
+	Sweeps = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "sweeps_total",
+		Help:      "Sweep transactions broadcast.",
+	})
+	SweptSatoshis = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "swept_satoshis_total",
+		Help:      "Satoshis paid out by sweep transactions.",
+	})
+)
+
+// ObservePublish records a finished publish
+func ObservePublish(client, tier, outcome string, submittedAt time.Time) {
+	Publishes.WithLabelValues(client, tier, outcome).Inc()
+	if !submittedAt.IsZero() {
+		PublishLatency.WithLabelValues(outcome).Observe(time.Since(submittedAt).Seconds())
+	}
+}
+
+// InstrumentARC wraps an ARC client's transport to record ARCLatency
+func InstrumentARC(next http.RoundTripper) http.RoundTripper {
+	return promhttp.InstrumentRoundTripperDuration(ARCLatency, next)
+}
+
+// Handler serves the default registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// QueueSource reports the train's queue for the queue depth gauges, and its
+// scheduler for the scheduler gauges
+type QueueSource interface {
+	LaneDepths() map[string]int
+	BatchesInFlight() int
+	SchedulerState() SchedulerState
+}
+
+// SchedulerState is the train scheduler's current decisions and counters
+type SchedulerState struct {
+	Mode              string // static or adaptive
+	BatchSize         int
+	Interval          time.Duration
+	ARCLatency        time.Duration    // Smoothed ARC batch latency
+	ConsecutiveErrors int              // ARC batch errors since the last success
+	Departures        map[string]int64 // Departure reason -> count
+	Adjustments       map[string]int64 // Batch size adjustment -> count
+}
+
+// UTXOCounter reports UTXO pool counts keyed "<type>_<status>"
+type UTXOCounter interface {
+	GetUTXOStats(ctx context.Context) (map[string]int64, error)
+}
+
+// RegisterPipeline adds gauges read from the train and the UTXO pool at scrape time
+func RegisterPipeline(queue QueueSource, utxos UTXOCounter) {
+	prometheus.MustRegister(&pipelineCollector{queue: queue, utxos: utxos})
+}
+
+var (
+	queueDepthDesc = prometheus.NewDesc(namespace+"_queue_depth",
+		"Transactions waiting in each train lane.", []string{"lane"}, nil)
+	batchesInFlightDesc = prometheus.NewDesc(namespace+"_batches_in_flight",
+		"Batches waiting on ARC.", nil, nil)
+	utxoPoolDesc = prometheus.NewDesc(namespace+"_utxos",
+		"UTXOs in the pool by type and status.", []string{"type", "status"}, nil)
+	utxoPoolUpDesc = prometheus.NewDesc(namespace+"_utxo_stats_up",
+		"Whether the last UTXO pool count succeeded.", nil, nil)
+	schedulerBatchSizeDesc = prometheus.NewDesc(namespace+"_scheduler_batch_size",
+		"Transactions the train scheduler lets the next batch carry.", []string{"mode"}, nil)
+	schedulerIntervalDesc = prometheus.NewDesc(namespace+"_scheduler_interval_seconds",
+		"Train scheduler departure interval, stretched during ARC backoff.", []string{"mode"}, nil)
+	schedulerARCLatencyDesc = prometheus.NewDesc(namespace+"_scheduler_arc_latency_seconds",
+		"ARC batch latency as smoothed by the train scheduler.", []string{"mode"}, nil)
+	schedulerErrorsDesc = prometheus.NewDesc(namespace+"_scheduler_consecutive_errors",
+		"ARC batch errors since the last success.", []string{"mode"}, nil)
+	schedulerDeparturesDesc = prometheus.NewDesc(namespace+"_scheduler_departures_total",
+		"Train departures by reason.", []string{"reason"}, nil)
+	schedulerAdjustmentsDesc = prometheus.NewDesc(namespace+"_scheduler_adjustments_total",
+		"Adaptive batch size adjustments by kind.", []string{"adjustment"}, nil)
+)
+
+// pipelineCollector reads its gauges when scraped, so they never go stale
+type pipelineCollector struct {
+	queue QueueSource
+	utxos UTXOCounter
+}
+
+func (p *pipelineCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+	ch <- batchesInFlightDesc
+	ch <- utxoPoolDesc
+	ch <- utxoPoolUpDesc
+	ch <- schedulerBatchSizeDesc
+	ch <- schedulerIntervalDesc
+	ch <- schedulerARCLatencyDesc
+	ch <- schedulerErrorsDesc
+	ch <- schedulerDeparturesDesc
+	ch <- schedulerAdjustmentsDesc
+}
+
+func (p *pipelineCollector) Collect(ch chan<- prometheus.Metric) {
+	for lane, depth := range p.queue.LaneDepths() {
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(depth), lane)
+	}
+	ch <- prometheus.MustNewConstMetric(batchesInFlightDesc, prometheus.GaugeValue, float64(p.queue.BatchesInFlight()))
+	p.collectScheduler(ch)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stats, err := p.utxos.GetUTXOStats(ctx)
+	if err != nil {
+		ch <- prometheus.MustNewConstMetric(utxoPoolUpDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(utxoPoolUpDesc, prometheus.GaugeValue, 1)
+	for key, count := range stats {
+		utxoType, status, ok := strings.Cut(key, "_")
+		if !ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(utxoPoolDesc, prometheus.GaugeValue, float64(count), utxoType, status)
+	}
+}
+
+// collectScheduler reports the scheduler's decisions and counters
+func (p *pipelineCollector) collectScheduler(ch chan<- prometheus.Metric) {
+	state := p.queue.SchedulerState()
+	ch <- prometheus.MustNewConstMetric(schedulerBatchSizeDesc, prometheus.GaugeValue, float64(state.BatchSize), state.Mode)
+	ch <- prometheus.MustNewConstMetric(schedulerIntervalDesc, prometheus.GaugeValue, state.Interval.Seconds(), state.Mode)
+	ch <- prometheus.MustNewConstMetric(schedulerARCLatencyDesc, prometheus.GaugeValue, state.ARCLatency.Seconds(), state.Mode)
+	ch <- prometheus.MustNewConstMetric(schedulerErrorsDesc, prometheus.GaugeValue, float64(state.ConsecutiveErrors), state.Mode)
+	for reason, count := range state.Departures {
+		ch <- prometheus.MustNewConstMetric(schedulerDeparturesDesc, prometheus.CounterValue, float64(count), reason)
+	}
+	for adjustment, count := range state.Adjustments {
+		ch <- prometheus.MustNewConstMetric(schedulerAdjustmentsDesc, prometheus.CounterValue, float64(count), adjustment)
+	}
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// fakePipeline stands in for the train and the UTXO pool
+type fakePipeline struct {
+	scheduler SchedulerState
+}
+
+func (f *fakePipeline) LaneDepths() map[string]int     { return map[string]int{"normal": 3} }
+func (f *fakePipeline) BatchesInFlight() int           { return 1 }
+func (f *fakePipeline) SchedulerState() SchedulerState { return f.scheduler }
+
+func (f *fakePipeline) GetUTXOStats(ctx context.Context) (map[string]int64, error) {
+	return map[string]int64{"publishing_available": 42}, nil
+}
+
+func TestPipelineCollectorReportsScheduler(t *testing.T) {
+	pipeline := &fakePipeline{scheduler: SchedulerState{
+		Mode:              "adaptive",
+		BatchSize:         250,
+		Interval:          1500 * time.Millisecond,
+		ARCLatency:        200 * time.Millisecond,
+		ConsecutiveErrors: 2,
+		Departures:        map[string]int64{"batch_full": 7, "schedule": 3},
+		Adjustments:       map[string]int64{"grow": 4, "shrink_error": 1},
+	}}
+	collector := &pipelineCollector{queue: pipeline, utxos: pipeline}
+
+	expected := `
+# HELP broadcaster_scheduler_adjustments_total Adaptive batch size adjustments by kind.
+# TYPE broadcaster_scheduler_adjustments_total counter
+broadcaster_scheduler_adjustments_total{adjustment="grow"} 4
+broadcaster_scheduler_adjustments_total{adjustment="shrink_error"} 1
+# HELP broadcaster_scheduler_arc_latency_seconds ARC batch latency as smoothed by the train scheduler.
+# TYPE broadcaster_scheduler_arc_latency_seconds gauge
+broadcaster_scheduler_arc_latency_seconds{mode="adaptive"} 0.2
+# HELP broadcaster_scheduler_batch_size Transactions the train scheduler lets the next batch carry.
+# TYPE broadcaster_scheduler_batch_size gauge
+broadcaster_scheduler_batch_size{mode="adaptive"} 250
+# HELP broadcaster_scheduler_consecutive_errors ARC batch errors since the last success.
+# TYPE broadcaster_scheduler_consecutive_errors gauge
+broadcaster_scheduler_consecutive_errors{mode="adaptive"} 2
+# HELP broadcaster_scheduler_departures_total Train departures by reason.
+# TYPE broadcaster_scheduler_departures_total counter
+broadcaster_scheduler_departures_total{reason="batch_full"} 7
+broadcaster_scheduler_departures_total{reason="schedule"} 3
+# HELP broadcaster_scheduler_interval_seconds Train scheduler departure interval, stretched during ARC backoff.
+# TYPE broadcaster_scheduler_interval_seconds gauge
+broadcaster_scheduler_interval_seconds{mode="adaptive"} 1.5
+`
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"broadcaster_scheduler_batch_size",
+		"broadcaster_scheduler_interval_seconds",
+		"broadcaster_scheduler_arc_latency_seconds",
+		"broadcaster_scheduler_consecutive_errors",
+		"broadcaster_scheduler_departures_total",
+		"broadcaster_scheduler_adjustments_total",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
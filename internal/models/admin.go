@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AdminRole decides which admin endpoints an account may call
+type AdminRole string
+
+const (
+	AdminRoleViewer    AdminRole = "viewer"   // Read-only: stats, client lists, incidents, audit log
+	AdminRoleOperator  AdminRole = "operator" // Viewer plus client management and emergency controls
+	AdminRoleTreasury  AdminRole = "treasury" // Viewer plus moving funds: sweeps, dust consolidation, splits
+	AdminRoleSuperuser AdminRole = "admin"    // Everything, including managing admin accounts
+)
+
+// Valid reports whether r is a known role
+func (r AdminRole) Valid() bool {
+	switch r {
+	case AdminRoleViewer, AdminRoleOperator, AdminRoleTreasury, AdminRoleSuperuser:
+		return true
+	}
+	return false
+}
+
+// AdminAccount is a named administrator
+type AdminAccount struct {
+	Username          string    `bson:"_id" json:"username"`
+	PasswordHash      string    `bson:"password_hash" json:"-"` // bcrypt, never exposed
+	Role              AdminRole `bson:"role" json:"role"`
+	IsActive          bool      `bson:"is_active" json:"isActive"`
+	PasswordChangedAt time.Time `bson:"password_changed_at" json:"passwordChangedAt"` // Tokens issued before this are rejected
+	CreatedAt         time.Time `bson:"created_at" json:"createdAt"`
+	UpdatedAt         time.Time `bson:"updated_at" json:"updatedAt"`
+}
+
+// AuditEntry records one admin action or security event
+// Entries are append-only; no store method updates or deletes them. Each one
+// is hash-chained to the entry before it, so a rewritten entry breaks the
+// chain from that point on.
+type AuditEntry struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Seq      int64              `bson:"seq,omitempty" json:"seq"`            // Position in the chain, from 1 (0 for entries written before chaining)
+	PrevHash string             `bson:"prev_hash,omitempty" json:"prevHash"` // Hash of entry Seq-1
+	Hash     string             `bson:"hash,omitempty" json:"hash"`          // SHA-256 over PrevHash and this entry's contents
+	At       time.Time          `bson:"at" json:"at"`
+	Actor    string             `bson:"actor" json:"actor"`                       // Admin username (attempted username for failed logins), client:<id> or system
+	Role     AdminRole          `bson:"role,omitempty" json:"role,omitempty"`     // Actor's role at the time
+	Action   string             `bson:"action" json:"action"`                     // e.g. "POST /admin/maintenance/sweep"
+	Target   string             `bson:"target,omitempty" json:"target,omitempty"` // Client ID or username acted on
+	Status   int                `bson:"status" json:"status"`                     // HTTP status returned (0 for background events and intents)
+	RemoteIP string             `bson:"remote_ip" json:"remoteIp"`
+	Details  map[string]string  `bson:"details,omitempty" json:"details,omitempty"`
+}
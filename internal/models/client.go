@@ -27,8 +27,24 @@ type Client struct {
 	IsActive      bool      `bson:"is_active" json:"isActive"`
 	SiteOrigin    string    `bson:"site_origin,omitempty" json:"siteOrigin,omitempty"`
 	MaxDailyTx    int       `bson:"max_daily_tx" json:"maxDailyTx"`
+	MaxInFlight   int       `bson:"max_in_flight" json:"maxInFlight"`     // Queued + broadcasting cap (0 = train default)
+	MaxPerSecond  int       `bson:"max_per_second" json:"maxPerSecond"`   // Sliding window rate limit (0 = none)
+	MaxPerMinute  int       `bson:"max_per_minute" json:"maxPerMinute"`   // Sliding window rate limit (0 = none)
+	AutoRepublish bool      `bson:"auto_republish" json:"autoRepublish"`  // Reissue double-spent payloads on a fresh UTXO
 	TxCount       int       `bson:"tx_count" json:"txCount"`              // Daily counter
 	LastResetDate string    `bson:"last_reset_date" json:"lastResetDate"` // YYYY-MM-DD
 	CreatedAt     time.Time `bson:"created_at" json:"createdAt"`
 	UpdatedAt     time.Time `bson:"updated_at" json:"updatedAt"`
 }
+
+// AuthSession is a client's BRC-103 mutual authentication session, keyed by
+// the session nonce this server issued in the handshake
+type AuthSession struct {
+	SessionNonce  string    `bson:"_id" json:"sessionNonce"`
+	PeerNonce     string    `bson:"peer_nonce" json:"peerNonce"`     // The client's own session nonce
+	IdentityKey   string    `bson:"identity_key" json:"identityKey"` // Compressed hex
+	ClientID      string    `bson:"client_id" json:"clientId"`
+	Authenticated bool      `bson:"authenticated" json:"authenticated"` // False until requested certificates check out
+	CreatedAt     time.Time `bson:"created_at" json:"createdAt"`
+	ExpiresAt     time.Time `bson:"expires_at" json:"expiresAt"`
+}
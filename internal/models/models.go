@@ -26,18 +26,22 @@ const (
 
 // UTXO represents a Bitcoin SV unspent transaction output
 type UTXO struct {
-	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Outpoint     string             `bson:"outpoint" json:"outpoint"`           // txid:vout
-	TxID         string             `bson:"txid" json:"txid"`                   // Transaction ID
-	Vout         uint32             `bson:"vout" json:"vout"`                   // Output index
-	Satoshis     uint64             `bson:"satoshis" json:"satoshis"`           // Value in satoshis
-	ScriptPubKey string             `bson:"script_pub_key" json:"scriptPubKey"` // Locking script (hex)
-	Status       UTXOStatus         `bson:"status" json:"status"`               // available, locked, spent
-	Type         UTXOType           `bson:"type" json:"type"`                   // funding, publishing, change
-	LockedAt     *time.Time         `bson:"locked_at,omitempty" json:"lockedAt,omitempty"`
-	SpentAt      *time.Time         `bson:"spent_at,omitempty" json:"spentAt,omitempty"`
-	CreatedAt    time.Time          `bson:"created_at" json:"createdAt"`
-	UpdatedAt    time.Time          `bson:"updated_at" json:"updatedAt"`
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Outpoint        string             `bson:"outpoint" json:"outpoint"`                // txid:vout
+	TxID            string             `bson:"txid" json:"txid"`                        // Transaction ID
+	Vout            uint32             `bson:"vout" json:"vout"`                        // Output index
+	Satoshis        uint64             `bson:"satoshis" json:"satoshis"`                // Value in satoshis
+	ScriptPubKey    string             `bson:"script_pub_key" json:"scriptPubKey"`      // Locking script (hex)
+	Status          UTXOStatus         `bson:"status" json:"status"`                    // available, locked, spent
+	Type            UTXOType           `bson:"type" json:"type"`                        // funding, publishing, change
+	DerivationIndex uint32             `bson:"derivation_index" json:"derivationIndex"` // BIP32 child index (0 for single-key)
+	DerivationPath  string             `bson:"derivation_path" json:"derivationPath"`   // Path of the controlling key, e.g. m/0'/1'/7 ("" for single-key)
+	LockedAt        *time.Time         `bson:"locked_at,omitempty" json:"lockedAt,omitempty"`
+	LockOwner       string             `bson:"lock_owner,omitempty" json:"lockOwner,omitempty"`            // Instance holding the lock
+	LeaseExpiresAt  *time.Time         `bson:"lease_expires_at,omitempty" json:"leaseExpiresAt,omitempty"` // Reclaimable after this unless renewed
+	SpentAt         *time.Time         `bson:"spent_at,omitempty" json:"spentAt,omitempty"`
+	CreatedAt       time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt       time.Time          `bson:"updated_at" json:"updatedAt"`
 }
 
 // RequestStatus represents the state of a broadcast request
@@ -58,20 +62,59 @@ type BroadcastResult struct {
 	Error     error
 }
 
+// BroadcastAttempt records one trip of a request's transaction to ARC
+type BroadcastAttempt struct {
+	Attempt   int       `bson:"attempt" json:"attempt"`
+	At        time.Time `bson:"at" json:"at"`
+	ARCStatus string    `bson:"arc_status,omitempty" json:"arcStatus,omitempty"`
+	Error     string    `bson:"error,omitempty" json:"error,omitempty"`
+	Retriable bool      `bson:"retriable" json:"retriable"`
+}
+
 // BroadcastRequest tracks a user's OP_RETURN publish request
 type BroadcastRequest struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UUID      string             `bson:"uuid" json:"uuid"` // User-facing identifier
-	RawTxHex  string             `bson:"raw_tx_hex" json:"rawTxHex"`
-	TxID      string             `bson:"txid,omitempty" json:"txid,omitempty"`
-	UTXOUsed  string             `bson:"utxo_used" json:"utxoUsed"` // Outpoint of publishing UTXO
-	Status    RequestStatus      `bson:"status" json:"status"`
-	ARCStatus string             `bson:"arc_status,omitempty" json:"arcStatus,omitempty"`
-	Error     string             `bson:"error,omitempty" json:"error,omitempty"`
-	CreatedAt time.Time          `bson:"created_at" json:"createdAt"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updatedAt"`
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UUID          string             `bson:"uuid" json:"uuid"` // User-facing identifier
+	RawTxHex      string             `bson:"raw_tx_hex" json:"rawTxHex"`
+	TxID          string             `bson:"txid,omitempty" json:"txid,omitempty"`
+	UTXOUsed      string             `bson:"utxo_used" json:"utxoUsed"` // Outpoint of publishing UTXO
+	Status        RequestStatus      `bson:"status" json:"status"`
+	ARCStatus     string             `bson:"arc_status,omitempty" json:"arcStatus,omitempty"`
+	Priority      string             `bson:"priority,omitempty" json:"priority,omitempty"` // Train lane
+	Error         string             `bson:"error,omitempty" json:"error,omitempty"`
+	Attempts      []BroadcastAttempt `bson:"attempts,omitempty" json:"attempts,omitempty"` // Failed ARC round trips
+	ClientID      string             `bson:"client_id,omitempty" json:"clientId,omitempty"`
+	CompetingTxs  []string           `bson:"competing_txs,omitempty" json:"competingTxs,omitempty"`   // Set when ARC reports a double spend
+	RepublishedAs string             `bson:"republished_as,omitempty" json:"republishedAs,omitempty"` // UUID of the replacement request
+	CreatedAt     time.Time          `bson:"created_at" json:"createdAt"`
+	UpdatedAt     time.Time          `bson:"updated_at" json:"updatedAt"`
 
 	// ResponseChan is used for synchronous wait mode (?wait=true)
 	// Not persisted to database
 	ResponseChan chan BroadcastResult `bson:"-" json:"-"`
 }
+
+// DoubleSpendIncident records one of our transactions losing its input to a
+// competing transaction
+type DoubleSpendIncident struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	RequestUUID    string             `bson:"request_uuid" json:"requestUuid"`
+	ClientID       string             `bson:"client_id,omitempty" json:"clientId,omitempty"`
+	UTXO           string             `bson:"utxo" json:"utxo"`                  // Contested outpoint
+	LosingTxID     string             `bson:"losing_txid" json:"losingTxid"`     // Our transaction
+	CompetingTxs   []string           `bson:"competing_txs" json:"competingTxs"` // What it lost to
+	ARCStatus      string             `bson:"arc_status" json:"arcStatus"`
+	ExtraInfo      string             `bson:"extra_info,omitempty" json:"extraInfo,omitempty"`
+	Republished    bool               `bson:"republished" json:"republished"`
+	RepublishUUID  string             `bson:"republish_uuid,omitempty" json:"republishUuid,omitempty"`
+	RepublishError string             `bson:"republish_error,omitempty" json:"republishError,omitempty"`
+	DetectedAt     time.Time          `bson:"detected_at" json:"detectedAt"`
+}
+
+// LeaderLease records which instance runs the singleton background workers
+type LeaderLease struct {
+	Name      string    `bson:"_id" json:"name"`
+	Holder    string    `bson:"holder" json:"holder"` // Lock owner ID of the leader
+	ExpiresAt time.Time `bson:"expires_at" json:"expiresAt"`
+	RenewedAt time.Time `bson:"renewed_at" json:"renewedAt"`
+}
@@ -2,33 +2,59 @@ package recovery
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"sync"
 	"time"
 
+	"github.com/akua/bsv-broadcaster/internal/arc"
 	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/leader"
+	"github.com/akua/bsv-broadcaster/internal/logging"
+	"github.com/akua/bsv-broadcaster/internal/metrics"
+	"github.com/akua/bsv-broadcaster/internal/models"
+	"github.com/bsv-blockchain/go-sdk/transaction"
 )
 
-// Janitor handles periodic cleanup of stuck UTXOs
+// reclaimBatch caps how many expired leases one pass reconciles
+const reclaimBatch = 100
+
+// retrySkippedAfter is how far a lease the janitor couldn't settle is pushed
+// back, so later passes reach the leases behind it
+const retrySkippedAfter = time.Minute
+
+// Janitor keeps this instance's UTXO leases alive and reclaims expired ones
+// An expired lease means its owner died or gave the UTXO up, but the tx it
+// was building may still have reached ARC, so each one is checked there first
+//
+// Every replica renews its own leases; only the elected leader reclaims, so
+// replicas don't all query ARC about the same expired leases. The leader also
+// purges expired request nonces.
 type Janitor struct {
-	db         *database.Database
-	interval   time.Duration
-	maxLockAge time.Duration
-	ctx        context.Context
-	cancel     context.CancelFunc
-	wg         sync.WaitGroup
+	db           database.Store
+	arcClient    *arc.Client
+	elector      *leader.Elector
+	renewEvery   time.Duration
+	reclaimEvery time.Duration
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
 }
 
 // NewJanitor creates a new janitor service
-func NewJanitor(db *database.Database, interval, maxLockAge time.Duration) *Janitor {
+// renewEvery must be comfortably shorter than the store's lease
+func NewJanitor(db database.Store, arcClient *arc.Client, elector *leader.Elector, renewEvery, reclaimEvery time.Duration) *Janitor {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	return &Janitor{
-		db:         db,
-		interval:   interval,
-		maxLockAge: maxLockAge,
-		ctx:        ctx,
-		cancel:     cancel,
+		db:           db,
+		arcClient:    arcClient,
+		elector:      elector,
+		renewEvery:   renewEvery,
+		reclaimEvery: reclaimEvery,
+		ctx:          ctx,
+		cancel:       cancel,
 	}
 }
 
@@ -36,15 +62,15 @@ func NewJanitor(db *database.Database, interval, maxLockAge time.Duration) *Jani
 func (j *Janitor) Start() {
 	j.wg.Add(1)
 	go j.run()
-	log.Printf("🧹 Janitor started: checking every %v for UTXOs locked > %v", j.interval, j.maxLockAge)
+	slog.Info("Janitor started", "renew_every", j.renewEvery, "reclaim_every", j.reclaimEvery)
 }
 
 // Stop gracefully stops the janitor
 func (j *Janitor) Stop() {
-	log.Println("🧹 Janitor stopping...")
+	slog.Info("Janitor stopping")
 	j.cancel()
 	j.wg.Wait()
-	log.Println("✓ Janitor stopped")
+	slog.Info("Janitor stopped")
 }
 
 // run is the main janitor loop
@@ -52,14 +78,19 @@ func (j *Janitor) run() {
 	defer j.wg.Done()
 
 	// Run immediately on startup
+	j.renew()
 	j.cleanup()
 
-	ticker := time.NewTicker(j.interval)
-	defer ticker.Stop()
+	renewTicker := time.NewTicker(j.renewEvery)
+	defer renewTicker.Stop()
+	reclaimTicker := time.NewTicker(j.reclaimEvery)
+	defer reclaimTicker.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-renewTicker.C:
+			j.renew()
+		case <-reclaimTicker.C:
 			j.cleanup()
 		case <-j.ctx.Done():
 			return
@@ -67,39 +98,243 @@ func (j *Janitor) run() {
 	}
 }
 
-// cleanup recovers stuck UTXOs
-func (j *Janitor) cleanup() {
+// renew extends the leases this instance holds
+func (j *Janitor) renew() {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	recovered, err := j.db.RecoverStuckUTXOs(ctx, j.maxLockAge)
+	if _, err := j.db.RenewLeases(ctx); err != nil {
+		slog.Error("Lease renewal failed - other instances may reclaim our UTXOs", logging.Err(err))
+	}
+}
+
+// cleanup reconciles expired leases and purges expired nonces, if this
+// replica is the leader
+func (j *Janitor) cleanup() {
+	if !j.elector.IsLeader() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	result, err := reclaimAllExpired(ctx, j.db, j.arcClient)
 	if err != nil {
-		log.Printf("❌ Janitor cleanup failed: %v", err)
+		slog.Error("Janitor cleanup failed", "result", result, logging.Err(err))
 		return
 	}
 
-	if recovered > 0 {
-		log.Printf("🧹 Janitor recovered %d stuck UTXOs", recovered)
+	if result.total() > 0 {
+		slog.Info("Janitor reconciled expired leases", "result", result)
+	}
+
+	purged, err := j.db.PurgeExpiredNonces(ctx)
+	if err != nil {
+		slog.Error("Failed to purge expired nonces", logging.Err(err))
+	} else if purged > 0 {
+		slog.Debug("Purged expired nonces", "count", purged)
+	}
+
+	purged, err = j.db.PurgeExpiredAuthSessions(ctx)
+	if err != nil {
+		slog.Error("Failed to purge expired auth sessions", logging.Err(err))
+	} else if purged > 0 {
+		slog.Debug("Purged expired auth sessions", "count", purged)
+	}
+
+	purged, err = j.db.PurgeExpiredRateCounters(ctx)
+	if err != nil {
+		slog.Error("Failed to purge expired rate counters", logging.Err(err))
+	} else if purged > 0 {
+		slog.Debug("Purged expired rate counters", "count", purged)
 	}
 }
 
-// RunStartupRecovery performs initial recovery on server startup
-func RunStartupRecovery(db *database.Database, maxAge time.Duration) error {
-	log.Println("🔧 Running startup recovery for stuck UTXOs...")
+// RunStartupRecovery reconciles leases left behind by earlier runs
+func RunStartupRecovery(db database.Store, arcClient *arc.Client) error {
+	slog.Info("Running startup recovery for expired UTXO leases")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
 	defer cancel()
 
-	recovered, err := db.RecoverStuckUTXOs(ctx, maxAge)
+	result, err := reclaimAllExpired(ctx, db, arcClient)
 	if err != nil {
 		return err
 	}
 
-	if recovered > 0 {
-		log.Printf("✓ Startup recovery: unlocked %d UTXOs", recovered)
+	if result.total() > 0 {
+		slog.Info("Startup recovery complete", "result", result)
 	} else {
-		log.Println("✓ Startup recovery: no stuck UTXOs found")
+		slog.Info("Startup recovery: no expired leases found")
+	}
+
+	return nil
+}
+
+// reclaimResult counts what a pass did with the expired leases it found
+type reclaimResult struct {
+	unlocked int // Tx never reached ARC, or ARC rejected it
+	spent    int // ARC holds the tx
+	skipped  int // Renewed or retaken meanwhile, or ARC couldn't say
+}
+
+func (r reclaimResult) total() int {
+	return r.unlocked + r.spent + r.skipped
+}
+
+func (r *reclaimResult) add(pass reclaimResult) {
+	r.unlocked += pass.unlocked
+	r.spent += pass.spent
+	r.skipped += pass.skipped
+}
+
+func (r reclaimResult) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("unlocked", r.unlocked),
+		slog.Int("spent", r.spent),
+		slog.Int("skipped", r.skipped),
+	)
+}
+
+// reclaimAllExpired reconciles expired leases a batch at a time until a
+// batch comes back short
+func reclaimAllExpired(ctx context.Context, db database.Store, arcClient *arc.Client) (reclaimResult, error) {
+	var result reclaimResult
+	for {
+		pass, err := reclaimExpired(ctx, db, arcClient)
+		result.add(pass)
+		if err != nil {
+			return result, err
+		}
+		if pass.total() < reclaimBatch {
+			return result, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+	}
+}
+
+// reclaimExpired reconciles one batch of expired leases against ARC, oldest
+// first; leases it can't settle are deferred so the next batch moves on
+func reclaimExpired(ctx context.Context, db database.Store, arcClient *arc.Client) (reclaimResult, error) {
+	var result reclaimResult
+
+	utxos, err := db.FindExpiredLeases(ctx, reclaimBatch)
+	if err != nil {
+		return result, err
+	}
+
+	for _, utxo := range utxos {
+		spent, err := reconcile(ctx, db, arcClient, utxo)
+		switch {
+		case err != nil:
+			slog.WarnContext(ctx, "Could not reconcile expired lease", logging.KeyUTXO, utxo.Outpoint, logging.Err(err))
+			result.skipped++
+			if err := deferLease(ctx, db, utxo); err != nil {
+				return result, err
+			}
+		case spent:
+			metrics.JanitorRecoveries.WithLabelValues("spent").Inc()
+			result.spent++
+		default:
+			if ok, err := db.ReclaimUTXO(ctx, utxo); err != nil {
+				slog.WarnContext(ctx, "Could not reclaim expired lease", logging.KeyUTXO, utxo.Outpoint, logging.Err(err))
+				result.skipped++
+				if err := deferLease(ctx, db, utxo); err != nil {
+					return result, err
+				}
+			} else if ok {
+				metrics.JanitorRecoveries.WithLabelValues("unlocked").Inc()
+				result.unlocked++
+			} else {
+				result.skipped++
+			}
+		}
 	}
 
+	return result, nil
+}
+
+// deferLease pushes back an expired lease the janitor couldn't settle; one
+// renewed or retaken meanwhile is left alone
+func deferLease(ctx context.Context, db database.Store, utxo *models.UTXO) error {
+	if _, err := db.DeferExpiredLease(ctx, utxo, time.Now().Add(retrySkippedAfter)); err != nil {
+		return fmt.Errorf("failed to defer expired lease %s: %w", utxo.Outpoint, err)
+	}
 	return nil
 }
+
+// reconcile settles the request holding an expired lease from ARC's view of
+// its transaction, reporting whether the UTXO turned out to be spent
+// A false result with no error means the UTXO is safe to unlock
+func reconcile(ctx context.Context, db database.Store, arcClient *arc.Client, utxo *models.UTXO) (bool, error) {
+	req, err := db.GetRequestByUTXO(ctx, utxo.Outpoint)
+	if err != nil {
+		return false, fmt.Errorf("failed to find request: %w", err)
+	}
+	if req == nil {
+		// Splitter, sweeper or reservation lock: nothing was broadcast
+		// without the lock holder recording it
+		return false, nil
+	}
+
+	txid := req.TxID
+	if txid == "" && req.RawTxHex != "" {
+		tx, err := transaction.NewTransactionFromHex(req.RawTxHex)
+		if err != nil {
+			return false, fmt.Errorf("request %s has an unreadable transaction: %w", req.UUID, err)
+		}
+		txid = tx.TxID().String()
+	}
+	if txid == "" {
+		return false, nil
+	}
+
+	resp, err := arcClient.GetTransactionStatus(ctx, txid)
+	if errors.Is(err, arc.ErrTxNotFound) {
+		failUnfinished(ctx, db, req, "", "lease expired before the transaction reached ARC")
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("ARC lookup failed: %w", err)
+	}
+
+	arcStatus := string(resp.TxStatus)
+	switch outcome := resp.Outcome(); {
+	case outcome == arc.OutcomeUnknown:
+		// Try again next pass rather than guess either way
+		return false, fmt.Errorf("ARC reports unrecognised status %q", resp.TxStatus)
+	case !outcome.HoldsInputs():
+		failUnfinished(ctx, db, req, arcStatus, resp.ExtraInfo)
+		return false, nil
+	case outcome == arc.OutcomeOrphan:
+		// ARC may still broadcast it once the parent shows up; the request
+		// stays pending and the lease is checked again next time
+		return false, fmt.Errorf("transaction %s is still waiting for its parent", txid)
+	case outcome == arc.OutcomeMined:
+		db.UpdateRequestStatus(ctx, req.UUID, models.RequestStatusMined, txid, arcStatus, "")
+	case outcome.Succeeded():
+		db.UpdateRequestStatus(ctx, req.UUID, models.RequestStatusSuccess, txid, arcStatus, "")
+	default:
+		failUnfinished(ctx, db, req, arcStatus, fmt.Sprintf("ARC reports %s", resp.TxStatus))
+	}
+
+	spent, err := db.MarkExpiredUTXOSpent(ctx, utxo, txid)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark spent: %w", err)
+	}
+	if !spent {
+		return false, fmt.Errorf("lock changed while reconciling")
+	}
+	return true, nil
+}
+
+// failUnfinished fails a request its owner never finished; requests that
+// already reached a final status keep it
+func failUnfinished(ctx context.Context, db database.Store, req *models.BroadcastRequest, arcStatus, msg string) {
+	if req.Status != models.RequestStatusPending && req.Status != models.RequestStatusProcessing {
+		return
+	}
+	db.UpdateRequestStatus(ctx, req.UUID, models.RequestStatusFailed, "", arcStatus, msg)
+}
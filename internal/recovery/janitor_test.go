@@ -0,0 +1,193 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/arc"
+	"github.com/akua/bsv-broadcaster/internal/arc/arctest"
+	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/models"
+	"github.com/bsv-blockchain/go-sdk/chainhash"
+	"github.com/bsv-blockchain/go-sdk/transaction"
+)
+
+func newJanitorStore() *database.MemoryStore {
+	db := database.NewMemoryStore()
+	db.SetLockOwner(database.NewLockOwner(time.Minute))
+	return db
+}
+
+// seedExpired leaves count publishing UTXOs locked by a dead instance whose
+// leases ran out expiredFor ago; with requests, each has a processing
+// request whose fate only ARC knows
+func seedExpired(t *testing.T, db *database.MemoryStore, first, count int, expiredFor time.Duration, requests bool) []string {
+	t.Helper()
+	ctx := context.Background()
+
+	lockedAt := time.Now().Add(-expiredFor - time.Minute)
+	expires := time.Now().Add(-expiredFor)
+	var outpoints []string
+	for i := first; i < first+count; i++ {
+		txid := fmt.Sprintf("%064x", i)
+		utxo := &models.UTXO{
+			Outpoint:       txid + ":0",
+			TxID:           txid,
+			Satoshis:       100,
+			Status:         models.UTXOStatusLocked,
+			Type:           models.UTXOTypePublishing,
+			LockedAt:       &lockedAt,
+			LockOwner:      "dead-instance",
+			LeaseExpiresAt: &expires,
+		}
+		if err := db.InsertUTXO(ctx, utxo); err != nil {
+			t.Fatal(err)
+		}
+		outpoints = append(outpoints, utxo.Outpoint)
+
+		if !requests {
+			continue
+		}
+		err := db.InsertBroadcastRequest(ctx, &models.BroadcastRequest{
+			UUID:     fmt.Sprintf("request-%d", i),
+			TxID:     fmt.Sprintf("%064x", i+1_000_000),
+			UTXOUsed: utxo.Outpoint,
+			Status:   models.RequestStatusProcessing,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return outpoints
+}
+
+func utxoStatus(t *testing.T, db *database.MemoryStore, status models.UTXOStatus) map[string]*models.UTXO {
+	t.Helper()
+
+	utxos, err := db.FindUTXOsByType(context.Background(), models.UTXOTypePublishing, status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byOutpoint := make(map[string]*models.UTXO, len(utxos))
+	for _, utxo := range utxos {
+		byOutpoint[utxo.Outpoint] = utxo
+	}
+	return byOutpoint
+}
+
+func TestReclaimAllExpiredDrainsEveryBatch(t *testing.T) {
+	db := newJanitorStore()
+	fake := arctest.NewServer()
+	defer fake.Close()
+	const expired = 2*reclaimBatch + 50
+	seedExpired(t, db, 1, expired, time.Minute, false)
+
+	result, err := reclaimAllExpired(context.Background(), db, fake.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.unlocked != expired || result.total() != expired {
+		t.Fatalf("result = %+v, want all %d unlocked", result, expired)
+	}
+	if got := len(utxoStatus(t, db, models.UTXOStatusAvailable)); got != expired {
+		t.Fatalf("%d UTXOs available, want %d", got, expired)
+	}
+}
+
+func TestReclaimAllExpiredDefersLeasesItCannotSettle(t *testing.T) {
+	db := newJanitorStore()
+
+	// ARC can't be reached, so the oldest batch - all with requests - can't
+	// be settled; the newer leases behind it need no lookup
+	unreachable := httptest.NewServer(nil)
+	unreachable.Close()
+	arcClient := arc.NewClient(unreachable.URL, "")
+	stuck := seedExpired(t, db, 1, reclaimBatch, 10*time.Minute, true)
+	free := seedExpired(t, db, reclaimBatch+1, 20, time.Minute, false)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	result, err := reclaimAllExpired(ctx, db, arcClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.skipped != len(stuck) || result.unlocked != len(free) {
+		t.Fatalf("result = %+v, want %d skipped and %d unlocked", result, len(stuck), len(free))
+	}
+
+	locked := utxoStatus(t, db, models.UTXOStatusLocked)
+	for _, outpoint := range stuck {
+		utxo, ok := locked[outpoint]
+		if !ok {
+			t.Fatalf("unsettled UTXO %s was released", outpoint)
+		}
+		if wait := time.Until(*utxo.LeaseExpiresAt); wait < retrySkippedAfter/2 {
+			t.Fatalf("unsettled lease expires in %v, want it pushed back by about %v", wait, retrySkippedAfter)
+		}
+	}
+	available := utxoStatus(t, db, models.UTXOStatusAvailable)
+	for _, outpoint := range free {
+		if _, ok := available[outpoint]; !ok {
+			t.Fatalf("UTXO %s behind the unsettled batch was never reclaimed", outpoint)
+		}
+	}
+
+	if again, err := db.FindExpiredLeases(context.Background(), reclaimBatch); err != nil || len(again) != 0 {
+		t.Fatalf("expired leases after the pass = %d (%v), want none until the deferral ends", len(again), err)
+	}
+}
+
+func TestReclaimWaitsOnOrphans(t *testing.T) {
+	ctx := context.Background()
+	db := newJanitorStore()
+	fake := arctest.NewServer()
+	defer fake.Close()
+	outpoint := seedExpired(t, db, 1, 1, time.Minute, true)[0]
+
+	// The request's transaction sits in ARC's orphan mempool
+	source, err := chainhash.NewHashFromHex(fmt.Sprintf("%064x", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := transaction.NewTransaction()
+	tx.AddInput(&transaction.TransactionInput{SourceTXID: source, SourceTxOutIndex: 0})
+	if err := tx.AddOpReturnOutput([]byte("orphan")); err != nil {
+		t.Fatal(err)
+	}
+	txid := tx.TxID().String()
+	fake.Orphan(txid)
+	if _, err := fake.Client().BroadcastBatch(ctx, []string{tx.Hex()}); err != nil {
+		t.Fatal(err)
+	}
+	db.UpdateRequestStatus(ctx, "request-1", models.RequestStatusPending, txid, string(arc.TxStatusSeenInOrphanMempool), "")
+
+	result, err := reclaimAllExpired(ctx, db, fake.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.skipped != 1 {
+		t.Fatalf("result = %+v, want the orphan skipped", result)
+	}
+	utxo, ok := utxoStatus(t, db, models.UTXOStatusLocked)[outpoint]
+	if !ok {
+		t.Fatalf("orphan's UTXO %s was released", outpoint)
+	}
+	if req, _ := db.GetRequestByUUID(ctx, "request-1"); req.Status != models.RequestStatusPending {
+		t.Fatalf("orphan request status = %s, want still pending", req.Status)
+	}
+
+	// Once the parent arrives and the tx is mined, the next pass settles it
+	fake.Mine(txid)
+	if _, err := db.DeferExpiredLease(ctx, utxo, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := reclaimAllExpired(ctx, db, fake.Client()); err != nil || result.spent != 1 {
+		t.Fatalf("result = %+v (%v), want the mined orphan spent", result, err)
+	}
+	if req, _ := db.GetRequestByUUID(ctx, "request-1"); req.Status != models.RequestStatusMined {
+		t.Fatalf("request status = %s, want %s", req.Status, models.RequestStatusMined)
+	}
+}
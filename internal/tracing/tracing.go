@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const serviceName = "bsv-broadcaster"
+
+// Span attribute keys shared across packages
+const (
+	AttrRequestUUID = attribute.Key("broadcast.request_uuid")
+	AttrClientID    = attribute.Key("broadcast.client_id")
+	AttrClientTier  = attribute.Key("broadcast.client_tier")
+	AttrTxID        = attribute.Key("broadcast.txid")
+	AttrUTXO        = attribute.Key("broadcast.utxo")
+	AttrBatchSize   = attribute.Key("broadcast.batch_size")
+	AttrARCStatus   = attribute.Key("arc.tx_status")
+)
+
+// Tracer returns the broadcaster's tracer
+// Until Setup installs a provider it is a no-op, so packages can trace
+// unconditionally
+func Tracer() trace.Tracer {
+	return otel.Tracer("github.com/akua/bsv-broadcaster")
+}
+
+// Setup installs the global tracer provider chosen by exporter:
+//
+//	otlp    OTLP over HTTP, configured by the standard OTEL_EXPORTER_OTLP_* variables
+//	stdout  pretty-printed spans on stdout, for local runs
+//	""/none tracing disabled
+//
+// The returned function flushes and stops the provider.
+func Setup(ctx context.Context, exporter string) (func(context.Context) error, error) {
+	var spanExporter sdktrace.SpanExporter
+	var err error
+
+	switch exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		spanExporter, err = otlptracehttp.New(ctx)
+	case "stdout":
+		spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q (want otlp, stdout or none)", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter: %w", exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", serviceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+// InstrumentHTTP wraps an outgoing transport so each call gets a client span
+// that is a child of the span in the request's context
+func InstrumentHTTP(next http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(next)
+}
+
+// RecordError marks span failed with err, if there is one
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
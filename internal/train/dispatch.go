@@ -0,0 +1,107 @@
+package train
+
+import (
+	"github.com/bsv-blockchain/go-sdk/transaction"
+)
+
+// describeTx fills in a TxWork's txid and parent txids from its raw hex
+// when the producer didn't supply them
+func describeTx(work *TxWork) {
+	if work.TxID != "" && work.DependsOn != nil {
+		return
+	}
+
+	tx, err := transaction.NewTransactionFromHex(work.RawTxHex)
+	if err != nil {
+		// ARC will reject it; there's nothing to order it against
+		return
+	}
+
+	if work.TxID == "" {
+		work.TxID = tx.TxID().String()
+	}
+	if work.DependsOn == nil {
+		parents := make([]string, 0, len(tx.Inputs))
+		for _, in := range tx.Inputs {
+			if in.SourceTXID != nil {
+				parents = append(parents, in.SourceTXID.String())
+			}
+		}
+		work.DependsOn = parents
+	}
+}
+
+// tryAcquireSlot claims one of the concurrent dispatch slots without blocking
+func (t *Train) tryAcquireSlot() bool {
+	select {
+	case t.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// dispatch broadcasts a batch on its own goroutine, holding a slot that the
+// caller has already acquired until ARC answers
+func (t *Train) dispatch(batch []TxWork) {
+	t.trackInFlight(batch)
+	t.batches.Add(1)
+
+	go func() {
+		defer t.batches.Done()
+		defer func() { <-t.slots }()
+		defer t.untrackInFlight(batch)
+
+		t.broadcastBatch(batch)
+
+		// A finished batch may unblock dependent transactions or free a slot
+		// for a backlog that is already waiting
+		select {
+		case t.arrivals <- struct{}{}:
+		default:
+		}
+	}()
+}
+
+// trackInFlight records the txids of a dispatched batch
+func (t *Train) trackInFlight(batch []TxWork) {
+	t.inFlightTxMu.Lock()
+	defer t.inFlightTxMu.Unlock()
+	for _, work := range batch {
+		if work.TxID != "" {
+			t.inFlightTx[work.TxID] = struct{}{}
+		}
+	}
+}
+
+// untrackInFlight forgets the txids of a completed batch
+func (t *Train) untrackInFlight(batch []TxWork) {
+	t.inFlightTxMu.Lock()
+	defer t.inFlightTxMu.Unlock()
+	for _, work := range batch {
+		delete(t.inFlightTx, work.TxID)
+	}
+}
+
+// blockedByInFlight reports whether any parent of the work is in a batch
+// that ARC hasn't answered yet - sending the child on a parallel train could
+// reach ARC before its parent and come back orphaned
+func (t *Train) blockedByInFlight(work TxWork) bool {
+	if len(work.DependsOn) == 0 {
+		return false
+	}
+
+	t.inFlightTxMu.Lock()
+	defer t.inFlightTxMu.Unlock()
+	for _, parent := range work.DependsOn {
+		if _, ok := t.inFlightTx[parent]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// BatchesInFlight returns how many batches are currently waiting on ARC
+func (t *Train) BatchesInFlight() int {
+	return len(t.slots)
+}
@@ -0,0 +1,119 @@
+package train
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/models"
+	"github.com/bsv-blockchain/go-sdk/script"
+	"github.com/bsv-blockchain/go-sdk/transaction"
+	"github.com/bsv-blockchain/go-sdk/transaction/template/p2pkh"
+)
+
+// chain builds a parent that pays back to the harness key and a child that
+// spends it, the way a split is followed by a publish on its output
+func (h *trainHarness) chain(t *testing.T, clientID string) (TxWork, TxWork) {
+	t.Helper()
+
+	addr, err := script.NewAddressFromPublicKey(h.key.PubKey(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock, err := p2pkh.Lock(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlocker, err := p2pkh.Unlock(h.key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h.next++
+	source := fmt.Sprintf("%064x", h.next)
+	parentTx := transaction.NewTransaction()
+	if err := parentTx.AddInputFrom(source, 0, lock.String(), 1000, unlocker); err != nil {
+		t.Fatal(err)
+	}
+	parentTx.AddOutput(&transaction.TransactionOutput{Satoshis: 900, LockingScript: lock})
+	if err := parentTx.Sign(); err != nil {
+		t.Fatal(err)
+	}
+
+	childTx := transaction.NewTransaction()
+	if err := childTx.AddInputFrom(parentTx.TxID().String(), 0, lock.String(), 900, unlocker); err != nil {
+		t.Fatal(err)
+	}
+	if err := childTx.AddOpReturnOutput([]byte("child")); err != nil {
+		t.Fatal(err)
+	}
+	if err := childTx.Sign(); err != nil {
+		t.Fatal(err)
+	}
+
+	var works []TxWork
+	for i, tx := range []*transaction.Transaction{parentTx, childTx} {
+		work := TxWork{
+			UUID:     fmt.Sprintf("request-%d-%d", h.next, i),
+			RawTxHex: tx.Hex(),
+			UTXOUsed: fmt.Sprintf("%s:0", tx.Inputs[0].SourceTXID),
+			Priority: PriorityNormal,
+			ClientID: clientID,
+		}
+		err := h.db.InsertBroadcastRequest(context.Background(), &models.BroadcastRequest{
+			UUID:     work.UUID,
+			RawTxHex: work.RawTxHex,
+			UTXOUsed: work.UTXOUsed,
+			Status:   models.RequestStatusPending,
+			ClientID: clientID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		works = append(works, work)
+	}
+	return works[0], works[1]
+}
+
+func TestChildWaitsForParentInFlight(t *testing.T) {
+	h := newTrainHarness(t)
+	parent, child := h.chain(t, "client")
+
+	// The parent leaves on a train that ARC is slow to answer
+	h.arc.SetLatency(200 * time.Millisecond)
+	if err := h.Enqueue(parent); err != nil {
+		t.Fatal(err)
+	}
+	if !h.tryAcquireSlot() {
+		t.Fatal("no dispatch slot free")
+	}
+	h.dispatch(h.assembleBatch(10))
+
+	// The child, derived from its raw hex as spending the parent, is held
+	// back while the parent's batch is out
+	if err := h.Enqueue(child); err != nil {
+		t.Fatal(err)
+	}
+	if batch := h.assembleBatch(10); len(batch) != 0 {
+		t.Fatalf("assembled %d transactions while the parent was in flight, want none", len(batch))
+	}
+	if h.QueueSize() != 1 {
+		t.Fatalf("queue size = %d, want the held child", h.QueueSize())
+	}
+
+	// Once ARC answers for the parent, the child departs
+	h.batches.Wait()
+	assertRequest(t, h, parent, models.RequestStatusSuccess)
+	batch := h.assembleBatch(10)
+	if len(batch) != 1 || batch[0].UUID != child.UUID {
+		t.Fatalf("assembled %+v after the parent landed, want the child", batch)
+	}
+	h.broadcastBatch(batch)
+	assertRequest(t, h, child, models.RequestStatusSuccess)
+
+	broadcasts := h.arc.Broadcasts()
+	if len(broadcasts) != 2 || broadcasts[0] != batch[0].DependsOn[0] || broadcasts[1] != batch[0].TxID {
+		t.Fatalf("ARC saw %v, want the parent then the child", broadcasts)
+	}
+}
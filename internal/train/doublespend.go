@@ -0,0 +1,169 @@
+package train
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/arc"
+	"github.com/akua/bsv-broadcaster/internal/logging"
+	"github.com/akua/bsv-broadcaster/internal/models"
+)
+
+// Republisher reissues the payload of a double-spent transaction on a fresh
+// UTXO, returning the UUID of the replacement request
+type Republisher func(ctx context.Context, work TxWork) (string, error)
+
+// SetRepublisher installs the hook used for clients with auto-republish on
+// Call before Start
+func (t *Train) SetRepublisher(r Republisher) {
+	t.republisher = r
+}
+
+// handleDoubleSpend deals with a transaction whose input is contested
+// DOUBLE_SPEND_ATTEMPTED isn't terminal - either side may still be mined - so
+// the work stays in flight, its UTXO leased, and is sent again after a
+// backoff; ARC answers a rebroadcast with the transaction's current status.
+// It is only failed (and republished) once ARC rejects it or a competing
+// transaction is mined.
+func (t *Train) handleDoubleSpend(ctx context.Context, work TxWork, resp arc.TxResponse) {
+	if work.incident == nil {
+		work.incident = newIncident(ctx, work, resp)
+	}
+
+	if lostTo, lost := t.settleDoubleSpend(ctx, work, resp); lost {
+		t.loseDoubleSpend(ctx, work, resp, lostTo)
+		return
+	}
+	t.awaitDoubleSpend(ctx, work, resp)
+}
+
+// newIncident starts tracking the first double spend report on a transaction
+// The incident is only stored if the transaction loses; a contest we win
+// leaves no record
+func newIncident(ctx context.Context, work TxWork, resp arc.TxResponse) *models.DoubleSpendIncident {
+	slog.WarnContext(work.logContext(ctx), "Double spend attempted", logging.KeyUTXO, work.UTXOUsed, "competing_txs", resp.CompetingTxs)
+	return &models.DoubleSpendIncident{
+		RequestUUID:  work.UUID,
+		ClientID:     work.ClientID,
+		UTXO:         work.UTXOUsed,
+		LosingTxID:   resp.TxID,
+		CompetingTxs: resp.CompetingTxs,
+		ARCStatus:    string(resp.TxStatus),
+		ExtraInfo:    resp.ExtraInfo,
+	}
+}
+
+// recordDoubleSpend saves the incident for a transaction that lost its input
+func (t *Train) recordDoubleSpend(ctx context.Context, work TxWork, resp arc.TxResponse) {
+	incident := work.incident
+	if len(resp.CompetingTxs) > 0 {
+		incident.CompetingTxs = resp.CompetingTxs
+	}
+	incident.ARCStatus = string(resp.TxStatus)
+	if resp.ExtraInfo != "" {
+		incident.ExtraInfo = resp.ExtraInfo
+	}
+
+	if err := t.db.RecordDoubleSpend(ctx, incident); err != nil {
+		slog.WarnContext(work.logContext(ctx), "Failed to record double spend", logging.KeyUTXO, work.UTXOUsed, logging.Err(err))
+	}
+}
+
+// settleDoubleSpend reports whether our transaction lost the outpoint, and to
+// whom: it has once ARC rejects it or a status lookup shows a competitor mined
+// A rejection is credited to the competitor ARC has mined or seen; the
+// winner is left empty when none of them can be found.
+func (t *Train) settleDoubleSpend(ctx context.Context, work TxWork, resp arc.TxResponse) (string, bool) {
+	competing := resp.CompetingTxs
+	if len(competing) == 0 {
+		competing = work.incident.CompetingTxs
+	}
+	rejected := resp.Outcome() == arc.OutcomeRejected
+
+	for _, txid := range competing {
+		other, err := t.lookupStatus(ctx, txid)
+		if err != nil {
+			if !errors.Is(err, arc.ErrTxNotFound) {
+				slog.WarnContext(work.logContext(ctx), "Competing transaction lookup failed", logging.KeyTxID, txid, logging.Err(err))
+			}
+			continue
+		}
+		outcome := other.Outcome()
+		if outcome == arc.OutcomeMined || (rejected && outcome.Succeeded()) {
+			return txid, true
+		}
+	}
+	return "", rejected
+}
+
+// awaitDoubleSpend puts contested work back at the front of its lane to be
+// checked again after a backoff, keeping its in-flight slot
+// A train shutting down can't wait, so the UTXO is left to the janitor, which
+// asks ARC before reclaiming it.
+func (t *Train) awaitDoubleSpend(ctx context.Context, work TxWork, resp arc.TxResponse) {
+	arcStatus := string(resp.TxStatus)
+
+	if t.ctx.Err() != nil {
+		t.db.ExpireLease(ctx, work.UTXOUsed)
+		msg := "double spend unsettled at shutdown"
+		if len(work.incident.CompetingTxs) > 0 {
+			msg = fmt.Sprintf("double spend against %s unsettled at shutdown", strings.Join(work.incident.CompetingTxs, ", "))
+		}
+		t.db.UpdateRequestStatus(ctx, work.UUID, models.RequestStatusFailed, "", arcStatus, msg)
+		t.complete(work, models.BroadcastResult{ARCStatus: arcStatus, Error: errors.New(msg)})
+		return
+	}
+
+	work.contestChecks++
+	backoff := retryBackoff(work.contestChecks)
+	work.NotBefore = time.Now().Add(backoff)
+	work.EnqueuedAt = work.NotBefore
+	t.db.UpdateRequestStatus(ctx, work.UUID, models.RequestStatusPending, "", arcStatus, "")
+	t.laneFor(work.Priority).pushFront(work)
+}
+
+// loseDoubleSpend fails a transaction that lost its input, republishing the
+// payload for clients with auto-republish on
+func (t *Train) loseDoubleSpend(ctx context.Context, work TxWork, resp arc.TxResponse, lostTo string) {
+	logCtx := work.logContext(ctx)
+	arcStatus := string(resp.TxStatus)
+	t.db.MarkUTXOSpent(ctx, work.UTXOUsed, lostTo)
+
+	t.recordDoubleSpend(ctx, work, resp)
+
+	msg := "double spend detected: lost to an unknown transaction"
+	if lostTo != "" {
+		msg = fmt.Sprintf("double spend detected: lost to %s", lostTo)
+	}
+	slog.WarnContext(logCtx, "Double spend lost", logging.KeyUTXO, work.UTXOUsed, "lost_to", lostTo)
+
+	// The replacement counts against the same client, so give up this
+	// one's in-flight slot first
+	t.release(work)
+
+	if work.AutoRepublish && t.republisher != nil {
+		newUUID, err := t.republisher(ctx, work)
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+			slog.ErrorContext(logCtx, "Republish failed", logging.Err(err))
+			msg += fmt.Sprintf(" (republish failed: %v)", err)
+		} else {
+			slog.InfoContext(logCtx, "Republished", "new_request_uuid", newUUID)
+			msg += fmt.Sprintf(" (republished as %s)", newUUID)
+		}
+
+		if !work.incident.ID.IsZero() {
+			if err := t.db.MarkDoubleSpendRepublished(ctx, work.incident, newUUID, errMsg); err != nil {
+				slog.WarnContext(logCtx, "Failed to record republish", logging.Err(err))
+			}
+		}
+	}
+
+	t.db.UpdateRequestStatus(ctx, work.UUID, models.RequestStatusFailed, "", arcStatus, msg)
+	t.finish(work, models.BroadcastResult{ARCStatus: arcStatus, Error: errors.New(msg)})
+}
@@ -0,0 +1,231 @@
+package train
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/arc"
+	"github.com/akua/bsv-broadcaster/internal/models"
+)
+
+// contest has ARC see a competing spend of work's UTXO first, so work's
+// broadcast comes back DOUBLE_SPEND_ATTEMPTED; it returns the competitor's txid
+func contest(t *testing.T, h *trainHarness, work TxWork) string {
+	t.Helper()
+
+	rawHex, txid := h.spend(t, h.utxo(t, work.UTXOUsed), "competitor")
+	if _, err := h.arc.Client().BroadcastBatch(context.Background(), []string{rawHex}); err != nil {
+		t.Fatal(err)
+	}
+	return txid
+}
+
+// contested broadcasts work against a competitor and returns it as requeued
+func contested(t *testing.T, h *trainHarness, work TxWork) TxWork {
+	t.Helper()
+
+	h.broadcastBatch([]TxWork{work})
+	queued := h.queued()
+	if len(queued) != 1 {
+		t.Fatalf("%d transactions requeued, want the contested one", len(queued))
+	}
+	return queued[0]
+}
+
+// incidents returns the double spends stored for the harness client
+func incidents(t *testing.T, h *trainHarness) []models.DoubleSpendIncident {
+	t.Helper()
+
+	got, err := h.db.ListDoubleSpendIncidents(context.Background(), "client", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestDoubleSpendAttemptWaitsForABlock(t *testing.T) {
+	h := newTrainHarness(t)
+	work := h.work(t, "client")
+	work.AutoRepublish = true
+	competitor := contest(t, h, work)
+	if err := h.acquire(work); err != nil {
+		t.Fatal(err)
+	}
+	h.SetRepublisher(func(ctx context.Context, work TxWork) (string, error) {
+		t.Fatal("republished while the double spend was unsettled")
+		return "", nil
+	})
+
+	requeued := contested(t, h, work)
+
+	if requeued.NotBefore.IsZero() || requeued.incident == nil {
+		t.Fatalf("requeued work = %+v, want a backoff and the incident", requeued)
+	}
+	req := assertRequest(t, h, work, models.RequestStatusPending)
+	if req.ARCStatus != string(arc.TxStatusDoubleSpend) || req.Error != "" {
+		t.Fatalf("request = %s %q, want a pending double spend", req.ARCStatus, req.Error)
+	}
+	if got := requeued.incident.CompetingTxs; len(got) != 1 || got[0] != competitor {
+		t.Fatalf("competing txs = %v, want [%s]", got, competitor)
+	}
+	assertUTXO(t, h, work, models.UTXOStatusLocked)
+	if got := h.inFlight["client"]; got != 1 {
+		t.Fatalf("client in flight = %d, want 1", got)
+	}
+
+	// Nothing is recorded while the contest is open
+	h.broadcastBatch([]TxWork{requeued})
+	if got := incidents(t, h); len(got) != 0 {
+		t.Fatalf("%d incidents while contested, want none", len(got))
+	}
+}
+
+func TestDoubleSpendWonWhenOursIsMined(t *testing.T) {
+	h := newTrainHarness(t)
+	work := h.work(t, "client")
+	contest(t, h, work)
+
+	requeued := contested(t, h, work)
+	h.arc.Mine(work.TxID)
+	h.broadcastBatch([]TxWork{requeued})
+
+	req := assertRequest(t, h, work, models.RequestStatusMined)
+	if req.Error != "" {
+		t.Fatalf("request error = %q, want none", req.Error)
+	}
+	assertUTXO(t, h, work, models.UTXOStatusSpent)
+
+	// A contest we win isn't an incident
+	if got := incidents(t, h); len(got) != 0 {
+		t.Fatalf("incidents = %+v, want none", got)
+	}
+}
+
+func TestDoubleSpendLostWhenCompetitorIsMined(t *testing.T) {
+	h := newTrainHarness(t)
+	work := h.work(t, "client")
+	work.ResponseChan = make(chan models.BroadcastResult, 1)
+	competitor := contest(t, h, work)
+
+	requeued := contested(t, h, work)
+	h.arc.Mine(competitor)
+	h.broadcastBatch([]TxWork{requeued})
+
+	req := assertRequest(t, h, work, models.RequestStatusFailed)
+	if !strings.Contains(req.Error, "lost to "+competitor) {
+		t.Fatalf("request error = %q, want lost to %s", req.Error, competitor)
+	}
+	assertUTXO(t, h, work, models.UTXOStatusSpent)
+	if result := <-work.ResponseChan; result.Error == nil {
+		t.Fatal("waiting client got no error")
+	}
+	if got := incidents(t, h); len(got) != 1 || got[0].LosingTxID != work.TxID {
+		t.Fatalf("incidents = %+v, want one for %s", got, work.TxID)
+	}
+}
+
+func TestDoubleSpendLostWhenOursIsRejected(t *testing.T) {
+	h := newTrainHarness(t)
+	work := h.work(t, "client")
+	competitor := contest(t, h, work)
+
+	requeued := contested(t, h, work)
+	h.arc.SetStatus(work.TxID, arc.TxStatusRejected)
+	h.broadcastBatch([]TxWork{requeued})
+
+	// Credited to the competitor ARC has seen, not yet mined
+	req := assertRequest(t, h, work, models.RequestStatusFailed)
+	if !strings.Contains(req.Error, "lost to "+competitor) {
+		t.Fatalf("request error = %q, want lost to %s", req.Error, competitor)
+	}
+
+	// Rejected as the loser, so the outpoint is gone rather than free again
+	assertUTXO(t, h, work, models.UTXOStatusSpent)
+}
+
+func TestDoubleSpendRejectedWithoutAKnownWinner(t *testing.T) {
+	h := newTrainHarness(t)
+	work := h.work(t, "client")
+	unknown := strings.Repeat("ab", 32)
+	h.arc.DoubleSpend(work.TxID, unknown)
+
+	requeued := contested(t, h, work)
+	h.arc.SetStatus(work.TxID, arc.TxStatusRejected)
+	h.broadcastBatch([]TxWork{requeued})
+
+	// ARC has never seen the named competitor, so nobody is credited -
+	// least of all our own rejected transaction
+	req := assertRequest(t, h, work, models.RequestStatusFailed)
+	if !strings.Contains(req.Error, "unknown transaction") || strings.Contains(req.Error, work.TxID) {
+		t.Fatalf("request error = %q, want lost to an unknown transaction", req.Error)
+	}
+	assertUTXO(t, h, work, models.UTXOStatusSpent)
+	if got := incidents(t, h); len(got) != 1 || got[0].ARCStatus != string(arc.TxStatusRejected) {
+		t.Fatalf("incidents = %+v, want one rejected", got)
+	}
+}
+
+func TestDoubleSpendRepublishesWithinInFlightCap(t *testing.T) {
+	h := newTrainHarness(t)
+	work := h.work(t, "client")
+	work.AutoRepublish = true
+	work.MaxInFlight = 1
+	if err := h.acquire(work); err != nil {
+		t.Fatal(err)
+	}
+	competitor := contest(t, h, work)
+
+	var replacement TxWork
+	h.SetRepublisher(func(ctx context.Context, lost TxWork) (string, error) {
+		replacement = h.work(t, lost.ClientID)
+		replacement.MaxInFlight = lost.MaxInFlight
+		return replacement.UUID, h.Enqueue(replacement)
+	})
+
+	requeued := contested(t, h, work)
+	h.arc.Mine(competitor)
+	h.broadcastBatch([]TxWork{requeued})
+
+	req := assertRequest(t, h, work, models.RequestStatusFailed)
+	if !strings.Contains(req.Error, "republished as "+replacement.UUID) {
+		t.Fatalf("request error = %q, want republished as %s", req.Error, replacement.UUID)
+	}
+	queued := h.queued()
+	if len(queued) != 1 || queued[0].UUID != replacement.UUID {
+		t.Fatalf("queued = %+v, want the replacement", queued)
+	}
+	if got := h.inFlight["client"]; got != 1 {
+		t.Fatalf("client in flight = %d, want 1 (the replacement)", got)
+	}
+
+	if got := incidents(t, h); len(got) != 1 || !got[0].Republished || got[0].RepublishUUID != replacement.UUID {
+		t.Fatalf("incidents = %+v, want one republished as %s", got, replacement.UUID)
+	}
+}
+
+func TestDoubleSpendUnsettledAtShutdown(t *testing.T) {
+	h := newTrainHarness(t)
+	work := h.work(t, "client")
+	work.ResponseChan = make(chan models.BroadcastResult, 1)
+	contest(t, h, work)
+	h.cancel()
+
+	h.broadcastBatch([]TxWork{work})
+
+	if queued := h.queued(); len(queued) != 0 {
+		t.Fatalf("%d transactions requeued during shutdown, want none", len(queued))
+	}
+	assertRequest(t, h, work, models.RequestStatusFailed)
+
+	// Still leased to us until the janitor asks ARC who won
+	utxo := assertUTXO(t, h, work, models.UTXOStatusLocked)
+	if utxo.LeaseExpiresAt == nil || utxo.LeaseExpiresAt.After(time.Now()) {
+		t.Fatalf("lease expires at %v, want expired", utxo.LeaseExpiresAt)
+	}
+	if result := <-work.ResponseChan; result.Error == nil || errors.Is(result.Error, ErrClientBacklog) {
+		t.Fatalf("result error = %v, want the unsettled double spend", result.Error)
+	}
+}
@@ -0,0 +1,117 @@
+package train
+
+import (
+	"sync"
+	"time"
+)
+
+// lane is a per-priority queue with its share of each departing train
+// Inside a lane every client has its own FIFO, and clients are served round
+// robin so one tenant's backlog can't starve the others in the same lane
+type lane struct {
+	priority Priority
+	weight   int
+	capacity int
+
+	mu     sync.Mutex
+	queues map[string][]TxWork // client ID -> pending work
+	order  []string            // clients with pending work, in service order
+	next   int                 // position in order of the next client to serve
+	size   int
+}
+
+// newLane creates an empty lane holding at most capacity transactions
+func newLane(priority Priority, weight, capacity int) *lane {
+	return &lane{
+		priority: priority,
+		weight:   weight,
+		capacity: capacity,
+		queues:   make(map[string][]TxWork),
+	}
+}
+
+// push appends work to its client's queue, returning false if the lane is full
+func (l *lane) push(work TxWork) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.size >= l.capacity {
+		return false
+	}
+
+	q, ok := l.queues[work.ClientID]
+	if !ok || len(q) == 0 {
+		l.order = append(l.order, work.ClientID)
+	}
+	l.queues[work.ClientID] = append(q, work)
+	l.size++
+	return true
+}
+
+// pushFront puts work back at the head of its client's queue
+// Used for transactions held back by the dispatcher; ignores capacity since
+// the work was already admitted once
+func (l *lane) pushFront(work TxWork) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	q, ok := l.queues[work.ClientID]
+	if !ok || len(q) == 0 {
+		l.order = append(l.order, work.ClientID)
+	}
+	l.queues[work.ClientID] = append([]TxWork{work}, q...)
+	l.size++
+}
+
+// pop takes the next transaction, rotating through clients one item at a time
+func (l *lane) pop() (TxWork, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.size == 0 {
+		return TxWork{}, false
+	}
+
+	if l.next >= len(l.order) {
+		l.next = 0
+	}
+
+	clientID := l.order[l.next]
+	q := l.queues[clientID]
+	work := q[0]
+	q[0] = TxWork{} // Drop references held by the backing array
+
+	if len(q) == 1 {
+		// Client drained: remove from rotation, next client slides into this slot
+		delete(l.queues, clientID)
+		l.order = append(l.order[:l.next], l.order[l.next+1:]...)
+	} else {
+		l.queues[clientID] = q[1:]
+		l.next++
+	}
+	l.size--
+
+	return work, true
+}
+
+// oldest returns when the longest-waiting transaction in the lane was enqueued
+func (l *lane) oldest() (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var oldest time.Time
+	for _, clientID := range l.order {
+		at := l.queues[clientID][0].EnqueuedAt
+		if oldest.IsZero() || at.Before(oldest) {
+			oldest = at
+		}
+	}
+	return oldest, !oldest.IsZero()
+}
+
+// len returns the number of transactions waiting in the lane
+func (l *lane) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.size
+}
@@ -0,0 +1,128 @@
+package train
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/database"
+)
+
+// newQueueTrain is a train that is never started or sent to ARC, for tests
+// that only exercise how batches are assembled from the lanes
+func newQueueTrain(t *testing.T, maxBatchSize int) *Train {
+	t.Helper()
+
+	tr := NewTrain(database.NewMemoryStore(), nil, NewStaticScheduler(time.Hour, maxBatchSize), 1, testMaxAttempts)
+	t.Cleanup(tr.cancel)
+	return tr
+}
+
+// enqueueN queues n transactions for client in a lane, with txids that say
+// where they came from
+func enqueueN(t *testing.T, tr *Train, p Priority, clientID string, n int) {
+	t.Helper()
+
+	for i := 0; i < n; i++ {
+		work := TxWork{
+			UUID:      fmt.Sprintf("%s-%s-%d", p, clientID, i),
+			Priority:  p,
+			ClientID:  clientID,
+			TxID:      fmt.Sprintf("%s-%s-%d", p, clientID, i),
+			DependsOn: []string{},
+		}
+		if err := tr.Enqueue(work); err != nil {
+			t.Fatalf("enqueue %s: %v", work.TxID, err)
+		}
+	}
+}
+
+func countByLane(batch []TxWork) map[Priority]int {
+	counts := make(map[Priority]int)
+	for _, work := range batch {
+		counts[work.Priority]++
+	}
+	return counts
+}
+
+func TestAssembleBatchWeightsLanes(t *testing.T) {
+	tr := newQueueTrain(t, 100)
+	for _, p := range Priorities {
+		enqueueN(t, tr, p, "", 100)
+	}
+
+	// One round departs six high, then three normal, then one bulk
+	batch := tr.assembleBatch(10)
+	var order []Priority
+	for _, work := range batch {
+		order = append(order, work.Priority)
+	}
+	want := []Priority{
+		PriorityHigh, PriorityHigh, PriorityHigh, PriorityHigh, PriorityHigh, PriorityHigh,
+		PriorityNormal, PriorityNormal, PriorityNormal,
+		PriorityBulk,
+	}
+	if fmt.Sprint(order) != fmt.Sprint(want) {
+		t.Fatalf("departure order = %v, want %v", order, want)
+	}
+	// Each lane is still first in, first out
+	if batch[0].TxID != "high--0" || batch[6].TxID != "normal--0" || batch[9].TxID != "bulk--0" {
+		t.Fatalf("first departures = %s, %s, %s, want the oldest of each lane", batch[0].TxID, batch[6].TxID, batch[9].TxID)
+	}
+
+	// With every lane busy, a full train carries them 6:3:1
+	counts := countByLane(tr.assembleBatch(80))
+	if counts[PriorityHigh] != 48 || counts[PriorityNormal] != 24 || counts[PriorityBulk] != 8 {
+		t.Fatalf("lane counts = %v, want 48 high, 24 normal, 8 bulk", counts)
+	}
+}
+
+func TestAssembleBatchGivesIdleLaneSharesAway(t *testing.T) {
+	tr := newQueueTrain(t, 100)
+	enqueueN(t, tr, PriorityNormal, "", 50)
+	enqueueN(t, tr, PriorityBulk, "", 50)
+
+	// No high-priority work, so normal and bulk split the train 3:1
+	counts := countByLane(tr.assembleBatch(40))
+	if counts[PriorityHigh] != 0 || counts[PriorityNormal] != 30 || counts[PriorityBulk] != 10 {
+		t.Fatalf("lane counts = %v, want 30 normal, 10 bulk", counts)
+	}
+
+	// Once normal runs dry, bulk fills the rest of the train on its own
+	counts = countByLane(tr.assembleBatch(100))
+	if counts[PriorityNormal] != 20 || counts[PriorityBulk] != 40 {
+		t.Fatalf("lane counts = %v, want the remaining 20 normal and 40 bulk", counts)
+	}
+	if tr.QueueSize() != 0 {
+		t.Fatalf("queue size = %d, want every lane drained", tr.QueueSize())
+	}
+}
+
+func TestAssembleBatchRoundRobinsClientsInALane(t *testing.T) {
+	tr := newQueueTrain(t, 100)
+	enqueueN(t, tr, PriorityNormal, "noisy", 50)
+	enqueueN(t, tr, PriorityNormal, "quiet", 3)
+
+	// The quiet client queued behind fifty others but still departs with
+	// the first train, taking turns with the noisy one
+	batch := tr.assembleBatch(6)
+	var clients []string
+	for _, work := range batch {
+		clients = append(clients, work.ClientID)
+	}
+	want := []string{"noisy", "quiet", "noisy", "quiet", "noisy", "quiet"}
+	if fmt.Sprint(clients) != fmt.Sprint(want) {
+		t.Fatalf("departing clients = %v, want %v", clients, want)
+	}
+
+	// With the quiet client done, the noisy one has the lane to itself
+	batch = tr.assembleBatch(10)
+	for _, work := range batch {
+		if work.ClientID != "noisy" {
+			t.Fatalf("departing client = %s, want noisy", work.ClientID)
+		}
+	}
+	if len(batch) != 10 {
+		t.Fatalf("batch size = %d, want 10", len(batch))
+	}
+}
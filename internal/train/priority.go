@@ -0,0 +1,55 @@
+package train
+
+// Priority selects which lane of the train a transaction waits in
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"   // Government tier / urgent publishes
+	PriorityNormal Priority = "normal" // Default lane
+	PriorityBulk   Priority = "bulk"   // Pilot tier / bulk loads
+)
+
+// Priorities lists every lane, highest priority first
+var Priorities = []Priority{PriorityHigh, PriorityNormal, PriorityBulk}
+
+// laneWeights is how many slots each lane gets per round when a batch is assembled
+// With all lanes busy a 1000-tx train carries ~600 high, ~300 normal and ~100 bulk
+var laneWeights = map[Priority]int{
+	PriorityHigh:   6,
+	PriorityNormal: 3,
+	PriorityBulk:   1,
+}
+
+// ParsePriority converts a request flag into a Priority
+func ParsePriority(s string) (Priority, bool) {
+	switch Priority(s) {
+	case PriorityHigh, PriorityNormal, PriorityBulk:
+		return Priority(s), true
+	default:
+		return "", false
+	}
+}
+
+// PriorityForTier maps a client security tier onto its default lane
+func PriorityForTier(tier string) Priority {
+	switch tier {
+	case "government":
+		return PriorityHigh
+	case "pilot":
+		return PriorityBulk
+	default:
+		return PriorityNormal
+	}
+}
+
+// Rank orders priorities so callers can cap a requested lane at a tier's lane
+func (p Priority) Rank() int {
+	switch p {
+	case PriorityHigh:
+		return 2
+	case PriorityBulk:
+		return 0
+	default:
+		return 1
+	}
+}
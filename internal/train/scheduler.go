@@ -0,0 +1,332 @@
+package train
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// QueueState is what a Scheduler sees when deciding whether the train leaves
+type QueueState struct {
+	Depth              int           // Transactions waiting across all lanes
+	OldestWait         time.Duration // How long the oldest waiting transaction has queued
+	SinceLastDeparture time.Duration
+}
+
+// Scheduler decides when the train departs and how many transactions it carries
+type Scheduler interface {
+	// MaxBatchSize is the largest batch the scheduler will ever ask for
+	MaxBatchSize() int
+	// BatchSize is the size of the next batch
+	BatchSize() int
+	// NextCheck is how long the train waits before asking ShouldDepart again
+	NextCheck() time.Duration
+	// ShouldDepart reports whether to depart now and why
+	ShouldDepart(state QueueState) (bool, string)
+	// RecordDeparture counts a batch that actually left for the given reason
+	RecordDeparture(reason string)
+	// Observe feeds back the outcome of an ARC batch call
+	Observe(batchSize int, latency time.Duration, err error)
+	// Snapshot returns the scheduler's current decisions for /health and /admin/stats
+	Snapshot() SchedulerSnapshot
+}
+
+// SchedulerSnapshot exposes scheduler state and decision counters
+type SchedulerSnapshot struct {
+	Mode              string           `json:"mode"`
+	BatchSize         int              `json:"batchSize"`
+	IntervalMs        int64            `json:"intervalMs"`
+	ARCLatencyMs      int64            `json:"arcLatencyMs"` // Smoothed ARC batch latency
+	ConsecutiveErrors int              `json:"consecutiveErrors"`
+	BackoffUntil      *time.Time       `json:"backoffUntil,omitempty"`
+	Departures        map[string]int64 `json:"departures"` // Departure reason -> count
+	Adjustments       map[string]int64 `json:"adjustments"`
+}
+
+// Departure reasons reported by schedulers
+const (
+	DepartBatchFull     = "batch_full"
+	DepartSchedule      = "schedule"
+	DepartLatencyTarget = "latency_target"
+	HoldBackoff         = "backoff"
+)
+
+// schedulerStats is the bookkeeping shared by both schedulers
+type schedulerStats struct {
+	mu          sync.Mutex
+	latencyEWMA time.Duration
+	departures  map[string]int64
+	adjustments map[string]int64
+}
+
+func newSchedulerStats() schedulerStats {
+	return schedulerStats{
+		departures:  make(map[string]int64),
+		adjustments: make(map[string]int64),
+	}
+}
+
+// observeLatency folds a latency sample into the moving average (caller holds mu)
+func (s *schedulerStats) observeLatency(latency time.Duration) {
+	if s.latencyEWMA == 0 {
+		s.latencyEWMA = latency
+		return
+	}
+	s.latencyEWMA = (s.latencyEWMA*4 + latency) / 5
+}
+
+// recordDeparture counts a departure by reason
+func (s *schedulerStats) recordDeparture(reason string) {
+	s.mu.Lock()
+	s.departures[reason]++
+	s.mu.Unlock()
+}
+
+// copyCounts snapshots a counter map (caller holds mu)
+func copyCounts(m map[string]int64) map[string]int64 {
+	out := make(map[string]int64, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+// StaticScheduler reproduces the classic train: a fixed interval and batch size
+type StaticScheduler struct {
+	interval     time.Duration
+	maxBatchSize int
+	stats        schedulerStats
+}
+
+// NewStaticScheduler creates a scheduler that departs every interval or when maxBatchSize is reached
+func NewStaticScheduler(interval time.Duration, maxBatchSize int) *StaticScheduler {
+	return &StaticScheduler{
+		interval:     interval,
+		maxBatchSize: maxBatchSize,
+		stats:        newSchedulerStats(),
+	}
+}
+
+func (s *StaticScheduler) MaxBatchSize() int        { return s.maxBatchSize }
+func (s *StaticScheduler) BatchSize() int           { return s.maxBatchSize }
+func (s *StaticScheduler) NextCheck() time.Duration { return s.interval }
+
+// ShouldDepart leaves when the batch is full or the interval has elapsed
+func (s *StaticScheduler) ShouldDepart(state QueueState) (bool, string) {
+	reason := ""
+	switch {
+	case state.Depth == 0:
+		return false, ""
+	case state.Depth >= s.maxBatchSize:
+		reason = DepartBatchFull
+	case state.SinceLastDeparture >= s.interval:
+		reason = DepartSchedule
+	default:
+		return false, ""
+	}
+	return true, reason
+}
+
+// RecordDeparture counts a departure by reason
+func (s *StaticScheduler) RecordDeparture(reason string) { s.stats.recordDeparture(reason) }
+
+// Observe records ARC latency for reporting only
+func (s *StaticScheduler) Observe(batchSize int, latency time.Duration, err error) {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+	if err == nil {
+		s.stats.observeLatency(latency)
+	}
+}
+
+// Snapshot returns the scheduler's fixed settings and counters
+func (s *StaticScheduler) Snapshot() SchedulerSnapshot {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	return SchedulerSnapshot{
+		Mode:         "static",
+		BatchSize:    s.maxBatchSize,
+		IntervalMs:   s.interval.Milliseconds(),
+		ARCLatencyMs: s.stats.latencyEWMA.Milliseconds(),
+		Departures:   copyCounts(s.stats.departures),
+		Adjustments:  copyCounts(s.stats.adjustments),
+	}
+}
+
+// String describes the scheduler for startup logs
+func (s *StaticScheduler) String() string {
+	return fmt.Sprintf("static: %v interval, max %d tx per batch", s.interval, s.maxBatchSize)
+}
+
+// AdaptiveScheduler tunes batch size and departure timing from ARC feedback
+//
+//   - Fast ARC responses grow the batch additively toward maxBatchSize
+//   - ARC errors or latency above target halve the batch and back off
+//     departures exponentially until ARC recovers
+//   - A transaction that would miss the latency target if it waited for the
+//     next scheduled departure makes the train leave early
+type AdaptiveScheduler struct {
+	baseInterval  time.Duration
+	maxBatchSize  int
+	minBatchSize  int
+	latencyTarget time.Duration
+	maxBackoff    time.Duration
+
+	batchSize         int
+	interval          time.Duration
+	consecutiveErrors int
+	backoffUntil      time.Time
+	stats             schedulerStats
+}
+
+// NewAdaptiveScheduler creates an adaptive scheduler
+// interval is the departure interval when ARC is healthy, maxBatchSize the
+// largest batch it will grow to and latencyTarget the end-to-end publish
+// latency it tries to keep queued transactions under
+func NewAdaptiveScheduler(interval time.Duration, maxBatchSize int, latencyTarget time.Duration) *AdaptiveScheduler {
+	minBatch := maxBatchSize / 20
+	if minBatch < 1 {
+		minBatch = 1
+	}
+
+	return &AdaptiveScheduler{
+		baseInterval:  interval,
+		maxBatchSize:  maxBatchSize,
+		minBatchSize:  minBatch,
+		latencyTarget: latencyTarget,
+		maxBackoff:    30 * time.Second,
+		batchSize:     maxBatchSize / 2,
+		interval:      interval,
+		stats:         newSchedulerStats(),
+	}
+}
+
+func (s *AdaptiveScheduler) MaxBatchSize() int { return s.maxBatchSize }
+
+// BatchSize returns the current adaptive batch size
+func (s *AdaptiveScheduler) BatchSize() int {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+	return s.batchSize
+}
+
+// NextCheck polls often enough to catch transactions approaching the latency target
+func (s *AdaptiveScheduler) NextCheck() time.Duration {
+	check := s.baseInterval / 10
+	if check < 50*time.Millisecond {
+		check = 50 * time.Millisecond
+	}
+	return check
+}
+
+// ShouldDepart leaves on a full batch, when the interval elapses, or early
+// when the oldest transaction would otherwise miss the latency target
+func (s *AdaptiveScheduler) ShouldDepart(state QueueState) (bool, string) {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	if state.Depth == 0 {
+		return false, ""
+	}
+
+	if time.Now().Before(s.backoffUntil) {
+		return false, HoldBackoff
+	}
+
+	reason := ""
+	switch {
+	case state.Depth >= s.batchSize:
+		reason = DepartBatchFull
+	case state.SinceLastDeparture >= s.interval:
+		reason = DepartSchedule
+	case s.latencyTarget > 0 && state.OldestWait+s.stats.latencyEWMA+s.NextCheck() >= s.latencyTarget:
+		reason = DepartLatencyTarget
+	default:
+		return false, ""
+	}
+	return true, reason
+}
+
+// RecordDeparture counts a departure by reason
+func (s *AdaptiveScheduler) RecordDeparture(reason string) { s.stats.recordDeparture(reason) }
+
+// Observe adjusts batch size and interval from the outcome of an ARC call
+func (s *AdaptiveScheduler) Observe(batchSize int, latency time.Duration, err error) {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	if err != nil {
+		s.consecutiveErrors++
+		s.shrink("error")
+
+		backoff := s.baseInterval << uint(s.consecutiveErrors)
+		if backoff > s.maxBackoff || backoff <= 0 {
+			backoff = s.maxBackoff
+		}
+		s.backoffUntil = time.Now().Add(backoff)
+		s.interval = backoff
+		return
+	}
+
+	s.consecutiveErrors = 0
+	s.backoffUntil = time.Time{}
+	s.stats.observeLatency(latency)
+
+	slow := s.latencyTarget > 0 && s.stats.latencyEWMA > s.latencyTarget/2
+	switch {
+	case slow:
+		s.shrink("slow")
+	case batchSize >= s.batchSize && s.batchSize < s.maxBatchSize:
+		// Only grow when we actually filled the current batch and ARC kept up
+		s.batchSize += s.maxBatchSize / 10
+		if s.batchSize > s.maxBatchSize {
+			s.batchSize = s.maxBatchSize
+		}
+		s.stats.adjustments["grow"]++
+	}
+
+	// Recover the interval gradually after a backoff
+	if s.interval > s.baseInterval {
+		s.interval /= 2
+		if s.interval < s.baseInterval {
+			s.interval = s.baseInterval
+		}
+	}
+}
+
+// shrink halves the batch size (caller holds mu)
+func (s *AdaptiveScheduler) shrink(reason string) {
+	s.batchSize /= 2
+	if s.batchSize < s.minBatchSize {
+		s.batchSize = s.minBatchSize
+	}
+	s.stats.adjustments["shrink_"+reason]++
+}
+
+// Snapshot returns the controller's current decisions
+func (s *AdaptiveScheduler) Snapshot() SchedulerSnapshot {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	snap := SchedulerSnapshot{
+		Mode:              "adaptive",
+		BatchSize:         s.batchSize,
+		IntervalMs:        s.interval.Milliseconds(),
+		ARCLatencyMs:      s.stats.latencyEWMA.Milliseconds(),
+		ConsecutiveErrors: s.consecutiveErrors,
+		Departures:        copyCounts(s.stats.departures),
+		Adjustments:       copyCounts(s.stats.adjustments),
+	}
+	if time.Now().Before(s.backoffUntil) {
+		until := s.backoffUntil
+		snap.BackoffUntil = &until
+	}
+	return snap
+}
+
+// String describes the scheduler for startup logs
+func (s *AdaptiveScheduler) String() string {
+	return fmt.Sprintf("adaptive: %v base interval, %d-%d tx per batch, %v latency target",
+		s.baseInterval, s.minBatchSize, s.maxBatchSize, s.latencyTarget)
+}
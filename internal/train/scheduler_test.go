@@ -0,0 +1,146 @@
+package train
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// arcSample is one ARC batch call fed back to a scheduler
+type arcSample struct {
+	batchSize int
+	latency   time.Duration
+	err       error
+}
+
+func TestAdaptiveSchedulerAdjusts(t *testing.T) {
+	errARC := errors.New("ARC unavailable")
+	fast := func(size int) arcSample { return arcSample{batchSize: size, latency: 100 * time.Millisecond} }
+	failed := arcSample{batchSize: 500, err: errARC}
+
+	// 100ms base interval, up to 1000 tx, 2s latency target: batches start
+	// at 500, grow by 100 and never shrink below 50
+	tests := []struct {
+		name         string
+		samples      []arcSample
+		wantBatch    int
+		wantInterval time.Duration
+		wantErrors   int
+		wantBackoff  bool
+	}{
+		{
+			name:         "fast full batches grow",
+			samples:      []arcSample{fast(500), fast(600)},
+			wantBatch:    700,
+			wantInterval: 100 * time.Millisecond,
+		},
+		{
+			name:         "partial batches don't grow",
+			samples:      []arcSample{fast(100), fast(499)},
+			wantBatch:    500,
+			wantInterval: 100 * time.Millisecond,
+		},
+		{
+			name:         "growth stops at the max",
+			samples:      []arcSample{fast(500), fast(600), fast(700), fast(800), fast(900), fast(1000), fast(1000)},
+			wantBatch:    1000,
+			wantInterval: 100 * time.Millisecond,
+		},
+		{
+			name:         "latency over half the target shrinks",
+			samples:      []arcSample{{batchSize: 500, latency: 1500 * time.Millisecond}},
+			wantBatch:    250,
+			wantInterval: 100 * time.Millisecond,
+		},
+		{
+			name:         "an error halves the batch and backs off",
+			samples:      []arcSample{failed},
+			wantBatch:    250,
+			wantInterval: 200 * time.Millisecond,
+			wantErrors:   1,
+			wantBackoff:  true,
+		},
+		{
+			name:         "consecutive errors back off exponentially",
+			samples:      []arcSample{failed, failed, failed},
+			wantBatch:    62,
+			wantInterval: 800 * time.Millisecond,
+			wantErrors:   3,
+			wantBackoff:  true,
+		},
+		{
+			name:         "backoff and shrinking are bounded",
+			samples:      []arcSample{failed, failed, failed, failed, failed, failed, failed, failed, failed, failed},
+			wantBatch:    50,
+			wantInterval: 30 * time.Second,
+			wantErrors:   10,
+			wantBackoff:  true,
+		},
+		{
+			name:         "recovery clears the backoff and halves the interval",
+			samples:      []arcSample{failed, failed, fast(125)},
+			wantBatch:    225,
+			wantInterval: 200 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewAdaptiveScheduler(100*time.Millisecond, 1000, 2*time.Second)
+			for _, sample := range tt.samples {
+				s.Observe(sample.batchSize, sample.latency, sample.err)
+			}
+
+			snap := s.Snapshot()
+			if snap.BatchSize != tt.wantBatch || s.BatchSize() != tt.wantBatch {
+				t.Errorf("batch size = %d, want %d", snap.BatchSize, tt.wantBatch)
+			}
+			if got := time.Duration(snap.IntervalMs) * time.Millisecond; got != tt.wantInterval {
+				t.Errorf("interval = %v, want %v", got, tt.wantInterval)
+			}
+			if snap.ConsecutiveErrors != tt.wantErrors {
+				t.Errorf("consecutive errors = %d, want %d", snap.ConsecutiveErrors, tt.wantErrors)
+			}
+			if (snap.BackoffUntil != nil) != tt.wantBackoff {
+				t.Errorf("backoff until = %v, want backing off %v", snap.BackoffUntil, tt.wantBackoff)
+			}
+
+			// A backing-off train holds even a full batch
+			depart, reason := s.ShouldDepart(QueueState{Depth: 1000})
+			if tt.wantBackoff && (depart || reason != HoldBackoff) {
+				t.Errorf("ShouldDepart while backing off = %v %q, want hold for %s", depart, reason, HoldBackoff)
+			}
+			if !tt.wantBackoff && (!depart || reason != DepartBatchFull) {
+				t.Errorf("ShouldDepart with a full batch = %v %q, want %s", depart, reason, DepartBatchFull)
+			}
+		})
+	}
+}
+
+func TestAdaptiveSchedulerDepartsForLatencyTarget(t *testing.T) {
+	s := NewAdaptiveScheduler(time.Second, 1000, 2*time.Second)
+	s.Observe(10, 500*time.Millisecond, nil)
+
+	tests := []struct {
+		name       string
+		state      QueueState
+		wantDepart bool
+		wantReason string
+	}{
+		{"empty queue", QueueState{}, false, ""},
+		{"fresh transactions wait", QueueState{Depth: 10, OldestWait: 100 * time.Millisecond}, false, ""},
+		{"interval elapsed", QueueState{Depth: 10, SinceLastDeparture: time.Second}, true, DepartSchedule},
+		// 1.4s queued + 0.5s at ARC + 0.1s until the next check reaches 2s
+		{"oldest would miss the target", QueueState{Depth: 10, OldestWait: 1400 * time.Millisecond}, true, DepartLatencyTarget},
+		{"full batch", QueueState{Depth: 500}, true, DepartBatchFull},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			depart, reason := s.ShouldDepart(tt.state)
+			if depart != tt.wantDepart || reason != tt.wantReason {
+				t.Fatalf("ShouldDepart = %v %q, want %v %q", depart, reason, tt.wantDepart, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package train
+
+import (
+	"context"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/models"
+	"github.com/akua/bsv-broadcaster/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startBatchSpan starts a batch's own trace, linked to the publish request
+// of every transaction it carries; the ARC call is traced as its child
+func startBatchSpan(ctx context.Context, batch []TxWork) (context.Context, trace.Span) {
+	links := make([]trace.Link, 0, len(batch))
+	for _, work := range batch {
+		if work.SpanContext.IsValid() {
+			links = append(links, trace.Link{
+				SpanContext: work.SpanContext,
+				Attributes:  []attribute.KeyValue{tracing.AttrRequestUUID.String(work.UUID)},
+			})
+		}
+	}
+
+	return tracing.Tracer().Start(ctx, "Train.broadcastBatch",
+		trace.WithNewRoot(),
+		trace.WithLinks(links...),
+		trace.WithAttributes(tracing.AttrBatchSize.Int(len(batch))),
+	)
+}
+
+// startWorkSpans starts a span per transaction in its publish request's
+// trace, covering its wait in the queue and this attempt at ARC
+// Each links back to the batch span that carried it.
+func startWorkSpans(batch []TxWork, batchSpan trace.Span) {
+	departed := time.Now()
+	for i := range batch {
+		work := &batch[i]
+		ctx := trace.ContextWithSpanContext(context.Background(), work.SpanContext)
+		_, work.span = tracing.Tracer().Start(ctx, "Train.broadcast",
+			trace.WithTimestamp(work.EnqueuedAt),
+			trace.WithLinks(trace.Link{SpanContext: batchSpan.SpanContext()}),
+			trace.WithAttributes(
+				tracing.AttrRequestUUID.String(work.UUID),
+				tracing.AttrClientID.String(work.ClientID),
+				tracing.AttrTxID.String(work.TxID),
+				attribute.String("train.lane", string(work.Priority)),
+				attribute.Int("train.attempt", work.Attempts+1),
+			),
+		)
+		work.span.AddEvent("departed", trace.WithTimestamp(departed))
+	}
+}
+
+// endWorkSpans ends the spans startWorkSpans began
+func endWorkSpans(batch []TxWork) {
+	for _, work := range batch {
+		if work.span != nil {
+			work.span.End()
+		}
+	}
+}
+
+// finishWorkSpan records a transaction's final result on its span
+func finishWorkSpan(work TxWork, result models.BroadcastResult) {
+	if work.span == nil {
+		return
+	}
+	work.span.SetAttributes(tracing.AttrARCStatus.String(result.ARCStatus))
+	if result.TXID != "" {
+		work.span.SetAttributes(tracing.AttrTxID.String(result.TXID))
+	}
+	tracing.RecordError(work.span, result.Error)
+}
@@ -2,49 +2,128 @@ package train
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/akua/bsv-broadcaster/internal/arc"
 	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/logging"
+	"github.com/akua/bsv-broadcaster/internal/metrics"
 	"github.com/akua/bsv-broadcaster/internal/models"
+	"github.com/akua/bsv-broadcaster/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// ErrClientBacklog is returned by Enqueue when a client already has its
+// maximum number of transactions queued or in flight
+var ErrClientBacklog = errors.New("client has too many transactions in flight")
+
+// errNoResponse marks batch items ARC's response didn't account for
+var errNoResponse = errors.New("ARC returned no response for transaction")
+
 // TxWork represents a transaction ready to be broadcast
 type TxWork struct {
-	UUID         string
-	RawTxHex     string
-	UTXOUsed     string
-	ResponseChan chan models.BroadcastResult // Optional for sync wait
+	UUID          string
+	RawTxHex      string
+	UTXOUsed      string
+	Priority      Priority                    // Lane to queue in (defaults to normal)
+	ClientID      string                      // Fair-queuing key (empty for unauthenticated requests)
+	Tier          string                      // Client tier, for metrics
+	MaxInFlight   int                         // Client's in-flight cap (0 = train default)
+	EnqueuedAt    time.Time                   // Set by Enqueue
+	TxID          string                      // Computed locally when the tx is built
+	DependsOn     []string                    // Parent txids (derived from RawTxHex if nil)
+	Attempts      int                         // Failed ARC round trips so far
+	NotBefore     time.Time                   // Retry backoff: not sent before this time
+	AutoRepublish bool                        // Reissue the payload if it loses a double spend
+	SubmittedAt   time.Time                   // When the publish request arrived (zero for republishes)
+	SpanContext   trace.SpanContext           // Publish request's span; the train traces under it
+	ResponseChan  chan models.BroadcastResult // Optional for sync wait
+	Refund        func(context.Context) error // Gives back the client's quota if ARC never takes the tx
+
+	span   trace.Span // This attempt's span, set while its batch is at ARC
+	unsent bool       // Failed without reaching ARC, so finish refunds it
+
+	// Set once ARC reports a double spend; the work stays contested until
+	// one side is mined (see handleDoubleSpend)
+	incident      *models.DoubleSpendIncident
+	contestChecks int
+}
+
+// logContext returns ctx with the work's request, client and txid attached to
+// log records
+func (w TxWork) logContext(ctx context.Context) context.Context {
+	return logging.With(ctx,
+		slog.String(logging.KeyRequestUUID, w.UUID),
+		slog.String(logging.KeyClientID, w.ClientID),
+		slog.String(logging.KeyTxID, w.TxID),
+	)
 }
 
 // Train implements the "train station" batching logic
-// Every N seconds, it collects up to M transactions and broadcasts them
+// Its Scheduler decides when it departs and how many transactions it carries
 type Train struct {
-	db           *database.Database
-	arcClient    *arc.Client
-	txQueue      chan TxWork
-	interval     time.Duration
-	maxBatchSize int
-	ctx          context.Context
-	cancel       context.CancelFunc
-	wg           sync.WaitGroup
+	db            database.Store
+	arcClient     *arc.Client
+	scheduler     Scheduler
+	lanes         []*lane // Ordered highest priority first
+	arrivals      chan struct{}
+	maxInFlight   int       // Default per-client cap
+	maxAttempts   int       // ARC round trips before a retriable failure is final
+	lastDeparture time.Time // Only touched by run
+	republisher   Republisher
+
+	inFlightMu sync.Mutex
+	inFlight   map[string]int // client ID -> queued + broadcasting
+
+	// Concurrent dispatch: each batch waiting on ARC holds a slot
+	slots        chan struct{}
+	batches      sync.WaitGroup
+	inFlightTxMu sync.Mutex
+	inFlightTx   map[string]struct{} // txids in batches ARC hasn't answered
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
 // NewTrain creates a new train worker
-func NewTrain(db *database.Database, arcClient *arc.Client, interval time.Duration, maxBatchSize int) *Train {
+// maxConcurrent bounds how many batches may be waiting on ARC at once and
+// maxAttempts how often a transaction is sent after retriable failures
+func NewTrain(db database.Store, arcClient *arc.Client, scheduler Scheduler, maxConcurrent, maxAttempts int) *Train {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	maxBatchSize := scheduler.MaxBatchSize()
+
+	lanes := make([]*lane, 0, len(Priorities))
+	for _, p := range Priorities {
+		lanes = append(lanes, newLane(p, laneWeights[p], maxBatchSize*10)) // Buffer for 10 trains per lane
+	}
+
 	return &Train{
-		db:           db,
-		arcClient:    arcClient,
-		txQueue:      make(chan TxWork, maxBatchSize*10), // Buffer for 10 trains
-		interval:     interval,
-		maxBatchSize: maxBatchSize,
-		ctx:          ctx,
-		cancel:       cancel,
+		db:          db,
+		arcClient:   arcClient,
+		lanes:       lanes,
+		scheduler:   scheduler,
+		arrivals:    make(chan struct{}, 1),
+		maxInFlight: maxBatchSize * 2, // Two trains' worth per client
+		maxAttempts: maxAttempts,
+		inFlight:    make(map[string]int),
+		slots:       make(chan struct{}, maxConcurrent),
+		inFlightTx:  make(map[string]struct{}),
+		ctx:         ctx,
+		cancel:      cancel,
 	}
 }
 
@@ -52,83 +131,266 @@ func NewTrain(db *database.Database, arcClient *arc.Client, interval time.Durati
 func (t *Train) Start() {
 	t.wg.Add(1)
 	go t.run()
-	log.Printf("🚂 Train started: %v interval, max %d tx per batch", t.interval, t.maxBatchSize)
+	slog.Info("Train started", "scheduler", fmt.Sprint(t.scheduler), "max_in_flight", cap(t.slots))
 }
 
-// Stop gracefully stops the train, finishing the current batch
+// Stop gracefully stops the train, draining the queue and waiting for every
+// in-flight batch to be answered by ARC
 func (t *Train) Stop() {
-	log.Println("🛑 Train stopping... finishing current batch")
+	slog.Info("Train stopping, finishing in-flight batches")
 	t.cancel()
 	t.wg.Wait()
-	log.Println("✓ Train stopped cleanly")
+	slog.Info("Train stopped cleanly")
 }
 
-// Enqueue adds a transaction to the queue
-func (t *Train) Enqueue(work TxWork) error {
+// Enqueue adds a transaction to the queue of its priority lane
+func (t *Train) Enqueue(work TxWork) (err error) {
+	l := t.laneFor(work.Priority)
+	work.EnqueuedAt = time.Now()
+	describeTx(&work)
+
+	_, span := tracing.Tracer().Start(trace.ContextWithSpanContext(context.Background(), work.SpanContext), "Train.Enqueue",
+		trace.WithAttributes(
+			tracing.AttrRequestUUID.String(work.UUID),
+			tracing.AttrTxID.String(work.TxID),
+			attribute.String("train.lane", string(l.priority)),
+		),
+	)
+	defer func() {
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
 	select {
-	case t.txQueue <- work:
-		return nil
 	case <-t.ctx.Done():
 		return fmt.Errorf("train is shutting down")
 	default:
-		return fmt.Errorf("queue is full")
 	}
+
+	if err := t.acquire(work); err != nil {
+		return err
+	}
+
+	if !l.push(work) {
+		t.release(work)
+		return fmt.Errorf("%s queue is full", l.priority)
+	}
+
+	// Wake the train so it can depart early once a full batch is waiting
+	select {
+	case t.arrivals <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// acquire reserves an in-flight slot for the work's client
+// Unauthenticated work (no client ID) is bounded only by lane capacity
+func (t *Train) acquire(work TxWork) error {
+	if work.ClientID == "" {
+		return nil
+	}
+
+	limit := work.MaxInFlight
+	if limit <= 0 {
+		limit = t.maxInFlight
+	}
+
+	t.inFlightMu.Lock()
+	defer t.inFlightMu.Unlock()
+
+	if t.inFlight[work.ClientID] >= limit {
+		return ErrClientBacklog
+	}
+	t.inFlight[work.ClientID]++
+	return nil
+}
+
+// release frees the in-flight slot held by the work's client
+func (t *Train) release(work TxWork) {
+	if work.ClientID == "" {
+		return
+	}
+
+	t.inFlightMu.Lock()
+	defer t.inFlightMu.Unlock()
+
+	if t.inFlight[work.ClientID] <= 1 {
+		delete(t.inFlight, work.ClientID)
+		return
+	}
+	t.inFlight[work.ClientID]--
+}
+
+// laneFor returns the lane for a priority, falling back to normal
+func (t *Train) laneFor(p Priority) *lane {
+	for _, l := range t.lanes {
+		if l.priority == p {
+			return l
+		}
+	}
+	return t.laneFor(PriorityNormal)
 }
 
 // run is the main train loop
 func (t *Train) run() {
 	defer t.wg.Done()
 
-	ticker := time.NewTicker(t.interval)
-	defer ticker.Stop()
-
-	var batch []TxWork
+	t.lastDeparture = time.Now()
+	timer := time.NewTimer(t.scheduler.NextCheck())
+	defer timer.Stop()
 
 	for {
 		select {
-		case work := <-t.txQueue:
-			// Add to current batch
-			batch = append(batch, work)
-
-			// If batch is full, broadcast immediately
-			if len(batch) >= t.maxBatchSize {
-				log.Printf("🚂 Train departing early (batch full: %d tx)", len(batch))
-				t.broadcastBatch(batch)
-				batch = nil
+		case <-t.arrivals:
+			// New work may have filled a batch - ask the scheduler
+			// and restart the clock if the train left
+			if t.departWhileReady() {
+				timer.Reset(t.scheduler.NextCheck())
 			}
 
-		case <-ticker.C:
-			// Time's up! Broadcast whatever we have
-			if len(batch) > 0 {
-				log.Printf("🚂 Train departing on schedule (%d tx)", len(batch))
-				t.broadcastBatch(batch)
-				batch = nil
-			}
+		case <-timer.C:
+			t.departWhileReady()
+			timer.Reset(t.scheduler.NextCheck())
 
 		case <-t.ctx.Done():
 			// Shutdown signal received
-			// Make final attempt to broadcast pending batch
-			if len(batch) > 0 {
-				log.Printf("🚂 Final departure: broadcasting %d pending tx", len(batch))
-				t.broadcastBatch(batch)
+			// Drain every lane so nothing queued is left behind
+			if remaining := t.QueueSize(); remaining > 0 {
+				slog.Info("Final departure", "pending", remaining)
+			}
+			for {
+				batch := t.assembleBatch(t.scheduler.MaxBatchSize())
+				if len(batch) == 0 {
+					// Everything left depends on an in-flight batch, or the
+					// queue is empty; in-flight batches may still requeue retries
+					t.batches.Wait()
+					if t.QueueSize() == 0 {
+						break
+					}
+					continue
+				}
+				t.slots <- struct{}{}
+				t.dispatch(batch)
 			}
 
-			// Drain any remaining items in queue
-			remaining := len(t.txQueue)
-			if remaining > 0 {
-				log.Printf("⚠️  Warning: %d transactions left in queue at shutdown", remaining)
-				finalBatch := make([]TxWork, 0, remaining)
-				for i := 0; i < remaining && i < t.maxBatchSize; i++ {
-					finalBatch = append(finalBatch, <-t.txQueue)
+			return
+		}
+	}
+}
+
+// departWhileReady keeps dispatching trains while the scheduler wants them,
+// so a deep backlog drains in back-to-back full batches
+func (t *Train) departWhileReady() bool {
+	departed := false
+	for t.maybeDepart() {
+		departed = true
+	}
+	return departed
+}
+
+// maybeDepart broadcasts the next batch if the scheduler says the train should leave
+func (t *Train) maybeDepart() bool {
+	state := QueueState{
+		Depth:              t.QueueSize(),
+		OldestWait:         t.oldestWait(),
+		SinceLastDeparture: time.Since(t.lastDeparture),
+	}
+
+	if state.Depth == 0 || !t.tryAcquireSlot() {
+		// Nothing to send, or every slot is waiting on ARC; the next
+		// completed batch wakes the loop again
+		return false
+	}
+
+	depart, reason := t.scheduler.ShouldDepart(state)
+	if !depart {
+		<-t.slots
+		return false
+	}
+
+	batch := t.assembleBatch(t.scheduler.BatchSize())
+	if len(batch) == 0 {
+		<-t.slots
+		return false
+	}
+
+	slog.Debug("Train departing", "reason", reason, "batch_size", len(batch), "in_flight", len(t.slots))
+	t.lastDeparture = time.Now()
+	t.scheduler.RecordDeparture(reason)
+	t.dispatch(batch)
+	return true
+}
+
+// oldestWait returns how long the oldest queued transaction has been waiting
+func (t *Train) oldestWait() time.Duration {
+	var oldest time.Time
+	for _, l := range t.lanes {
+		if at, ok := l.oldest(); ok && (oldest.IsZero() || at.Before(oldest)) {
+			oldest = at
+		}
+	}
+	if oldest.IsZero() {
+		return 0
+	}
+	return time.Since(oldest)
+}
+
+// assembleBatch pulls up to size transactions from the lanes using
+// weighted round robin, so each round takes up to `weight` items per lane and
+// capacity unused by an empty lane flows to the others
+//
+// Transactions whose parents are still in flight (or held back themselves)
+// stay queued so a child never races its parent on a parallel train, as do
+// retries still backing off (unless the train is shutting down)
+func (t *Train) assembleBatch(size int) []TxWork {
+	var batch []TxWork
+	var held []TxWork
+	heldTx := make(map[string]struct{})
+
+	for len(batch) < size {
+		progressed := false
+
+		for _, l := range t.lanes {
+			for i := 0; i < l.weight && len(batch) < size; i++ {
+				work, ok := l.pop()
+				if !ok {
+					break
 				}
-				if len(finalBatch) > 0 {
-					t.broadcastBatch(finalBatch)
+				progressed = true
+
+				backingOff := time.Now().Before(work.NotBefore) && t.ctx.Err() == nil
+				if backingOff || t.blockedByInFlight(work) || dependsOnAny(work, heldTx) {
+					held = append(held, work)
+					heldTx[work.TxID] = struct{}{}
+					i-- // Held work doesn't use up the lane's share
+					continue
 				}
+				batch = append(batch, work)
 			}
+		}
 
-			return
+		if !progressed {
+			break
+		}
+	}
+
+	// Put held work back in its original order
+	for i := len(held) - 1; i >= 0; i-- {
+		t.laneFor(held[i].Priority).pushFront(held[i])
+	}
+
+	return batch
+}
+
+// dependsOnAny reports whether the work spends an output of any txid in set
+func dependsOnAny(work TxWork, set map[string]struct{}) bool {
+	for _, parent := range work.DependsOn {
+		if _, ok := set[parent]; ok {
+			return true
 		}
 	}
+	return false
 }
 
 // broadcastBatch sends a batch of transactions to ARC
@@ -140,6 +402,11 @@ func (t *Train) broadcastBatch(batch []TxWork) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
+	ctx, span := startBatchSpan(ctx, batch)
+	defer span.End()
+	startWorkSpans(batch, span)
+	defer endWorkSpans(batch)
+
 	// Extract hex strings
 	hexes := make([]string, len(batch))
 	for i, work := range batch {
@@ -152,96 +419,303 @@ func (t *Train) broadcastBatch(batch []TxWork) {
 	}
 
 	// Broadcast to ARC
+	metrics.TrainBatchSize.Observe(float64(len(batch)))
+	start := time.Now()
 	responses, err := t.arcClient.BroadcastBatch(ctx, hexes)
+	t.scheduler.Observe(len(batch), time.Since(start), err)
 	if err != nil {
-		log.Printf("❌ Batch broadcast failed: %v", err)
+		tracing.RecordError(span, err)
+		slog.ErrorContext(ctx, "Batch broadcast failed", "retriable", arc.IsRetriable(err), "batch_size", len(batch), logging.Err(err))
+		t.handleUnanswered(batch, err)
+		return
+	}
 
-		// Mark all as failed
-		for _, work := range batch {
-			t.db.UpdateRequestStatus(ctx, work.UUID, models.RequestStatusFailed, "", "", err.Error())
-			t.db.UnlockUTXO(ctx, work.UTXOUsed)
+	// Match responses by txid - ARC doesn't promise to answer in order, or
+	// for every transaction
+	byTxID := make(map[string]int, len(batch))
+	for i, work := range batch {
+		if work.TxID != "" {
+			byTxID[work.TxID] = i
 		}
-		return
 	}
 
 	// Process responses
 	successCount := 0
 	failCount := 0
+	answered := make([]bool, len(batch))
 
-	for i, resp := range responses {
-		work := batch[i]
-
-		var resultError error
-		var txid string
-		var arcStatus string
+	for _, resp := range responses {
+		i, ok := byTxID[resp.TxID]
+		if !ok {
+			slog.WarnContext(ctx, "Ignoring ARC response for unknown txid", logging.KeyTxID, resp.TxID, "arc_status", resp.TxStatus)
+			continue
+		}
+		if answered[i] {
+			slog.WarnContext(ctx, "Ignoring duplicate ARC response", logging.KeyTxID, resp.TxID, "arc_status", resp.TxStatus)
+			continue
+		}
+		answered[i] = true
 
-		switch resp.TxStatus {
-		case arc.TxStatusAccepted, arc.TxStatusSeenOnNetwork:
-			// Success! Mark UTXO as spent and request as successful
-			t.db.MarkUTXOSpent(ctx, work.UTXOUsed, resp.TxID)
-			t.db.UpdateRequestStatus(ctx, work.UUID, models.RequestStatusSuccess, resp.TxID, string(resp.TxStatus), "")
+		if t.handleResponse(ctx, batch[i], resp) {
 			successCount++
-			txid = resp.TxID
-			arcStatus = string(resp.TxStatus)
+		} else {
+			failCount++
+		}
+	}
 
-		case arc.TxStatusMined:
-			// Even better - already mined
-			t.db.MarkUTXOSpent(ctx, work.UTXOUsed, resp.TxID)
-			t.db.UpdateRequestStatus(ctx, work.UUID, models.RequestStatusMined, resp.TxID, string(resp.TxStatus), "")
-			successCount++
-			txid = resp.TxID
-			arcStatus = string(resp.TxStatus)
+	// Anything ARC didn't answer for goes down the retry path
+	var unanswered []TxWork
+	for i, work := range batch {
+		if !answered[i] {
+			unanswered = append(unanswered, work)
+		}
+	}
+	if len(unanswered) > 0 {
+		slog.WarnContext(ctx, "ARC did not answer for every transaction", "answered", len(batch)-len(unanswered), "batch_size", len(batch))
+		t.handleUnanswered(unanswered, errNoResponse)
+	}
 
-		case arc.TxStatusDoubleSpend:
-			// Someone else spent this UTXO - mark as spent anyway
-			t.db.MarkUTXOSpent(ctx, work.UTXOUsed, resp.TxID)
-			t.db.UpdateRequestStatus(ctx, work.UUID, models.RequestStatusFailed, "", string(resp.TxStatus), "double spend detected")
-			failCount++
-			resultError = fmt.Errorf("double spend detected")
-			arcStatus = string(resp.TxStatus)
+	span.SetAttributes(
+		attribute.Int("broadcast.succeeded", successCount),
+		attribute.Int("broadcast.failed", failCount),
+		attribute.Int("broadcast.unanswered", len(unanswered)),
+	)
+	slog.InfoContext(ctx, "Batch complete", "succeeded", successCount, "failed", failCount, "unanswered", len(unanswered))
+}
 
-		case arc.TxStatusRejected:
-			// Transaction rejected - unlock UTXO for reuse
-			t.db.UnlockUTXO(ctx, work.UTXOUsed)
-			t.db.UpdateRequestStatus(ctx, work.UUID, models.RequestStatusFailed, "", string(resp.TxStatus), resp.ExtraInfo)
-			failCount++
-			resultError = fmt.Errorf("ARC rejected: %s", resp.ExtraInfo)
-			arcStatus = string(resp.TxStatus)
+// handleResponse applies ARC's verdict on one transaction and reports whether it succeeded
+func (t *Train) handleResponse(ctx context.Context, work TxWork, resp arc.TxResponse) bool {
+	var resultError error
+	var txid string
+	arcStatus := string(resp.TxStatus)
+
+	switch resp.Outcome() {
+	case arc.OutcomePending, arc.OutcomeAccepted:
+		// Success! ARC has the transaction - mark UTXO as spent and request as successful
+		t.db.MarkUTXOSpent(ctx, work.UTXOUsed, resp.TxID)
+		t.db.UpdateRequestStatus(ctx, work.UUID, models.RequestStatusSuccess, resp.TxID, arcStatus, "")
+		txid = resp.TxID
+
+	case arc.OutcomeMined:
+		// Even better - already mined
+		t.db.MarkUTXOSpent(ctx, work.UTXOUsed, resp.TxID)
+		t.db.UpdateRequestStatus(ctx, work.UUID, models.RequestStatusMined, resp.TxID, arcStatus, "")
+		txid = resp.TxID
+
+	case arc.OutcomeDoubleSpend:
+		// Someone else is spending this UTXO too; handleDoubleSpend
+		// finishes the work once one side is mined
+		t.handleDoubleSpend(ctx, work, resp)
+		return false
 
-		default:
-			// Unknown status - mark as failed and unlock UTXO
-			t.db.UnlockUTXO(ctx, work.UTXOUsed)
-			t.db.UpdateRequestStatus(ctx, work.UUID, models.RequestStatusFailed, "", string(resp.TxStatus), resp.ExtraInfo)
-			failCount++
-			resultError = fmt.Errorf("broadcast failed: %s", resp.ExtraInfo)
-			arcStatus = string(resp.TxStatus)
+	case arc.OutcomeOrphan:
+		// Not final: ARC keeps the tx and broadcasts it once the parent
+		// shows up. The request stays pending with the ARC status, and the
+		// lease goes to the janitor, which follows the tx up with ARC until
+		// it's mined or dropped
+		t.db.ExpireLease(ctx, work.UTXOUsed)
+		t.db.UpdateRequestStatus(ctx, work.UUID, models.RequestStatusPending, resp.TxID, arcStatus, "")
+		txid = resp.TxID
+
+	case arc.OutcomeRejected:
+		if work.incident != nil {
+			// ARC rejects the loser of a double spend; its input is gone
+			t.handleDoubleSpend(ctx, work, resp)
+			return false
 		}
 
-		// Notify waiting client if they're listening (sync mode)
-		if work.ResponseChan != nil {
-			result := models.BroadcastResult{
-				TXID:      txid,
-				ARCStatus: arcStatus,
-				Error:     resultError,
-			}
+		// Transaction rejected - unlock UTXO for reuse
+		t.db.UnlockUTXO(ctx, work.UTXOUsed)
+		t.db.UpdateRequestStatus(ctx, work.UUID, models.RequestStatusFailed, "", arcStatus, resp.ExtraInfo)
+		resultError = fmt.Errorf("ARC rejected: %s", resp.ExtraInfo)
+
+	default:
+		// Unknown status - ARC may still hold the tx, so give up the lease
+		// and let the janitor ask ARC again before anyone reuses the UTXO
+		t.db.ExpireLease(ctx, work.UTXOUsed)
+		t.db.UpdateRequestStatus(ctx, work.UUID, models.RequestStatusFailed, "", arcStatus, resp.ExtraInfo)
+		resultError = fmt.Errorf("unexpected ARC status %q: %s", resp.TxStatus, resp.ExtraInfo)
+	}
+
+	t.complete(work, models.BroadcastResult{
+		TXID:      txid,
+		ARCStatus: arcStatus,
+		Error:     resultError,
+	})
+
+	return resultError == nil
+}
+
+// handleUnanswered deals with transactions ARC gave no verdict on, either
+// because the whole call failed or because the response left them out
+// The signed transactions may still have reached ARC, so each one is looked
+// up by txid first. Unknown ones are requeued unchanged (rebroadcasting the
+// same tx is idempotent) until maxAttempts, then failed.
+func (t *Train) handleUnanswered(batch []TxWork, batchErr error) {
+	retriable := arc.IsRetriable(batchErr)
+
+	// Fresh context: the batch context may be what timed out
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Only worth asking ARC about txs it might have processed, and only
+	// while it keeps answering
+	lookups := retriable
+	var requeued []TxWork
 
-			// Non-blocking send (client may have timed out)
-			select {
-			case work.ResponseChan <- result:
-				// Successfully notified
+	for _, work := range batch {
+		work.Attempts++
+		attempt := models.BroadcastAttempt{
+			Attempt:   work.Attempts,
+			At:        time.Now(),
+			Error:     batchErr.Error(),
+			Retriable: retriable,
+		}
+
+		// A refused request definitely didn't land, and neither did a tx we
+		// couldn't even parse for a txid
+		known := !retriable || work.TxID == ""
+		if lookups && work.TxID != "" {
+			resp, err := t.lookupStatus(ctx, work.TxID)
+			switch {
+			case err == nil:
+				// ARC has it after all
+				attempt.ARCStatus = string(resp.TxStatus)
+				t.db.AppendRequestAttempt(ctx, work.UUID, attempt)
+				t.handleResponse(ctx, work, *resp)
+				continue
+			case errors.Is(err, arc.ErrTxNotFound):
+				known = true
 			default:
-				// Client already timed out, no-op
+				slog.WarnContext(ctx, "ARC status lookup failed, skipping lookups for rest of batch", logging.Err(err))
+				lookups = false
 			}
-			close(work.ResponseChan)
 		}
+
+		t.db.AppendRequestAttempt(ctx, work.UUID, attempt)
+
+		if retriable && work.Attempts < t.maxAttempts {
+			backoff := retryBackoff(work.Attempts)
+			work.NotBefore = time.Now().Add(backoff)
+			work.EnqueuedAt = work.NotBefore
+			msg := fmt.Sprintf("attempt %d failed, retrying in %v: %v", work.Attempts, backoff, batchErr)
+			t.db.UpdateRequestStatus(ctx, work.UUID, models.RequestStatusPending, "", "", msg)
+			requeued = append(requeued, work)
+			continue
+		}
+
+		t.db.UpdateRequestStatus(ctx, work.UUID, models.RequestStatusFailed, "", "", batchErr.Error())
+		if known {
+			t.db.UnlockUTXO(ctx, work.UTXOUsed)
+			work.unsent = true
+		} else {
+			// We can't tell whether ARC has the tx, so don't hand the UTXO
+			// to another request; the janitor checks with ARC before reclaiming it
+			t.db.ExpireLease(ctx, work.UTXOUsed)
+			slog.WarnContext(work.logContext(ctx), "Leaving UTXO to the janitor: transaction status unknown", logging.KeyUTXO, work.UTXOUsed)
+		}
+		t.complete(work, models.BroadcastResult{Error: batchErr})
+	}
+
+	// Back at the front in their original order, so a parent still goes
+	// out ahead of its child
+	for i := len(requeued) - 1; i >= 0; i-- {
+		t.laneFor(requeued[i].Priority).pushFront(requeued[i])
+	}
+	if len(requeued) > 0 {
+		slog.InfoContext(ctx, "Requeued transactions for retry", "count", len(requeued))
+	}
+}
+
+// lookupStatus asks ARC for a transaction's status with a short timeout
+func (t *Train) lookupStatus(ctx context.Context, txid string) (*arc.TxResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	return t.arcClient.GetTransactionStatus(ctx, txid)
+}
+
+// retryBackoff returns the delay before the given retry attempt
+func retryBackoff(attempt int) time.Duration {
+	backoff := 2 * time.Second << uint(attempt-1)
+	if backoff > time.Minute || backoff <= 0 {
+		backoff = time.Minute
+	}
+	return backoff
+}
+
+// complete notifies a waiting client and frees the work's in-flight slot
+func (t *Train) complete(work TxWork, result models.BroadcastResult) {
+	t.release(work)
+	t.finish(work, result)
+}
+
+// finish records a result, refunds the client's quota if ARC never got the
+// tx and notifies a waiting client, for work whose in-flight slot is already free
+func (t *Train) finish(work TxWork, result models.BroadcastResult) {
+	finishWorkSpan(work, result)
+
+	outcome := metrics.OutcomeSuccess
+	if result.Error != nil {
+		outcome = metrics.OutcomeFailed
+	}
+	metrics.ObservePublish(work.ClientID, work.Tier, outcome, work.SubmittedAt)
+
+	if work.unsent && work.Refund != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := work.Refund(ctx); err != nil {
+			slog.ErrorContext(work.logContext(ctx), "Failed to refund quota", logging.Err(err))
+		}
+		cancel()
 	}
 
-	log.Printf("✓ Batch complete: %d success, %d failed", successCount, failCount)
+	// Notify waiting client if they're listening (sync mode)
+	if work.ResponseChan != nil {
+		// Non-blocking send (client may have timed out)
+		select {
+		case work.ResponseChan <- result:
+			// Successfully notified
+		default:
+			// Client already timed out, no-op
+		}
+		close(work.ResponseChan)
+	}
 }
 
-// QueueSize returns the current number of transactions waiting
+// QueueSize returns the current number of transactions waiting across all lanes
 func (t *Train) QueueSize() int {
-	return len(t.txQueue)
+	total := 0
+	for _, l := range t.lanes {
+		total += l.len()
+	}
+	return total
+}
+
+// LaneDepths returns the number of transactions waiting in each priority lane
+func (t *Train) LaneDepths() map[string]int {
+	depths := make(map[string]int, len(t.lanes))
+	for _, l := range t.lanes {
+		depths[string(l.priority)] = l.len()
+	}
+	return depths
+}
+
+// SchedulerStats returns the scheduler's current decisions and counters
+func (t *Train) SchedulerStats() SchedulerSnapshot {
+	return t.scheduler.Snapshot()
+}
+
+// SchedulerState reports the scheduler's state for the scheduler gauges
+func (t *Train) SchedulerState() metrics.SchedulerState {
+	snap := t.scheduler.Snapshot()
+	return metrics.SchedulerState{
+		Mode:              snap.Mode,
+		BatchSize:         snap.BatchSize,
+		Interval:          time.Duration(snap.IntervalMs) * time.Millisecond,
+		ARCLatency:        time.Duration(snap.ARCLatencyMs) * time.Millisecond,
+		ConsecutiveErrors: snap.ConsecutiveErrors,
+		Departures:        snap.Departures,
+		Adjustments:       snap.Adjustments,
+	}
 }
 
 // IsRunning returns true if the train is still processing
@@ -0,0 +1,513 @@
+package train
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/akua/bsv-broadcaster/internal/arc"
+	"github.com/akua/bsv-broadcaster/internal/arc/arctest"
+	"github.com/akua/bsv-broadcaster/internal/database"
+	"github.com/akua/bsv-broadcaster/internal/models"
+	ec "github.com/bsv-blockchain/go-sdk/primitives/ec"
+	"github.com/bsv-blockchain/go-sdk/script"
+	"github.com/bsv-blockchain/go-sdk/transaction"
+	"github.com/bsv-blockchain/go-sdk/transaction/template/p2pkh"
+)
+
+const testMaxAttempts = 3
+
+// trainHarness is a train that isn't started, so tests drive its batches
+// by hand against a fake ARC
+type trainHarness struct {
+	*Train
+	db   *database.MemoryStore
+	arc  *arctest.Server
+	key  *ec.PrivateKey
+	next int
+}
+
+func newTrainHarness(t *testing.T) *trainHarness {
+	t.Helper()
+
+	db := database.NewMemoryStore()
+	db.SetLockOwner(database.NewLockOwner(time.Minute))
+
+	key, err := ec.NewPrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := arctest.NewServer()
+	t.Cleanup(fake.Close)
+
+	tr := NewTrain(db, fake.Client(), NewStaticScheduler(time.Hour, 10), 1, testMaxAttempts)
+	return &trainHarness{Train: tr, db: db, arc: fake, key: key}
+}
+
+// work locks a fresh publishing UTXO, spends it in an OP_RETURN transaction
+// and saves the request, the way the publish handler does
+func (h *trainHarness) work(t *testing.T, clientID string) TxWork {
+	t.Helper()
+	ctx := context.Background()
+
+	h.next++
+	addr, err := script.NewAddressFromPublicKey(h.key.PubKey(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock, err := p2pkh.Lock(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sourceTxID := fmt.Sprintf("%064x", h.next)
+	err = h.db.InsertUTXO(ctx, &models.UTXO{
+		Outpoint:     sourceTxID + ":0",
+		TxID:         sourceTxID,
+		Satoshis:     100,
+		ScriptPubKey: lock.String(),
+		Status:       models.UTXOStatusAvailable,
+		Type:         models.UTXOTypePublishing,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	utxo, err := h.db.FindAndLockUTXO(ctx, models.UTXOTypePublishing)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rawHex, txid := h.spend(t, utxo, fmt.Sprintf("payload %d", h.next))
+
+	work := TxWork{
+		UUID:     fmt.Sprintf("request-%d", h.next),
+		RawTxHex: rawHex,
+		UTXOUsed: utxo.Outpoint,
+		TxID:     txid,
+		Priority: PriorityNormal,
+		ClientID: clientID,
+	}
+	err = h.db.InsertBroadcastRequest(ctx, &models.BroadcastRequest{
+		UUID:     work.UUID,
+		RawTxHex: rawHex,
+		UTXOUsed: utxo.Outpoint,
+		Status:   models.RequestStatusPending,
+		ClientID: clientID,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return work
+}
+
+// spend signs a transaction moving utxo into an OP_RETURN carrying payload
+func (h *trainHarness) spend(t *testing.T, utxo *models.UTXO, payload string) (string, string) {
+	t.Helper()
+
+	unlocker, err := p2pkh.Unlock(h.key, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := transaction.NewTransaction()
+	if err := tx.AddInputFrom(utxo.TxID, utxo.Vout, utxo.ScriptPubKey, utxo.Satoshis, unlocker); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.AddOpReturnOutput([]byte(payload)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Sign(); err != nil {
+		t.Fatal(err)
+	}
+	return tx.Hex(), tx.TxID().String()
+}
+
+// request returns the stored request
+func (h *trainHarness) request(t *testing.T, uuid string) *models.BroadcastRequest {
+	t.Helper()
+
+	req, err := h.db.GetRequestByUUID(context.Background(), uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+// utxo returns the stored UTXO at outpoint
+func (h *trainHarness) utxo(t *testing.T, outpoint string) *models.UTXO {
+	t.Helper()
+
+	for _, status := range []models.UTXOStatus{models.UTXOStatusAvailable, models.UTXOStatusLocked, models.UTXOStatusSpent} {
+		utxos, err := h.db.FindUTXOsByType(context.Background(), models.UTXOTypePublishing, status)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, utxo := range utxos {
+			if utxo.Outpoint == outpoint {
+				return utxo
+			}
+		}
+	}
+	t.Fatalf("UTXO %s not found", outpoint)
+	return nil
+}
+
+// queued pops everything waiting in the normal lane
+func (h *trainHarness) queued() []TxWork {
+	var works []TxWork
+	for {
+		work, ok := h.laneFor(PriorityNormal).pop()
+		if !ok {
+			return works
+		}
+		works = append(works, work)
+	}
+}
+
+func assertRequest(t *testing.T, h *trainHarness, work TxWork, status models.RequestStatus) *models.BroadcastRequest {
+	t.Helper()
+
+	req := h.request(t, work.UUID)
+	if req.Status != status {
+		t.Fatalf("request %s status = %s (%s), want %s", work.UUID, req.Status, req.Error, status)
+	}
+	return req
+}
+
+func assertUTXO(t *testing.T, h *trainHarness, work TxWork, status models.UTXOStatus) *models.UTXO {
+	t.Helper()
+
+	utxo := h.utxo(t, work.UTXOUsed)
+	if utxo.Status != status {
+		t.Fatalf("UTXO %s status = %s, want %s", work.UTXOUsed, utxo.Status, status)
+	}
+	return utxo
+}
+
+func TestBroadcastBatchAcceptsEveryAnswer(t *testing.T) {
+	h := newTrainHarness(t)
+	batch := []TxWork{h.work(t, "client"), h.work(t, "client"), h.work(t, "client")}
+
+	h.broadcastBatch(batch)
+
+	for _, work := range batch {
+		req := assertRequest(t, h, work, models.RequestStatusSuccess)
+		if req.TxID != work.TxID {
+			t.Fatalf("request txid = %s, want %s", req.TxID, work.TxID)
+		}
+		assertUTXO(t, h, work, models.UTXOStatusSpent)
+	}
+	if got := h.arc.BatchCount(); got != 1 {
+		t.Fatalf("batches = %d, want 1", got)
+	}
+}
+
+func TestBroadcastBatchLooksUpTransactionsLeftOutOfAShortResponse(t *testing.T) {
+	h := newTrainHarness(t)
+	batch := []TxWork{h.work(t, "client"), h.work(t, "client"), h.work(t, "client")}
+	h.arc.Omit(batch[1].TxID)
+
+	h.broadcastBatch(batch)
+
+	// ARC processed the omitted transaction, so the status lookup settles it
+	for _, work := range batch {
+		assertRequest(t, h, work, models.RequestStatusSuccess)
+		assertUTXO(t, h, work, models.UTXOStatusSpent)
+	}
+	req := h.request(t, batch[1].UUID)
+	if len(req.Attempts) != 1 || req.Attempts[0].Error != errNoResponse.Error() {
+		t.Fatalf("attempts = %+v, want one recording the missing answer", req.Attempts)
+	}
+	if queued := h.queued(); len(queued) != 0 {
+		t.Fatalf("%d transactions requeued, want none", len(queued))
+	}
+}
+
+func TestBroadcastBatchIgnoresUnknownAndDuplicateAnswers(t *testing.T) {
+	h := newTrainHarness(t)
+	batch := []TxWork{h.work(t, "client"), h.work(t, "client")}
+
+	// A stray answer for a txid nobody sent, and a second, contradicting
+	// answer for one that was
+	h.arc.Extra(arc.TxResponse{TxID: strings.Repeat("ab", 32), TxStatus: arc.TxStatusRejected})
+	h.arc.Extra(arc.TxResponse{TxID: batch[0].TxID, TxStatus: arc.TxStatusRejected, ExtraInfo: "second answer"})
+
+	h.broadcastBatch(batch)
+
+	for _, work := range batch {
+		assertRequest(t, h, work, models.RequestStatusSuccess)
+		assertUTXO(t, h, work, models.UTXOStatusSpent)
+	}
+	if got := h.inFlight["client"]; got != 0 {
+		t.Fatalf("client in flight = %d, want 0", got)
+	}
+}
+
+// countRefunds counts the quota refunds the train makes for work
+func countRefunds(work *TxWork) *int {
+	refunds := new(int)
+	work.Refund = func(ctx context.Context) error {
+		*refunds++
+		return nil
+	}
+	return refunds
+}
+
+func TestBroadcastBatchRequeuesRetriableFailures(t *testing.T) {
+	h := newTrainHarness(t)
+	batch := []TxWork{h.work(t, "client"), h.work(t, "client")}
+	refunds := countRefunds(&batch[0])
+	for _, work := range batch {
+		if err := h.acquire(work); err != nil {
+			t.Fatal(err)
+		}
+	}
+	h.arc.FailNext(503, 1)
+
+	before := time.Now()
+	h.broadcastBatch(batch)
+
+	queued := h.queued()
+	if len(queued) != len(batch) {
+		t.Fatalf("%d transactions requeued, want %d", len(queued), len(batch))
+	}
+	for i, work := range queued {
+		if work.TxID != batch[i].TxID {
+			t.Fatalf("requeued[%d] = %s, want %s (order kept)", i, work.TxID, batch[i].TxID)
+		}
+		if work.Attempts != 1 {
+			t.Fatalf("attempts = %d, want 1", work.Attempts)
+		}
+		if !work.NotBefore.After(before.Add(retryBackoff(1) - time.Second)) {
+			t.Fatalf("NotBefore = %v, want about %v from now", work.NotBefore, retryBackoff(1))
+		}
+		assertRequest(t, h, work, models.RequestStatusPending)
+		assertUTXO(t, h, work, models.UTXOStatusLocked)
+	}
+
+	// Requeued work keeps its in-flight slots, and its quota until it's done
+	if got := h.inFlight["client"]; got != len(batch) {
+		t.Fatalf("client in flight = %d, want %d", got, len(batch))
+	}
+	if *refunds != 0 {
+		t.Fatalf("%d refunds for requeued work, want none", *refunds)
+	}
+}
+
+func TestBroadcastBatchFailsAfterMaxAttempts(t *testing.T) {
+	h := newTrainHarness(t)
+	work := h.work(t, "client")
+	work.Attempts = testMaxAttempts - 1
+	work.ResponseChan = make(chan models.BroadcastResult, 1)
+	refunds := countRefunds(&work)
+	if err := h.acquire(work); err != nil {
+		t.Fatal(err)
+	}
+	h.arc.FailNext(503, 1)
+
+	h.broadcastBatch([]TxWork{work})
+
+	if queued := h.queued(); len(queued) != 0 {
+		t.Fatalf("%d transactions requeued, want none", len(queued))
+	}
+	req := assertRequest(t, h, work, models.RequestStatusFailed)
+	if len(req.Attempts) != 1 || req.Attempts[0].Attempt != testMaxAttempts {
+		t.Fatalf("attempts = %+v, want attempt %d recorded", req.Attempts, testMaxAttempts)
+	}
+
+	// ARC never saw it, so the UTXO is safe to reuse and the client isn't
+	// charged for it
+	assertUTXO(t, h, work, models.UTXOStatusAvailable)
+	if *refunds != 1 {
+		t.Fatalf("%d quota refunds, want 1", *refunds)
+	}
+
+	result := <-work.ResponseChan
+	if result.Error == nil {
+		t.Fatal("waiting client got no error")
+	}
+	if got := h.inFlight["client"]; got != 0 {
+		t.Fatalf("client in flight = %d, want 0", got)
+	}
+}
+
+func TestBroadcastBatchFailsNonRetriableErrorsAtOnce(t *testing.T) {
+	h := newTrainHarness(t)
+	work := h.work(t, "client")
+	refunds := countRefunds(&work)
+	h.arc.FailNext(400, 1)
+
+	h.broadcastBatch([]TxWork{work})
+
+	if queued := h.queued(); len(queued) != 0 {
+		t.Fatalf("%d transactions requeued, want none", len(queued))
+	}
+	assertRequest(t, h, work, models.RequestStatusFailed)
+	assertUTXO(t, h, work, models.UTXOStatusAvailable)
+	if *refunds != 1 {
+		t.Fatalf("%d quota refunds, want 1", *refunds)
+	}
+}
+
+func TestBroadcastBatchKeepsTransactionsMinedInStaleBlocks(t *testing.T) {
+	h := newTrainHarness(t)
+	work := h.work(t, "client")
+	h.arc.SetDefaultStatus(arc.TxStatusMinedInStaleBlock)
+
+	h.broadcastBatch([]TxWork{work})
+
+	req := assertRequest(t, h, work, models.RequestStatusSuccess)
+	if req.ARCStatus != string(arc.TxStatusMinedInStaleBlock) {
+		t.Fatalf("ARC status = %s, want %s", req.ARCStatus, arc.TxStatusMinedInStaleBlock)
+	}
+	assertUTXO(t, h, work, models.UTXOStatusSpent)
+}
+
+func TestBroadcastBatchLeavesOrphansPending(t *testing.T) {
+	h := newTrainHarness(t)
+	work := h.work(t, "client")
+	if err := h.acquire(work); err != nil {
+		t.Fatal(err)
+	}
+	h.arc.Orphan(work.TxID)
+
+	h.broadcastBatch([]TxWork{work})
+
+	// ARC may still broadcast it once the parent turns up
+	req := assertRequest(t, h, work, models.RequestStatusPending)
+	if req.TxID != work.TxID || req.ARCStatus != string(arc.TxStatusSeenInOrphanMempool) || req.Error != "" {
+		t.Fatalf("request = %s %s %q, want the orphan's txid and ARC status", req.TxID, req.ARCStatus, req.Error)
+	}
+
+	// The UTXO is left to the janitor rather than reused or written off
+	utxo := assertUTXO(t, h, work, models.UTXOStatusLocked)
+	if utxo.LockOwner != "" || utxo.LeaseExpiresAt == nil || utxo.LeaseExpiresAt.After(time.Now()) {
+		t.Fatalf("UTXO lease = %q until %v, want it expired for the janitor", utxo.LockOwner, utxo.LeaseExpiresAt)
+	}
+	if got, ok := h.inFlight["client"]; ok {
+		t.Fatalf("client in flight = %d, want the slot released", got)
+	}
+}
+
+func TestBroadcastBatchUnlocksRejectedTransactions(t *testing.T) {
+	h := newTrainHarness(t)
+	batch := []TxWork{h.work(t, "client"), h.work(t, "client")}
+	refunds := countRefunds(&batch[0])
+	h.arc.Reject(batch[0].TxID, "script verification failed")
+
+	h.broadcastBatch(batch)
+
+	req := assertRequest(t, h, batch[0], models.RequestStatusFailed)
+	if !strings.Contains(req.Error, "script verification failed") {
+		t.Fatalf("request error = %q, want ARC's reason", req.Error)
+	}
+	assertUTXO(t, h, batch[0], models.UTXOStatusAvailable)
+
+	// ARC did the work of judging it, so it stays charged
+	if *refunds != 0 {
+		t.Fatalf("%d quota refunds for a rejected transaction, want none", *refunds)
+	}
+
+	assertRequest(t, h, batch[1], models.RequestStatusSuccess)
+	assertUTXO(t, h, batch[1], models.UTXOStatusSpent)
+}
+
+func TestTrainAnswersWaitingClientsThroughSlowARC(t *testing.T) {
+	h := newTrainHarness(t)
+	h.Train = NewTrain(h.db, h.arc.Client(), NewStaticScheduler(10*time.Millisecond, 10), 2, testMaxAttempts)
+	h.arc.SetLatency(50 * time.Millisecond)
+	h.Start()
+	defer h.Stop()
+
+	works := make([]TxWork, 5)
+	for i := range works {
+		works[i] = h.work(t, "client")
+		works[i].ResponseChan = make(chan models.BroadcastResult, 1)
+		if err := h.Enqueue(works[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, work := range works {
+		select {
+		case result := <-work.ResponseChan:
+			if result.Error != nil || result.TXID != work.TxID {
+				t.Fatalf("result = %+v, want %s accepted", result, work.TxID)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no answer for %s", work.TxID)
+		}
+	}
+
+	// A block later, status lookups see them mined
+	h.arc.Mine()
+	for _, work := range works {
+		resp, err := h.lookupStatus(context.Background(), work.TxID)
+		if err != nil || resp.Outcome() != arc.OutcomeMined {
+			t.Fatalf("status of %s = %+v (%v), want mined", work.TxID, resp, err)
+		}
+	}
+}
+
+func TestEnqueueCapsClientInFlight(t *testing.T) {
+	h := newTrainHarness(t)
+	enqueue := func(clientID string) (TxWork, error) {
+		work := h.work(t, clientID)
+		work.MaxInFlight = 2
+		return work, h.Enqueue(work)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := enqueue("client"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := enqueue("client"); !errors.Is(err, ErrClientBacklog) {
+		t.Fatalf("enqueue over the cap = %v, want ErrClientBacklog", err)
+	}
+	// The cap is per client
+	if _, err := enqueue("other"); err != nil {
+		t.Fatalf("another client's enqueue = %v", err)
+	}
+
+	// A completed broadcast frees its slot
+	h.broadcastBatch(h.assembleBatch(1))
+	if got := h.inFlight["client"]; got != 1 {
+		t.Fatalf("client in flight after a broadcast = %d, want 1", got)
+	}
+	if _, err := enqueue("client"); err != nil {
+		t.Fatalf("enqueue after a broadcast completed = %v", err)
+	}
+}
+
+func TestLostDoubleSpendReleasesInFlight(t *testing.T) {
+	h := newTrainHarness(t)
+	next := func() TxWork {
+		work := h.work(t, "client")
+		work.MaxInFlight = 1
+		return work
+	}
+	work := next()
+	competitor := contest(t, h, work)
+	if err := h.Enqueue(work); err != nil {
+		t.Fatal(err)
+	}
+
+	// A contested transaction keeps its slot while it waits
+	requeued := contested(t, h, h.queued()[0])
+	if err := h.Enqueue(next()); !errors.Is(err, ErrClientBacklog) {
+		t.Fatalf("enqueue while contested = %v, want ErrClientBacklog", err)
+	}
+
+	h.arc.Mine(competitor)
+	h.broadcastBatch([]TxWork{requeued})
+	assertRequest(t, h, work, models.RequestStatusFailed)
+	if got, ok := h.inFlight["client"]; ok {
+		t.Fatalf("client in flight after losing = %d, want none", got)
+	}
+	if err := h.Enqueue(next()); err != nil {
+		t.Fatalf("enqueue after losing the double spend = %v", err)
+	}
+}